@@ -0,0 +1,126 @@
+package api
+
+import (
+	"app-bookstore/config"
+	"app-bookstore/lib"
+	"app-bookstore/model"
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+var ErrBookNotFound = lib.NotFound("book not found")
+
+// AccessPolicy decides which book access levels a user may see and borrow,
+// based on the identifiers of the roles they hold.
+type AccessPolicy struct {
+	db            *sqlx.DB
+	levelsByRole  map[string][]string
+	defaultLevels []string
+	adminRoles    []string
+}
+
+func NewAccessPolicy(db *sqlx.DB, cfg config.Access) *AccessPolicy {
+	return &AccessPolicy{
+		db:            db,
+		levelsByRole:  cfg.LevelsByRole,
+		defaultLevels: cfg.DefaultLevels,
+		adminRoles:    cfg.AdminRoles,
+	}
+}
+
+// AllowedLevels returns the union of access levels granted by the user's roles.
+// Users whose roles are not in the mapping fall back to the default levels.
+func (ap *AccessPolicy) AllowedLevels(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	identifiers, err := model.GetUserRoleIdentifiers(ctx, ap.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	levels := []string{}
+	mapped := false
+	for _, identifier := range identifiers {
+		roleLevels, ok := ap.levelsByRole[identifier]
+		if !ok {
+			continue
+		}
+
+		mapped = true
+		for _, level := range roleLevels {
+			if !slices.Contains(levels, level) {
+				levels = append(levels, level)
+			}
+		}
+	}
+
+	if !mapped {
+		levels = append(levels, ap.defaultLevels...)
+	}
+
+	return levels, nil
+}
+
+func (ap *AccessPolicy) Allows(ctx context.Context, userID uuid.UUID, accessLevel string) (bool, error) {
+	levels, err := ap.AllowedLevels(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return slices.Contains(levels, accessLevel), nil
+}
+
+// IsAdmin reports whether any of the user's roles is configured as an admin role.
+func (ap *AccessPolicy) IsAdmin(ctx context.Context, userID uuid.UUID) (bool, error) {
+	identifiers, err := model.GetUserRoleIdentifiers(ctx, ap.db, userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, identifier := range identifiers {
+		if slices.Contains(ap.adminRoles, identifier) {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// CheckIncludeDeleted rejects include_deleted for users who are not admins.
+func (ap *AccessPolicy) CheckIncludeDeleted(ctx context.Context, filter lib.Filter) error {
+	if !filter.IncludeDeleted {
+		return nil
+	}
+
+	claims, err := lib.ClaimsFromContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return lib.Unauthorized("failed to parse access token")
+	}
+
+	admin, err := ap.IsAdmin(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !admin {
+		return lib.Forbidden("include_deleted is only available to admins")
+	}
+
+	return nil
+}
+
+// notifyPermissionChange asks every server instance to drop the cached
+// permissions in scope. A failure is only logged; cached entries still expire.
+func notifyPermissionChange(ctx context.Context, db sqlx.ExtContext, scope string, key string) {
+	err := model.NotifyPermissionChange(ctx, db, scope, key)
+	if err != nil {
+		log.Error().Err(err).Str("scope", scope).Msg("failed to notify permission change")
+	}
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"app-bookstore/lib"
+	"app-bookstore/model"
+	"context"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/types"
+	"github.com/rs/zerolog/log"
+)
+
+type AuditLogModule struct {
+	db   *sqlx.DB
+	name string
+	JWT  lib.Jwt
+}
+
+func NewAuditLogModule(db *sqlx.DB, jwt lib.Jwt) *AuditLogModule {
+	return &AuditLogModule{
+		db:   db,
+		name: "audit-log-module",
+		JWT:  jwt,
+	}
+}
+
+func (al *AuditLogModule) List(ctx context.Context, filter lib.Filter, auditFilter model.AuditLogFilter, dateFilter model.DateFilter) ([]model.AuditLogResponse, lib.Pagination, error) {
+	auditLogs, total, err := model.GetAllAuditLogs(ctx, al.db, filter, auditFilter, dateFilter)
+	if err != nil {
+		return nil, lib.Pagination{}, err
+	}
+
+	auditLogs, hasMore := lib.TrimPage(filter, auditLogs)
+
+	var response []model.AuditLogResponse
+	var last lib.Cursor
+	for _, auditLog := range auditLogs {
+		response = append(response, auditLog.Response())
+		last = lib.Cursor{CreatedAt: auditLog.CreatedAt, ID: auditLog.ID}
+	}
+
+	return response, lib.NewPagination(filter, total, len(auditLogs), hasMore, last), nil
+}
+
+// auditEntry describes one change. Before and After are snapshots of the entity
+// (usually its Response()) and are stored as JSON; leave them nil when absent.
+type auditEntry struct {
+	Actor    uuid.UUID
+	Action   string
+	Entity   string
+	EntityID uuid.UUID
+	Before   interface{}
+	After    interface{}
+	Details  interface{}
+}
+
+// recordAudit writes entry once the change it describes has been committed. A
+// failure is logged rather than returned because the change already happened.
+func recordAudit(ctx context.Context, db sqlx.ExtContext, entry auditEntry) {
+	meta := lib.RequestMetaFromContext(ctx)
+
+	auditLog := model.AuditLogModel{
+		UserID:     uuid.NullUUID{UUID: entry.Actor, Valid: entry.Actor != uuid.Nil},
+		Action:     entry.Action,
+		Entity:     entry.Entity,
+		EntityID:   uuid.NullUUID{UUID: entry.EntityID, Valid: entry.EntityID != uuid.Nil},
+		BeforeData: auditJSON(entry.Before),
+		AfterData:  auditJSON(entry.After),
+		Details:    auditJSON(entry.Details),
+		RequestID:  meta.RequestID,
+		ClientIP:   meta.ClientIP,
+	}
+
+	err := auditLog.Insert(ctx, db)
+	if err != nil {
+		log.Error().Err(err).
+			Str("action", entry.Action).
+			Str("entity", entry.Entity).
+			Str("entity_id", entry.EntityID.String()).
+			Str("request_id", meta.RequestID).
+			Msg("failed to write audit log")
+	}
+}
+
+func auditJSON(value interface{}) types.NullJSONText {
+	if value == nil {
+		return types.NullJSONText{}
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		log.Error().Err(err).Msg("failed to encode audit snapshot")
+		return types.NullJSONText{}
+	}
+
+	return types.NullJSONText{JSONText: raw, Valid: true}
+}
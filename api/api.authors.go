@@ -4,7 +4,6 @@ import (
 	"app-bookstore/lib"
 	"app-bookstore/model"
 	"context"
-	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,16 +12,18 @@ import (
 )
 
 type AuthorsModule struct {
-	db   *sqlx.DB
-	name string
-	JWT  lib.Jwt
+	db     *sqlx.DB
+	name   string
+	JWT    lib.Jwt
+	access *AccessPolicy
 }
 
-func NewUserAuthorsModule(db *sqlx.DB, jwt lib.Jwt) *AuthorsModule {
+func NewUserAuthorsModule(db *sqlx.DB, jwt lib.Jwt, access *AccessPolicy) *AuthorsModule {
 	return &AuthorsModule{
-		db:   db,
-		name: "authors-module",
-		JWT:  jwt,
+		db:     db,
+		name:   "authors-module",
+		JWT:    jwt,
+		access: access,
 	}
 }
 
@@ -31,17 +32,26 @@ type AuthorsParam struct {
 	Bio  string `json:"bio"`
 }
 
-func (a *AuthorsModule) List(ctx context.Context, filter lib.Filter, dateFilter model.DateFilter) ([]model.AuthorsRespose, error) {
-	authorRequest, err := model.GetAllAuthors(ctx, a.db, filter, dateFilter)
+func (a *AuthorsModule) List(ctx context.Context, filter lib.Filter, dateFilter model.DateFilter) ([]model.AuthorsRespose, lib.Pagination, error) {
+	err := a.access.CheckIncludeDeleted(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, lib.Pagination{}, err
+	}
+
+	authorRequest, total, err := model.GetAllAuthors(ctx, a.db, filter, dateFilter)
+	if err != nil {
+		return nil, lib.Pagination{}, err
 	}
 
+	authorRequest, hasMore := lib.TrimPage(filter, authorRequest)
+
 	var response []model.AuthorsRespose
+	var last lib.Cursor
 	for _, author := range authorRequest {
 		response = append(response, author.Response())
+		last = lib.Cursor{CreatedAt: author.CreatedAt, ID: author.ID}
 	}
-	return response, err
+	return response, lib.NewPagination(filter, total, len(authorRequest), hasMore, last), nil
 }
 
 func (a *AuthorsModule) Detail(ctx context.Context, id uuid.UUID) (model.AuthorsRespose, error) {
@@ -55,12 +65,12 @@ func (a *AuthorsModule) Detail(ctx context.Context, id uuid.UUID) (model.Authors
 func (a *AuthorsModule) Create(ctx context.Context, token string, param AuthorsParam) (interface{}, error) {
 	claims, err := a.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired access token")
+		return nil, lib.Unauthorized("invalid or expired access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
 	}
 
 	author := model.AuthorsModel{
@@ -76,18 +86,31 @@ func (a *AuthorsModule) Create(ctx context.Context, token string, param AuthorsP
 		return nil, err
 	}
 
+	recordAudit(ctx, a.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditCreate,
+		Entity:   model.EntityAuthors,
+		EntityID: author.ID,
+		After:    author.Response(),
+	})
+
 	return author.Response(), nil
 }
 
 func (a *AuthorsModule) Update(ctx context.Context, token string, param AuthorsParam, id uuid.UUID) (interface{}, error) {
 	claims, err := a.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired access token")
+		return nil, lib.Unauthorized("invalid or expired access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOneAuthors(ctx, a.db, id)
+	if err != nil {
+		return nil, err
 	}
 
 	authors := model.AuthorsModel{
@@ -109,5 +132,120 @@ func (a *AuthorsModule) Update(ctx context.Context, token string, param AuthorsP
 		return nil, err
 	}
 
+	recordAudit(ctx, a.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditUpdate,
+		Entity:   model.EntityAuthors,
+		EntityID: id,
+		Before:   before.Response(),
+		After:    authors.Response(),
+	})
+
 	return authors.Response(), nil
 }
+
+func (a *AuthorsModule) Restore(ctx context.Context, token string, id uuid.UUID) (model.AuthorsRespose, error) {
+	claims, err := a.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return model.AuthorsRespose{}, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return model.AuthorsRespose{}, lib.Unauthorized("failed to parse access token")
+	}
+
+	err = model.Restore(ctx, a.db, model.TableAuthors, id, userID)
+	if err != nil {
+		return model.AuthorsRespose{}, err
+	}
+
+	restored, err := a.Detail(ctx, id)
+	if err != nil {
+		return model.AuthorsRespose{}, err
+	}
+
+	recordAudit(ctx, a.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditRestore,
+		Entity:   model.EntityAuthors,
+		EntityID: id,
+		After:    restored,
+	})
+
+	return restored, nil
+}
+
+// Delete soft deletes the author. An author with live books is refused unless
+// force is set, in which case the books are deleted with it; books that are on
+// loan or held are never deleted so loan history stays intact.
+func (a *AuthorsModule) Delete(ctx context.Context, token string, id uuid.UUID, force bool) error {
+	claims, err := a.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOneAuthors(ctx, a.db, id)
+	if err != nil {
+		return err
+	}
+
+	tx, err := a.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	bookIDs, err := model.GetActiveBookIDsByAuthor(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	if len(bookIDs) > 0 {
+		if !force {
+			return lib.Conflict("author still has active books, set force=true to delete them too")
+		}
+
+		circulating, err := model.CountBookCirculation(ctx, tx, bookIDs)
+		if err != nil {
+			return err
+		}
+
+		if circulating > 0 {
+			return lib.Conflict("author has books that are on loan or reserved")
+		}
+
+		for _, bookID := range bookIDs {
+			err = model.SoftDelete(ctx, tx, model.TableBooks, bookID, userID)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	err = model.SoftDelete(ctx, tx, model.TableAuthors, id, userID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, a.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditDelete,
+		Entity:   model.EntityAuthors,
+		EntityID: id,
+		Before:   before.Response(),
+		Details:  map[string]interface{}{"force": force, "book_ids": bookIDs},
+	})
+
+	return nil
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"app-bookstore/lib"
+	"app-bookstore/model"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type BookCopyModule struct {
+	db   *sqlx.DB
+	name string
+	JWT  lib.Jwt
+}
+
+func NewBookCopiesModule(db *sqlx.DB, jwt lib.Jwt) *BookCopyModule {
+	return &BookCopyModule{
+		db:   db,
+		name: "book-copies-module",
+		JWT:  jwt,
+	}
+}
+
+type BookCopyParam struct {
+	Barcode         string    `json:"barcode" validate:"required"`
+	Condition       string    `json:"condition" validate:"omitempty,oneof=new good fair poor damaged"`
+	ShelfLocation   string    `json:"shelf_location"`
+	AcquisitionDate time.Time `json:"acquisition_date"`
+	Status          string    `json:"status" validate:"omitempty,oneof=available withdrawn"`
+}
+
+func (bc *BookCopyModule) List(ctx context.Context, bookID uuid.UUID, filter lib.Filter) ([]model.BookCopyResponse, lib.Pagination, error) {
+	bookCopies, total, err := model.GetAllBookCopies(ctx, bc.db, bookID, filter)
+	if err != nil {
+		return nil, lib.Pagination{}, err
+	}
+
+	bookCopies, hasMore := lib.TrimPage(filter, bookCopies)
+
+	var response []model.BookCopyResponse
+	var last lib.Cursor
+	for _, bookCopy := range bookCopies {
+		response = append(response, bookCopy.Response())
+		last = lib.Cursor{CreatedAt: bookCopy.CreatedAt, ID: bookCopy.ID}
+	}
+
+	return response, lib.NewPagination(filter, total, len(bookCopies), hasMore, last), nil
+}
+
+func (bc *BookCopyModule) Detail(ctx context.Context, id uuid.UUID) (model.BookCopyResponse, error) {
+	bookCopy, err := model.GetOneBookCopy(ctx, bc.db, id)
+	if err != nil {
+		return model.BookCopyResponse{}, err
+	}
+
+	return bookCopy.Response(), nil
+}
+
+func (bc *BookCopyModule) Create(ctx context.Context, token string, param BookCopyParam, bookID uuid.UUID) (interface{}, error) {
+	claims, err := bc.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return nil, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	bookCopy := model.BookCopyModel{
+		ID:            uuid.New(),
+		BookID:        bookID,
+		Barcode:       param.Barcode,
+		Condition:     param.Condition,
+		ShelfLocation: param.ShelfLocation,
+		AcquisitionDate: pq.NullTime{
+			Time:  param.AcquisitionDate,
+			Valid: !param.AcquisitionDate.IsZero(),
+		},
+		Status:    lib.Available,
+		CreatedAt: time.Now(),
+		CreatedBy: userID,
+	}
+
+	if bookCopy.Condition == "" {
+		bookCopy.Condition = "good"
+	}
+
+	err = bookCopy.Insert(ctx, bc.db)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, bc.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditCreate,
+		Entity:   model.EntityBookCopies,
+		EntityID: bookCopy.ID,
+		After:    bookCopy.Response(),
+	})
+
+	return bookCopy.Response(), nil
+}
+
+func (bc *BookCopyModule) Update(ctx context.Context, token string, param BookCopyParam, id uuid.UUID) (interface{}, error) {
+	claims, err := bc.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return nil, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	current, err := model.GetOneBookCopy(ctx, bc.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// status borrowed dan on_hold hanya diatur oleh pinjaman dan reservasi
+	status := param.Status
+	if status == "" || current.Status == lib.Borrowed || current.Status == lib.OnHold {
+		status = current.Status
+	}
+
+	condition := param.Condition
+	if condition == "" {
+		condition = current.Condition
+	}
+
+	bookCopy := model.BookCopyModel{
+		ID:            id,
+		Barcode:       param.Barcode,
+		Condition:     condition,
+		ShelfLocation: param.ShelfLocation,
+		AcquisitionDate: pq.NullTime{
+			Time:  param.AcquisitionDate,
+			Valid: !param.AcquisitionDate.IsZero(),
+		},
+		Status: status,
+		UpdatedAt: pq.NullTime{
+			Time:  time.Now(),
+			Valid: true,
+		},
+		UpdatedBy: uuid.NullUUID{
+			UUID:  userID,
+			Valid: true,
+		},
+	}
+
+	err = bookCopy.Update(ctx, bc.db)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, bc.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditUpdate,
+		Entity:   model.EntityBookCopies,
+		EntityID: id,
+		Before:   current.Response(),
+		After:    bookCopy.Response(),
+	})
+
+	return bookCopy.Response(), nil
+}
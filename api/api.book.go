@@ -4,6 +4,7 @@ import (
 	"app-bookstore/lib"
 	"app-bookstore/model"
 	"context"
+	"database/sql"
 	"errors"
 	"time"
 
@@ -13,16 +14,18 @@ import (
 )
 
 type BookModule struct {
-	db   *sqlx.DB
-	name string
-	JWT  lib.Jwt
+	db     *sqlx.DB
+	name   string
+	JWT    lib.Jwt
+	access *AccessPolicy
 }
 
-func NewBooksModule(db *sqlx.DB, jwt lib.Jwt) *BookModule {
+func NewBooksModule(db *sqlx.DB, jwt lib.Jwt, access *AccessPolicy) *BookModule {
 	return &BookModule{
-		db:   db,
-		name: "books-module",
-		JWT:  jwt,
+		db:     db,
+		name:   "books-module",
+		JWT:    jwt,
+		access: access,
 	}
 }
 
@@ -33,41 +36,88 @@ type BookParam struct {
 	CategoryID    string `json:"category_id"`
 	PublishedYear int    `json:"published_year"`
 	ISBN          string `json:"isbn"`
-	Status        string `json:"status"`
 	AccessLevel   string `json:"access_level"`
 }
 
-func (b *BookModule) List(ctx context.Context, filter lib.Filter, dateFilter model.DateFilter) ([]model.BookResponse, error) {
-	bookRequest, err := model.GetAllBooks(ctx, b.db, filter, dateFilter)
+func (b *BookModule) List(ctx context.Context, token string, filter lib.Filter, dateFilter model.DateFilter) ([]model.BookResponse, lib.Pagination, error) {
+	err := b.access.CheckIncludeDeleted(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, lib.Pagination{}, err
+	}
+
+	claims, err := b.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return nil, lib.Pagination{}, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, lib.Pagination{}, lib.Unauthorized("failed to parse access token")
+	}
+
+	filter.AccessLevels, err = b.access.AllowedLevels(ctx, userID)
+	if err != nil {
+		return nil, lib.Pagination{}, err
+	}
+
+	bookRequest, total, err := model.GetAllBooks(ctx, b.db, filter, dateFilter)
+	if err != nil {
+		return nil, lib.Pagination{}, err
 	}
 
+	bookRequest, hasMore := lib.TrimPage(filter, bookRequest)
+
 	var response []model.BookResponse
+	var last lib.Cursor
 	for _, book := range bookRequest {
 		response = append(response, book.Response())
+		last = lib.Cursor{CreatedAt: book.CreatedAt, ID: book.ID}
 	}
 
-	return response, nil
+	return response, lib.NewPagination(filter, total, len(bookRequest), hasMore, last), nil
 }
 
-func (b *BookModule) Detail(ctx context.Context, id uuid.UUID) (model.BookResponse, error) {
+func (b *BookModule) Detail(ctx context.Context, token string, id uuid.UUID) (model.BookResponse, error) {
+	claims, err := b.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return model.BookResponse{}, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return model.BookResponse{}, lib.Unauthorized("failed to parse access token")
+	}
+
 	bookRequest, err := model.GetOneBooks(ctx, b.db, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return model.BookResponse{}, ErrBookNotFound
+		}
+		return model.BookResponse{}, err
+	}
+
+	// buku di luar level akses role diperlakukan seolah tidak ada
+	allowed, err := b.access.Allows(ctx, userID, bookRequest.AccessLevel)
 	if err != nil {
 		return model.BookResponse{}, err
 	}
+
+	if !allowed {
+		return model.BookResponse{}, ErrBookNotFound
+	}
+
 	return bookRequest.Response(), nil
 }
 
 func (b *BookModule) Create(ctx context.Context, token string, param BookParam) (interface{}, error) {
 	claims, err := b.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired access token")
+		return nil, lib.Unauthorized("invalid or expired access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
 	}
 
 	book := model.BookModel{
@@ -84,7 +134,6 @@ func (b *BookModule) Create(ctx context.Context, token string, param BookParam)
 		},
 		PublishedYear: param.PublishedYear,
 		ISBN:          param.ISBN,
-		Status:        param.Status,
 		AccessLevel:   param.AccessLevel,
 		CreatedAt:     time.Now(),
 		CreatedBy:     userID,
@@ -95,18 +144,31 @@ func (b *BookModule) Create(ctx context.Context, token string, param BookParam)
 		return nil, err
 	}
 
+	recordAudit(ctx, b.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditCreate,
+		Entity:   model.EntityBooks,
+		EntityID: book.ID,
+		After:    book.Response(),
+	})
+
 	return book.Response(), nil
 }
 
 func (b *BookModule) Update(ctx context.Context, token string, param BookParam, id uuid.UUID) (interface{}, error) {
 	claims, err := b.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired access token")
+		return nil, lib.Unauthorized("invalid or expired access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOneBooks(ctx, b.db, id)
+	if err != nil {
+		return nil, err
 	}
 
 	book := model.BookModel{
@@ -123,7 +185,6 @@ func (b *BookModule) Update(ctx context.Context, token string, param BookParam,
 		},
 		PublishedYear: param.PublishedYear,
 		ISBN:          param.ISBN,
-		Status:        param.Status,
 		AccessLevel:   param.AccessLevel,
 		UpdatedAt: pq.NullTime{
 			Time:  time.Now(),
@@ -140,5 +201,100 @@ func (b *BookModule) Update(ctx context.Context, token string, param BookParam,
 		return nil, err
 	}
 
-	return book.Response(), nil
+	recordAudit(ctx, b.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditUpdate,
+		Entity:   model.EntityBooks,
+		EntityID: id,
+		Before:   before.Response(),
+		After:    book.Response(),
+	})
+
+	return b.Detail(ctx, token, id)
+}
+
+func (b *BookModule) Restore(ctx context.Context, token string, id uuid.UUID) (model.BookResponse, error) {
+	claims, err := b.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return model.BookResponse{}, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return model.BookResponse{}, lib.Unauthorized("failed to parse access token")
+	}
+
+	err = model.Restore(ctx, b.db, model.TableBooks, id, userID)
+	if err != nil {
+		return model.BookResponse{}, err
+	}
+
+	restored, err := b.Detail(ctx, token, id)
+	if err != nil {
+		return model.BookResponse{}, err
+	}
+
+	recordAudit(ctx, b.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditRestore,
+		Entity:   model.EntityBooks,
+		EntityID: id,
+		After:    restored,
+	})
+
+	return restored, nil
+}
+
+// Delete soft deletes the book. Books with open loans or active holds are
+// refused; loans themselves are never touched.
+func (b *BookModule) Delete(ctx context.Context, token string, id uuid.UUID) error {
+	claims, err := b.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOneBooks(ctx, b.db, id)
+	if err != nil {
+		return err
+	}
+
+	tx, err := b.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	circulating, err := model.CountBookCirculation(ctx, tx, []uuid.UUID{id})
+	if err != nil {
+		return err
+	}
+
+	if circulating > 0 {
+		return lib.Conflict("book is on loan or reserved")
+	}
+
+	err = model.SoftDelete(ctx, tx, model.TableBooks, id, userID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, b.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditDelete,
+		Entity:   model.EntityBooks,
+		EntityID: id,
+		Before:   before.Response(),
+	})
+
+	return nil
 }
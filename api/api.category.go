@@ -4,7 +4,6 @@ import (
 	"app-bookstore/lib"
 	"app-bookstore/model"
 	"context"
-	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,16 +12,18 @@ import (
 )
 
 type CategoryModule struct {
-	db   *sqlx.DB
-	name string
-	JWT  lib.Jwt
+	db     *sqlx.DB
+	name   string
+	JWT    lib.Jwt
+	access *AccessPolicy
 }
 
-func NewCategoriesModule(db *sqlx.DB, jwt lib.Jwt) *CategoryModule {
+func NewCategoriesModule(db *sqlx.DB, jwt lib.Jwt, access *AccessPolicy) *CategoryModule {
 	return &CategoryModule{
-		db:   db,
-		name: "categories-module",
-		JWT:  jwt,
+		db:     db,
+		name:   "categories-module",
+		JWT:    jwt,
+		access: access,
 	}
 }
 
@@ -30,18 +31,27 @@ type CategoryParam struct {
 	Name string `json:"name"`
 }
 
-func (c *CategoryModule) List(ctx context.Context) ([]model.CategoryResponse, error) {
-	categoriesRequest, err := model.GetAllCategory(ctx, c.db)
+func (c *CategoryModule) List(ctx context.Context, filter lib.Filter) ([]model.CategoryResponse, lib.Pagination, error) {
+	err := c.access.CheckIncludeDeleted(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, lib.Pagination{}, err
+	}
+
+	categoriesRequest, total, err := model.GetAllCategory(ctx, c.db, filter)
+	if err != nil {
+		return nil, lib.Pagination{}, err
 	}
 
+	categoriesRequest, hasMore := lib.TrimPage(filter, categoriesRequest)
+
 	var response []model.CategoryResponse
+	var last lib.Cursor
 	for _, category := range categoriesRequest {
 		response = append(response, category.Response())
+		last = lib.Cursor{CreatedAt: category.CreatedAt, ID: category.ID}
 	}
 
-	return response, nil
+	return response, lib.NewPagination(filter, total, len(categoriesRequest), hasMore, last), nil
 }
 
 func (c *CategoryModule) Detail(ctx context.Context, id uuid.UUID) (model.CategoryResponse, error) {
@@ -56,12 +66,12 @@ func (c *CategoryModule) Detail(ctx context.Context, id uuid.UUID) (model.Catego
 func (c *CategoryModule) Create(ctx context.Context, token string, param CategoryParam) (interface{}, error) {
 	claims, err := c.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired token")
+		return nil, lib.Unauthorized("invalid or expired token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("invalid user id in token")
+		return nil, lib.Unauthorized("invalid user id in token")
 	}
 
 	category := model.CategoryModel{
@@ -76,18 +86,31 @@ func (c *CategoryModule) Create(ctx context.Context, token string, param Categor
 		return nil, err
 	}
 
+	recordAudit(ctx, c.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditCreate,
+		Entity:   model.EntityCategories,
+		EntityID: category.ID,
+		After:    category.Response(),
+	})
+
 	return category.Response(), nil
 }
 
 func (c *CategoryModule) Update(ctx context.Context, token string, param CategoryParam, id uuid.UUID) (interface{}, error) {
 	claims, err := c.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired token")
+		return nil, lib.Unauthorized("invalid or expired token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("invalid user id in token")
+		return nil, lib.Unauthorized("invalid user id in token")
+	}
+
+	before, err := model.GetOneCategories(ctx, c.db, id)
+	if err != nil {
+		return nil, err
 	}
 
 	category := model.CategoryModel{
@@ -108,5 +131,79 @@ func (c *CategoryModule) Update(ctx context.Context, token string, param Categor
 		return nil, err
 	}
 
+	recordAudit(ctx, c.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditUpdate,
+		Entity:   model.EntityCategories,
+		EntityID: id,
+		Before:   before.Response(),
+		After:    category.Response(),
+	})
+
 	return category.Response(), nil
 }
+
+// Delete soft deletes the category; the row stays in the table for history and restore.
+func (c *CategoryModule) Delete(ctx context.Context, token string, id uuid.UUID) error {
+	claims, err := c.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOneCategories(ctx, c.db, id)
+	if err != nil {
+		return err
+	}
+
+	err = model.SoftDelete(ctx, c.db, model.TableCategories, id, userID)
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, c.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditDelete,
+		Entity:   model.EntityCategories,
+		EntityID: id,
+		Before:   before.Response(),
+	})
+
+	return nil
+}
+
+func (c *CategoryModule) Restore(ctx context.Context, token string, id uuid.UUID) (model.CategoryResponse, error) {
+	claims, err := c.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return model.CategoryResponse{}, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return model.CategoryResponse{}, lib.Unauthorized("failed to parse access token")
+	}
+
+	err = model.Restore(ctx, c.db, model.TableCategories, id, userID)
+	if err != nil {
+		return model.CategoryResponse{}, err
+	}
+
+	restored, err := c.Detail(ctx, id)
+	if err != nil {
+		return model.CategoryResponse{}, err
+	}
+
+	recordAudit(ctx, c.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditRestore,
+		Entity:   model.EntityCategories,
+		EntityID: id,
+		After:    restored,
+	})
+
+	return restored, nil
+}
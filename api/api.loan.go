@@ -1,10 +1,13 @@
 package api
 
 import (
+	"app-bookstore/config"
 	"app-bookstore/lib"
 	"app-bookstore/model"
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,113 +15,299 @@ import (
 	"github.com/lib/pq"
 )
 
+var ErrLoanReturned = lib.Conflict("loan has already been returned")
+
 type LoansModule struct {
-	db   *sqlx.DB
-	name string
-	JWT  lib.Jwt
+	db     *sqlx.DB
+	name   string
+	JWT    lib.Jwt
+	policy config.Loan
+	access *AccessPolicy
 }
 
-func NewLoansModule(db *sqlx.DB, jwt lib.Jwt) *LoansModule {
+func NewLoansModule(db *sqlx.DB, jwt lib.Jwt, policy config.Loan, access *AccessPolicy) *LoansModule {
 	return &LoansModule{
-		db:   db,
-		name: "loans-module",
-		JWT:  jwt,
+		db:     db,
+		name:   "loans-module",
+		JWT:    jwt,
+		policy: policy,
+		access: access,
 	}
 }
 
 type LoansParam struct {
-	BookID     uuid.UUID `json:"book_id"`
-	MemberID   uuid.UUID `json:"member_id"`
-	LoanDate   time.Time `json:"loan_date"`
-	ReturnDate time.Time `json:"return_date"`
-	Status     string    `json:"status"`
+	BookID   uuid.UUID `json:"book_id" validate:"required"`
+	CopyID   uuid.UUID `json:"copy_id"`
+	MemberID uuid.UUID `json:"member_id" validate:"required"`
 }
 
 type ReturnParam struct {
-	Status string `json:"status"`
+	Status string `json:"status" validate:"required,oneof=returned"`
+}
+
+type LoanReturnResponse struct {
+	Loan model.LoansResponse `json:"loan"`
+	Fine *model.FineResponse `json:"fine,omitempty"`
+}
+
+type OverdueLoanResponse struct {
+	Loan        model.LoansResponse `json:"loan"`
+	DaysOverdue int                 `json:"days_overdue"`
+	FineAmount  int64               `json:"fine_amount"`
+}
+
+type MemberBalanceResponse struct {
+	MemberID uuid.UUID            `json:"member_id"`
+	Accruing int64                `json:"accruing"`
+	Settled  int64                `json:"settled"`
+	Total    int64                `json:"total"`
+	Fines    []model.FineResponse `json:"fines"`
+}
+
+type LoanDetailResponse struct {
+	model.LoansResponse
+	Renewals []model.LoanRenewalResponse `json:"renewals"`
+}
+
+type memberLoanPolicy struct {
+	LoanDays    int
+	MaxRenewals int
+	Fine        lib.FinePolicy
+}
+
+// policyFor resolves the member's loan period and fine rate, falling back to config.
+func (l *LoansModule) policyFor(ctx context.Context, memberID uuid.UUID) (memberLoanPolicy, error) {
+	policy := memberLoanPolicy{
+		LoanDays:    l.policy.PeriodDays,
+		MaxRenewals: l.policy.MaxRenewals,
+		Fine: lib.FinePolicy{
+			DailyRate: l.policy.FineDailyRate,
+			MaxAmount: l.policy.FineMaxAmount,
+		},
+	}
+
+	rolePolicy, err := model.GetLoanPolicyForUser(ctx, l.db, memberID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return policy, nil
+		}
+		return policy, err
+	}
+
+	policy.LoanDays = rolePolicy.LoanDays
+	policy.MaxRenewals = rolePolicy.MaxRenewals
+	policy.Fine.DailyRate = rolePolicy.DailyFine
+	return policy, nil
 }
 
-func (l *LoansModule) List(ctx context.Context, filter lib.Filter, dateFilter model.DateFilter) ([]model.LoansResponse, error) {
-	loanResponse, err := model.GetAllLoans(ctx, l.db, filter, dateFilter)
+func (l *LoansModule) List(ctx context.Context, filter lib.Filter, dateFilter model.DateFilter) ([]model.LoansResponse, lib.Pagination, error) {
+	loanResponse, total, err := model.GetAllLoans(ctx, l.db, filter, dateFilter)
 	if err != nil {
-		return nil, err
+		return nil, lib.Pagination{}, err
 	}
 
+	loanResponse, hasMore := lib.TrimPage(filter, loanResponse)
+
 	var response []model.LoansResponse
+	var last lib.Cursor
 	for _, loan := range loanResponse {
 		response = append(response, loan.Response())
+		last = lib.Cursor{CreatedAt: loan.CreatedAt, ID: loan.ID}
 	}
 
-	return response, nil
+	return response, lib.NewPagination(filter, total, len(loanResponse), hasMore, last), nil
 }
 
-func (l *LoansModule) Detail(ctx context.Context, id uuid.UUID) (model.LoansResponse, error) {
+func (l *LoansModule) Detail(ctx context.Context, id uuid.UUID) (LoanDetailResponse, error) {
 	loanResponse, err := model.GetOneLoans(ctx, l.db, id)
 	if err != nil {
-		return model.LoansResponse{}, err
+		return LoanDetailResponse{}, err
+	}
+
+	renewals, err := model.GetLoanRenewals(ctx, l.db, id)
+	if err != nil {
+		return LoanDetailResponse{}, err
 	}
 
-	return loanResponse.Response(), nil
+	response := LoanDetailResponse{
+		LoansResponse: loanResponse.Response(),
+		Renewals:      []model.LoanRenewalResponse{},
+	}
+	for _, renewal := range renewals {
+		response.Renewals = append(response.Renewals, renewal.Response())
+	}
+
+	return response, nil
 }
 
 func (l *LoansModule) Create(ctx context.Context, token string, param LoansParam) (interface{}, error) {
 	claims, err := l.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired token")
+		return nil, lib.Unauthorized("invalid or expired token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("invalid user id in token")
+		return nil, lib.Unauthorized("invalid user id in token")
+	}
+
+	book, err := model.GetOneBooks(ctx, l.db, param.BookID)
+	if err != nil {
+		return nil, ErrBookNotFound
+	}
+
+	allowed, err := l.access.Allows(ctx, param.MemberID, book.AccessLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	if !allowed {
+		return nil, lib.Forbidden(fmt.Sprintf("member's role is not allowed to borrow %s books", book.AccessLevel))
+	}
+
+	copyID, reservation, err := l.resolveCopy(ctx, param)
+	if err != nil {
+		return nil, err
 	}
 
+	policy, err := l.policyFor(ctx, param.MemberID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
 	loans := model.LoansModel{
-		ID:       uuid.New(),
-		BookID:   param.BookID,
-		MemberID: param.MemberID,
-		LoanDate: time.Now(),
-		ReturnDate: pq.NullTime{
-			Time:  param.ReturnDate,
-			Valid: true,
-		},
-		Status:    "borrowed",
-		CreatedAt: time.Now(),
+		ID:        uuid.New(),
+		BookID:    param.BookID,
+		CopyID:    copyID,
+		MemberID:  param.MemberID,
+		LoanDate:  now,
+		DueDate:   lib.DueDate(now, policy.LoanDays),
+		Status:    lib.Borrowed,
+		CreatedAt: now,
 		CreatedBy: userID,
 	}
 
-	err = loans.Insert(ctx, l.db)
+	tx, err := l.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = loans.Insert(ctx, tx)
 	if err != nil {
 		return nil, err
 	}
 
+	if reservation.Valid {
+		err = model.UpdateReservationStatus(ctx, tx, reservation.UUID, lib.Fulfilled, userID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrReservationInactive
+			}
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, l.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditCreate,
+		Entity:   model.EntityLoans,
+		EntityID: loans.ID,
+		After:    loans.Response(),
+		Details:  map[string]interface{}{"reservation_id": reservation},
+	})
+
 	return loans.Response(), nil
 }
 
+// resolveCopy returns the copy set aside for the member's hold, the requested copy
+// if it is lendable, or otherwise any available copy of the book.
+func (l *LoansModule) resolveCopy(ctx context.Context, param LoansParam) (uuid.UUID, uuid.NullUUID, error) {
+	reservation, err := model.GetReadyReservation(ctx, l.db, param.BookID, param.MemberID)
+	if err == nil && reservation.CopyID.Valid && (param.CopyID == uuid.Nil || param.CopyID == reservation.CopyID.UUID) {
+		return reservation.CopyID.UUID, uuid.NullUUID{UUID: reservation.ID, Valid: true}, nil
+	}
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, uuid.NullUUID{}, err
+	}
+
+	if param.CopyID == uuid.Nil {
+		copyID, err := model.GetAvailableCopyID(ctx, l.db, param.BookID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return uuid.Nil, uuid.NullUUID{}, lib.Conflict("no copy of this book is available, place a hold instead")
+			}
+			return uuid.Nil, uuid.NullUUID{}, err
+		}
+		return copyID, uuid.NullUUID{}, nil
+	}
+
+	bookCopy, err := model.GetOneBookCopy(ctx, l.db, param.CopyID)
+	if err != nil {
+		return uuid.Nil, uuid.NullUUID{}, lib.NotFound("copy not found")
+	}
+
+	if bookCopy.BookID != param.BookID {
+		return uuid.Nil, uuid.NullUUID{}, lib.Unprocessable("copy does not belong to this book")
+	}
+
+	if bookCopy.Status != lib.Available {
+		return uuid.Nil, uuid.NullUUID{}, lib.Conflict("copy is not available")
+	}
+
+	return bookCopy.ID, uuid.NullUUID{}, nil
+}
+
 func (l *LoansModule) Return(ctx context.Context, token string, param ReturnParam, id uuid.UUID) (interface{}, error) {
 	claims, err := l.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired access token")
+		return nil, lib.Unauthorized("invalid or expired access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
 	}
 
-	var returnDate pq.NullTime
-	if param.Status == "returned" {
-		returnDate = pq.NullTime{
-			Time:  time.Now(),
-			Valid: true,
-		}
+	current, err := model.GetOneLoans(ctx, l.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if current.Status == lib.Returned {
+		return nil, ErrLoanReturned
+	}
+
+	policy, err := l.policyFor(ctx, current.MemberID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	days, amount := lib.CalculateFine(current.DueDate, now, policy.Fine)
+
+	tx, err := l.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
 	}
+	defer tx.Rollback()
 
 	loans := model.LoansModel{
-		ID:         id,
-		Status:     param.Status,
-		ReturnDate: returnDate,
+		ID:     id,
+		Status: lib.Returned,
+		ReturnDate: pq.NullTime{
+			Time:  now,
+			Valid: true,
+		},
 		UpdatedAt: pq.NullTime{
-			Time:  time.Now(),
+			Time:  now,
 			Valid: true,
 		},
 		UpdatedBy: uuid.NullUUID{
@@ -127,9 +316,257 @@ func (l *LoansModule) Return(ctx context.Context, token string, param ReturnPara
 		},
 	}
 
-	err = loans.Update(ctx, l.db, id)
+	err = loans.Update(ctx, tx, id)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrLoanReturned
+		}
 		return nil, err
 	}
-	return loans.Response(), nil
+
+	// trigger copy_status_on_return sudah mengembalikan eksemplar ke 'available'
+	err = releaseCopy(ctx, tx, loans.BookID, loans.CopyID, lib.Available, l.policy.HoldPickupWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	response := LoanReturnResponse{Loan: loans.Response()}
+	if days > 0 {
+		fine := model.FineModel{
+			LoanID:      id,
+			MemberID:    current.MemberID,
+			DaysOverdue: days,
+			DailyRate:   policy.Fine.DailyRate,
+			Amount:      amount,
+			Status:      lib.Settled,
+			SettledAt: pq.NullTime{
+				Time:  now,
+				Valid: true,
+			},
+			CreatedBy: userID,
+		}
+
+		err = fine.Upsert(ctx, tx)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		fineResponse := fine.Response()
+		response.Fine = &fineResponse
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, l.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditReturn,
+		Entity:   model.EntityLoans,
+		EntityID: id,
+		Before:   current.Response(),
+		After:    response,
+	})
+
+	return response, nil
+}
+
+// Renew extends a loan by another loan period unless the member used up their
+// renewals, the loan is overdue, or someone is waiting for the book.
+func (l *LoansModule) Renew(ctx context.Context, token string, id uuid.UUID) (interface{}, error) {
+	claims, err := l.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return nil, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	loan, err := model.GetOneLoans(ctx, l.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if loan.Status == lib.Returned {
+		return nil, lib.Conflict("loan has already been returned")
+	}
+
+	now := time.Now()
+	if loan.Status == lib.Overdue || lib.DaysOverdue(loan.DueDate, now) > 0 {
+		return nil, lib.Conflict("overdue loans cannot be renewed")
+	}
+
+	policy, err := l.policyFor(ctx, loan.MemberID)
+	if err != nil {
+		return nil, err
+	}
+
+	if loan.RenewalCount >= policy.MaxRenewals {
+		return nil, lib.Conflict("maximum number of renewals reached")
+	}
+
+	waiting, err := model.CountWaitingReservations(ctx, l.db, loan.BookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if waiting > 0 {
+		return nil, lib.Conflict("another member has a hold on this book")
+	}
+
+	tx, err := l.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before := loan.Response()
+	previousDueDate := loan.DueDate
+	newDueDate := previousDueDate.AddDate(0, 0, policy.LoanDays)
+
+	err = loan.Renew(ctx, tx, newDueDate, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, lib.Conflict("loan changed while renewing, please retry")
+		}
+		return nil, err
+	}
+
+	renewal := model.LoanRenewalModel{
+		ID:              uuid.New(),
+		LoanID:          loan.ID,
+		PreviousDueDate: previousDueDate,
+		NewDueDate:      newDueDate,
+		CreatedAt:       now,
+		CreatedBy:       userID,
+	}
+
+	err = renewal.Insert(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, l.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditRenew,
+		Entity:   model.EntityLoans,
+		EntityID: id,
+		Before:   before,
+		After:    loan.Response(),
+		Details:  renewal.Response(),
+	})
+
+	return l.Detail(ctx, id)
+}
+
+// RefreshOverdue flags loans past their due date and recalculates their accruing fines.
+func (l *LoansModule) RefreshOverdue(ctx context.Context) error {
+	now := time.Now()
+	loans, err := model.GetOpenLoansDueBefore(ctx, l.db, now)
+	if err != nil {
+		return err
+	}
+
+	for _, loan := range loans {
+		policy, err := l.policyFor(ctx, loan.MemberID)
+		if err != nil {
+			return err
+		}
+
+		days, amount := lib.CalculateFine(loan.DueDate, now, policy.Fine)
+		if days == 0 {
+			continue
+		}
+
+		if loan.Status == lib.Borrowed {
+			err = model.MarkLoanOverdue(ctx, l.db, loan.ID)
+			if err != nil {
+				return err
+			}
+		}
+
+		fine := model.FineModel{
+			LoanID:      loan.ID,
+			MemberID:    loan.MemberID,
+			DaysOverdue: days,
+			DailyRate:   policy.Fine.DailyRate,
+			Amount:      amount,
+			Status:      lib.Accruing,
+			CreatedBy:   lib.SystemID,
+		}
+
+		err = fine.Upsert(ctx, l.db)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ListOverdue computes the overdue state of open loans without writing it;
+// the loan-overdue job is what stores it.
+func (l *LoansModule) ListOverdue(ctx context.Context) ([]OverdueLoanResponse, error) {
+	now := time.Now()
+	loans, err := model.GetOpenLoansDueBefore(ctx, l.db, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var response []OverdueLoanResponse
+	for _, loan := range loans {
+		policy, err := l.policyFor(ctx, loan.MemberID)
+		if err != nil {
+			return nil, err
+		}
+
+		days, amount := lib.CalculateFine(loan.DueDate, now, policy.Fine)
+		if days == 0 {
+			continue
+		}
+
+		// job belum tentu sudah berjalan sejak pinjaman lewat jatuh tempo
+		if loan.Status == lib.Borrowed {
+			loan.Status = lib.Overdue
+		}
+
+		response = append(response, OverdueLoanResponse{
+			Loan:        loan.Response(),
+			DaysOverdue: days,
+			FineAmount:  amount,
+		})
+	}
+
+	return response, nil
+}
+
+func (l *LoansModule) MemberBalance(ctx context.Context, memberID uuid.UUID) (MemberBalanceResponse, error) {
+	fines, err := model.GetFinesByMember(ctx, l.db, memberID)
+	if err != nil {
+		return MemberBalanceResponse{}, err
+	}
+
+	response := MemberBalanceResponse{
+		MemberID: memberID,
+		Fines:    []model.FineResponse{},
+	}
+	for _, fine := range fines {
+		if fine.Status == lib.Settled {
+			response.Settled += fine.Amount
+		} else {
+			response.Accruing += fine.Amount
+		}
+		response.Fines = append(response.Fines, fine.Response())
+	}
+	response.Total = response.Accruing + response.Settled
+
+	return response, nil
 }
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"app-bookstore/lib"
+	"app-bookstore/model"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type LoanPolicyModule struct {
+	db   *sqlx.DB
+	name string
+	JWT  lib.Jwt
+}
+
+func NewLoanPolicyModule(db *sqlx.DB, jwt lib.Jwt) *LoanPolicyModule {
+	return &LoanPolicyModule{
+		db:   db,
+		name: "loan-policy-module",
+		JWT:  jwt,
+	}
+}
+
+type LoanPolicyParam struct {
+	RoleID      uuid.UUID `json:"role_id" validate:"required"`
+	LoanDays    int       `json:"loan_days" validate:"required,gt=0"`
+	DailyFine   int64     `json:"daily_fine" validate:"gte=0"`
+	MaxRenewals int       `json:"max_renewals" validate:"gte=0"`
+}
+
+func (lp *LoanPolicyModule) List(ctx context.Context, filter lib.Filter) ([]model.LoanPolicyResponse, lib.Pagination, error) {
+	policies, total, err := model.GetAllLoanPolicies(ctx, lp.db, filter)
+	if err != nil {
+		return nil, lib.Pagination{}, err
+	}
+
+	policies, hasMore := lib.TrimPage(filter, policies)
+
+	var response []model.LoanPolicyResponse
+	var last lib.Cursor
+	for _, policy := range policies {
+		response = append(response, policy.Response())
+		last = lib.Cursor{CreatedAt: policy.CreatedAt, ID: policy.ID}
+	}
+
+	return response, lib.NewPagination(filter, total, len(policies), hasMore, last), nil
+}
+
+func (lp *LoanPolicyModule) Detail(ctx context.Context, id uuid.UUID) (model.LoanPolicyResponse, error) {
+	policy, err := model.GetOneLoanPolicy(ctx, lp.db, id)
+	if err != nil {
+		return model.LoanPolicyResponse{}, err
+	}
+
+	return policy.Response(), nil
+}
+
+func (lp *LoanPolicyModule) Create(ctx context.Context, token string, param LoanPolicyParam) (interface{}, error) {
+	claims, err := lp.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return nil, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	policy := model.LoanPolicyModel{
+		ID:          uuid.New(),
+		RoleID:      param.RoleID,
+		LoanDays:    param.LoanDays,
+		DailyFine:   param.DailyFine,
+		MaxRenewals: param.MaxRenewals,
+		CreatedAt:   time.Now(),
+		CreatedBy:   userID,
+	}
+
+	err = policy.Insert(ctx, lp.db)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, lp.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditCreate,
+		Entity:   model.EntityLoanPolicies,
+		EntityID: policy.ID,
+		After:    policy.Response(),
+	})
+
+	return policy.Response(), nil
+}
+
+func (lp *LoanPolicyModule) Update(ctx context.Context, token string, param LoanPolicyParam, id uuid.UUID) (interface{}, error) {
+	claims, err := lp.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return nil, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOneLoanPolicy(ctx, lp.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	policy := model.LoanPolicyModel{
+		ID:          id,
+		LoanDays:    param.LoanDays,
+		DailyFine:   param.DailyFine,
+		MaxRenewals: param.MaxRenewals,
+		UpdatedAt: pq.NullTime{
+			Time:  time.Now(),
+			Valid: true,
+		},
+		UpdatedBy: uuid.NullUUID{
+			UUID:  userID,
+			Valid: true,
+		},
+	}
+
+	err = policy.Update(ctx, lp.db)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, lp.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditUpdate,
+		Entity:   model.EntityLoanPolicies,
+		EntityID: id,
+		Before:   before.Response(),
+		After:    policy.Response(),
+	})
+
+	return policy.Response(), nil
+}
@@ -0,0 +1,199 @@
+package api
+
+import (
+	"app-bookstore/config"
+	"app-bookstore/lib"
+	"app-bookstore/model"
+	"context"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+// loginThrottle slows down password guessing before any password is hashed.
+// Each failure of a username doubles the wait before its next attempt, and
+// too many failures of a username or a client IP lock it for a while.
+// Attempts are counted when they start and given back when they succeed.
+type loginThrottle struct {
+	db  *sqlx.DB
+	cfg config.Login
+}
+
+func usernameKey(username string) string {
+	return "username:" + strings.ToLower(strings.TrimSpace(username))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+// totpKey counts wrong two-factor codes of a user. Only a completed login
+// clears it, so passing the password again does not buy more guesses.
+func totpKey(userID uuid.UUID) string {
+	return "totp:" + userID.String()
+}
+
+func (lt *loginThrottle) keys(username string, ip string) []string {
+	keys := []string{usernameKey(username)}
+	if ip != "" {
+		keys = append(keys, ipKey(ip))
+	}
+	return keys
+}
+
+// reserve counts the attempt against the IP and the username before any
+// password is hashed, so a burst of parallel guesses cannot all get through
+// before the first of them fails. The attempt is refused while either is
+// locked or the username is still backing off, without saying whether the
+// username exists. A correct password gives it back through release. It
+// returns the username's counter.
+func (lt *loginThrottle) reserve(ctx context.Context, username string, ip string) (model.LoginAttemptModel, error) {
+	if ip != "" {
+		_, reserved, err := model.ReserveLoginAttempt(ctx, lt.db, ipKey(ip), lt.limit(lt.cfg.MaxIPFailures, false))
+		if err != nil {
+			return model.LoginAttemptModel{}, err
+		}
+
+		if !reserved {
+			return model.LoginAttemptModel{}, lt.refused(ctx, lt.keys(username, ip), usernameKey(username))
+		}
+	}
+
+	attempt, reserved, err := model.ReserveLoginAttempt(ctx, lt.db, usernameKey(username), lt.limit(lt.cfg.MaxFailures, true))
+	if err != nil {
+		return attempt, err
+	}
+
+	if !reserved {
+		return attempt, lt.refused(ctx, lt.keys(username, ip), usernameKey(username))
+	}
+
+	return attempt, nil
+}
+
+// reserveTOTP counts a two-factor code against the user before it is
+// checked, refusing it while the user's code counter is locked or backing off.
+func (lt *loginThrottle) reserveTOTP(ctx context.Context, userID uuid.UUID) error {
+	_, reserved, err := model.ReserveLoginAttempt(ctx, lt.db, totpKey(userID), lt.limit(lt.cfg.MaxFailures, true))
+	if err != nil {
+		return err
+	}
+
+	if !reserved {
+		return lt.refused(ctx, []string{totpKey(userID)}, totpKey(userID))
+	}
+
+	return nil
+}
+
+func (lt *loginThrottle) limit(threshold int, backoff bool) model.AttemptLimit {
+	limit := model.AttemptLimit{
+		Window:    lt.cfg.FailureWindow,
+		Threshold: threshold,
+		Lockout:   lt.cfg.LockoutDuration,
+	}
+
+	if backoff {
+		limit.BackoffBase = lt.cfg.BackoffBase
+		limit.BackoffLimit = lt.backoffLimit()
+	}
+
+	return limit
+}
+
+// refused is the error of an attempt that could not be reserved.
+func (lt *loginThrottle) refused(ctx context.Context, keys []string, backoffKey string) error {
+	err := lt.wait(ctx, keys, backoffKey)
+	if err == nil {
+		// lockout atau backoff-nya baru saja habis di antara dua query
+		err = lib.TooManyRequests("too many failed login attempts, try again later", time.Second)
+	}
+	return err
+}
+
+// wait fails with TooManyRequests while any of keys is locked, or while
+// backoffKey is still backing off from its last failure.
+func (lt *loginThrottle) wait(ctx context.Context, keys []string, backoffKey string) error {
+	attempts, err := model.GetLoginAttempts(ctx, lt.db, keys)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var wait time.Duration
+	for _, attempt := range attempts {
+		var until time.Time
+		if attempt.LockedUntil.Valid {
+			until = attempt.LockedUntil.Time
+		}
+
+		if attempt.Key == backoffKey {
+			backoffUntil := attempt.LastFailedAt.Add(lt.backoff(attempt.Failures))
+			if backoffUntil.After(until) {
+				until = backoffUntil
+			}
+		}
+
+		if remaining := until.Sub(now); remaining > wait {
+			wait = remaining
+		}
+	}
+
+	if wait > 0 {
+		return lib.TooManyRequests("too many failed login attempts, try again later", wait)
+	}
+
+	return nil
+}
+
+// backoff is the wait after the given number of consecutive failures, capped
+// at the lockout duration.
+func (lt *loginThrottle) backoff(failures int) time.Duration {
+	limit := lt.backoffLimit()
+
+	if failures <= 0 || lt.cfg.BackoffBase <= 0 {
+		return 0
+	}
+
+	wait := lt.cfg.BackoffBase
+	for i := 1; i < failures; i++ {
+		wait *= 2
+		if wait >= limit {
+			return limit
+		}
+	}
+
+	return min(wait, limit)
+}
+
+func (lt *loginThrottle) backoffLimit() time.Duration {
+	if lt.cfg.LockoutDuration > 0 {
+		return lt.cfg.LockoutDuration
+	}
+	return lt.cfg.FailureWindow
+}
+
+// release gives back the attempt reserved for the username and IP once the
+// password turned out to be correct.
+func (lt *loginThrottle) release(ctx context.Context, username string, ip string) {
+	err := model.ReleaseLoginAttempt(ctx, lt.db, usernameKey(username), lt.cfg.MaxFailures)
+	if err == nil && ip != "" {
+		err = model.ReleaseLoginAttempt(ctx, lt.db, ipKey(ip), lt.cfg.MaxIPFailures)
+	}
+	if err != nil {
+		log.Error().Err(err).Msg("failed to release login attempt")
+	}
+}
+
+// reset forgets the failures of the username and its two-factor codes once a
+// login has completed. The IP counter is kept so logging into one account does
+// not reset guessing at others.
+func (lt *loginThrottle) reset(ctx context.Context, username string, userID uuid.UUID) {
+	_, err := model.ClearLoginAttempts(ctx, lt.db, []string{usernameKey(username), totpKey(userID)})
+	if err != nil {
+		log.Error().Err(err).Msg("failed to reset login attempts")
+	}
+}
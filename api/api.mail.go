@@ -0,0 +1,37 @@
+package api
+
+import (
+	"app-bookstore/lib"
+	"context"
+	"net/url"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+)
+
+// sendMail delivers msg in the background, so neither a slow mail server nor
+// the time it takes reveals anything to the caller. Failures are only logged.
+func sendMail(ctx context.Context, mailer lib.Mailer, msg lib.Message, userID uuid.UUID) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+		defer cancel()
+
+		if err := mailer.Send(ctx, msg); err != nil {
+			log.Error().Err(err).Str("user_id", userID.String()).Str("subject", msg.Subject).Msg("failed to send email")
+		}
+	}()
+}
+
+// tokenLink appends token to base as the "token" query parameter.
+func tokenLink(base string, token string) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base + "?token=" + url.QueryEscape(token)
+	}
+
+	query := u.Query()
+	query.Set("token", token)
+	u.RawQuery = query.Encode()
+	return u.String()
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"app-bookstore/config"
+	"app-bookstore/lib"
+	"app-bookstore/model"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// passwordPolicy guards every path that sets a password: the configured rules
+// plus no reuse of the user's last historySize passwords.
+type passwordPolicy struct {
+	rules       lib.PasswordPolicy
+	historySize int
+}
+
+func newPasswordPolicy(cfg config.Password) *passwordPolicy {
+	return &passwordPolicy{
+		rules: lib.PasswordPolicy{
+			MinLength:     cfg.MinLength,
+			MaxLength:     cfg.MaxLength,
+			RequireUpper:  cfg.RequireUpper,
+			RequireLower:  cfg.RequireLower,
+			RequireDigit:  cfg.RequireDigit,
+			RequireSymbol: cfg.RequireSymbol,
+			DenyCommon:    cfg.DenyCommon,
+		},
+		historySize: cfg.HistorySize,
+	}
+}
+
+// check returns a lib.PasswordPolicyError listing every broken rule. For an
+// existing user currentHash is their hash now; it counts as the newest entry
+// of the history.
+func (pp *passwordPolicy) check(ctx context.Context, db sqlx.QueryerContext, userID uuid.UUID, username string, password string, currentHash string) error {
+	violations := pp.rules.Validate(password, username)
+	if violations != nil {
+		return lib.PasswordPolicyError(violations)
+	}
+
+	if pp.historySize <= 0 || userID == uuid.Nil {
+		return nil
+	}
+
+	hashes, err := model.GetPasswordHistory(ctx, db, userID, pp.historySize)
+	if err != nil {
+		return err
+	}
+
+	if currentHash != "" && !slices.Contains(hashes, currentHash) {
+		hashes = append([]string{currentHash}, hashes...)
+	}
+	if len(hashes) > pp.historySize {
+		hashes = hashes[:pp.historySize]
+	}
+
+	for _, hash := range hashes {
+		if lib.CheckPassword(password, hash) {
+			return lib.PasswordPolicyError(map[string]string{
+				lib.RuleReuse: fmt.Sprintf("must not be one of your last %d passwords", pp.historySize),
+			})
+		}
+	}
+
+	return nil
+}
+
+// hash hashes an accepted password.
+func (pp *passwordPolicy) hash(password string) (string, error) {
+	hash, err := lib.HashPassword(password)
+	if err != nil {
+		return "", errors.New("failed to hash password")
+	}
+
+	return hash, nil
+}
+
+// remember adds the hash the user just got to their password history.
+func (pp *passwordPolicy) remember(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, hash string) error {
+	if pp.historySize <= 0 {
+		return nil
+	}
+
+	return model.AddPasswordHistory(ctx, db, userID, hash, pp.historySize)
+}
+
+// set stores the new password of the user and adds it to the history.
+func (pp *passwordPolicy) set(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, hash string) error {
+	err := model.UpdatePassword(ctx, db, userID, hash)
+	if err != nil {
+		return errors.New("failed to update password")
+	}
+
+	return pp.remember(ctx, db, userID, hash)
+}
@@ -0,0 +1,436 @@
+package api
+
+import (
+	"app-bookstore/lib"
+	"app-bookstore/model"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// verificationRequested is the answer to every resend request, so it does not
+// tell whether the username exists or still needs verifying.
+const verificationRequested = "If the account is waiting for email verification, a new verification link has been sent"
+
+// ProfileResponse is the account of the signed-in user. PendingEmail is a new
+// address that replaces Email once it is verified.
+type ProfileResponse struct {
+	model.UserResponse
+	PendingEmail string `json:"pending_email,omitempty"`
+}
+
+type ProfileParam struct {
+	FullName string `json:"full_name" validate:"omitempty,max=255"`
+	Phone    string `json:"phone" validate:"omitempty,phone"`
+	Email    string `json:"email" validate:"omitempty,email,max=255"`
+}
+
+type CloseAccountParam struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type VerifyEmailParam struct {
+	Token string `json:"token" validate:"required"`
+}
+
+type ResendVerificationParam struct {
+	Username string `json:"username" validate:"required"`
+}
+
+type SuspendUserParam struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+type ReactivateUserParam struct {
+	Reason string `json:"reason" validate:"omitempty,max=500"`
+}
+
+func nullString(value string) sql.NullString {
+	value = strings.TrimSpace(value)
+	return sql.NullString{String: value, Valid: value != ""}
+}
+
+// startEmailVerification sends a link that makes email the verified address
+// of the user. Links sent before stop working.
+func (u *UserModule) startEmailVerification(ctx context.Context, user *model.UserModel, email string) error {
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	token, err := u.newEmailVerification(ctx, tx, user.ID, email)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	u.sendVerification(ctx, user, email, token)
+	return nil
+}
+
+// newEmailVerification replaces the pending verifications of the user inside
+// tx and returns the token of the new one.
+func (u *UserModule) newEmailVerification(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, email string) (string, error) {
+	token, err := lib.GenerateVerificationToken()
+	if err != nil {
+		return "", errors.New("failed to generate verification token")
+	}
+
+	verification := model.EmailVerificationModel{
+		UserID:    userID,
+		Email:     email,
+		TokenHash: lib.HashToken(token),
+		ExpiresAt: u.now().Add(u.mail.VerifyTokenTTL),
+	}
+
+	err = model.DeleteUserEmailVerifications(ctx, tx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	err = verification.Insert(ctx, tx)
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// sendVerification mails the link once its transaction is committed.
+func (u *UserModule) sendVerification(ctx context.Context, user *model.UserModel, email string, token string) {
+	sendMail(ctx, u.mailer, lib.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nOpen this link to verify your email address:\n\n%s\n\nThe link expires in %s. If you did not ask for this, you can ignore this email.\n",
+			user.Username, tokenLink(u.mail.VerifyURL, token), u.mail.VerifyTokenTTL,
+		),
+	}, user.ID)
+}
+
+// sendEmailInUse tells the owner of a verified address that someone tried to
+// register it again.
+func (u *UserModule) sendEmailInUse(ctx context.Context, email string) {
+	sendMail(ctx, u.mailer, lib.Message{
+		To:      email,
+		Subject: "Your email address is already registered",
+		Body: "Hello,\n\nSomeone tried to register a new account with this email address, which already belongs to an account. " +
+			"If it was you, log in or reset your password instead. If not, you can ignore this email.\n",
+	}, uuid.Nil)
+}
+
+func (u *UserModule) Me(ctx context.Context, token string) (ProfileResponse, error) {
+	userID, err := u.caller(token)
+	if err != nil {
+		return ProfileResponse{}, err
+	}
+
+	return u.profile(ctx, userID)
+}
+
+func (u *UserModule) profile(ctx context.Context, userID uuid.UUID) (ProfileResponse, error) {
+	user, err := model.GetUserByID(ctx, u.db, userID)
+	if err != nil {
+		return ProfileResponse{}, lib.NotFound("user not found")
+	}
+
+	pendingEmail, err := model.GetPendingEmail(ctx, u.db, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return ProfileResponse{}, err
+	}
+
+	// alamat yang sama dengan email sekarang hanya menunggu verifikasi ulang
+	if strings.EqualFold(pendingEmail, user.Email.String) && user.EmailVerifiedAt.Valid {
+		pendingEmail = ""
+	}
+
+	return ProfileResponse{
+		UserResponse: user.Response(),
+		PendingEmail: pendingEmail,
+	}, nil
+}
+
+// UpdateMe replaces the full name and phone of the signed-in user. A new or
+// still unverified email is not stored until the link sent to it is opened;
+// leaving email empty keeps the current one.
+func (u *UserModule) UpdateMe(ctx context.Context, token string, param ProfileParam) (ProfileResponse, error) {
+	userID, err := u.caller(token)
+	if err != nil {
+		return ProfileResponse{}, err
+	}
+
+	user, err := model.GetUserByID(ctx, u.db, userID)
+	if err != nil {
+		return ProfileResponse{}, lib.NotFound("user not found")
+	}
+
+	email := strings.TrimSpace(param.Email)
+	verifyEmail := email != "" && (!strings.EqualFold(email, user.Email.String) || !user.EmailVerifiedAt.Valid)
+	if verifyEmail {
+		inUse, err := model.EmailInUse(ctx, u.db, email, userID)
+		if err != nil {
+			return ProfileResponse{}, err
+		}
+		if inUse {
+			return ProfileResponse{}, lib.Conflict("email address is already in use")
+		}
+	}
+
+	err = model.UpdateUserProfile(ctx, u.db, userID, nullString(param.FullName), nullString(param.Phone))
+	if err != nil {
+		return ProfileResponse{}, err
+	}
+
+	if verifyEmail {
+		err = u.startEmailVerification(ctx, user, email)
+		if err != nil {
+			return ProfileResponse{}, err
+		}
+	}
+
+	response, err := u.profile(ctx, userID)
+	if err != nil {
+		return ProfileResponse{}, err
+	}
+
+	recordAudit(ctx, u.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditProfileUpdate,
+		Entity:   model.EntityUsers,
+		EntityID: userID,
+		Before:   user.Response(),
+		After:    response.UserResponse,
+		Details:  map[string]interface{}{"email_verification_sent": verifyEmail},
+	})
+
+	return response, nil
+}
+
+// CloseMe closes the account of the signed-in user after checking their
+// password, and ends all of their sessions.
+func (u *UserModule) CloseMe(ctx context.Context, token string, param CloseAccountParam) error {
+	userID, err := u.caller(token)
+	if err != nil {
+		return err
+	}
+
+	user, err := model.GetUserByID(ctx, u.db, userID)
+	if err != nil {
+		return lib.NotFound("user not found")
+	}
+
+	if !lib.CheckPassword(param.Password, user.Password) {
+		return lib.Unprocessable("password is incorrect")
+	}
+
+	return u.changeStatus(ctx, userID, userID, []string{lib.UserActive}, lib.UserClosed, "closed by the account owner", model.AuditAccountClose)
+}
+
+// VerifyEmail makes the address the token was sent to the verified email of
+// its user, and activates a user who registered with it.
+func (u *UserModule) VerifyEmail(ctx context.Context, param VerifyEmailParam) (interface{}, error) {
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	verification, err := model.ConsumeEmailVerification(ctx, tx, lib.HashToken(param.Token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, lib.Unauthorized("invalid or expired token")
+		}
+		return nil, err
+	}
+
+	inUse, err := model.EmailInUse(ctx, tx, verification.Email, verification.UserID)
+	if err != nil {
+		return nil, err
+	}
+	if inUse {
+		return nil, lib.Conflict("email address is already in use")
+	}
+
+	err = model.VerifyUserEmail(ctx, tx, verification.UserID, verification.Email)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	notifyPermissionChange(ctx, u.db, lib.ScopeUser, verification.UserID.String())
+
+	recordAudit(ctx, u.db, auditEntry{
+		Actor:    verification.UserID,
+		Action:   model.AuditEmailVerify,
+		Entity:   model.EntityUsers,
+		EntityID: verification.UserID,
+		Details:  map[string]interface{}{"email": verification.Email},
+	})
+
+	return map[string]string{
+		"message": "Email address verified",
+	}, nil
+}
+
+// ResendVerification sends a new link to a user who has not verified the
+// email they registered with. The response is the same in every case.
+func (u *UserModule) ResendVerification(ctx context.Context, param ResendVerificationParam) (interface{}, error) {
+	user, err := model.GetUserByUsername(ctx, u.db, param.Username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	if user != nil && user.Status == lib.UserPendingVerification && user.Email.Valid {
+		err = u.startEmailVerification(ctx, user, user.Email.String)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return map[string]string{
+		"message": verificationRequested,
+	}, nil
+}
+
+// Suspend stops the user from logging in and ends all of their sessions.
+func (u *UserModule) Suspend(ctx context.Context, token string, userID uuid.UUID, param SuspendUserParam) (model.UserResponse, error) {
+	actorID, err := u.caller(token)
+	if err != nil {
+		return model.UserResponse{}, err
+	}
+
+	if actorID == userID {
+		return model.UserResponse{}, lib.Unprocessable("you cannot suspend your own account")
+	}
+
+	err = u.changeStatus(ctx, actorID, userID, []string{lib.UserActive, lib.UserPendingVerification}, lib.UserSuspended, param.Reason, model.AuditSuspend)
+	if err != nil {
+		return model.UserResponse{}, err
+	}
+
+	return u.userResponse(ctx, userID)
+}
+
+// Reactivate lets a suspended or closed user log in again. A user who was
+// suspended before verifying their email goes back to waiting for it.
+func (u *UserModule) Reactivate(ctx context.Context, token string, userID uuid.UUID, param ReactivateUserParam) (model.UserResponse, error) {
+	actorID, err := u.caller(token)
+	if err != nil {
+		return model.UserResponse{}, err
+	}
+
+	user, err := model.GetUserByID(ctx, u.db, userID)
+	if err != nil {
+		return model.UserResponse{}, lib.NotFound("user not found")
+	}
+
+	status := lib.UserActive
+	if user.PreviousStatus.String == lib.UserPendingVerification && !user.EmailVerifiedAt.Valid {
+		status = lib.UserPendingVerification
+	}
+
+	err = u.changeStatus(ctx, actorID, userID, []string{lib.UserSuspended, lib.UserClosed}, status, param.Reason, model.AuditReactivate)
+	if err != nil {
+		return model.UserResponse{}, err
+	}
+
+	// link lama sudah dihapus saat disuspend
+	if status == lib.UserPendingVerification && user.Email.Valid {
+		err = u.startEmailVerification(ctx, user, user.Email.String)
+		if err != nil {
+			return model.UserResponse{}, err
+		}
+	}
+
+	return u.userResponse(ctx, userID)
+}
+
+func (u *UserModule) userResponse(ctx context.Context, userID uuid.UUID) (model.UserResponse, error) {
+	user, err := model.GetUserByID(ctx, u.db, userID)
+	if err != nil {
+		return model.UserResponse{}, lib.NotFound("user not found")
+	}
+
+	return user.Response(), nil
+}
+
+// changeStatus moves the user from one of from to status. Leaving active also
+// revokes every session and pending token of the user.
+func (u *UserModule) changeStatus(ctx context.Context, actorID uuid.UUID, userID uuid.UUID, from []string, status string, reason string, action string) error {
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	changed, err := model.SetUserStatus(ctx, tx, userID, from, status, reason, actorID)
+	if err != nil {
+		return err
+	}
+
+	if !changed {
+		current, err := model.GetUserStatus(ctx, tx, userID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return lib.NotFound("user not found")
+			}
+			return err
+		}
+		return lib.Conflict(fmt.Sprintf("user is %s and cannot be made %s", current, status))
+	}
+
+	var revoked []model.RevokedSessionModel
+	if status != lib.UserActive {
+		revoked, err = model.RevokeUserSessions(ctx, tx, userID, "", actorID)
+		if err != nil {
+			return err
+		}
+
+		err = model.DeleteUserResetTokens(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+
+		err = model.DeleteUserEmailVerifications(ctx, tx, userID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	notifyPermissionChange(ctx, u.db, lib.ScopeUser, userID.String())
+	revokedSessions := notifyRevokedSessions(ctx, u.db, revoked)
+
+	recordAudit(ctx, u.db, auditEntry{
+		Actor:    actorID,
+		Action:   action,
+		Entity:   model.EntityUsers,
+		EntityID: userID,
+		After:    map[string]interface{}{"status": status},
+		Details: map[string]interface{}{
+			"from":             from,
+			"reason":           reason,
+			"revoked_sessions": revokedSessions,
+		},
+	})
+
+	return nil
+}
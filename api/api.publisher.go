@@ -4,7 +4,6 @@ import (
 	"app-bookstore/lib"
 	"app-bookstore/model"
 	"context"
-	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,16 +12,18 @@ import (
 )
 
 type PublisherModule struct {
-	db   *sqlx.DB
-	name string
-	JWT  lib.Jwt
+	db     *sqlx.DB
+	name   string
+	JWT    lib.Jwt
+	access *AccessPolicy
 }
 
-func NewPublisherModule(db *sqlx.DB, jwt lib.Jwt) *PublisherModule {
+func NewPublisherModule(db *sqlx.DB, jwt lib.Jwt, access *AccessPolicy) *PublisherModule {
 	return &PublisherModule{
-		db:   db,
-		name: "publisher-module",
-		JWT:  jwt,
+		db:     db,
+		name:   "publisher-module",
+		JWT:    jwt,
+		access: access,
 	}
 }
 
@@ -32,18 +33,27 @@ type PublisherParam struct {
 	Phone   string `json:"phone"`
 }
 
-func (p *PublisherModule) List(ctx context.Context, filter lib.Filter, dateFilter model.DateFilter) ([]model.PublisherResponse, error) {
-	publiserRequest, err := model.GetAllPublisher(ctx, p.db, filter, dateFilter)
+func (p *PublisherModule) List(ctx context.Context, filter lib.Filter, dateFilter model.DateFilter) ([]model.PublisherResponse, lib.Pagination, error) {
+	err := p.access.CheckIncludeDeleted(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, lib.Pagination{}, err
+	}
+
+	publiserRequest, total, err := model.GetAllPublisher(ctx, p.db, filter, dateFilter)
+	if err != nil {
+		return nil, lib.Pagination{}, err
 	}
 
+	publiserRequest, hasMore := lib.TrimPage(filter, publiserRequest)
+
 	var response []model.PublisherResponse
+	var last lib.Cursor
 	for _, publisher := range publiserRequest {
 		response = append(response, publisher.Response())
+		last = lib.Cursor{CreatedAt: publisher.CreatedAt, ID: publisher.ID}
 	}
 
-	return response, err
+	return response, lib.NewPagination(filter, total, len(publiserRequest), hasMore, last), nil
 }
 
 func (p *PublisherModule) Detail(ctx context.Context, id uuid.UUID) (model.PublisherResponse, error) {
@@ -58,12 +68,12 @@ func (p *PublisherModule) Detail(ctx context.Context, id uuid.UUID) (model.Publi
 func (p *PublisherModule) Create(ctx context.Context, token string, param PublisherParam) (interface{}, error) {
 	claims, err := p.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired token")
+		return nil, lib.Unauthorized("invalid or expired token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
 	}
 
 	publisher := model.PublisherModel{
@@ -80,18 +90,31 @@ func (p *PublisherModule) Create(ctx context.Context, token string, param Publis
 		return nil, err
 	}
 
+	recordAudit(ctx, p.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditCreate,
+		Entity:   model.EntityPublishers,
+		EntityID: publisher.ID,
+		After:    publisher.Response(),
+	})
+
 	return publisher.Response(), nil
 }
 
 func (p *PublisherModule) Update(ctx context.Context, token string, param PublisherParam, id uuid.UUID) (interface{}, error) {
 	claims, err := p.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired access token")
+		return nil, lib.Unauthorized("invalid or expired access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOnePublisher(ctx, p.db, id)
+	if err != nil {
+		return nil, err
 	}
 
 	publisher := model.PublisherModel{
@@ -114,5 +137,79 @@ func (p *PublisherModule) Update(ctx context.Context, token string, param Publis
 		return nil, err
 	}
 
+	recordAudit(ctx, p.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditUpdate,
+		Entity:   model.EntityPublishers,
+		EntityID: id,
+		Before:   before.Response(),
+		After:    publisher.Response(),
+	})
+
 	return publisher.Response(), nil
 }
+
+// Delete soft deletes the publisher; the row stays in the table for history and restore.
+func (p *PublisherModule) Delete(ctx context.Context, token string, id uuid.UUID) error {
+	claims, err := p.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOnePublisher(ctx, p.db, id)
+	if err != nil {
+		return err
+	}
+
+	err = model.SoftDelete(ctx, p.db, model.TablePublishers, id, userID)
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, p.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditDelete,
+		Entity:   model.EntityPublishers,
+		EntityID: id,
+		Before:   before.Response(),
+	})
+
+	return nil
+}
+
+func (p *PublisherModule) Restore(ctx context.Context, token string, id uuid.UUID) (model.PublisherResponse, error) {
+	claims, err := p.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return model.PublisherResponse{}, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return model.PublisherResponse{}, lib.Unauthorized("failed to parse access token")
+	}
+
+	err = model.Restore(ctx, p.db, model.TablePublishers, id, userID)
+	if err != nil {
+		return model.PublisherResponse{}, err
+	}
+
+	restored, err := p.Detail(ctx, id)
+	if err != nil {
+		return model.PublisherResponse{}, err
+	}
+
+	recordAudit(ctx, p.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditRestore,
+		Entity:   model.EntityPublishers,
+		EntityID: id,
+		After:    restored,
+	})
+
+	return restored, nil
+}
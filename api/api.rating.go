@@ -4,7 +4,6 @@ import (
 	"app-bookstore/lib"
 	"app-bookstore/model"
 	"context"
-	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,16 +12,18 @@ import (
 )
 
 type RatingModule struct {
-	db   *sqlx.DB
-	name string
-	JWT  lib.Jwt
+	db     *sqlx.DB
+	name   string
+	JWT    lib.Jwt
+	access *AccessPolicy
 }
 
-func NewRatingModule(db *sqlx.DB, jwt lib.Jwt) *RatingModule {
+func NewRatingModule(db *sqlx.DB, jwt lib.Jwt, access *AccessPolicy) *RatingModule {
 	return &RatingModule{
-		db:   db,
-		name: "ratings-module",
-		JWT:  jwt,
+		db:     db,
+		name:   "ratings-module",
+		JWT:    jwt,
+		access: access,
 	}
 }
 
@@ -37,18 +38,27 @@ type RatingUpdateParam struct {
 	Review string `json:"review"`
 }
 
-func (rt *RatingModule) List(ctx context.Context) ([]model.RatingResponse, error) {
-	ratingRequest, err := model.GetAllRatings(ctx, rt.db)
+func (rt *RatingModule) List(ctx context.Context, filter lib.Filter) ([]model.RatingResponse, lib.Pagination, error) {
+	err := rt.access.CheckIncludeDeleted(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, lib.Pagination{}, err
+	}
+
+	ratingRequest, total, err := model.GetAllRatings(ctx, rt.db, filter)
+	if err != nil {
+		return nil, lib.Pagination{}, err
 	}
 
+	ratingRequest, hasMore := lib.TrimPage(filter, ratingRequest)
+
 	var response []model.RatingResponse
+	var last lib.Cursor
 	for _, rating := range ratingRequest {
 		response = append(response, rating.Response())
+		last = lib.Cursor{CreatedAt: rating.CreatedAt, ID: rating.ID}
 	}
 
-	return response, nil
+	return response, lib.NewPagination(filter, total, len(ratingRequest), hasMore, last), nil
 }
 
 func (rt *RatingModule) Detail(ctx context.Context, id uuid.UUID) (model.RatingResponse, error) {
@@ -62,12 +72,12 @@ func (rt *RatingModule) Detail(ctx context.Context, id uuid.UUID) (model.RatingR
 func (rt *RatingModule) Create(ctx context.Context, token string, param RatingParam) (interface{}, error) {
 	claims, err := rt.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired access token")
+		return nil, lib.Unauthorized("invalid or expired access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
 	}
 
 	rating := model.RatingModel{
@@ -85,18 +95,31 @@ func (rt *RatingModule) Create(ctx context.Context, token string, param RatingPa
 		return nil, err
 	}
 
+	recordAudit(ctx, rt.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditCreate,
+		Entity:   model.EntityRatings,
+		EntityID: rating.ID,
+		After:    rating.Response(),
+	})
+
 	return rating.Response(), nil
 }
 
 func (rt *RatingModule) Update(ctx context.Context, token string, param RatingUpdateParam, id uuid.UUID) (interface{}, error) {
 	claims, err := rt.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired access token")
+		return nil, lib.Unauthorized("invalid or expired access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOneRating(ctx, rt.db, id)
+	if err != nil {
+		return nil, err
 	}
 
 	rating := model.RatingModel{
@@ -118,6 +141,80 @@ func (rt *RatingModule) Update(ctx context.Context, token string, param RatingUp
 		return nil, err
 	}
 
+	recordAudit(ctx, rt.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditUpdate,
+		Entity:   model.EntityRatings,
+		EntityID: id,
+		Before:   before.Response(),
+		After:    rating.Response(),
+	})
+
 	return rating.Response(), nil
 
 }
+
+// Delete soft deletes the rating; the row stays in the table for history and restore.
+func (rt *RatingModule) Delete(ctx context.Context, token string, id uuid.UUID) error {
+	claims, err := rt.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOneRating(ctx, rt.db, id)
+	if err != nil {
+		return err
+	}
+
+	err = model.SoftDelete(ctx, rt.db, model.TableRatings, id, userID)
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, rt.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditDelete,
+		Entity:   model.EntityRatings,
+		EntityID: id,
+		Before:   before.Response(),
+	})
+
+	return nil
+}
+
+func (rt *RatingModule) Restore(ctx context.Context, token string, id uuid.UUID) (model.RatingResponse, error) {
+	claims, err := rt.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return model.RatingResponse{}, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return model.RatingResponse{}, lib.Unauthorized("failed to parse access token")
+	}
+
+	err = model.Restore(ctx, rt.db, model.TableRatings, id, userID)
+	if err != nil {
+		return model.RatingResponse{}, err
+	}
+
+	restored, err := rt.Detail(ctx, id)
+	if err != nil {
+		return model.RatingResponse{}, err
+	}
+
+	recordAudit(ctx, rt.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditRestore,
+		Entity:   model.EntityRatings,
+		EntityID: id,
+		After:    restored,
+	})
+
+	return restored, nil
+}
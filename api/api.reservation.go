@@ -0,0 +1,325 @@
+package api
+
+import (
+	"app-bookstore/config"
+	"app-bookstore/lib"
+	"app-bookstore/model"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var ErrReservationInactive = lib.Conflict("reservation is no longer active")
+
+type ReservationModule struct {
+	db     *sqlx.DB
+	name   string
+	JWT    lib.Jwt
+	policy config.Loan
+	access *AccessPolicy
+}
+
+func NewReservationModule(db *sqlx.DB, jwt lib.Jwt, policy config.Loan, access *AccessPolicy) *ReservationModule {
+	return &ReservationModule{
+		db:     db,
+		name:   "reservation-module",
+		JWT:    jwt,
+		policy: policy,
+		access: access,
+	}
+}
+
+type ReservationParam struct {
+	BookID uuid.UUID `json:"book_id" validate:"required"`
+}
+
+// releaseCopy hands a copy that just came back to the next member in the queue,
+// or puts it back on the shelf when nobody is waiting. The copy is locked first
+// and left alone when it is no longer in the from status, e.g. because it was
+// lent out in the meantime.
+func releaseCopy(ctx context.Context, db sqlx.ExtContext, bookID uuid.UUID, copyID uuid.UUID, from string, window time.Duration) error {
+	status, err := model.LockCopyStatus(ctx, db, copyID)
+	if err != nil {
+		return err
+	}
+
+	if status != from {
+		return nil
+	}
+
+	promoted, err := model.PromoteNextReservation(ctx, db, bookID, copyID, time.Now().Add(window))
+	if err != nil {
+		return err
+	}
+
+	if promoted {
+		return nil
+	}
+
+	if status == lib.Available {
+		return nil
+	}
+
+	return model.SetCopyStatus(ctx, db, copyID, lib.Available)
+}
+
+func (rv *ReservationModule) ListMine(ctx context.Context, token string) ([]model.ReservationResponse, error) {
+	claims, err := rv.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return nil, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	reservations, err := model.GetReservationsByMember(ctx, rv.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var response []model.ReservationResponse
+	for _, reservation := range reservations {
+		response = append(response, reservation.Response())
+	}
+
+	return response, nil
+}
+
+func (rv *ReservationModule) Queue(ctx context.Context, bookID uuid.UUID) ([]model.ReservationResponse, error) {
+	reservations, err := model.GetReservationQueue(ctx, rv.db, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	var response []model.ReservationResponse
+	for _, reservation := range reservations {
+		response = append(response, reservation.Response())
+	}
+
+	return response, nil
+}
+
+func (rv *ReservationModule) Place(ctx context.Context, token string, param ReservationParam) (interface{}, error) {
+	claims, err := rv.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return nil, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	book, err := model.GetOneBooks(ctx, rv.db, param.BookID)
+	if err != nil {
+		return nil, ErrBookNotFound
+	}
+
+	allowed, err := rv.access.Allows(ctx, userID, book.AccessLevel)
+	if err != nil {
+		return nil, err
+	}
+
+	if !allowed {
+		return nil, ErrBookNotFound
+	}
+
+	if book.TotalCopies == 0 {
+		return nil, lib.Conflict("book has no lendable copies")
+	}
+
+	waiting, err := model.CountWaitingReservations(ctx, rv.db, param.BookID)
+	if err != nil {
+		return nil, err
+	}
+
+	// antrean kosong dan masih ada eksemplar: langsung pinjam saja
+	if book.AvailableCopies > 0 && waiting == 0 {
+		return nil, lib.Conflict("book is available, borrow it instead")
+	}
+
+	reservation := model.ReservationModel{
+		ID:        uuid.New(),
+		BookID:    param.BookID,
+		MemberID:  userID,
+		Status:    lib.Waiting,
+		CreatedAt: time.Now(),
+		CreatedBy: userID,
+	}
+
+	err = reservation.Insert(ctx, rv.db)
+	if err != nil {
+		return nil, err
+	}
+
+	created, err := model.GetOneReservation(ctx, rv.db, reservation.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, rv.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditCreate,
+		Entity:   model.EntityReservations,
+		EntityID: created.ID,
+		After:    created.Response(),
+	})
+
+	return created.Response(), nil
+}
+
+func (rv *ReservationModule) Cancel(ctx context.Context, token string, id uuid.UUID) (interface{}, error) {
+	claims, err := rv.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return nil, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	tx, err := rv.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	reservation, err := model.LockReservation(ctx, tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if reservation.MemberID != userID {
+		return nil, lib.Forbidden("reservation does not belong to this user")
+	}
+
+	err = model.UpdateReservationStatus(ctx, tx, id, lib.Cancelled, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrReservationInactive
+		}
+		return nil, err
+	}
+
+	if reservation.Status == lib.Ready && reservation.CopyID.Valid {
+		err = releaseCopy(ctx, tx, reservation.BookID, reservation.CopyID.UUID, lib.OnHold, rv.policy.HoldPickupWindow)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	before := reservation.Response()
+	reservation.Status = lib.Cancelled
+
+	recordAudit(ctx, rv.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditCancel,
+		Entity:   model.EntityReservations,
+		EntityID: id,
+		Before:   before,
+		After:    reservation.Response(),
+	})
+
+	return reservation.Response(), nil
+}
+
+// ExpireHolds releases copies whose pickup window has passed and fills
+// waiting queues from copies that are sitting on the shelf.
+func (rv *ReservationModule) ExpireHolds(ctx context.Context) error {
+	expired, err := model.GetExpiredReservations(ctx, rv.db, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, reservation := range expired {
+		err := rv.expire(ctx, reservation)
+		if err != nil {
+			return err
+		}
+	}
+
+	copies, err := model.GetPromotableCopies(ctx, rv.db)
+	if err != nil {
+		return err
+	}
+
+	for _, promotable := range copies {
+		err := rv.promote(ctx, promotable)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// expire closes a hold whose pickup window has passed. The hold is locked and
+// checked again, since the member may have picked the copy up after it was listed.
+func (rv *ReservationModule) expire(ctx context.Context, listed model.ReservationModel) error {
+	tx, err := rv.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	reservation, err := model.LockReservation(ctx, tx, listed.ID)
+	if err != nil {
+		return err
+	}
+
+	if reservation.Status != lib.Ready || !reservation.ExpiresAt.Valid || reservation.ExpiresAt.Time.After(time.Now()) {
+		return nil
+	}
+
+	err = model.UpdateReservationStatus(ctx, tx, reservation.ID, lib.Expired, lib.SystemID)
+	if err != nil {
+		return err
+	}
+
+	if reservation.CopyID.Valid {
+		err = releaseCopy(ctx, tx, reservation.BookID, reservation.CopyID.UUID, lib.OnHold, rv.policy.HoldPickupWindow)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// promote sets a shelved copy aside for the next waiting member, provided the
+// copy is still available once it is locked.
+func (rv *ReservationModule) promote(ctx context.Context, promotable model.PromotableCopy) error {
+	tx, err := rv.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	status, err := model.LockCopyStatus(ctx, tx, promotable.CopyID)
+	if err != nil {
+		return err
+	}
+
+	if status != lib.Available {
+		return nil
+	}
+
+	_, err = model.PromoteNextReservation(ctx, tx, promotable.BookID, promotable.CopyID, time.Now().Add(rv.policy.HoldPickupWindow))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -1,42 +1,56 @@
 package api
 
 import (
+	"app-bookstore/config"
 	"app-bookstore/lib"
 	"app-bookstore/model"
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
 )
 
+// resetRequested is the answer to every reset request, so it does not tell
+// whether the username exists or has an email address.
+const resetRequested = "If the account exists and has a verified email address, a password reset link has been sent to it"
+
 type PasswordResetModule struct {
-	db   *sqlx.DB
-	name string
-	JWT  lib.Jwt
+	db        *sqlx.DB
+	name      string
+	JWT       lib.Jwt
+	passwords *passwordPolicy
+	mailer    lib.Mailer
+	mail      config.Mail
 }
 
-func NewPasswordResetModule(db *sqlx.DB, jwt lib.Jwt) *PasswordResetModule {
+func NewPasswordResetModule(db *sqlx.DB, jwt lib.Jwt, cfg *config.Config, mailer lib.Mailer) *PasswordResetModule {
 	return &PasswordResetModule{
-		db:   db,
-		name: "password_reset",
-		JWT:  jwt,
+		db:        db,
+		name:      "password_reset",
+		JWT:       jwt,
+		passwords: newPasswordPolicy(cfg.Password),
+		mailer:    mailer,
+		mail:      cfg.Mail,
 	}
 }
 
 type PasswordResetRequestParam struct {
-	Username string `json:"username"`
+	Username string `json:"username" validate:"required"`
 }
 
 type PasswordResetConfigParam struct {
-	Token       string `json:"token"`
-	NewPassword string `json:"new_password"`
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required"`
 }
 
 func (pr *PasswordResetModule) ValidateToken(ctx context.Context, token string) (interface{}, error) {
-	userID, err := model.ValidateResetToken(ctx, pr.db, token)
+	userID, err := model.ValidateResetToken(ctx, pr.db, lib.HashToken(token))
 	if err != nil {
-		return nil, errors.New("invalid or expired token")
+		return nil, lib.Unauthorized("invalid or expired token")
 	}
 
 	return map[string]string{
@@ -45,55 +59,228 @@ func (pr *PasswordResetModule) ValidateToken(ctx context.Context, token string)
 	}, nil
 }
 
+// RequestReset emails a reset link to the user and invalidates the links sent
+// before. The response is the same whether or not a link was sent, and the
+// token is issued in the background so the response time does not tell either.
+// Requests are capped per username and per client IP, and no new link is sent
+// while the last one is younger than the cooldown.
 func (pr *PasswordResetModule) RequestReset(ctx context.Context, param PasswordResetRequestParam) (interface{}, error) {
+	err := pr.limitRequest(ctx, param.Username, lib.RequestMetaFromContext(ctx).ClientIP)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := model.GetUserByUsername(ctx, pr.db, param.Username)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	// hanya ke alamat yang sudah diverifikasi, dan tidak untuk akun yang tidak aktif
+	if user != nil && user.Email.Valid && user.EmailVerifiedAt.Valid && user.Status == lib.UserActive {
+		go func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), time.Minute)
+			defer cancel()
+
+			if err := pr.issueToken(ctx, user); err != nil {
+				log.Error().Err(err).Str("user_id", user.ID.String()).Msg("failed to issue password reset token")
+			}
+		}()
+	}
+
+	return map[string]string{
+		"message": resetRequested,
+	}, nil
+}
+
+// limitRequest counts the request against the username and the client IP in
+// the login_attempts store, under keys of their own so resets do not lock
+// logins. It counts whether or not the username exists.
+func (pr *PasswordResetModule) limitRequest(ctx context.Context, username string, ip string) error {
+	keys := []string{"reset:" + usernameKey(username)}
+	limits := []int{pr.mail.ResetMaxRequests}
+	if ip != "" {
+		keys = append(keys, "reset:"+ipKey(ip))
+		limits = append(limits, pr.mail.ResetMaxIPRequests)
+	}
+
+	for i, key := range keys {
+		limit := model.AttemptLimit{
+			Window:    pr.mail.ResetWindow,
+			Threshold: limits[i],
+			Lockout:   pr.mail.ResetWindow,
+		}
+
+		_, reserved, err := model.ReserveLoginAttempt(ctx, pr.db, key, limit)
+		if err != nil {
+			return err
+		}
+
+		if !reserved {
+			return pr.refused(ctx, key)
+		}
+	}
+
+	return nil
+}
+
+// refused is the error of a reset request over the cap of key, telling how
+// long until the key is unlocked.
+func (pr *PasswordResetModule) refused(ctx context.Context, key string) error {
+	attempts, err := model.GetLoginAttempts(ctx, pr.db, []string{key})
 	if err != nil {
-		return nil, errors.New("username not found")
+		return err
+	}
+
+	wait := time.Second
+	for _, attempt := range attempts {
+		if attempt.LockedUntil.Valid {
+			wait = max(time.Until(attempt.LockedUntil.Time), wait)
+		}
 	}
 
+	return lib.TooManyRequests("too many password reset requests, try again later", wait)
+}
+
+func (pr *PasswordResetModule) issueToken(ctx context.Context, user *model.UserModel) error {
 	token, err := lib.GenerateResetToken()
 	if err != nil {
-		return nil, errors.New("failed to generate reset token")
+		return errors.New("failed to generate reset token")
 	}
 
 	passwordReset := model.PasswordResetModel{
 		UserID:    user.ID,
-		Token:     token,
-		ExpiresAt: time.Now().Add(5 * time.Minute),
-		CreatedAt: time.Now(),
+		TokenHash: lib.HashToken(token),
+		ExpiresAt: time.Now().Add(pr.mail.ResetTokenTTL),
 	}
 
-	err = passwordReset.Insert(ctx, pr.db)
+	tx, err := pr.db.BeginTxx(ctx, nil)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	defer tx.Rollback()
 
-	return map[string]string{
-		"message": "this token for testing",
-		"token":   token,
-	}, nil
+	err = model.LockUserResetTokens(ctx, tx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	// link sebelumnya masih baru, jangan kirim email lagi
+	issued, err := model.ResetIssuedSince(ctx, tx, user.ID, time.Now().Add(-pr.mail.ResetCooldown))
+	if err != nil {
+		return err
+	}
+
+	if issued {
+		return nil
+	}
+
+	err = model.DeleteUserResetTokens(ctx, tx, user.ID)
+	if err != nil {
+		return err
+	}
+
+	err = passwordReset.Insert(ctx, tx)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, pr.db, auditEntry{
+		Actor:    user.ID,
+		Action:   model.AuditResetRequest,
+		Entity:   model.EntityPasswordResets,
+		EntityID: user.ID,
+		Details:  map[string]interface{}{"expires_at": passwordReset.ExpiresAt},
+	})
+
+	sendMail(ctx, pr.mailer, pr.resetMessage(user, token), user.ID)
+
+	return nil
+}
+
+func (pr *PasswordResetModule) resetMessage(user *model.UserModel, token string) lib.Message {
+	link := tokenLink(pr.mail.ResetURL, token)
+
+	return lib.Message{
+		To:      user.Email.String,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hello %s,\n\nSomeone asked to reset the password of your account. Open this link to choose a new one:\n\n%s\n\nThe link expires in %s and can be used once. If you did not ask for this, you can ignore this email.\n",
+			user.Username, link, pr.mail.ResetTokenTTL,
+		),
+	}
 }
 
 func (pr *PasswordResetModule) ResetPassword(ctx context.Context, param PasswordResetConfigParam) (interface{}, error) {
-	userID, err := model.ValidateResetToken(ctx, pr.db, param.Token)
+	tokenHash := lib.HashToken(param.Token)
+
+	userID, err := model.ValidateResetToken(ctx, pr.db, tokenHash)
+	if err != nil {
+		return nil, lib.Unauthorized("invalid or expired token")
+	}
+
+	user, err := model.GetUserByID(ctx, pr.db, userID)
 	if err != nil {
-		return nil, errors.New("invalid or expired token")
+		return nil, lib.NotFound("user not found")
 	}
 
-	hashedPass, err := lib.HashPassword(param.NewPassword)
+	err = pr.passwords.check(ctx, pr.db, userID, user.Username, param.NewPassword, user.Password)
 	if err != nil {
-		return nil, errors.New("failed to hash password")
+		return nil, err
 	}
 
-	err = model.UpdatePassword(ctx, pr.db, userID, hashedPass)
+	hashedPass, err := pr.passwords.hash(param.NewPassword)
 	if err != nil {
-		return nil, errors.New("failed to update password")
+		return nil, err
 	}
 
-	err = model.DeleteResetToken(ctx, pr.db, param.Token)
+	tx, err := pr.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
+
+	// token dihapus di transaksi yang sama, jadi dua permintaan dengan token
+	// yang sama tidak bisa sama-sama berhasil
+	consumedBy, err := model.ConsumeResetToken(ctx, tx, tokenHash)
+	if err != nil || consumedBy != userID {
+		return nil, lib.Unauthorized("invalid or expired token")
+	}
+
+	err = pr.passwords.set(ctx, tx, userID, hashedPass)
+	if err != nil {
+		return nil, err
+	}
+
+	err = model.DeleteUserResetTokens(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// siapa pun yang login dengan kata sandi lama harus login ulang
+	revoked, err := model.RevokeUserSessions(ctx, tx, userID, "", userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	revokedSessions := notifyRevokedSessions(ctx, pr.db, revoked)
+
+	recordAudit(ctx, pr.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditPasswordReset,
+		Entity:   model.EntityUsers,
+		EntityID: userID,
+		Details:  map[string]interface{}{"revoked_sessions": revokedSessions},
+	})
 
 	return map[string]string{
 		"message": "Password successfully reset",
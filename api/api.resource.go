@@ -4,7 +4,6 @@ import (
 	"app-bookstore/lib"
 	"app-bookstore/model"
 	"context"
-	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,37 +12,50 @@ import (
 )
 
 type ResourceModule struct {
-	db   *sqlx.DB
-	name string
-	JWT  lib.Jwt
+	db     *sqlx.DB
+	name   string
+	JWT    lib.Jwt
+	access *AccessPolicy
 }
 
-func NewResourceModule(db *sqlx.DB, jwt lib.Jwt) *ResourceModule {
+func NewResourceModule(db *sqlx.DB, jwt lib.Jwt, access *AccessPolicy) *ResourceModule {
 	return &ResourceModule{
-		db:   db,
-		name: "resource-module",
-		JWT:  jwt,
+		db:     db,
+		name:   "resource-module",
+		JWT:    jwt,
+		access: access,
 	}
 }
 
+// ResourceParam.Endpoint is a mux route template such as /api/v1/books/{id},
+// or a prefix ending in "/*" that covers every route below it.
 type ResourceParam struct {
-	Name        string `json:"name"`
-	Endpoint    string `json:"endpoint"`
-	Method      string `json:"method"`
-	Description string `json:"description"`
+	Name        string   `json:"name"`
+	Endpoint    string   `json:"endpoint"`
+	Methods     []string `json:"methods" validate:"omitempty,dive,oneof=GET POST PUT PATCH DELETE"`
+	Description string   `json:"description"`
 }
 
-func (rs *ResourceModule) List(ctx context.Context) ([]model.ResourceResponse, error) {
-	resources, err := model.GetAllResources(ctx, rs.db)
+func (rs *ResourceModule) List(ctx context.Context, filter lib.Filter) ([]model.ResourceResponse, lib.Pagination, error) {
+	err := rs.access.CheckIncludeDeleted(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, lib.Pagination{}, err
+	}
+
+	resources, total, err := model.GetAllResources(ctx, rs.db, filter)
+	if err != nil {
+		return nil, lib.Pagination{}, err
 	}
 
+	resources, hasMore := lib.TrimPage(filter, resources)
+
 	var response []model.ResourceResponse
+	var last lib.Cursor
 	for _, resource := range resources {
 		response = append(response, resource.Response())
+		last = lib.Cursor{CreatedAt: resource.CreatedAt, ID: resource.ID}
 	}
-	return response, nil
+	return response, lib.NewPagination(filter, total, len(resources), hasMore, last), nil
 }
 
 func (rs *ResourceModule) Detail(ctx context.Context, id uuid.UUID) (model.ResourceResponse, error) {
@@ -58,12 +70,20 @@ func (rs *ResourceModule) Detail(ctx context.Context, id uuid.UUID) (model.Resou
 func (rs *ResourceModule) Create(ctx context.Context, token string, param ResourceParam) (interface{}, error) {
 	claims, err := rs.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("failed to verify access token")
+		return nil, lib.Unauthorized("failed to verify access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	if !lib.ValidEndpointPattern(param.Endpoint) {
+		return nil, lib.Unprocessable("endpoint must start with / and may only end in /*")
+	}
+
+	if len(param.Methods) == 0 {
+		return nil, lib.Unprocessable("resource needs at least one method")
 	}
 
 	resourceID := uuid.New()
@@ -71,7 +91,7 @@ func (rs *ResourceModule) Create(ctx context.Context, token string, param Resour
 		ID:          resourceID,
 		Name:        param.Name,
 		Endpoint:    param.Endpoint,
-		Method:      param.Method,
+		Methods:     param.Methods,
 		Description: param.Description,
 		CreatedAt:   time.Now(),
 		CreatedBy:   userID,
@@ -82,25 +102,48 @@ func (rs *ResourceModule) Create(ctx context.Context, token string, param Resour
 		return nil, err
 	}
 
+	recordAudit(ctx, rs.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditCreate,
+		Entity:   model.EntityResources,
+		EntityID: resource.ID,
+		After:    resource.Response(),
+	})
+
 	return resource.Response(), nil
 }
 
 func (rs *ResourceModule) Update(ctx context.Context, token string, param ResourceParam, id uuid.UUID) (interface{}, error) {
 	claims, err := rs.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired access token")
+		return nil, lib.Unauthorized("invalid or expired access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	if param.Endpoint != "" && !lib.ValidEndpointPattern(param.Endpoint) {
+		return nil, lib.Unprocessable("endpoint must start with / and may only end in /*")
+	}
+
+	before, err := model.GetOneResource(ctx, rs.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	// methods kosong berarti tidak diubah
+	var methods pq.StringArray
+	if len(param.Methods) > 0 {
+		methods = param.Methods
 	}
 
 	resource := model.ResourceModel{
 		ID:          id,
 		Name:        param.Name,
 		Endpoint:    param.Endpoint,
-		Method:      param.Method,
+		Methods:     methods,
 		Description: param.Description,
 		UpdatedAt: pq.NullTime{
 			Time:  time.Now(),
@@ -117,5 +160,85 @@ func (rs *ResourceModule) Update(ctx context.Context, token string, param Resour
 		return nil, err
 	}
 
+	notifyPermissionChange(ctx, rs.db, lib.ScopeGrants, "")
+
+	recordAudit(ctx, rs.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditUpdate,
+		Entity:   model.EntityResources,
+		EntityID: id,
+		Before:   before.Response(),
+		After:    resource.Response(),
+	})
+
 	return resource.Response(), nil
 }
+
+// Delete soft deletes the resource; the row stays in the table for history and restore.
+func (rs *ResourceModule) Delete(ctx context.Context, token string, id uuid.UUID) error {
+	claims, err := rs.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOneResource(ctx, rs.db, id)
+	if err != nil {
+		return err
+	}
+
+	err = model.SoftDelete(ctx, rs.db, model.TableResources, id, userID)
+	if err != nil {
+		return err
+	}
+
+	notifyPermissionChange(ctx, rs.db, lib.ScopeGrants, "")
+
+	recordAudit(ctx, rs.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditDelete,
+		Entity:   model.EntityResources,
+		EntityID: id,
+		Before:   before.Response(),
+	})
+
+	return nil
+}
+
+func (rs *ResourceModule) Restore(ctx context.Context, token string, id uuid.UUID) (model.ResourceResponse, error) {
+	claims, err := rs.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return model.ResourceResponse{}, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return model.ResourceResponse{}, lib.Unauthorized("failed to parse access token")
+	}
+
+	err = model.Restore(ctx, rs.db, model.TableResources, id, userID)
+	if err != nil {
+		return model.ResourceResponse{}, err
+	}
+
+	restored, err := rs.Detail(ctx, id)
+	if err != nil {
+		return model.ResourceResponse{}, err
+	}
+
+	notifyPermissionChange(ctx, rs.db, lib.ScopeGrants, "")
+
+	recordAudit(ctx, rs.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditRestore,
+		Entity:   model.EntityResources,
+		EntityID: id,
+		After:    restored,
+	})
+
+	return restored, nil
+}
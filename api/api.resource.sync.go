@@ -0,0 +1,254 @@
+package api
+
+import (
+	"app-bookstore/lib"
+	"app-bookstore/model"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// Route is one registered mux route template with the methods it serves.
+type Route struct {
+	Template string
+	Methods  []string
+}
+
+type ResourceSyncOption struct {
+	DryRun bool
+	// GrantRole is the identifier of a role that gets every method of every
+	// resource. Empty means no grants are added.
+	GrantRole string
+}
+
+const (
+	SyncCreate        = "create"
+	SyncAddMethods    = "add_methods"
+	SyncRouteMissing  = "route_missing"
+	SyncRouteRestored = "route_restored"
+	SyncGrant         = "grant"
+)
+
+type ResourceSyncChange struct {
+	Action     string    `json:"action"`
+	ResourceID uuid.UUID `json:"resource_id"`
+	Endpoint   string    `json:"endpoint"`
+	Methods    []string  `json:"methods"`
+	Role       string    `json:"role,omitempty"`
+}
+
+type ResourceSyncReport struct {
+	DryRun  bool                 `json:"dry_run"`
+	Changes []ResourceSyncChange `json:"changes"`
+}
+
+// Sync makes every route reachable through some resource: routes not covered
+// by an existing resource (wildcards included) get their methods added to the
+// resource with the same endpoint, or a new resource. Resources matching no
+// route are flagged with route_missing_at. With DryRun nothing is written and
+// the report is the diff that would be applied.
+func (rs *ResourceModule) Sync(ctx context.Context, routes []Route, option ResourceSyncOption) (ResourceSyncReport, error) {
+	report := ResourceSyncReport{DryRun: option.DryRun}
+
+	resources, err := model.GetLiveResources(ctx, rs.db)
+	if err != nil {
+		return report, err
+	}
+
+	for _, route := range routes {
+		var uncovered []string
+		for _, method := range route.Methods {
+			if !resourcesCover(resources, route.Template, method) {
+				uncovered = append(uncovered, method)
+			}
+		}
+
+		if len(uncovered) == 0 {
+			continue
+		}
+
+		i := slices.IndexFunc(resources, func(resource model.ResourceModel) bool {
+			return resource.Endpoint == route.Template
+		})
+		if i >= 0 {
+			resources[i].Methods = append(resources[i].Methods, uncovered...)
+			report.Changes = append(report.Changes, ResourceSyncChange{
+				Action:     SyncAddMethods,
+				ResourceID: resources[i].ID,
+				Endpoint:   route.Template,
+				Methods:    uncovered,
+			})
+			continue
+		}
+
+		resource := model.ResourceModel{
+			ID:       uuid.New(),
+			Name:     route.Template,
+			Endpoint: route.Template,
+			Methods:  uncovered,
+		}
+		resources = append(resources, resource)
+		report.Changes = append(report.Changes, ResourceSyncChange{
+			Action:     SyncCreate,
+			ResourceID: resource.ID,
+			Endpoint:   route.Template,
+			Methods:    uncovered,
+		})
+	}
+
+	for i, resource := range resources {
+		routed := slices.ContainsFunc(routes, func(route Route) bool {
+			return lib.MatchEndpoint(resource.Endpoint, route.Template)
+		})
+
+		switch {
+		case !routed && !resource.RouteMissingAt.Valid:
+			resources[i].RouteMissingAt.Valid = true
+			report.Changes = append(report.Changes, ResourceSyncChange{
+				Action:     SyncRouteMissing,
+				ResourceID: resource.ID,
+				Endpoint:   resource.Endpoint,
+				Methods:    resource.Methods,
+			})
+		case routed && resource.RouteMissingAt.Valid:
+			resources[i].RouteMissingAt.Valid = false
+			report.Changes = append(report.Changes, ResourceSyncChange{
+				Action:     SyncRouteRestored,
+				ResourceID: resource.ID,
+				Endpoint:   resource.Endpoint,
+				Methods:    resource.Methods,
+			})
+		}
+	}
+
+	var roleID uuid.UUID
+	if option.GrantRole != "" {
+		role, err := model.GetRoleByIdentifier(ctx, rs.db, option.GrantRole)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return report, lib.NotFound(fmt.Sprintf("role %q does not exist", option.GrantRole))
+			}
+			return report, err
+		}
+		roleID = role.ID
+
+		granted, err := model.GetRoleGrantKeys(ctx, rs.db, roleID)
+		if err != nil {
+			return report, err
+		}
+
+		for _, resource := range resources {
+			if resource.RouteMissingAt.Valid {
+				continue
+			}
+
+			var missing []string
+			for _, method := range resource.Methods {
+				if !granted[method+" "+resource.ID.String()] {
+					missing = append(missing, method)
+				}
+			}
+
+			if len(missing) > 0 {
+				report.Changes = append(report.Changes, ResourceSyncChange{
+					Action:     SyncGrant,
+					ResourceID: resource.ID,
+					Endpoint:   resource.Endpoint,
+					Methods:    missing,
+					Role:       option.GrantRole,
+				})
+			}
+		}
+	}
+
+	if option.DryRun || len(report.Changes) == 0 {
+		return report, nil
+	}
+
+	err = rs.applySync(ctx, resources, roleID, report.Changes)
+	if err != nil {
+		return report, err
+	}
+
+	return report, nil
+}
+
+func (rs *ResourceModule) applySync(ctx context.Context, resources []model.ResourceModel, roleID uuid.UUID, changes []ResourceSyncChange) error {
+	byID := make(map[uuid.UUID]model.ResourceModel, len(resources))
+	for _, resource := range resources {
+		byID[resource.ID] = resource
+	}
+
+	tx, err := rs.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var missing, restored []uuid.UUID
+	for _, change := range changes {
+		switch change.Action {
+		case SyncCreate:
+			resource := byID[change.ResourceID]
+			err = resource.InsertRouteResource(ctx, tx)
+		case SyncAddMethods:
+			err = model.SetResourceMethods(ctx, tx, change.ResourceID, byID[change.ResourceID].Methods)
+		case SyncRouteMissing:
+			missing = append(missing, change.ResourceID)
+		case SyncRouteRestored:
+			restored = append(restored, change.ResourceID)
+		case SyncGrant:
+			for _, method := range change.Methods {
+				err = model.GrantRoleResource(ctx, tx, roleID, change.ResourceID, method)
+				if err != nil {
+					break
+				}
+			}
+		}
+
+		if err != nil {
+			return fmt.Errorf("%s %s: %w", change.Action, change.Endpoint, err)
+		}
+	}
+
+	if len(missing) > 0 {
+		err = model.SetResourceRouteMissing(ctx, tx, missing, true)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(restored) > 0 {
+		err = model.SetResourceRouteMissing(ctx, tx, restored, false)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	notifyPermissionChange(ctx, rs.db, lib.ScopeGrants, "")
+
+	recordAudit(ctx, rs.db, auditEntry{
+		Action:  model.AuditSync,
+		Entity:  model.EntityResources,
+		Details: map[string]interface{}{"changes": changes},
+	})
+
+	return nil
+}
+
+// resourcesCover reports whether some resource already lets grants reach the
+// route template with method.
+func resourcesCover(resources []model.ResourceModel, template string, method string) bool {
+	return slices.ContainsFunc(resources, func(resource model.ResourceModel) bool {
+		return slices.Contains(resource.Methods, method) && lib.MatchEndpoint(resource.Endpoint, template)
+	})
+}
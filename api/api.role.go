@@ -4,7 +4,9 @@ import (
 	"app-bookstore/lib"
 	"app-bookstore/model"
 	"context"
+	"database/sql"
 	"errors"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,38 +15,50 @@ import (
 )
 
 type RoleModule struct {
-	db   *sqlx.DB
-	name string
-	JWT  lib.Jwt
+	db     *sqlx.DB
+	name   string
+	JWT    lib.Jwt
+	access *AccessPolicy
 }
 
-func NewRoleModule(db *sqlx.DB, jwt lib.Jwt) *RoleModule {
+func NewRoleModule(db *sqlx.DB, jwt lib.Jwt, access *AccessPolicy) *RoleModule {
 	return &RoleModule{
-		db:   db,
-		name: "role-module",
-		JWT:  jwt,
+		db:     db,
+		name:   "role-module",
+		JWT:    jwt,
+		access: access,
 	}
 }
 
 type RoleParam struct {
-	Identifier  string    `json:"identifier"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	CreatedBy   uuid.UUID `json:"created_by"`
+	Identifier  string        `json:"identifier"`
+	Description string        `json:"description"`
+	ParentID    uuid.NullUUID `json:"parent_role_id"`
+	CreatedAt   time.Time     `json:"created_at"`
+	CreatedBy   uuid.UUID     `json:"created_by"`
 }
 
-func (r *RoleModule) List(ctx context.Context) ([]model.RoleResponse, error) {
-	roles, err := model.GetAllRoles(ctx, r.db)
+func (r *RoleModule) List(ctx context.Context, filter lib.Filter) ([]model.RoleResponse, lib.Pagination, error) {
+	err := r.access.CheckIncludeDeleted(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, lib.Pagination{}, err
+	}
+
+	roles, total, err := model.GetAllRoles(ctx, r.db, filter)
+	if err != nil {
+		return nil, lib.Pagination{}, err
 	}
 
+	roles, hasMore := lib.TrimPage(filter, roles)
+
 	var response []model.RoleResponse
+	var last lib.Cursor
 	for _, role := range roles {
 		response = append(response, role.Response())
+		last = lib.Cursor{CreatedAt: role.CreatedAt, ID: role.ID}
 	}
 
-	return response, nil
+	return response, lib.NewPagination(filter, total, len(roles), hasMore, last), nil
 }
 
 func (r *RoleModule) Detail(ctx context.Context, id uuid.UUID) (model.RoleResponse, error) {
@@ -59,12 +73,12 @@ func (r *RoleModule) Detail(ctx context.Context, id uuid.UUID) (model.RoleRespon
 func (r *RoleModule) Create(ctx context.Context, token string, param RoleParam) (interface{}, error) {
 	claims, err := r.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("failed to verify access token")
+		return nil, lib.Unauthorized("failed to verify access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
 	}
 
 	roleID := uuid.New()
@@ -72,33 +86,54 @@ func (r *RoleModule) Create(ctx context.Context, token string, param RoleParam)
 		ID:          roleID,
 		Identifier:  param.Identifier,
 		Description: param.Description,
+		ParentID:    param.ParentID,
 		CreatedAt:   time.Now(),
 		CreatedBy:   userID,
 	}
 
+	// role baru belum punya turunan, cukup pastikan parent-nya ada
+	err = r.checkParent(ctx, r.db, uuid.Nil, param.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
 	err = role.Insert(ctx, r.db)
 	if err != nil {
 		return nil, err
 	}
 
+	recordAudit(ctx, r.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditCreate,
+		Entity:   model.EntityRoles,
+		EntityID: role.ID,
+		After:    role.Response(),
+	})
+
 	return role.Response(), nil
 }
 
 func (r *RoleModule) UpdateRole(ctx context.Context, token string, param RoleParam, id uuid.UUID) (interface{}, error) {
 	claims, err := r.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired access token")
+		return nil, lib.Unauthorized("invalid or expired access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOneRole(ctx, r.db, id)
+	if err != nil {
+		return nil, err
 	}
 
 	role := model.RoleModel{
 		ID:          id,
 		Identifier:  param.Identifier,
 		Description: param.Description,
+		ParentID:    param.ParentID,
 		UpdatedAt: pq.NullTime{
 			Time:  time.Now(),
 			Valid: true,
@@ -109,10 +144,178 @@ func (r *RoleModule) UpdateRole(ctx context.Context, token string, param RolePar
 		},
 	}
 
-	err = role.Update(ctx, r.db)
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = model.LockRoleHierarchy(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.checkParent(ctx, tx, id, param.ParentID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = role.Update(ctx, tx)
 	if err != nil {
 		return nil, err
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	if before.ParentID != role.ParentID {
+		notifyPermissionChange(ctx, r.db, lib.ScopeGrants, "")
+	}
+
+	recordAudit(ctx, r.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditUpdate,
+		Entity:   model.EntityRoles,
+		EntityID: id,
+		Before:   before.Response(),
+		After:    role.Response(),
+	})
+
 	return role.Response(), nil
 }
+
+// Delete soft deletes the role; the row stays in the table for history and restore.
+func (r *RoleModule) Delete(ctx context.Context, token string, id uuid.UUID) error {
+	claims, err := r.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOneRole(ctx, r.db, id)
+	if err != nil {
+		return err
+	}
+
+	err = model.SoftDelete(ctx, r.db, model.TableRoles, id, userID)
+	if err != nil {
+		return err
+	}
+
+	notifyPermissionChange(ctx, r.db, lib.ScopeAll, "")
+
+	recordAudit(ctx, r.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditDelete,
+		Entity:   model.EntityRoles,
+		EntityID: id,
+		Before:   before.Response(),
+	})
+
+	return nil
+}
+
+func (r *RoleModule) Restore(ctx context.Context, token string, id uuid.UUID) (model.RoleResponse, error) {
+	claims, err := r.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return model.RoleResponse{}, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return model.RoleResponse{}, lib.Unauthorized("failed to parse access token")
+	}
+
+	err = model.Restore(ctx, r.db, model.TableRoles, id, userID)
+	if err != nil {
+		return model.RoleResponse{}, err
+	}
+
+	restored, err := r.Detail(ctx, id)
+	if err != nil {
+		return model.RoleResponse{}, err
+	}
+
+	notifyPermissionChange(ctx, r.db, lib.ScopeAll, "")
+
+	recordAudit(ctx, r.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditRestore,
+		Entity:   model.EntityRoles,
+		EntityID: id,
+		After:    restored,
+	})
+
+	return restored, nil
+}
+
+// EffectivePermissions lists the grants the role has, inherited ones included,
+// with the role each comes from. Applied marks the grant that decides access
+// for its resource and method.
+func (r *RoleModule) EffectivePermissions(ctx context.Context, id uuid.UUID) ([]model.EffectivePermissionResponse, error) {
+	_, err := model.GetOneRole(ctx, r.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := model.GetEffectivePermissions(ctx, r.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	response := []model.EffectivePermissionResponse{}
+	decided := map[string]bool{}
+	for _, permission := range permissions {
+		item := permission.Response()
+
+		// baris pertama per resource+method adalah yang menentukan
+		key := permission.Method + " " + permission.ResourceID.String()
+		item.Applied = !decided[key]
+		decided[key] = true
+
+		response = append(response, item)
+	}
+
+	return response, nil
+}
+
+// checkParent rejects a parent that does not exist or that has the role among
+// its ancestors, which would close a cycle. id is uuid.Nil for a new role.
+func (r *RoleModule) checkParent(ctx context.Context, db sqlx.QueryerContext, id uuid.UUID, parent uuid.NullUUID) error {
+	if !parent.Valid {
+		return nil
+	}
+
+	if parent.UUID == id {
+		return lib.Unprocessable("role cannot be its own parent")
+	}
+
+	_, err := model.GetOneRole(ctx, r.db, parent.UUID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return lib.Unprocessable("parent role does not exist")
+		}
+		return err
+	}
+
+	if id == uuid.Nil {
+		return nil
+	}
+
+	ancestorIDs, err := model.GetRoleAncestorIDs(ctx, db, parent.UUID)
+	if err != nil {
+		return err
+	}
+
+	if slices.Contains(ancestorIDs, id) {
+		return lib.Unprocessable("parent role would create a cycle in the role hierarchy")
+	}
+
+	return nil
+}
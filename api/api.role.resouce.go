@@ -4,7 +4,10 @@ import (
 	"app-bookstore/lib"
 	"app-bookstore/model"
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,16 +16,18 @@ import (
 )
 
 type RoleResourceModule struct {
-	db   *sqlx.DB
-	name string
-	JWT  lib.Jwt
+	db     *sqlx.DB
+	name   string
+	JWT    lib.Jwt
+	access *AccessPolicy
 }
 
-func NewRoleResourceModule(db *sqlx.DB, jwt lib.Jwt) *RoleResourceModule {
+func NewRoleResourceModule(db *sqlx.DB, jwt lib.Jwt, access *AccessPolicy) *RoleResourceModule {
 	return &RoleResourceModule{
-		db:   db,
-		name: "role-resource-module",
-		JWT:  jwt,
+		db:     db,
+		name:   "role-resource-module",
+		JWT:    jwt,
+		access: access,
 	}
 }
 
@@ -30,21 +35,39 @@ type RoleResouceParam struct {
 	RoleID     uuid.UUID `json:"role_id"`
 	ResourceID uuid.UUID `json:"resource_id"`
 	Method     string    `json:"method"`
+	Effect     string    `json:"effect" validate:"omitempty,oneof=allow deny"`
 	IsActive   bool      `json:"is_active"`
 }
 
-func (rr *RoleResourceModule) List(ctx context.Context) ([]model.RoleResourceResponse, error) {
-	roleResources, err := model.GetAllRoleResource(ctx, rr.db)
+// effect defaults to allow, so existing clients keep creating plain grants.
+func (p RoleResouceParam) effect() string {
+	if p.Effect == "" {
+		return model.EffectAllow
+	}
+	return p.Effect
+}
+
+func (rr *RoleResourceModule) List(ctx context.Context, filter lib.Filter) ([]model.RoleResourceResponse, lib.Pagination, error) {
+	err := rr.access.CheckIncludeDeleted(ctx, filter)
 	if err != nil {
-		return nil, err
+		return nil, lib.Pagination{}, err
 	}
 
+	roleResources, total, err := model.GetAllRoleResource(ctx, rr.db, filter)
+	if err != nil {
+		return nil, lib.Pagination{}, err
+	}
+
+	roleResources, hasMore := lib.TrimPage(filter, roleResources)
+
 	var response []model.RoleResourceResponse
+	var last lib.Cursor
 	for _, roleResource := range roleResources {
 		response = append(response, roleResource.Response())
+		last = lib.Cursor{CreatedAt: roleResource.CreatedAt, ID: roleResource.ID}
 	}
 
-	return response, nil
+	return response, lib.NewPagination(filter, total, len(roleResources), hasMore, last), nil
 }
 
 func (rr *RoleResourceModule) Detail(ctx context.Context, id uuid.UUID) (model.RoleResourceResponse, error) {
@@ -59,12 +82,17 @@ func (rr *RoleResourceModule) Detail(ctx context.Context, id uuid.UUID) (model.R
 func (rr *RoleResourceModule) Create(ctx context.Context, token string, param RoleResouceParam) (interface{}, error) {
 	claims, err := rr.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("failed to verify access token")
+		return nil, lib.Unauthorized("failed to verify access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	err = rr.checkMethod(ctx, param.ResourceID, param.Method)
+	if err != nil {
+		return nil, err
 	}
 
 	roleResourceID := uuid.New()
@@ -73,6 +101,7 @@ func (rr *RoleResourceModule) Create(ctx context.Context, token string, param Ro
 		RoleID:     param.RoleID,
 		ResourceID: param.ResourceID,
 		Method:     param.Method,
+		Effect:     param.effect(),
 		IsActive:   param.IsActive,
 		CreatedAt:  time.Now(),
 		CreatedBy:  userID,
@@ -83,18 +112,38 @@ func (rr *RoleResourceModule) Create(ctx context.Context, token string, param Ro
 		return nil, err
 	}
 
+	notifyPermissionChange(ctx, rr.db, lib.ScopeGrants, "")
+
+	recordAudit(ctx, rr.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditCreate,
+		Entity:   model.EntityRoleResources,
+		EntityID: roleResource.ID,
+		After:    roleResource.Response(),
+	})
+
 	return roleResource.Response(), nil
 }
 
 func (rr *RoleResourceModule) Update(ctx context.Context, token string, param RoleResouceParam, id uuid.UUID) (interface{}, error) {
 	claims, err := rr.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired access token")
+		return nil, lib.Unauthorized("invalid or expired access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOneRoleResource(ctx, rr.db, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = rr.checkMethod(ctx, param.ResourceID, param.Method)
+	if err != nil {
+		return nil, err
 	}
 
 	roleResouce := model.RoleResourceModel{
@@ -102,6 +151,7 @@ func (rr *RoleResourceModule) Update(ctx context.Context, token string, param Ro
 		RoleID:     param.RoleID,
 		ResourceID: param.ResourceID,
 		Method:     param.Method,
+		Effect:     param.effect(),
 		IsActive:   param.IsActive,
 		UpdatedAt: pq.NullTime{
 			Time:  time.Now(),
@@ -118,5 +168,103 @@ func (rr *RoleResourceModule) Update(ctx context.Context, token string, param Ro
 		return nil, err
 	}
 
+	notifyPermissionChange(ctx, rr.db, lib.ScopeGrants, "")
+
+	recordAudit(ctx, rr.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditUpdate,
+		Entity:   model.EntityRoleResources,
+		EntityID: id,
+		Before:   before.Response(),
+		After:    roleResouce.Response(),
+	})
+
 	return roleResouce.Response(), nil
 }
+
+// Delete soft deletes the role resource; the row stays in the table for history and restore.
+func (rr *RoleResourceModule) Delete(ctx context.Context, token string, id uuid.UUID) error {
+	claims, err := rr.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOneRoleResource(ctx, rr.db, id)
+	if err != nil {
+		return err
+	}
+
+	err = model.SoftDelete(ctx, rr.db, model.TableRoleResources, id, userID)
+	if err != nil {
+		return err
+	}
+
+	notifyPermissionChange(ctx, rr.db, lib.ScopeGrants, "")
+
+	recordAudit(ctx, rr.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditDelete,
+		Entity:   model.EntityRoleResources,
+		EntityID: id,
+		Before:   before.Response(),
+	})
+
+	return nil
+}
+
+func (rr *RoleResourceModule) Restore(ctx context.Context, token string, id uuid.UUID) (model.RoleResourceResponse, error) {
+	claims, err := rr.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return model.RoleResourceResponse{}, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return model.RoleResourceResponse{}, lib.Unauthorized("failed to parse access token")
+	}
+
+	err = model.Restore(ctx, rr.db, model.TableRoleResources, id, userID)
+	if err != nil {
+		return model.RoleResourceResponse{}, err
+	}
+
+	restored, err := rr.Detail(ctx, id)
+	if err != nil {
+		return model.RoleResourceResponse{}, err
+	}
+
+	notifyPermissionChange(ctx, rr.db, lib.ScopeGrants, "")
+
+	recordAudit(ctx, rr.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditRestore,
+		Entity:   model.EntityRoleResources,
+		EntityID: id,
+		After:    restored,
+	})
+
+	return restored, nil
+}
+
+// checkMethod rejects grants for a method the resource does not declare; such
+// a grant would never match in CheckAccess.
+func (rr *RoleResourceModule) checkMethod(ctx context.Context, resourceID uuid.UUID, method string) error {
+	resource, err := model.GetOneResource(ctx, rr.db, resourceID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return lib.Unprocessable("resource does not exist")
+		}
+		return err
+	}
+
+	if !slices.Contains(resource.Methods, method) {
+		return lib.Unprocessable(fmt.Sprintf("resource does not allow method %s", method))
+	}
+
+	return nil
+}
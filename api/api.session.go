@@ -0,0 +1,210 @@
+package api
+
+import (
+	"app-bookstore/lib"
+	"app-bookstore/model"
+	"context"
+	"slices"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+// SessionModule manages logins. A session as the user sees it is a token
+// family: every token pair rotated from one login shares its ID.
+type SessionModule struct {
+	db   *sqlx.DB
+	name string
+	JWT  lib.Jwt
+}
+
+func NewSessionModule(db *sqlx.DB, jwt lib.Jwt) *SessionModule {
+	return &SessionModule{
+		db:   db,
+		name: "session-module",
+		JWT:  jwt,
+	}
+}
+
+type RevokedSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+func (s *SessionModule) caller(token string) (uuid.UUID, error) {
+	claims, err := s.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return uuid.Nil, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return uuid.Nil, lib.Unauthorized("invalid user id in token")
+	}
+
+	return userID, nil
+}
+
+// MySessions lists the caller's live sessions; the one the token belongs to is
+// marked current.
+func (s *SessionModule) MySessions(ctx context.Context, token string) ([]model.UserSessionResponse, error) {
+	userID, err := s.caller(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.list(ctx, userID, lib.HashToken(token))
+}
+
+// RevokeMySession ends one of the caller's sessions, the current one included.
+func (s *SessionModule) RevokeMySession(ctx context.Context, token string, id uuid.UUID) error {
+	userID, err := s.caller(token)
+	if err != nil {
+		return err
+	}
+
+	return s.revoke(ctx, userID, userID, id)
+}
+
+// RevokeMyOtherSessions ends every session of the caller except the current one.
+func (s *SessionModule) RevokeMyOtherSessions(ctx context.Context, token string) (RevokedSessionsResponse, error) {
+	userID, err := s.caller(token)
+	if err != nil {
+		return RevokedSessionsResponse{}, err
+	}
+
+	return s.revokeAll(ctx, userID, userID, lib.HashToken(token))
+}
+
+// UserSessions lists the live sessions of any user.
+func (s *SessionModule) UserSessions(ctx context.Context, userID uuid.UUID) ([]model.UserSessionResponse, error) {
+	_, err := model.GetUserByID(ctx, s.db, userID)
+	if err != nil {
+		return nil, lib.NotFound("user not found")
+	}
+
+	return s.list(ctx, userID, "")
+}
+
+// RevokeUserSession ends one session of any user.
+func (s *SessionModule) RevokeUserSession(ctx context.Context, token string, userID uuid.UUID, id uuid.UUID) error {
+	actorID, err := s.caller(token)
+	if err != nil {
+		return err
+	}
+
+	return s.revoke(ctx, actorID, userID, id)
+}
+
+// RevokeUserSessions ends every session of any user.
+func (s *SessionModule) RevokeUserSessions(ctx context.Context, token string, userID uuid.UUID) (RevokedSessionsResponse, error) {
+	actorID, err := s.caller(token)
+	if err != nil {
+		return RevokedSessionsResponse{}, err
+	}
+
+	_, err = model.GetUserByID(ctx, s.db, userID)
+	if err != nil {
+		return RevokedSessionsResponse{}, lib.NotFound("user not found")
+	}
+
+	return s.revokeAll(ctx, actorID, userID, "")
+}
+
+// PurgeExpired deletes sessions that can no longer be used and expired
+// password reset and email verification tokens.
+func (s *SessionModule) PurgeExpired(ctx context.Context) error {
+	sessions, err := model.PurgeExpiredSessions(ctx, s.db)
+	if err != nil {
+		return err
+	}
+
+	resetTokens, err := model.PurgeExpiredResetTokens(ctx, s.db)
+	if err != nil {
+		return err
+	}
+
+	verifications, err := model.PurgeExpiredEmailVerifications(ctx, s.db)
+	if err != nil {
+		return err
+	}
+
+	if sessions > 0 || resetTokens > 0 || verifications > 0 {
+		log.Info().Msgf("Purged %d expired sessions, %d expired password reset tokens and %d expired email verifications", sessions, resetTokens, verifications)
+	}
+
+	return nil
+}
+
+func (s *SessionModule) list(ctx context.Context, userID uuid.UUID, currentTokenHash string) ([]model.UserSessionResponse, error) {
+	sessions, err := model.GetUserSessions(ctx, s.db, userID, currentTokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	response := []model.UserSessionResponse{}
+	for _, session := range sessions {
+		response = append(response, session.Response())
+	}
+
+	return response, nil
+}
+
+func (s *SessionModule) revoke(ctx context.Context, actorID uuid.UUID, userID uuid.UUID, id uuid.UUID) error {
+	revoked, err := model.RevokeUserSession(ctx, s.db, userID, id, actorID)
+	if err != nil {
+		return err
+	}
+
+	if len(revoked) == 0 {
+		return lib.NotFound("session not found")
+	}
+
+	for _, tokenHash := range revoked {
+		notifyPermissionChange(ctx, s.db, lib.ScopeSession, tokenHash)
+	}
+
+	recordAudit(ctx, s.db, auditEntry{
+		Actor:    actorID,
+		Action:   model.AuditSessionRevoke,
+		Entity:   model.EntitySessions,
+		EntityID: id,
+		Details:  map[string]interface{}{"user_id": userID},
+	})
+
+	return nil
+}
+
+func (s *SessionModule) revokeAll(ctx context.Context, actorID uuid.UUID, userID uuid.UUID, keepTokenHash string) (RevokedSessionsResponse, error) {
+	revoked, err := model.RevokeUserSessions(ctx, s.db, userID, keepTokenHash, actorID)
+	if err != nil {
+		return RevokedSessionsResponse{}, err
+	}
+
+	families := notifyRevokedSessions(ctx, s.db, revoked)
+	if len(families) > 0 {
+		recordAudit(ctx, s.db, auditEntry{
+			Actor:    actorID,
+			Action:   model.AuditSessionRevoke,
+			Entity:   model.EntityUsers,
+			EntityID: userID,
+			Details:  map[string]interface{}{"session_ids": families, "kept_current": keepTokenHash != ""},
+		})
+	}
+
+	return RevokedSessionsResponse{Revoked: len(families)}, nil
+}
+
+// notifyRevokedSessions drops the revoked tokens from every session cache and
+// returns the IDs of the sessions they belonged to.
+func notifyRevokedSessions(ctx context.Context, db sqlx.ExtContext, revoked []model.RevokedSessionModel) []uuid.UUID {
+	var families []uuid.UUID
+	for _, session := range revoked {
+		notifyPermissionChange(ctx, db, lib.ScopeSession, session.TokenHash)
+		if !slices.Contains(families, session.FamilyID) {
+			families = append(families, session.FamilyID)
+		}
+	}
+
+	return families
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"app-bookstore/lib"
+	"app-bookstore/model"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type RefreshTokenParam struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// issueSession signs a new access token and refresh token for the user and
+// stores their hashes as a session of familyID, along with the device the
+// request came from. A login starts a new family. Only active users get one;
+// the status stays locked until db commits.
+func (u *UserModule) issueSession(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, familyID uuid.UUID) (*LoginResponse, error) {
+	status, err := model.LockUserStatus(ctx, db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = lib.AccountStatusError(status)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionID := uuid.New()
+	if familyID == uuid.Nil {
+		familyID = sessionID
+	}
+
+	token, expiredAt, err := u.JWT.GenerateToken(&lib.JwtData{
+		UserID:    userID.String(),
+		SessionID: sessionID.String(),
+	})
+	if err != nil {
+		return nil, errors.New("failed to generate access token")
+	}
+
+	refreshToken, err := lib.GenerateRefreshToken()
+	if err != nil {
+		return nil, errors.New("failed to generate refresh token")
+	}
+
+	now := u.now()
+	refreshExpiresAt := now.Add(u.refreshTTL)
+	meta := lib.RequestMetaFromContext(ctx)
+
+	session := model.SessionModel{
+		ID:               sessionID,
+		FamilyID:         familyID,
+		UserID:           userID,
+		TokenHash:        lib.HashToken(token),
+		Expiration:       time.Unix(expiredAt, 0),
+		RefreshTokenHash: lib.HashToken(refreshToken),
+		RefreshExpiresAt: pq.NullTime{Time: refreshExpiresAt, Valid: true},
+		Device:           lib.DeviceName(meta.UserAgent),
+		IPAddress:        meta.ClientIP,
+		UserAgent:        meta.UserAgent,
+		CreatedAt:        now,
+		CreatedBy:        userID,
+	}
+
+	err = session.Insert(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResponse{
+		TokenType:        "Bearer",
+		AccessToken:      token,
+		ExpiresAt:        time.Unix(expiredAt, 0),
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExpiresAt,
+	}, nil
+}
+
+// Refresh rotates a refresh token into a new token pair. A refresh token can
+// be used once; presenting it again means it leaked, so the whole family is
+// revoked and the user has to log in again.
+func (u *UserModule) Refresh(ctx context.Context, param RefreshTokenParam) (*LoginResponse, error) {
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	session, err := model.GetSessionByRefreshToken(ctx, tx, lib.HashToken(param.RefreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, lib.Unauthorized("invalid refresh token")
+		}
+		return nil, err
+	}
+
+	if session.RotatedAt.Valid || session.RevokedAt.Valid {
+		revoked, err := model.RevokeSessionFamily(ctx, tx, session.FamilyID)
+		if err != nil {
+			return nil, err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tokenHash := range revoked {
+			notifyPermissionChange(ctx, u.db, lib.ScopeSession, tokenHash)
+		}
+
+		recordAudit(ctx, u.db, auditEntry{
+			Actor:    session.UserID,
+			Action:   model.AuditTokenReuse,
+			Entity:   model.EntitySessions,
+			EntityID: session.ID,
+			Details:  map[string]interface{}{"family_id": session.FamilyID, "revoked_sessions": len(revoked)},
+		})
+
+		return nil, lib.Unauthorized("refresh token has already been used, please login again")
+	}
+
+	if !session.RefreshExpiresAt.Valid || u.now().After(session.RefreshExpiresAt.Time) {
+		return nil, lib.Unauthorized("refresh token has expired, please login again")
+	}
+
+	err = model.MarkSessionRotated(ctx, tx, session.ID, session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := u.issueSession(ctx, tx, session.UserID, session.FamilyID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, u.db, auditEntry{
+		Actor:    session.UserID,
+		Action:   model.AuditTokenRefresh,
+		Entity:   model.EntitySessions,
+		EntityID: session.ID,
+		Details:  map[string]interface{}{"family_id": session.FamilyID},
+	})
+
+	return response, nil
+}
@@ -0,0 +1,623 @@
+package api
+
+import (
+	"app-bookstore/lib"
+	"app-bookstore/model"
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+var errInvalidTwoFactorCode = lib.Unprocessable("invalid two-factor code")
+
+type ChallengeParam struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+type ChallengeCodeParam struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required"`
+}
+
+type TwoFactorCodeParam struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// TwoFactorChallengeResponse is what Login returns instead of tokens when a
+// second step is needed. TwoFactor is "verify" (send a code to /login/2fa) or
+// "enroll" (set up TOTP through /login/2fa/enroll first).
+type TwoFactorChallengeResponse struct {
+	TwoFactor      string    `json:"two_factor"`
+	ChallengeToken string    `json:"challenge_token"`
+	ExpiresAt      time.Time `json:"expires_at"`
+}
+
+type TwoFactorEnrollResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+type TwoFactorConfirmResponse struct {
+	RecoveryCodes []string       `json:"recovery_codes"`
+	Session       *LoginResponse `json:"session,omitempty"`
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled           bool `json:"enabled"`
+	Pending           bool `json:"pending"`
+	Required          bool `json:"required"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// twoFactorState reports whether the user has confirmed TOTP and whether one
+// of their roles requires it.
+func (u *UserModule) twoFactorState(ctx context.Context, userID uuid.UUID) (enrolled bool, required bool, err error) {
+	totp, err := model.GetUserTOTP(ctx, u.db, userID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return false, false, err
+	}
+	enrolled = err == nil && totp.ConfirmedAt.Valid
+
+	roleIDs, err := model.GetUserRoleIDs(ctx, u.db, userID)
+	if err != nil {
+		return false, false, err
+	}
+
+	required, err = model.RolesRequireTwoFactor(ctx, u.db, roleIDs, u.twoFactor.RequiredRoles)
+	if err != nil {
+		return false, false, err
+	}
+
+	return enrolled, required, nil
+}
+
+func (u *UserModule) startChallenge(ctx context.Context, userID uuid.UUID, purpose string) (*TwoFactorChallengeResponse, error) {
+	token, err := lib.GenerateChallengeToken()
+	if err != nil {
+		return nil, errors.New("failed to generate challenge token")
+	}
+
+	now := u.now()
+	challenge := model.LoginChallengeModel{
+		ID:        uuid.New(),
+		UserID:    userID,
+		TokenHash: lib.HashToken(token),
+		Purpose:   purpose,
+		ExpiresAt: now.Add(u.twoFactor.ChallengeTTL),
+		CreatedAt: now,
+	}
+
+	err = challenge.Insert(ctx, u.db)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TwoFactorChallengeResponse{
+		TwoFactor:      purpose,
+		ChallengeToken: token,
+		ExpiresAt:      challenge.ExpiresAt,
+	}, nil
+}
+
+// lockChallenge loads a usable challenge of the given purpose inside tx.
+func (u *UserModule) lockChallenge(ctx context.Context, tx *sqlx.Tx, token string, purpose string) (model.LoginChallengeModel, error) {
+	challenge, err := model.LockLoginChallenge(ctx, tx, lib.HashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return challenge, lib.Unauthorized("invalid challenge token")
+		}
+		return challenge, err
+	}
+
+	switch {
+	case challenge.Purpose != purpose:
+		return challenge, lib.Unauthorized("invalid challenge token")
+	case challenge.UsedAt.Valid, !u.now().Before(challenge.ExpiresAt):
+		return challenge, lib.Unauthorized("challenge has expired, please login again")
+	case u.twoFactor.MaxAttempts > 0 && challenge.Attempts >= u.twoFactor.MaxAttempts:
+		return challenge, lib.Unauthorized("too many invalid codes, please login again")
+	}
+
+	return challenge, nil
+}
+
+// challengeFailed counts a wrong code against the challenge. The username, IP
+// and the user's codes were already counted by checkThrottle, so guessing codes
+// is throttled like guessing passwords and a new password login does not start
+// the count over.
+func (u *UserModule) challengeFailed(ctx context.Context, tx *sqlx.Tx, challenge model.LoginChallengeModel) error {
+	err := model.CountChallengeAttempt(ctx, tx, challenge.ID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	return lib.Unauthorized("invalid two-factor code")
+}
+
+// checkThrottle reserves an attempt in the login throttle and the wrong code
+// counter of the challenge's user, and returns the user.
+func (u *UserModule) checkThrottle(ctx context.Context, userID uuid.UUID) (*model.UserModel, error) {
+	user, err := model.GetUserByID(ctx, u.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = u.throttle.reserve(ctx, user.Username, lib.RequestMetaFromContext(ctx).ClientIP)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.throttle.reserveTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// codeAccepted gives back the attempts checkThrottle reserved once the code
+// was right and the session is open.
+func (u *UserModule) codeAccepted(ctx context.Context, user *model.UserModel) {
+	u.throttle.release(ctx, user.Username, lib.RequestMetaFromContext(ctx).ClientIP)
+	u.throttle.reset(ctx, user.Username, user.ID)
+}
+
+// useCode spends a TOTP code or, when allowRecovery is set, a recovery code of
+// the user. It returns which kind matched, or "" for a wrong code.
+func (u *UserModule) useCode(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, code string, allowRecovery bool) (string, error) {
+	totp, err := model.LockUserTOTP(ctx, tx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", lib.Unprocessable("two-factor authentication is not set up")
+		}
+		return "", err
+	}
+
+	now := u.now()
+	if step, ok := lib.ValidateTOTP(totp.Secret, code, now, totp.LastUsedStep); ok {
+		err = model.UseTOTPStep(ctx, tx, userID, step, now)
+		if err != nil {
+			return "", err
+		}
+		return "totp", nil
+	}
+
+	if !allowRecovery || !totp.ConfirmedAt.Valid {
+		return "", nil
+	}
+
+	used, err := model.UseRecoveryCode(ctx, tx, userID, lib.HashToken(lib.NormalizeRecoveryCode(code)), now)
+	if err != nil || !used {
+		return "", err
+	}
+
+	return "recovery_code", nil
+}
+
+// newRecoveryCodes replaces the user's recovery codes and returns them in
+// plain text; this is the only time they are shown.
+func (u *UserModule) newRecoveryCodes(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID) ([]string, error) {
+	codes, err := lib.GenerateRecoveryCodes(u.twoFactor.RecoveryCodes)
+	if err != nil {
+		return nil, err
+	}
+
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = lib.HashToken(lib.NormalizeRecoveryCode(code))
+	}
+
+	err = model.ReplaceRecoveryCodes(ctx, tx, userID, hashes, u.now())
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// enroll stores a new pending secret for the user.
+func (u *UserModule) enroll(ctx context.Context, userID uuid.UUID) (*TwoFactorEnrollResponse, error) {
+	user, err := model.GetUserByID(ctx, u.db, userID)
+	if err != nil {
+		return nil, lib.NotFound("user not found")
+	}
+
+	secret, err := lib.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := model.SavePendingTOTP(ctx, u.db, userID, secret, u.now())
+	if err != nil {
+		return nil, err
+	}
+
+	if !saved {
+		return nil, lib.Conflict("two-factor authentication is already enabled")
+	}
+
+	return &TwoFactorEnrollResponse{
+		Secret:     secret,
+		OTPAuthURI: lib.TOTPURI(u.twoFactor.Issuer, user.Username, secret),
+	}, nil
+}
+
+// confirm enables the pending secret with its first code inside tx and
+// returns fresh recovery codes.
+func (u *UserModule) confirm(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID, code string) ([]string, error) {
+	totp, err := model.LockUserTOTP(ctx, tx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, lib.Unprocessable("start two-factor enrollment first")
+		}
+		return nil, err
+	}
+
+	if totp.ConfirmedAt.Valid {
+		return nil, lib.Conflict("two-factor authentication is already enabled")
+	}
+
+	kind, err := u.useCode(ctx, tx, userID, code, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == "" {
+		return nil, errInvalidTwoFactorCode
+	}
+
+	return u.newRecoveryCodes(ctx, tx, userID)
+}
+
+// VerifyLogin finishes a login of an enrolled user with a TOTP or recovery code.
+func (u *UserModule) VerifyLogin(ctx context.Context, param ChallengeCodeParam) (*LoginResponse, error) {
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	challenge, err := u.lockChallenge(ctx, tx, param.ChallengeToken, model.ChallengeVerify)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.checkThrottle(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	kind, err := u.useCode(ctx, tx, challenge.UserID, param.Code, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == "" {
+		return nil, u.challengeFailed(ctx, tx, challenge)
+	}
+
+	err = model.MarkChallengeUsed(ctx, tx, challenge.ID, u.now())
+	if err != nil {
+		return nil, err
+	}
+
+	response, evicted, err := u.openSession(ctx, tx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	u.codeAccepted(ctx, user)
+	u.sessionOpened(ctx, challenge.UserID, evicted, map[string]interface{}{"two_factor": kind})
+
+	return response, nil
+}
+
+// EnrollLogin starts TOTP enrollment for a user whose role requires it and
+// who has not enrolled yet. The challenge stays valid for ConfirmEnrollLogin.
+func (u *UserModule) EnrollLogin(ctx context.Context, param ChallengeParam) (*TwoFactorEnrollResponse, error) {
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	challenge, err := u.lockChallenge(ctx, tx, param.ChallengeToken, model.ChallengeEnroll)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.enroll(ctx, challenge.UserID)
+}
+
+// ConfirmEnrollLogin enables TOTP with its first code and finishes the login.
+func (u *UserModule) ConfirmEnrollLogin(ctx context.Context, param ChallengeCodeParam) (*TwoFactorConfirmResponse, error) {
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	challenge, err := u.lockChallenge(ctx, tx, param.ChallengeToken, model.ChallengeEnroll)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := u.checkThrottle(ctx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := u.confirm(ctx, tx, challenge.UserID, param.Code)
+	if err != nil {
+		if errors.Is(err, errInvalidTwoFactorCode) {
+			return nil, u.challengeFailed(ctx, tx, challenge)
+		}
+		return nil, err
+	}
+
+	err = model.MarkChallengeUsed(ctx, tx, challenge.ID, u.now())
+	if err != nil {
+		return nil, err
+	}
+
+	response, evicted, err := u.openSession(ctx, tx, challenge.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	u.codeAccepted(ctx, user)
+	recordAudit(ctx, u.db, auditEntry{
+		Actor:    challenge.UserID,
+		Action:   model.AuditTwoFactorOn,
+		Entity:   model.EntityUsers,
+		EntityID: challenge.UserID,
+	})
+	u.sessionOpened(ctx, challenge.UserID, evicted, map[string]interface{}{"two_factor": "totp"})
+
+	return &TwoFactorConfirmResponse{RecoveryCodes: codes, Session: response}, nil
+}
+
+func (u *UserModule) caller(token string) (uuid.UUID, error) {
+	claims, err := u.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return uuid.Nil, lib.Unauthorized("invalid or expired token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return uuid.Nil, lib.Unauthorized("invalid user id in token")
+	}
+
+	return userID, nil
+}
+
+func (u *UserModule) TwoFactorStatus(ctx context.Context, token string) (TwoFactorStatusResponse, error) {
+	userID, err := u.caller(token)
+	if err != nil {
+		return TwoFactorStatusResponse{}, err
+	}
+
+	enrolled, required, err := u.twoFactorState(ctx, userID)
+	if err != nil {
+		return TwoFactorStatusResponse{}, err
+	}
+
+	status := TwoFactorStatusResponse{Enabled: enrolled, Required: required}
+	if !enrolled {
+		_, err = model.GetUserTOTP(ctx, u.db, userID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return status, err
+		}
+		status.Pending = err == nil
+		return status, nil
+	}
+
+	status.RecoveryCodesLeft, err = model.CountUnusedRecoveryCodes(ctx, u.db, userID)
+	if err != nil {
+		return status, err
+	}
+
+	return status, nil
+}
+
+// EnrollTwoFactor starts TOTP enrollment for the signed-in user. It is only
+// enabled once ConfirmTwoFactor gets a valid code.
+func (u *UserModule) EnrollTwoFactor(ctx context.Context, token string) (*TwoFactorEnrollResponse, error) {
+	userID, err := u.caller(token)
+	if err != nil {
+		return nil, err
+	}
+
+	return u.enroll(ctx, userID)
+}
+
+func (u *UserModule) ConfirmTwoFactor(ctx context.Context, token string, param TwoFactorCodeParam) (*TwoFactorConfirmResponse, error) {
+	userID, err := u.caller(token)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	codes, err := u.confirm(ctx, tx, userID, param.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, u.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditTwoFactorOn,
+		Entity:   model.EntityUsers,
+		EntityID: userID,
+	})
+
+	return &TwoFactorConfirmResponse{RecoveryCodes: codes}, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes; a current TOTP code is
+// required so a stolen session alone cannot do it.
+func (u *UserModule) RegenerateRecoveryCodes(ctx context.Context, token string, param TwoFactorCodeParam) (*TwoFactorConfirmResponse, error) {
+	userID, err := u.caller(token)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	kind, err := u.useCode(ctx, tx, userID, param.Code, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if kind == "" {
+		return nil, errInvalidTwoFactorCode
+	}
+
+	codes, err := u.newRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, u.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditRecoveryCodes,
+		Entity:   model.EntityUsers,
+		EntityID: userID,
+	})
+
+	return &TwoFactorConfirmResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns TOTP off with a current TOTP or recovery code. Users
+// whose role requires two-factor cannot turn it off.
+func (u *UserModule) DisableTwoFactor(ctx context.Context, token string, param TwoFactorCodeParam) error {
+	userID, err := u.caller(token)
+	if err != nil {
+		return err
+	}
+
+	_, required, err := u.twoFactorState(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if required {
+		return lib.Forbidden("two-factor authentication is required for your role")
+	}
+
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	kind, err := u.useCode(ctx, tx, userID, param.Code, true)
+	if err != nil {
+		return err
+	}
+
+	if kind == "" {
+		return errInvalidTwoFactorCode
+	}
+
+	_, err = model.DeleteUserTOTP(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, u.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditTwoFactorOff,
+		Entity:   model.EntityUsers,
+		EntityID: userID,
+		Details:  map[string]interface{}{"code": kind},
+	})
+
+	return nil
+}
+
+// ResetTwoFactor lets an admin remove the TOTP of a user who lost their
+// device. A user whose role requires it enrolls again on the next login.
+func (u *UserModule) ResetTwoFactor(ctx context.Context, token string, userID uuid.UUID) error {
+	actorID, err := u.caller(token)
+	if err != nil {
+		return err
+	}
+
+	_, err = model.GetUserByID(ctx, u.db, userID)
+	if err != nil {
+		return lib.NotFound("user not found")
+	}
+
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	removed, err := model.DeleteUserTOTP(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !removed {
+		return lib.NotFound("user has no two-factor authentication")
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, u.db, auditEntry{
+		Actor:    actorID,
+		Action:   model.AuditTwoFactorOff,
+		Entity:   model.EntityUsers,
+		EntityID: userID,
+		Details:  map[string]interface{}{"reset": true},
+	})
+
+	return nil
+}
@@ -0,0 +1,293 @@
+package api
+
+import (
+	"app-bookstore/config"
+	"app-bookstore/internal/dbtest"
+	"app-bookstore/lib"
+	"app-bookstore/model"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP"
+
+// expectTOTP expects useCode to lock the user's confirmed secret, last used
+// at lastUsedStep.
+func expectTOTP(mock *dbtest.Mock, userID uuid.UUID, lastUsedStep int64) {
+	confirmedAt := testNow.Add(-24 * time.Hour)
+	mock.ExpectQuery(`^SELECT user_id, secret, confirmed_at, last_used_step, created_at, updated_at FROM user_totp WHERE user_id = \$1 FOR UPDATE$`).
+		WithArgs(userID).
+		WillReturnRows(dbtest.NewRows("user_id", "secret", "confirmed_at", "last_used_step", "created_at", "updated_at").
+			AddRow(userID.String(), testTOTPSecret, confirmedAt, lastUsedStep, confirmedAt, nil))
+}
+
+// expectUseStep expects useCode to spend the TOTP step at now.
+func expectUseStep(mock *dbtest.Mock, userID uuid.UUID, step int64, now time.Time) {
+	mock.ExpectExec(`^UPDATE user_totp SET last_used_step = \$2, .* WHERE user_id = \$1$`).
+		WithArgs(userID, step, now).
+		WillReturnResult(1)
+}
+
+// expectRecoveryCode expects useCode to spend the recovery code, which only
+// succeeds while it is unused.
+func expectRecoveryCode(mock *dbtest.Mock, userID uuid.UUID, code string, unused bool) {
+	var affected int64
+	if unused {
+		affected = 1
+	}
+
+	mock.ExpectExec(`^UPDATE user_recovery_codes SET used_at = \$3 WHERE user_id = \$1 AND code_hash = \$2 AND used_at IS NULL$`).
+		WithArgs(userID, lib.HashToken(lib.NormalizeRecoveryCode(code)), testNow).
+		WillReturnResult(affected)
+}
+
+// expectChallenge expects lockChallenge to lock the challenge with token.
+func expectChallenge(mock *dbtest.Mock, token string, c model.LoginChallengeModel) {
+	var usedAt interface{}
+	if c.UsedAt.Valid {
+		usedAt = c.UsedAt.Time
+	}
+
+	mock.ExpectQuery(`^SELECT id, user_id, token_hash, purpose, attempts, expires_at, used_at, created_at FROM login_challenges WHERE token_hash = \$1 FOR UPDATE$`).
+		WithArgs(lib.HashToken(token)).
+		WillReturnRows(dbtest.NewRows("id", "user_id", "token_hash", "purpose", "attempts", "expires_at", "used_at", "created_at").
+			AddRow(c.ID.String(), c.UserID.String(), c.TokenHash, c.Purpose, int64(c.Attempts), c.ExpiresAt, usedAt, c.CreatedAt))
+}
+
+// testClock is a settable clock for UserModule.now.
+type testClock struct {
+	t time.Time
+}
+
+func (c *testClock) now() time.Time {
+	return c.t
+}
+
+var testNow = time.Date(2024, 5, 1, 12, 0, 15, 0, time.UTC)
+
+func newTwoFactorTest(t *testing.T) (*UserModule, *dbtest.Mock, *testClock) {
+	t.Helper()
+
+	db, mock := dbtest.New(t)
+
+	clock := &testClock{t: testNow}
+	module := &UserModule{
+		db:        db,
+		twoFactor: config.TwoFactor{ChallengeTTL: 5 * time.Minute, MaxAttempts: 5},
+		now:       clock.now,
+	}
+
+	return module, mock, clock
+}
+
+func totpCodeAt(t *testing.T, at time.Time) string {
+	t.Helper()
+
+	code, err := lib.TOTPCode(testTOTPSecret, lib.TOTPStep(at))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+// useCode runs UserModule.useCode in its own transaction, as its callers do.
+func useCode(t *testing.T, u *UserModule, userID uuid.UUID, code string, allowRecovery bool) string {
+	t.Helper()
+
+	tx, err := u.db.BeginTxx(context.Background(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	kind, err := u.useCode(context.Background(), tx, userID, code, allowRecovery)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	return kind
+}
+
+func TestUseCodeTOTPWindow(t *testing.T) {
+	tests := []struct {
+		name     string
+		codeAt   time.Duration
+		wantKind string
+	}{
+		{"current step", 0, "totp"},
+		{"previous step within skew", -lib.TOTPPeriod, "totp"},
+		{"next step within skew", lib.TOTPPeriod, "totp"},
+		{"two steps behind", -2 * lib.TOTPPeriod, ""},
+		{"two steps ahead", 2 * lib.TOTPPeriod, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, mock, _ := newTwoFactorTest(t)
+			userID := uuid.New()
+
+			expectTOTP(mock, userID, 0)
+			if tt.wantKind == "totp" {
+				expectUseStep(mock, userID, lib.TOTPStep(testNow.Add(tt.codeAt)), testNow)
+			}
+
+			code := totpCodeAt(t, testNow.Add(tt.codeAt))
+			if kind := useCode(t, u, userID, code, false); kind != tt.wantKind {
+				t.Errorf("useCode() = %q, want %q", kind, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestUseCodeRejectsReplay(t *testing.T) {
+	u, mock, clock := newTwoFactorTest(t)
+	userID := uuid.New()
+	step := lib.TOTPStep(testNow)
+
+	expectTOTP(mock, userID, 0)
+	expectUseStep(mock, userID, step, testNow)
+
+	code := totpCodeAt(t, testNow)
+	if kind := useCode(t, u, userID, code, false); kind != "totp" {
+		t.Fatalf("first use = %q, want totp", kind)
+	}
+
+	// kode yang sama masih di dalam jendela waktu, tapi sudah dipakai
+	clock.t = testNow.Add(lib.TOTPPeriod)
+	expectTOTP(mock, userID, step)
+	if kind := useCode(t, u, userID, code, false); kind != "" {
+		t.Errorf("replayed code = %q, want it rejected", kind)
+	}
+
+	expectTOTP(mock, userID, step)
+	older := totpCodeAt(t, testNow.Add(-lib.TOTPPeriod))
+	if kind := useCode(t, u, userID, older, false); kind != "" {
+		t.Errorf("code of an earlier step = %q, want it rejected", kind)
+	}
+
+	expectTOTP(mock, userID, step)
+	expectUseStep(mock, userID, step+1, clock.t)
+	next := totpCodeAt(t, clock.t)
+	if kind := useCode(t, u, userID, next, false); kind != "totp" {
+		t.Errorf("code of the next step = %q, want totp", kind)
+	}
+}
+
+func TestUseCodeRecoveryCodeOnce(t *testing.T) {
+	u, mock, _ := newTwoFactorTest(t)
+	userID := uuid.New()
+
+	expectTOTP(mock, userID, 0)
+	if kind := useCode(t, u, userID, "k7qmz-3xw2a", false); kind != "" {
+		t.Errorf("recovery code where none is allowed = %q, want it rejected", kind)
+	}
+
+	expectTOTP(mock, userID, 0)
+	expectRecoveryCode(mock, userID, "k7qmz-3xw2a", true)
+	if kind := useCode(t, u, userID, "K7QMZ 3XW2A", true); kind != "recovery_code" {
+		t.Fatalf("first use = %q, want recovery_code", kind)
+	}
+
+	expectTOTP(mock, userID, 0)
+	expectRecoveryCode(mock, userID, "k7qmz-3xw2a", false)
+	if kind := useCode(t, u, userID, "k7qmz-3xw2a", true); kind != "" {
+		t.Errorf("second use = %q, want it rejected", kind)
+	}
+}
+
+func TestLockChallengeExpiry(t *testing.T) {
+	const token = "challenge-token"
+
+	tests := []struct {
+		name    string
+		now     time.Time
+		modify  func(c *model.LoginChallengeModel)
+		purpose string
+		wantErr string
+	}{
+		{
+			name:    "fresh challenge",
+			now:     testNow,
+			purpose: model.ChallengeVerify,
+		},
+		{
+			name:    "just before expiry",
+			now:     testNow.Add(5*time.Minute - time.Second),
+			purpose: model.ChallengeVerify,
+		},
+		{
+			name:    "at expiry",
+			now:     testNow.Add(5 * time.Minute),
+			purpose: model.ChallengeVerify,
+			wantErr: "challenge has expired, please login again",
+		},
+		{
+			name:    "already used",
+			now:     testNow,
+			modify:  func(c *model.LoginChallengeModel) { c.UsedAt.Time, c.UsedAt.Valid = testNow, true },
+			purpose: model.ChallengeVerify,
+			wantErr: "challenge has expired, please login again",
+		},
+		{
+			name:    "too many attempts",
+			now:     testNow,
+			modify:  func(c *model.LoginChallengeModel) { c.Attempts = 5 },
+			purpose: model.ChallengeVerify,
+			wantErr: "too many invalid codes, please login again",
+		},
+		{
+			name:    "other purpose",
+			now:     testNow,
+			purpose: model.ChallengeEnroll,
+			wantErr: "invalid challenge token",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, mock, clock := newTwoFactorTest(t)
+			stored := model.LoginChallengeModel{
+				ID:        uuid.New(),
+				UserID:    uuid.New(),
+				TokenHash: lib.HashToken(token),
+				Purpose:   model.ChallengeVerify,
+				ExpiresAt: testNow.Add(u.twoFactor.ChallengeTTL),
+				CreatedAt: testNow,
+			}
+			if tt.modify != nil {
+				tt.modify(&stored)
+			}
+			expectChallenge(mock, token, stored)
+			clock.t = tt.now
+
+			tx, err := u.db.BeginTxx(context.Background(), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tx.Rollback()
+
+			challenge, err := u.lockChallenge(context.Background(), tx, token, tt.purpose)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("lockChallenge() error = %v", err)
+				}
+				if challenge.ID != stored.ID {
+					t.Errorf("lockChallenge() = %v, want %v", challenge.ID, stored.ID)
+				}
+				return
+			}
+
+			var appErr *lib.AppError
+			if !errors.As(err, &appErr) || appErr.Message != tt.wantErr {
+				t.Errorf("lockChallenge() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -1,10 +1,13 @@
 package api
 
 import (
+	"app-bookstore/config"
 	"app-bookstore/lib"
 	"app-bookstore/model"
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,40 +15,74 @@ import (
 	"github.com/lib/pq"
 )
 
+// Role modes for an approved user request, see config.Access.ApprovedRoleMode.
+const (
+	RoleModeReplace = "replace"
+	RoleModeAdd     = "add"
+)
+
 type UserRequestModule struct {
-	db   *sqlx.DB
-	name string
-	JWT  lib.Jwt
+	db       *sqlx.DB
+	name     string
+	JWT      lib.Jwt
+	roleMode string
 }
 
-func NewUserRequestModule(db *sqlx.DB, jwt lib.Jwt) *UserRequestModule {
+func NewUserRequestModule(db *sqlx.DB, jwt lib.Jwt, cfg config.Access) *UserRequestModule {
 	return &UserRequestModule{
-		db:   db,
-		name: "user-request-module",
-		JWT:  jwt,
+		db:       db,
+		name:     "user-request-module",
+		JWT:      jwt,
+		roleMode: cfg.ApprovedRoleMode,
 	}
 }
 
+// UserRequestParam.UserID defaults to the caller, so users can request a role
+// for themselves without knowing their own ID.
 type UserRequestParam struct {
 	UserID        uuid.UUID `json:"user_id"`
-	RequestRoleID uuid.UUID `json:"requested_role_id"`
+	RequestRoleID uuid.UUID `json:"requested_role_id" validate:"required"`
 }
 
 type UserRequestUpdateParam struct {
-	Status string `json:"status"`
+	Status          string `json:"status" validate:"required,oneof=approved rejected"`
+	RejectionReason string `json:"rejection_reason" validate:"max=500"`
+}
+
+func (ur *UserRequestModule) ListUserRequest(ctx context.Context, filter lib.Filter) ([]model.UserRequestResponse, lib.Pagination, error) {
+	return ur.list(ctx, filter, uuid.Nil)
 }
 
-func (ur *UserRequestModule) ListUserRequest(ctx context.Context) ([]model.UserRequestResponse, error) {
-	userRequest, err := model.GetAllUserRequest(ctx, ur.db)
+// MyRequests lists the caller's own requests.
+func (ur *UserRequestModule) MyRequests(ctx context.Context, token string, filter lib.Filter) ([]model.UserRequestResponse, lib.Pagination, error) {
+	claims, err := ur.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, err
+		return nil, lib.Pagination{}, lib.Unauthorized("invalid or expired access token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return nil, lib.Pagination{}, lib.Unauthorized("failed to parse access token")
+	}
+
+	return ur.list(ctx, filter, userID)
+}
+
+func (ur *UserRequestModule) list(ctx context.Context, filter lib.Filter, userID uuid.UUID) ([]model.UserRequestResponse, lib.Pagination, error) {
+	userRequest, total, err := model.GetAllUserRequest(ctx, ur.db, filter, userID)
+	if err != nil {
+		return nil, lib.Pagination{}, err
 	}
 
+	userRequest, hasMore := lib.TrimPage(filter, userRequest)
+
 	var response []model.UserRequestResponse
+	var last lib.Cursor
 	for _, userRequest := range userRequest {
 		response = append(response, userRequest.Response())
+		last = lib.Cursor{CreatedAt: userRequest.CreatedAt, ID: userRequest.ID}
 	}
-	return response, err
+	return response, lib.NewPagination(filter, total, len(userRequest), hasMore, last), nil
 }
 
 func (ur *UserRequestModule) GetOneUserRequest(ctx context.Context, id uuid.UUID) (model.UserRequestResponse, error) {
@@ -61,12 +98,16 @@ func (ur *UserRequestModule) GetOneUserRequest(ctx context.Context, id uuid.UUID
 func (ur *UserRequestModule) CreateUserRequest(ctx context.Context, token string, param UserRequestParam) (interface{}, error) {
 	claims, err := ur.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("failed to verify access token")
+		return nil, lib.Unauthorized("failed to verify access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	if param.UserID == uuid.Nil {
+		param.UserID = userID
 	}
 
 	roleExists, err := model.CheckRoleExist(ctx, ur.db, param.RequestRoleID)
@@ -75,14 +116,33 @@ func (ur *UserRequestModule) CreateUserRequest(ctx context.Context, token string
 	}
 
 	if !roleExists {
-		return nil, errors.New("requested role does not exist")
+		return nil, lib.Unprocessable("requested role does not exist")
+	}
+
+	hasRole, err := model.UserHasRole(ctx, ur.db, param.UserID, param.RequestRoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if hasRole {
+		return nil, lib.Conflict("user already has the requested role")
+	}
+
+	// index unik pada request pending tetap menjaga kalau dua request masuk bersamaan
+	pending, err := model.HasPendingUserRequest(ctx, ur.db, param.UserID, param.RequestRoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if pending {
+		return nil, lib.Conflict("a request for this role is already pending")
 	}
 
 	userRequest := model.UserRequestModel{
 		ID:              uuid.New(),
 		UserID:          param.UserID,
 		RequestUserRole: param.RequestRoleID,
-		Status:          "pending",
+		Status:          model.UserRequestPending,
 		CreatedAt:       time.Now(),
 		CreatedBy:       userID,
 	}
@@ -92,37 +152,147 @@ func (ur *UserRequestModule) CreateUserRequest(ctx context.Context, token string
 		return nil, err
 	}
 
+	recordAudit(ctx, ur.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditCreate,
+		Entity:   model.EntityUserRequests,
+		EntityID: userRequest.ID,
+		After:    userRequest.Response(),
+	})
+
 	return userRequest.Response(), nil
 }
 
+// UpdateUserRequest approves or rejects a pending request. Approval assigns the
+// requested role in the same transaction; with the "replace" role mode the
+// user's guest role is removed at the same time.
 func (ur *UserRequestModule) UpdateUserRequest(ctx context.Context, token string, param UserRequestUpdateParam, id uuid.UUID) (interface{}, error) {
 	claims, err := ur.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired access token")
+		return nil, lib.Unauthorized("invalid or expired access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	if param.Status == model.UserRequestApproved && param.RejectionReason != "" {
+		return nil, lib.Unprocessable("rejection_reason is only allowed when rejecting")
+	}
+
+	guestRoleID, err := model.GetGuestRoleID(ctx, ur.db)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	tx, err := ur.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	before, err := model.GetUserRequestForReview(ctx, tx, id)
+	if err != nil {
+		return nil, err
 	}
 
+	if before.Status != model.UserRequestPending {
+		return nil, lib.Conflict(fmt.Sprintf("request has already been %s", before.Status))
+	}
+
+	now := time.Now()
 	userRequest := model.UserRequestModel{
-		ID:     id,
-		Status: param.Status,
-		UpdatedAt: pq.NullTime{
-			Time:  time.Now(),
-			Valid: true,
-		},
-		UpdatedBy: uuid.NullUUID{
-			UUID:  userID,
-			Valid: true,
-		},
+		ID:              id,
+		Status:          param.Status,
+		RejectionReason: sql.NullString{String: param.RejectionReason, Valid: param.RejectionReason != ""},
+		ReviewedBy:      uuid.NullUUID{UUID: userID, Valid: true},
+		ReviewedAt:      pq.NullTime{Time: now, Valid: true},
+	}
+
+	var granted *model.UserRoleModel
+	var removedGuest []uuid.UUID
+	if param.Status == model.UserRequestApproved {
+		roleExists, err := model.CheckLiveRoleExist(ctx, tx, before.RequestUserRole)
+		if err != nil {
+			return nil, err
+		}
+
+		if !roleExists {
+			return nil, lib.Unprocessable("requested role no longer exists")
+		}
+
+		hasRole, err := model.UserHasRole(ctx, tx, before.UserID, before.RequestUserRole)
+		if err != nil {
+			return nil, err
+		}
+
+		if !hasRole {
+			granted = &model.UserRoleModel{
+				ID:        uuid.New(),
+				UserID:    before.UserID,
+				RoleID:    before.RequestUserRole,
+				CreatedAt: now,
+				CreatedBy: userID,
+			}
+
+			err = granted.Insert(ctx, tx)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		if ur.roleMode != RoleModeAdd && guestRoleID != uuid.Nil && guestRoleID != before.RequestUserRole {
+			removedGuest, err = model.RemoveUserRole(ctx, tx, before.UserID, guestRoleID)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	err = userRequest.Review(ctx, tx)
+	if err != nil {
+		return nil, err
 	}
 
-	err = userRequest.Update(ctx, ur.db, id)
+	err = tx.Commit()
 	if err != nil {
 		return nil, err
 	}
 
+	if param.Status == model.UserRequestApproved {
+		notifyPermissionChange(ctx, ur.db, lib.ScopeUser, before.UserID.String())
+	}
+
+	recordAudit(ctx, ur.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditUpdate,
+		Entity:   model.EntityUserRequests,
+		EntityID: id,
+		Before:   before.Response(),
+		After:    userRequest.Response(),
+	})
+
+	if granted != nil {
+		recordAudit(ctx, ur.db, auditEntry{
+			Actor:    userID,
+			Action:   model.AuditCreate,
+			Entity:   model.EntityUserRoles,
+			EntityID: granted.ID,
+			After:    granted.Response(),
+			Details:  map[string]interface{}{"user_request_id": id},
+		})
+	}
+
+	for _, userRoleID := range removedGuest {
+		recordAudit(ctx, ur.db, auditEntry{
+			Actor:    userID,
+			Action:   model.AuditDelete,
+			Entity:   model.EntityUserRoles,
+			EntityID: userRoleID,
+			Details:  map[string]interface{}{"user_request_id": id, "role_id": guestRoleID},
+		})
+	}
+
 	return userRequest.Response(), nil
 }
@@ -4,7 +4,6 @@ import (
 	"app-bookstore/lib"
 	"app-bookstore/model"
 	"context"
-	"errors"
 	"time"
 
 	"github.com/google/uuid"
@@ -31,18 +30,22 @@ type UserRolesParam struct {
 	RoleID uuid.UUID `json:"role_id"`
 }
 
-func (uro *UserRoleModule) ListUserRole(ctx context.Context) ([]model.UserRoleResponse, error) {
-	userRoles, err := model.GetAllUserRoles(ctx, uro.db)
+func (uro *UserRoleModule) ListUserRole(ctx context.Context, filter lib.Filter) ([]model.UserRoleResponse, lib.Pagination, error) {
+	userRoles, total, err := model.GetAllUserRoles(ctx, uro.db, filter)
 	if err != nil {
-		return nil, err
+		return nil, lib.Pagination{}, err
 	}
 
+	userRoles, hasMore := lib.TrimPage(filter, userRoles)
+
 	var response []model.UserRoleResponse
+	var last lib.Cursor
 	for _, userRole := range userRoles {
 		response = append(response, userRole.Response())
+		last = lib.Cursor{CreatedAt: userRole.CreatedAt, ID: userRole.ID}
 	}
 
-	return response, nil
+	return response, lib.NewPagination(filter, total, len(userRoles), hasMore, last), nil
 }
 
 func (uro *UserRoleModule) GetOne(ctx context.Context, id uuid.UUID) (model.UserRoleResponse, error) {
@@ -57,12 +60,12 @@ func (uro *UserRoleModule) GetOne(ctx context.Context, id uuid.UUID) (model.User
 func (uro *UserRoleModule) CreateUserRoles(ctx context.Context, token string, param UserRolesParam) (interface{}, error) {
 	claims, err := uro.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("failed to verify access token")
+		return nil, lib.Unauthorized("failed to verify access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
 	}
 
 	userRolesID := uuid.New()
@@ -78,18 +81,34 @@ func (uro *UserRoleModule) CreateUserRoles(ctx context.Context, token string, pa
 	if err != nil {
 		return nil, err
 	}
+
+	notifyPermissionChange(ctx, uro.db, lib.ScopeUser, userRoles.UserID.String())
+
+	recordAudit(ctx, uro.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditCreate,
+		Entity:   model.EntityUserRoles,
+		EntityID: userRoles.ID,
+		After:    userRoles.Response(),
+	})
+
 	return userRoles.Response(), nil
 }
 
 func (uro *UserRoleModule) UpdateUserRole(ctx context.Context, token string, param UserRolesParam, id uuid.UUID) (interface{}, error) {
 	claims, err := uro.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return nil, errors.New("invalid or expired access token")
+		return nil, lib.Unauthorized("invalid or expired access token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return nil, errors.New("failed to parse access token")
+		return nil, lib.Unauthorized("failed to parse access token")
+	}
+
+	before, err := model.GetOneUserRole(ctx, uro.db, id)
+	if err != nil {
+		return nil, err
 	}
 
 	userRole := model.UserRoleModel{
@@ -108,8 +127,22 @@ func (uro *UserRoleModule) UpdateUserRole(ctx context.Context, token string, par
 
 	err = userRole.Update(ctx, uro.db)
 	if err != nil {
-		return err, nil
+		return nil, err
 	}
 
+	notifyPermissionChange(ctx, uro.db, lib.ScopeUser, before.UserID.String())
+	if userRole.UserID != before.UserID {
+		notifyPermissionChange(ctx, uro.db, lib.ScopeUser, userRole.UserID.String())
+	}
+
+	recordAudit(ctx, uro.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditUpdate,
+		Entity:   model.EntityUserRoles,
+		EntityID: id,
+		Before:   before.Response(),
+		After:    userRole.Response(),
+	})
+
 	return userRole.Response(), nil
 }
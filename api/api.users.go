@@ -1,37 +1,65 @@
 package api
 
 import (
+	"app-bookstore/config"
 	"app-bookstore/lib"
 	"app-bookstore/model"
 	"context"
+	"database/sql"
 	"errors"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
 )
 
 type UserModule struct {
-	db   *sqlx.DB
-	name string
-	JWT  lib.Jwt
+	db          *sqlx.DB
+	name        string
+	JWT         lib.Jwt
+	refreshTTL  time.Duration
+	maxSessions int
+	throttle    *loginThrottle
+	twoFactor   config.TwoFactor
+	passwords   *passwordPolicy
+	mailer      lib.Mailer
+	mail        config.Mail
+	// now is the clock for two-factor codes, challenges and sessions.
+	now func() time.Time
 }
 
-func NewUserModule(db *sqlx.DB, jwt lib.Jwt) *UserModule {
+func NewUserModule(db *sqlx.DB, jwt lib.Jwt, cfg *config.Config, mailer lib.Mailer) *UserModule {
 	return &UserModule{
-		db:   db,
-		name: "user-module",
-		JWT:  jwt,
+		db:          db,
+		name:        "user-module",
+		JWT:         jwt,
+		refreshTTL:  cfg.App.RefreshTokenTTL,
+		maxSessions: cfg.Session.MaxPerUser,
+		throttle:    &loginThrottle{db: db, cfg: cfg.Login},
+		twoFactor:   cfg.TwoFactor,
+		passwords:   newPasswordPolicy(cfg.Password),
+		mailer:      mailer,
+		mail:        cfg.Mail,
+		now:         time.Now,
 	}
 }
 
 type UserParam struct {
-	Username  string    `json:"username"`
-	Password  string    `json:"password"`
+	Username  string    `json:"username" validate:"required,max=255"`
+	Password  string    `json:"password" validate:"required"`
 	CreatedAt time.Time `json:"created_at"`
 	CreatedBy uuid.UUID `json:"created_by"`
 }
 
+type RegisterParam struct {
+	Username string `json:"username" validate:"required,max=255"`
+	Email    string `json:"email" validate:"required,email,max=255"`
+	Password string `json:"password" validate:"required"`
+	FullName string `json:"full_name" validate:"omitempty,max=255"`
+	Phone    string `json:"phone" validate:"omitempty,phone"`
+}
+
 type ChangePasswordParam struct {
 	CurrentPassword string `json:"current_password" validate:"required"`
 	NewPassword     string `json:"new_password" validate:"required"`
@@ -39,43 +67,85 @@ type ChangePasswordParam struct {
 }
 
 type LoginResponse struct {
-	AccessToken string    `json:"access_token"`
-	ExpiresAt   time.Time `json:"expires_at"`
+	TokenType        string    `json:"token_type"`
+	AccessToken      string    `json:"access_token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
 }
 
-func (u *UserModule) List(ctx context.Context, filter lib.Filter, dateFilter model.DateFilter) ([]model.UserResponse, error) {
-	users, err := model.GetAllUser(ctx, u.db, filter, dateFilter)
+func (u *UserModule) List(ctx context.Context, filter lib.Filter, dateFilter model.DateFilter) ([]model.UserResponse, lib.Pagination, error) {
+	users, total, err := model.GetAllUser(ctx, u.db, filter, dateFilter)
 	if err != nil {
-		return nil, err
+		return nil, lib.Pagination{}, err
 	}
 
+	users, hasMore := lib.TrimPage(filter, users)
+
 	var response []model.UserResponse
+	var last lib.Cursor
 	for _, user := range users {
 		response = append(response, user.Response())
+		last = lib.Cursor{CreatedAt: user.CreatedAt, ID: user.ID}
 	}
-	return response, nil
+	return response, lib.NewPagination(filter, total, len(users), hasMore, last), nil
 }
 
-func (u *UserModule) Register(ctx context.Context, param UserParam) (interface{}, error) {
-	hashPassword, err := lib.HashPassword(param.Password)
+// Register creates a guest account waiting for its email address to be
+// verified, and sends the verification link. An address another account has
+// already verified gets a notice instead, and the same response.
+func (u *UserModule) Register(ctx context.Context, param RegisterParam) (interface{}, error) {
+	err := u.passwords.check(ctx, u.db, uuid.Nil, param.Username, param.Password, "")
 	if err != nil {
 		return nil, err
 	}
 
+	hashPassword, err := u.passwords.hash(param.Password)
+	if err != nil {
+		return nil, err
+	}
+
+	// alamat yang sudah diverifikasi user lain tidak bisa dipakai lagi, tapi
+	// jawabannya tetap sama supaya tidak ketahuan alamat mana yang terdaftar
+	inUse, err := model.EmailInUse(ctx, u.db, param.Email, uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+	if inUse {
+		u.sendEmailInUse(ctx, param.Email)
+		return registerResponse, nil
+	}
+
 	user := &model.UserModel{
 		ID:        uuid.New(),
 		Username:  param.Username,
+		Email:     nullString(param.Email),
+		FullName:  nullString(param.FullName),
+		Phone:     nullString(param.Phone),
+		Status:    lib.UserPendingVerification,
 		Password:  hashPassword,
 		CreatedAt: time.Now(),
 		CreatedBy: lib.SystemID,
 	}
 
-	err = user.Insert(ctx, u.db)
+	// user tanpa role atau tanpa link verifikasi tidak boleh tertinggal
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	err = user.Insert(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = u.passwords.remember(ctx, tx, user.ID, hashPassword)
 	if err != nil {
 		return nil, err
 	}
 
-	guestRoleID, err := model.GetGuestRoleID(ctx, u.db)
+	guestRoleID, err := model.GetGuestRoleID(ctx, tx)
 	if err != nil {
 		return nil, err
 	}
@@ -88,97 +158,377 @@ func (u *UserModule) Register(ctx context.Context, param UserParam) (interface{}
 		CreatedBy: lib.SystemID,
 	}
 
-	err = userRole.AssignGuestRoles(ctx, u.db)
+	err = userRole.AssignGuestRoles(ctx, tx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := u.newEmailVerification(ctx, tx, user.ID, param.Email)
 	if err != nil {
 		return nil, err
 	}
 
-	return user.Response(), nil
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, u.db, auditEntry{
+		Actor:    user.ID,
+		Action:   model.AuditRegister,
+		Entity:   model.EntityUsers,
+		EntityID: user.ID,
+		After:    user.Response(),
+		Details:  map[string]interface{}{"role_id": guestRoleID},
+	})
+
+	u.sendVerification(ctx, user, param.Email, token)
+
+	return registerResponse, nil
+}
+
+// registerResponse is the answer to every registration, whether or not the
+// email address already belongs to a verified account.
+var registerResponse = map[string]string{
+	"message": "Check your email to finish registering",
 }
 
-func (u *UserModule) Login(ctx context.Context, param UserParam) (*LoginResponse, error) {
+// Login reserves an attempt in the throttle before hashing anything, and
+// answers a wrong password and an unknown username alike, in about the same
+// time. Users with two-factor, or who must enroll in it, get a
+// TwoFactorChallengeResponse instead of a LoginResponse.
+func (u *UserModule) Login(ctx context.Context, param UserParam) (interface{}, error) {
+	ip := lib.RequestMetaFromContext(ctx).ClientIP
+
+	attempt, err := u.throttle.reserve(ctx, param.Username, ip)
+	if err != nil {
+		return nil, err
+	}
+
 	user, err := model.GetUserByUsername(ctx, u.db, param.Username)
 	if err != nil {
-		return nil, errors.New("invalid username or password")
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+
+		lib.CheckDummyPassword(param.Password)
+		return nil, u.loginFailed(ctx, param.Username, attempt, uuid.Nil)
 	}
 
 	if !lib.CheckPassword(param.Password, user.Password) {
-		return nil, errors.New("invalid password")
+		return nil, u.loginFailed(ctx, param.Username, attempt, user.ID)
 	}
 
-	tokenData := &lib.JwtData{
-		UserID: user.ID.String(),
+	u.throttle.release(ctx, param.Username, ip)
+
+	u.upgradeHash(ctx, user.ID, param.Password, user.Password)
+
+	// status baru diberitahu setelah kata sandinya benar
+	err = lib.AccountStatusError(user.Status)
+	if err != nil {
+		return nil, err
 	}
 
-	token, expiredAt, err := u.JWT.GenerateToken(tokenData)
+	// dengan 2FA, login berhenti di challenge dan sesi baru dibuat setelah
+	// kodenya benar; throttle baru direset saat itu juga
+	enrolled, required, err := u.twoFactorState(ctx, user.ID)
 	if err != nil {
-		return nil, errors.New("failed to generate access token")
+		return nil, err
 	}
 
-	session := model.SessionModel{
-		UserID:     user.ID,
-		Token:      token,
-		Expiration: time.Unix(expiredAt, 0),
-		CreatedAt:  time.Now(),
-		CreatedBy:  user.ID,
+	switch {
+	case enrolled:
+		return u.startChallenge(ctx, user.ID, model.ChallengeVerify)
+	case required:
+		return u.startChallenge(ctx, user.ID, model.ChallengeEnroll)
 	}
 
-	err = session.Insert(ctx, u.db)
+	tx, err := u.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
+	defer tx.Rollback()
 
-	return &LoginResponse{
-		AccessToken: token,
-		ExpiresAt:   time.Unix(expiredAt, 0),
-	}, nil
+	response, evicted, err := u.openSession(ctx, tx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return nil, err
+	}
+
+	u.throttle.reset(ctx, user.Username, user.ID)
+	u.sessionOpened(ctx, user.ID, evicted, nil)
+
+	return response, nil
+}
+
+// upgradeHash rehashes a password that was just verified when its hash uses
+// an older algorithm or costs. A failure only means it is tried next login.
+func (u *UserModule) upgradeHash(ctx context.Context, userID uuid.UUID, password string, hash string) {
+	if !lib.NeedsRehash(hash) {
+		return
+	}
+
+	newHash, err := u.passwords.hash(password)
+	if err == nil {
+		err = model.UpdatePasswordHash(ctx, u.db, userID, hash, newHash)
+	}
+	if err != nil {
+		log.Error().Err(err).Str("user_id", userID.String()).Msg("failed to upgrade password hash")
+	}
+}
+
+// openSession issues a new login for the user inside tx, first revoking the
+// oldest sessions so the new one stays within the limit.
+func (u *UserModule) openSession(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID) (*LoginResponse, []model.RevokedSessionModel, error) {
+	var evicted []model.RevokedSessionModel
+	if u.maxSessions > 0 {
+		err := model.LockUserSessions(ctx, tx, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		evicted, err = model.EvictOldestSessions(ctx, tx, userID, u.maxSessions-1)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	response, err := u.issueSession(ctx, tx, userID, uuid.Nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return response, evicted, nil
+}
+
+// sessionOpened runs once the openSession transaction is committed.
+func (u *UserModule) sessionOpened(ctx context.Context, userID uuid.UUID, evicted []model.RevokedSessionModel, details interface{}) {
+	evictedSessions := notifyRevokedSessions(ctx, u.db, evicted)
+
+	recordAudit(ctx, u.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditLogin,
+		Entity:   model.EntityUsers,
+		EntityID: userID,
+		Details:  details,
+	})
+
+	if len(evictedSessions) > 0 {
+		recordAudit(ctx, u.db, auditEntry{
+			Actor:    userID,
+			Action:   model.AuditSessionEvict,
+			Entity:   model.EntityUsers,
+			EntityID: userID,
+			Details:  map[string]interface{}{"session_ids": evictedSessions, "max_sessions": u.maxSessions},
+		})
+	}
+}
+
+// loginFailed audits a failure, already counted when the attempt was reserved,
+// and returns the error every failed login gets.
+func (u *UserModule) loginFailed(ctx context.Context, username string, attempt model.LoginAttemptModel, userID uuid.UUID) error {
+	details := map[string]interface{}{"username": username, "failures": attempt.Failures}
+	if attempt.LockedUntil.Valid {
+		details["locked_until"] = attempt.LockedUntil.Time
+	}
+
+	recordAudit(ctx, u.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditLoginFailed,
+		Entity:   model.EntityUsers,
+		EntityID: userID,
+		Details:  details,
+	})
+
+	return lib.Unauthorized("invalid username or password")
+}
+
+// Unlock lifts the login lockout and backoff of the user.
+func (u *UserModule) Unlock(ctx context.Context, token string, userID uuid.UUID) error {
+	claims, err := u.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return lib.Unauthorized("invalid or expired token")
+	}
+
+	actorID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return lib.Unauthorized("invalid user id in token")
+	}
+
+	user, err := model.GetUserByID(ctx, u.db, userID)
+	if err != nil {
+		return lib.NotFound("user not found")
+	}
+
+	cleared, err := model.ClearLoginAttempts(ctx, u.db, []string{usernameKey(user.Username), totpKey(userID)})
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, u.db, auditEntry{
+		Actor:    actorID,
+		Action:   model.AuditUnlock,
+		Entity:   model.EntityUsers,
+		EntityID: userID,
+		Details:  map[string]interface{}{"had_failures": cleared > 0},
+	})
+
+	return nil
+}
+
+// PurgeLoginRecords deletes failure counters that no longer slow anyone down
+// and expired login challenges. Password reset requests share the counters,
+// so they are kept for the longer of the two windows.
+func (u *UserModule) PurgeLoginRecords(ctx context.Context) error {
+	_, err := model.PurgeLoginAttempts(ctx, u.db, max(u.throttle.cfg.FailureWindow, u.mail.ResetWindow))
+	if err != nil {
+		return err
+	}
+
+	_, err = model.PurgeLoginChallenges(ctx, u.db, u.now())
+	return err
 }
 
 func (u *UserModule) ChangePassword(ctx context.Context, token string, param ChangePasswordParam) error {
 	claims, err := u.JWT.VerifyAccessToken(token)
 	if err != nil {
-		return errors.New("invalid or expired token")
+		return lib.Unauthorized("invalid or expired token")
 	}
 
 	userID, err := uuid.Parse(claims.UserID)
 	if err != nil {
-		return errors.New("invalid user id in token")
+		return lib.Unauthorized("invalid user id in token")
 	}
 
 	user, err := model.GetUserByID(ctx, u.db, userID)
 	if err != nil {
-		return errors.New("user not found")
+		return lib.NotFound("user not found")
 	}
 
 	if !lib.CheckPassword(param.CurrentPassword, user.Password) {
-		return errors.New("current password is incorrect")
+		return lib.Unprocessable("current password is incorrect")
 	}
 
 	if param.NewPassword != param.ConfirmPassword {
-		return errors.New("new password and confirm password do not macth")
+		return lib.Unprocessable("new password and confirm password do not macth")
+	}
+
+	err = u.passwords.check(ctx, u.db, userID, user.Username, param.NewPassword, user.Password)
+	if err != nil {
+		return err
 	}
 
-	hashedPassword, err := lib.HashPassword(param.NewPassword)
+	hashedPassword, err := u.passwords.hash(param.NewPassword)
 	if err != nil {
-		return errors.New("failed to hash password")
+		return err
 	}
 
-	err = model.Update(ctx, u.db, userID, hashedPassword)
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = u.passwords.set(ctx, tx, userID, hashedPassword)
 	if err != nil {
 		return errors.New("failed to change password")
 	}
 
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, u.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditPasswordChange,
+		Entity:   model.EntityUsers,
+		EntityID: userID,
+	})
+
 	return nil
 }
 
 func (u *UserModule) Logout(ctx context.Context, token string) error {
 	if token == "" {
-		return errors.New("token is invalid")
+		return lib.Unauthorized("token is invalid")
 	}
 
-	err := model.DeleteSessionByToken(ctx, u.db, token)
+	revoked, err := model.RevokeSessionFamilyByToken(ctx, u.db, lib.HashToken(token))
 	if err != nil {
 		return err
 	}
+
+	// token yang sudah kedaluwarsa tetap boleh logout, hanya aktornya yang tidak diketahui
+	var userID uuid.UUID
+	claims, err := u.JWT.VerifyAccessToken(token)
+	if err == nil {
+		userID, _ = uuid.Parse(claims.UserID)
+	}
+
+	for _, tokenHash := range revoked {
+		notifyPermissionChange(ctx, u.db, lib.ScopeSession, tokenHash)
+	}
+
+	recordAudit(ctx, u.db, auditEntry{
+		Actor:    userID,
+		Action:   model.AuditLogout,
+		Entity:   model.EntityUsers,
+		EntityID: userID,
+	})
+
 	return nil
 }
+
+type PermissionsResponse struct {
+	UserID      uuid.UUID                  `json:"user_id"`
+	Roles       []string                   `json:"roles"`
+	Permissions []model.PermissionResponse `json:"permissions"`
+}
+
+// Permissions returns the union of the grants of every role the user holds,
+// the same set CheckAccess authorizes against.
+func (u *UserModule) Permissions(ctx context.Context, token string) (PermissionsResponse, error) {
+	claims, err := u.JWT.VerifyAccessToken(token)
+	if err != nil {
+		return PermissionsResponse{}, lib.Unauthorized("invalid or expired token")
+	}
+
+	userID, err := uuid.Parse(claims.UserID)
+	if err != nil {
+		return PermissionsResponse{}, lib.Unauthorized("invalid user id in token")
+	}
+
+	roleIDs, err := model.GetUserRoleIDs(ctx, u.db, userID)
+	if err != nil {
+		return PermissionsResponse{}, err
+	}
+
+	roles, err := model.GetUserRoleIdentifiers(ctx, u.db, userID)
+	if err != nil {
+		return PermissionsResponse{}, err
+	}
+
+	permissions, err := model.GetRolesPermissions(ctx, u.db, roleIDs)
+	if err != nil {
+		return PermissionsResponse{}, err
+	}
+
+	response := PermissionsResponse{
+		UserID:      userID,
+		Roles:       roles,
+		Permissions: []model.PermissionResponse{},
+	}
+	if response.Roles == nil {
+		response.Roles = []string{}
+	}
+	for _, permission := range permissions {
+		response.Permissions = append(response.Permissions, permission.Response())
+	}
+
+	return response, nil
+}
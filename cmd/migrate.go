@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"app-bookstore/database/migrations"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Apply or roll back database migrations",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		migrator := newMigrator()
+		if err := migrator.Up(cmd.Context()); err != nil {
+			log.Fatal().Msgf("Failed to migrate up: %v", err)
+		}
+		printMigrationStatus(cmd.Context(), migrator)
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [steps]",
+	Short: "Roll back the latest migration, or the given number of migrations",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		steps := 1
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 {
+				log.Fatal().Msgf("Invalid steps %q", args[0])
+			}
+			steps = n
+		}
+
+		migrator := newMigrator()
+		if err := migrator.Down(cmd.Context(), steps); err != nil {
+			log.Fatal().Msgf("Failed to migrate down: %v", err)
+		}
+		printMigrationStatus(cmd.Context(), migrator)
+	},
+}
+
+var migrateGotoCmd = &cobra.Command{
+	Use:   "goto N",
+	Short: "Migrate up or down to version N (0 rolls back everything)",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		version, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil || version < 0 {
+			log.Fatal().Msgf("Invalid version %q", args[0])
+		}
+
+		migrator := newMigrator()
+		if err := migrator.Goto(cmd.Context(), version); err != nil {
+			log.Fatal().Msgf("Failed to migrate to version %d: %v", version, err)
+		}
+		printMigrationStatus(cmd.Context(), migrator)
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show applied and pending migrations",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		printMigrationStatus(cmd.Context(), newMigrator())
+	},
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateGotoCmd, migrateStatusCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func newMigrator() *migrations.Migrator {
+	migrator, err := migrations.NewMigrator(dbPool)
+	if err != nil {
+		log.Fatal().Msgf("Failed to load migrations: %v", err)
+	}
+	return migrator
+}
+
+func printMigrationStatus(ctx context.Context, migrator *migrations.Migrator) {
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		log.Fatal().Msgf("Failed to read migration status: %v", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, status := range statuses {
+		appliedAt := "-"
+		if !status.AppliedAt.IsZero() {
+			appliedAt = status.AppliedAt.Local().Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(w, "%06d\t%s\t%s\t%s\n", status.Version, status.Name, status.State, appliedAt)
+	}
+	w.Flush()
+}
@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"app-bookstore/api"
+	"app-bookstore/helper"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog/log"
+	"github.com/spf13/cobra"
+)
+
+var resourcesCmd = &cobra.Command{
+	Use:   "resources",
+	Short: "Manage the resources used for access control",
+}
+
+var resourcesSyncCmd = &cobra.Command{
+	Use:   "sync",
+	Short: "Sync the resources table with the registered routes",
+	Long: `Walks the routes guarded by CheckAccess and makes sure each path template and
+method is covered by a resource, creating or extending resources as needed.
+Resources whose endpoint no longer matches any route are flagged with
+route_missing_at. With --grant-role the role also gets every method of every
+resource. Use --dry-run to print the diff without writing anything.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		grantRole := cfg.Resources.GrantRole
+		if cmd.Flags().Changed("grant-role") {
+			grantRole, _ = cmd.Flags().GetString("grant-role")
+		}
+
+		_, protected := newRouter(helper.NewMiddleware(jwtService, dbPool, nil))
+		report, err := syncResources(cmd.Context(), protected, api.ResourceSyncOption{
+			DryRun:    dryRun,
+			GrantRole: strings.TrimSpace(grantRole),
+		})
+		if err != nil {
+			log.Fatal().Msgf("Failed to sync resources: %v", err)
+		}
+
+		printResourceSync(report)
+	},
+}
+
+func init() {
+	resourcesSyncCmd.Flags().Bool("dry-run", false, "print the changes without applying them")
+	resourcesSyncCmd.Flags().String("grant-role", "", "identifier of a role to grant every resource method (default RESOURCE_SYNC_GRANT_ROLE)")
+	resourcesCmd.AddCommand(resourcesSyncCmd)
+	rootCmd.AddCommand(resourcesCmd)
+}
+
+func syncResources(ctx context.Context, protected *mux.Router, option api.ResourceSyncOption) (api.ResourceSyncReport, error) {
+	routes, err := collectRoutes(protected)
+	if err != nil {
+		return api.ResourceSyncReport{}, err
+	}
+
+	resourceModule := api.NewResourceModule(dbPool, jwtService, api.NewAccessPolicy(dbPool, cfg.Access))
+	return resourceModule.Sync(ctx, routes, option)
+}
+
+// syncResourcesOnStart runs the sync before serving. A failure is logged and
+// the server starts anyway with the resources it has.
+func syncResourcesOnStart(ctx context.Context, protected *mux.Router) {
+	report, err := syncResources(ctx, protected, api.ResourceSyncOption{GrantRole: cfg.Resources.GrantRole})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to sync resources on start")
+		return
+	}
+
+	log.Info().Msgf("Resources synced, %d changes applied", len(report.Changes))
+}
+
+// collectRoutes groups the router's routes by path template, so GET and POST
+// registered separately on the same path become one Route.
+func collectRoutes(router *mux.Router) ([]api.Route, error) {
+	var routes []api.Route
+	err := router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		i := slices.IndexFunc(routes, func(r api.Route) bool {
+			return r.Template == template
+		})
+		if i < 0 {
+			routes = append(routes, api.Route{Template: template})
+			i = len(routes) - 1
+		}
+
+		for _, method := range methods {
+			if !slices.Contains(routes[i].Methods, method) {
+				routes[i].Methods = append(routes[i].Methods, method)
+			}
+		}
+
+		return nil
+	})
+
+	return routes, err
+}
+
+func printResourceSync(report api.ResourceSyncReport) {
+	if len(report.Changes) == 0 {
+		fmt.Println("Resources are in sync with the routes.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CHANGE\tENDPOINT\tMETHODS\tROLE\tRESOURCE")
+	for _, change := range report.Changes {
+		role := change.Role
+		if role == "" {
+			role = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", change.Action, change.Endpoint, strings.Join(change.Methods, ","), role, change.ResourceID)
+	}
+	w.Flush()
+
+	if report.DryRun {
+		fmt.Printf("\n%d changes, dry run: nothing was written\n", len(report.Changes))
+		return
+	}
+	fmt.Printf("\n%d changes applied\n", len(report.Changes))
+}
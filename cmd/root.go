@@ -7,6 +7,7 @@ import (
 	"app-bookstore/helper"
 	"app-bookstore/lib"
 	"app-bookstore/router"
+	"context"
 	"net/http"
 	"os"
 
@@ -65,45 +66,109 @@ func initDatabase() {
 
 	dbPool = db.DB
 
-	seeder.SeedSuperAdmin(dbPool)
-
-	router.Init(dbPool, jwtService)
-
 	log.Info().Msg("Database connect successfully...")
 }
 
 func initJWT() {
-	jwtService = lib.NewJWT(cfg)
+	var err error
+	jwtService, err = lib.NewJWT(cfg)
+	if err != nil {
+		log.Fatal().Msgf("Failed to load JWT keys: %v", err)
+	}
+}
+
+// newPermissionCache returns nil when the cache is disabled. Otherwise it keeps
+// the cache in sync with other instances through LISTEN/NOTIFY.
+func newPermissionCache(ctx context.Context) *lib.PermissionCache {
+	if !cfg.Cache.Enabled {
+		log.Info().Msg("Permission cache disabled")
+		return nil
+	}
+
+	permissionCache := lib.NewPermissionCache(cfg.Cache.SessionTTL, cfg.Cache.PermissionTTL)
+	go func() {
+		if err := helper.ListenPermissionChanges(ctx, cfg.PostgresDSN(), permissionCache); err != nil {
+			log.Error().Err(err).Msg("Permission cache listener stopped, entries will only expire by TTL")
+		}
+	}()
+
+	return permissionCache
 }
 
 func startServer(cmd *cobra.Command, args []string) {
-	r := mux.NewRouter()
+	seeder.SeedSuperAdmin(dbPool)
+	// stdout dan file tidak mengirim email, jadi link reset dan verifikasi
+	// tidak akan pernah sampai ke user
+	if cfg.App.AppEnv == "production" && cfg.Mail.Driver != "smtp" {
+		log.Fatal().Msgf("Mail driver %q does not deliver email; set MAIL_DRIVER=smtp in production", cfg.Mail.Driver)
+	}
+
+	permissionCache := newPermissionCache(cmd.Context())
+	mailer, err := lib.NewMailer(cfg.Mail)
+	if err != nil {
+		log.Fatal().Msgf("Failed to initialize mailer: %v", err)
+	}
+
+	router.Init(dbPool, jwtService, cfg, permissionCache, mailer)
+	router.StartJobs(cmd.Context(), cfg)
 
 	log.Info().Msg("Starting server...")
-	mw := helper.NewMiddleware(jwtService, dbPool)
+	proxies, err := lib.ParseTrustedProxies(cfg.App.TrustedProxies)
+	if err != nil {
+		log.Fatal().Msgf("Failed to parse TRUSTED_PROXIES: %v", err)
+	}
+
+	mw := helper.NewMiddleware(jwtService, dbPool, permissionCache)
+	mw.Proxies = proxies
+	r, protected := newRouter(mw)
+
+	if cfg.Resources.SyncOnStart {
+		syncResourcesOnStart(cmd.Context(), protected)
+	}
+
+	log.Info().Msgf("Server running on port %s", cfg.App.AppPort)
+	if err := http.ListenAndServe(":"+cfg.App.AppPort, r); err != nil {
+		log.Fatal().Msgf("Failed to start server: %v", err)
+	}
+
+}
+
+// newRouter mounts every API route. protected is the subrouter guarded by
+// CheckAccess; its routes are the ones that need a resource.
+func newRouter(mw *helper.Middleware) (r *mux.Router, protected *mux.Router) {
+	r = mux.NewRouter()
+	r.Use(mw.RequestContext)
+	v1.NewAPIWellKnown(r)
 
 	public := r.PathPrefix("/api/v1").Subrouter()
 	v1.NewAPIUser(public)
 	v1.NewAPIResetPass(public)
 
-	protected := r.PathPrefix("/api/v1").Subrouter()
+	authenticated := r.PathPrefix("/api/v1").Subrouter()
+	authenticated.Use(mw.Authenticate)
+	v1.NewAPIMe(authenticated)
+
+	protected = r.PathPrefix("/api/v1").Subrouter()
 	protected.Use(mw.CheckAccess)
 
 	v1.NewAPIRole(protected)
 	v1.NewAPIUserRequest(protected)
 	v1.NewAPIUserRoles(protected)
+	v1.NewAPIUserAdmin(protected)
+	v1.NewAPISession(protected)
 	v1.NewAPIResource(protected)
 	v1.NewAPIRoleResource(protected)
 	v1.NewAPIAuthors(protected)
 	v1.NewAPIPublisher(protected)
 	v1.NewAPICategories(protected)
 	v1.NewAPIBooks(protected)
+	v1.NewAPIBookCopies(protected)
 	v1.NewAPILoans(protected)
+	v1.NewAPILoanPolicy(protected)
+	v1.NewAPIReservation(protected)
 	v1.NewAPIRating(protected)
+	v1.NewAPIAuditLog(protected)
+	v1.NewAPIPermissionCache(protected)
 
-	log.Info().Msgf("Server running on port %s", cfg.App.AppPort)
-	if err := http.ListenAndServe(":"+cfg.App.AppPort, r); err != nil {
-		log.Fatal().Msgf("Failed to start server: %v", err)
-	}
-
+	return r, protected
 }
@@ -4,6 +4,8 @@ import (
 	"log"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -13,6 +15,20 @@ type App struct {
 	AppEnv       string `json:"app_env"`
 	JwtSecretKey string `json:"jwt_secret_key"`
 	JwtIssuer    string `json:"jwt_issuer"`
+
+	AccessTokenTTL  time.Duration `json:"access_token_ttl"`
+	RefreshTokenTTL time.Duration `json:"refresh_token_ttl"`
+
+	// JwtSigningKeyFile is an RSA or Ed25519 private key PEM. When set, tokens
+	// are signed with it instead of JwtSecretKey; JwtVerifyKeyFiles are the
+	// previous keys still accepted during a rotation.
+	JwtSigningKeyFile string   `json:"jwt_signing_key_file"`
+	JwtVerifyKeyFiles []string `json:"jwt_verify_key_files"`
+	JwtAcceptHMAC     bool     `json:"jwt_accept_hmac"`
+
+	// TrustedProxies are the addresses or CIDR ranges of the reverse proxies in
+	// front of the app. Forwarded client IPs are only believed from these.
+	TrustedProxies []string `json:"trusted_proxies"`
 }
 
 type PsqlDB struct {
@@ -25,9 +41,130 @@ type PsqlDB struct {
 	DBMaxIdle int    `json:"dbmaxidle"`
 }
 
+type Loan struct {
+	PeriodDays    int           `json:"period_days"`
+	FineDailyRate int64         `json:"fine_daily_rate"`
+	FineMaxAmount int64         `json:"fine_max_amount"`
+	SweepInterval time.Duration `json:"sweep_interval"`
+	MaxRenewals   int           `json:"max_renewals"`
+
+	HoldPickupWindow  time.Duration `json:"hold_pickup_window"`
+	HoldSweepInterval time.Duration `json:"hold_sweep_interval"`
+}
+
+// Access maps role identifiers to the book access levels they may see and borrow.
+type Access struct {
+	LevelsByRole  map[string][]string `json:"levels_by_role"`
+	DefaultLevels []string            `json:"default_levels"`
+	AdminRoles    []string            `json:"admin_roles"`
+	// ApprovedRoleMode is "replace" to swap the guest role for the role an
+	// approved user request asked for, or "add" to keep both.
+	ApprovedRoleMode string `json:"approved_role_mode"`
+}
+
+// Cache tunes the in-process permission cache used by the access middleware.
+type Cache struct {
+	Enabled       bool          `json:"enabled"`
+	SessionTTL    time.Duration `json:"session_ttl"`
+	PermissionTTL time.Duration `json:"permission_ttl"`
+}
+
+// Resources controls syncing the resources table with the registered routes.
+type Resources struct {
+	SyncOnStart bool   `json:"sync_on_start"`
+	GrantRole   string `json:"grant_role"`
+}
+
+// Session limits how many logins a user keeps and how often dead sessions
+// and reset tokens are purged.
+type Session struct {
+	// MaxPerUser is the number of live sessions a user may have; a login over
+	// the limit revokes the oldest. 0 means no limit.
+	MaxPerUser    int           `json:"max_per_user"`
+	PurgeInterval time.Duration `json:"purge_interval"`
+}
+
+// Login throttles password guessing. Failures are counted per username and
+// per client IP within FailureWindow.
+type Login struct {
+	// MaxFailures locks a username for LockoutDuration; MaxIPFailures does the
+	// same for a client IP. 0 disables the lockout.
+	MaxFailures     int           `json:"max_failures"`
+	MaxIPFailures   int           `json:"max_ip_failures"`
+	LockoutDuration time.Duration `json:"lockout_duration"`
+	FailureWindow   time.Duration `json:"failure_window"`
+	// BackoffBase is the wait after the first failure of a username; it doubles
+	// with every further failure.
+	BackoffBase time.Duration `json:"backoff_base"`
+}
+
+// TwoFactor configures TOTP. Users holding one of RequiredRoles, directly or
+// through role inheritance, must enroll before they get a session.
+type TwoFactor struct {
+	Issuer        string        `json:"issuer"`
+	RequiredRoles []string      `json:"required_roles"`
+	ChallengeTTL  time.Duration `json:"challenge_ttl"`
+	MaxAttempts   int           `json:"max_attempts"`
+	RecoveryCodes int           `json:"recovery_codes"`
+}
+
+// Password is the policy every new password must meet. HistorySize is how
+// many earlier passwords of a user may not be reused, the current one included.
+type Password struct {
+	MinLength     int  `json:"min_length"`
+	MaxLength     int  `json:"max_length"`
+	RequireUpper  bool `json:"require_upper"`
+	RequireLower  bool `json:"require_lower"`
+	RequireDigit  bool `json:"require_digit"`
+	RequireSymbol bool `json:"require_symbol"`
+	DenyCommon    bool `json:"deny_common"`
+	HistorySize   int  `json:"history_size"`
+}
+
+// Mail is how notifications such as password reset links are delivered.
+// Driver is "smtp", "file" (appended to FilePath) or "stdout"; the last two
+// are for development and tests.
+type Mail struct {
+	Driver       string `json:"driver"`
+	From         string `json:"from"`
+	SMTPHost     string `json:"smtp_host"`
+	SMTPPort     int    `json:"smtp_port"`
+	SMTPUsername string `json:"smtp_username"`
+	SMTPPassword string `json:"smtp_password"`
+	// SMTPTimeout bounds connecting to the SMTP server and the whole exchange.
+	SMTPTimeout time.Duration `json:"smtp_timeout"`
+	FilePath    string        `json:"file_path"`
+
+	// ResetURL is the page that takes a reset token; the token is appended
+	// as the "token" query parameter.
+	ResetURL      string        `json:"reset_url"`
+	ResetTokenTTL time.Duration `json:"reset_token_ttl"`
+	// ResetCooldown is how long after a link was sent no new one is sent to
+	// the same user. ResetMaxRequests and ResetMaxIPRequests cap the reset
+	// requests per username and per client IP within ResetWindow; 0 means no cap.
+	ResetCooldown      time.Duration `json:"reset_cooldown"`
+	ResetMaxRequests   int           `json:"reset_max_requests"`
+	ResetMaxIPRequests int           `json:"reset_max_ip_requests"`
+	ResetWindow        time.Duration `json:"reset_window"`
+
+	// VerifyURL is the page that takes an email verification token, passed
+	// the same way as ResetURL.
+	VerifyURL      string        `json:"verify_url"`
+	VerifyTokenTTL time.Duration `json:"verify_token_ttl"`
+}
+
 type Config struct {
-	App  App
-	Psql PsqlDB
+	App       App
+	Psql      PsqlDB
+	Loan      Loan
+	Access    Access
+	Cache     Cache
+	Resources Resources
+	Session   Session
+	Login     Login
+	TwoFactor TwoFactor
+	Password  Password
+	Mail      Mail
 }
 
 func parseEnvInt(key string, defaultValue int) int {
@@ -39,18 +176,89 @@ func parseEnvInt(key string, defaultValue int) int {
 	return value
 }
 
+func parseEnvString(key string, defaultValue string) string {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
+
+func parseEnvBool(key string, defaultValue bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(key))
+	if err != nil {
+		return defaultValue
+	}
+
+	return value
+}
+
+func parseEnvList(key string, defaultValue []string) []string {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
+
+// parseEnvRoleLevels reads a mapping like "guest=public;member=public,member-only".
+func parseEnvRoleLevels(key string, defaultValue map[string][]string) map[string][]string {
+	value := strings.TrimSpace(os.Getenv(key))
+	if value == "" {
+		return defaultValue
+	}
+
+	mapping := map[string][]string{}
+	for _, entry := range strings.Split(value, ";") {
+		role, levels, found := strings.Cut(entry, "=")
+		role = strings.TrimSpace(role)
+		if !found || role == "" {
+			log.Printf("Ignoring malformed %s entry %q", key, entry)
+			continue
+		}
+
+		for _, level := range strings.Split(levels, ",") {
+			if level = strings.TrimSpace(level); level != "" {
+				mapping[role] = append(mapping[role], level)
+			}
+		}
+	}
+
+	return mapping
+}
+
 func NewConfig() *Config {
 	err := godotenv.Load()
 	if err != nil {
 		log.Printf("No .env file found. Falling back to environtment variables: %v", err)
 	}
 
+	adminRoles := parseEnvList("ACCESS_ADMIN_ROLES", []string{"librarian", "admin", "super-admin"})
+
 	return &Config{
 		App: App{
 			AppPort:      os.Getenv("APP_PORT"),
 			AppEnv:       os.Getenv("APP_ENV"),
 			JwtSecretKey: os.Getenv("JWT_SECRET_KEY"),
 			JwtIssuer:    os.Getenv("JWT_ISSUER"),
+
+			AccessTokenTTL:  time.Duration(parseEnvInt("ACCESS_TOKEN_MINUTES", 15)) * time.Minute,
+			RefreshTokenTTL: time.Duration(parseEnvInt("REFRESH_TOKEN_DAYS", 30)) * 24 * time.Hour,
+
+			JwtSigningKeyFile: strings.TrimSpace(os.Getenv("JWT_SIGNING_KEY_FILE")),
+			JwtVerifyKeyFiles: parseEnvList("JWT_VERIFY_KEY_FILES", nil),
+			JwtAcceptHMAC:     parseEnvBool("JWT_ACCEPT_HMAC", true),
+
+			TrustedProxies: parseEnvList("TRUSTED_PROXIES", nil),
 		},
 		Psql: PsqlDB{
 			Host:      os.Getenv("DATABASE_HOST"),
@@ -61,5 +269,86 @@ func NewConfig() *Config {
 			DBMaxOpen: parseEnvInt("DATABASE_MAX_OPEN", 100),
 			DBMaxIdle: parseEnvInt("DATABASE_MAX_IDLE", 20),
 		},
+		Loan: Loan{
+			PeriodDays:    parseEnvInt("LOAN_PERIOD_DAYS", 14),
+			FineDailyRate: int64(parseEnvInt("LOAN_FINE_DAILY_RATE", 1000)),
+			FineMaxAmount: int64(parseEnvInt("LOAN_FINE_MAX_AMOUNT", 0)),
+			SweepInterval: time.Duration(parseEnvInt("LOAN_OVERDUE_SWEEP_MINUTES", 60)) * time.Minute,
+			MaxRenewals:   parseEnvInt("LOAN_MAX_RENEWALS", 2),
+
+			HoldPickupWindow:  time.Duration(parseEnvInt("LOAN_HOLD_PICKUP_HOURS", 72)) * time.Hour,
+			HoldSweepInterval: time.Duration(parseEnvInt("LOAN_HOLD_SWEEP_MINUTES", 15)) * time.Minute,
+		},
+		Access: Access{
+			LevelsByRole: parseEnvRoleLevels("ACCESS_LEVELS_BY_ROLE", map[string][]string{
+				"guest":       {"public"},
+				"member":      {"public", "member-only"},
+				"librarian":   {"public", "member-only", "admin-only"},
+				"admin":       {"public", "member-only", "admin-only"},
+				"super-admin": {"public", "member-only", "admin-only"},
+			}),
+			DefaultLevels: parseEnvList("ACCESS_LEVELS_DEFAULT", []string{"public"}),
+			AdminRoles:    adminRoles,
+
+			ApprovedRoleMode: parseEnvString("USER_REQUEST_ROLE_MODE", "replace"),
+		},
+		Cache: Cache{
+			Enabled:       parseEnvBool("PERMISSION_CACHE_ENABLED", true),
+			SessionTTL:    time.Duration(parseEnvInt("SESSION_CACHE_SECONDS", 30)) * time.Second,
+			PermissionTTL: time.Duration(parseEnvInt("PERMISSION_CACHE_SECONDS", 300)) * time.Second,
+		},
+		Resources: Resources{
+			SyncOnStart: parseEnvBool("RESOURCE_SYNC_ON_START", false),
+			GrantRole:   strings.TrimSpace(os.Getenv("RESOURCE_SYNC_GRANT_ROLE")),
+		},
+		Session: Session{
+			MaxPerUser:    parseEnvInt("SESSION_MAX_PER_USER", 10),
+			PurgeInterval: time.Duration(parseEnvInt("SESSION_PURGE_MINUTES", 60)) * time.Minute,
+		},
+		Login: Login{
+			MaxFailures:     parseEnvInt("LOGIN_MAX_FAILURES", 5),
+			MaxIPFailures:   parseEnvInt("LOGIN_MAX_IP_FAILURES", 50),
+			LockoutDuration: time.Duration(parseEnvInt("LOGIN_LOCKOUT_MINUTES", 15)) * time.Minute,
+			FailureWindow:   time.Duration(parseEnvInt("LOGIN_FAILURE_WINDOW_MINUTES", 15)) * time.Minute,
+			BackoffBase:     time.Duration(parseEnvInt("LOGIN_BACKOFF_SECONDS", 1)) * time.Second,
+		},
+		TwoFactor: TwoFactor{
+			Issuer:        parseEnvString("TWO_FACTOR_ISSUER", "Bookstore"),
+			RequiredRoles: parseEnvList("TWO_FACTOR_REQUIRED_ROLES", adminRoles),
+			ChallengeTTL:  time.Duration(parseEnvInt("TWO_FACTOR_CHALLENGE_MINUTES", 5)) * time.Minute,
+			MaxAttempts:   parseEnvInt("TWO_FACTOR_MAX_ATTEMPTS", 5),
+			RecoveryCodes: parseEnvInt("TWO_FACTOR_RECOVERY_CODES", 10),
+		},
+		Password: Password{
+			MinLength:     parseEnvInt("PASSWORD_MIN_LENGTH", 10),
+			MaxLength:     parseEnvInt("PASSWORD_MAX_LENGTH", 128),
+			RequireUpper:  parseEnvBool("PASSWORD_REQUIRE_UPPER", true),
+			RequireLower:  parseEnvBool("PASSWORD_REQUIRE_LOWER", true),
+			RequireDigit:  parseEnvBool("PASSWORD_REQUIRE_DIGIT", true),
+			RequireSymbol: parseEnvBool("PASSWORD_REQUIRE_SYMBOL", false),
+			DenyCommon:    parseEnvBool("PASSWORD_DENY_COMMON", true),
+			HistorySize:   parseEnvInt("PASSWORD_HISTORY_SIZE", 5),
+		},
+		Mail: Mail{
+			Driver:       parseEnvString("MAIL_DRIVER", "stdout"),
+			From:         parseEnvString("MAIL_FROM", "no-reply@bookstore.local"),
+			SMTPHost:     os.Getenv("MAIL_SMTP_HOST"),
+			SMTPPort:     parseEnvInt("MAIL_SMTP_PORT", 587),
+			SMTPUsername: os.Getenv("MAIL_SMTP_USERNAME"),
+			SMTPPassword: os.Getenv("MAIL_SMTP_PASSWORD"),
+			SMTPTimeout:  time.Duration(parseEnvInt("MAIL_SMTP_TIMEOUT_SECONDS", 30)) * time.Second,
+			FilePath:     parseEnvString("MAIL_FILE_PATH", "mail.log"),
+
+			ResetURL:      parseEnvString("PASSWORD_RESET_URL", "http://localhost:3000/reset-password"),
+			ResetTokenTTL: time.Duration(parseEnvInt("PASSWORD_RESET_TOKEN_MINUTES", 30)) * time.Minute,
+
+			ResetCooldown:      time.Duration(parseEnvInt("PASSWORD_RESET_COOLDOWN_MINUTES", 5)) * time.Minute,
+			ResetMaxRequests:   parseEnvInt("PASSWORD_RESET_MAX_REQUESTS", 5),
+			ResetMaxIPRequests: parseEnvInt("PASSWORD_RESET_MAX_IP_REQUESTS", 20),
+			ResetWindow:        time.Duration(parseEnvInt("PASSWORD_RESET_WINDOW_MINUTES", 60)) * time.Minute,
+
+			VerifyURL:      parseEnvString("EMAIL_VERIFY_URL", "http://localhost:3000/verify-email"),
+			VerifyTokenTTL: time.Duration(parseEnvInt("EMAIL_VERIFY_TOKEN_HOURS", 24)) * time.Hour,
+		},
 	}
 }
@@ -13,14 +13,19 @@ type Postgres struct {
 	DB *sqlx.DB
 }
 
-func (cfg Config) ConnectionPostgres() (*Postgres, error) {
-	dbConnString := fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
+// PostgresDSN is the connection string shared by the pool and the LISTEN connection.
+func (cfg Config) PostgresDSN() string {
+	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable",
 		cfg.Psql.User,
 		cfg.Psql.Password,
 		cfg.Psql.Host,
 		cfg.Psql.Port,
 		cfg.Psql.DBName,
 	)
+}
+
+func (cfg Config) ConnectionPostgres() (*Postgres, error) {
+	dbConnString := cfg.PostgresDSN()
 
 	db, err := sqlx.Connect("postgres", dbConnString)
 	if err != nil {
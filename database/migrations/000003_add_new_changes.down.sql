@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS role_resources;
+DROP TABLE IF EXISTS resources;
@@ -0,0 +1 @@
+DROP FUNCTION IF EXISTS update_book_status_on_return();
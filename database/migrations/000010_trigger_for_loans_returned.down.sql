@@ -0,0 +1 @@
+DROP TRIGGER IF EXISTS book_status_on_return ON loans;
@@ -0,0 +1,44 @@
+DROP TRIGGER IF EXISTS copy_status_on_return ON loans;
+DROP TRIGGER IF EXISTS copy_status_on_loan ON loans;
+DROP FUNCTION IF EXISTS update_copy_status_on_return();
+DROP FUNCTION IF EXISTS update_copy_status_on_loan();
+
+DROP INDEX IF EXISTS unique_loan_copy;
+ALTER TABLE loans DROP COLUMN IF EXISTS copy_id;
+CREATE UNIQUE INDEX IF NOT EXISTS unique_loan_book ON loans (book_id) WHERE return_date IS NULL;
+
+DROP TABLE IF EXISTS book_copies;
+DROP FUNCTION IF EXISTS refresh_book_status();
+
+CREATE OR REPLACE FUNCTION update_book_status_on_loan()
+RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE books
+    SET status = 'borrowed'
+    WHERE id = NEW.book_id;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER book_status_on_loan
+AFTER INSERT ON loans
+FOR EACH ROW
+EXECUTE FUNCTION update_book_status_on_loan();
+
+CREATE OR REPLACE FUNCTION update_book_status_on_return()
+RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE books
+    SET status = 'available'
+    WHERE id = NEW.book_id;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER book_status_on_return
+AFTER UPDATE OF return_date ON loans
+FOR EACH ROW
+WHEN (NEW.return_date IS NOT NULL)
+EXECUTE FUNCTION update_book_status_on_return();
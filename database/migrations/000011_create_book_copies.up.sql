@@ -0,0 +1,99 @@
+-- Tabel book_copies (eksemplar fisik dari satu judul buku)
+CREATE TABLE IF NOT EXISTS book_copies (
+    id UUID NOT NULL DEFAULT GEN_RANDOM_UUID(),
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    barcode VARCHAR(64) NOT NULL UNIQUE,
+    condition VARCHAR(20) NOT NULL DEFAULT 'good' CHECK (condition IN ('new', 'good', 'fair', 'poor', 'damaged')),
+    shelf_location VARCHAR(100),
+    acquisition_date DATE,
+    status VARCHAR(20) NOT NULL DEFAULT 'available' CHECK (status IN ('available', 'borrowed', 'withdrawn')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_by UUID,
+    updated_at TIMESTAMPTZ,
+    updated_by UUID,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_book_copies_book_status ON book_copies (book_id, status);
+
+-- setiap buku lama jadi satu eksemplar
+INSERT INTO book_copies (book_id, barcode, acquisition_date, status, created_at, created_by)
+SELECT
+    b.id,
+    'LEGACY-' || b.isbn,
+    b.created_at::DATE,
+    b.status,
+    b.created_at,
+    b.created_by
+FROM books b;
+
+ALTER TABLE loans ADD COLUMN copy_id UUID REFERENCES book_copies(id) ON DELETE CASCADE;
+
+UPDATE loans l
+SET copy_id = c.id
+FROM book_copies c
+WHERE c.book_id = l.book_id;
+
+ALTER TABLE loans ALTER COLUMN copy_id SET NOT NULL;
+
+-- satu pinjaman aktif per eksemplar, bukan per judul
+DROP INDEX IF EXISTS unique_loan_book;
+CREATE UNIQUE INDEX unique_loan_copy ON loans (copy_id) WHERE status = 'borrowed';
+
+DROP TRIGGER IF EXISTS book_status_on_loan ON loans;
+DROP TRIGGER IF EXISTS book_status_on_return ON loans;
+DROP FUNCTION IF EXISTS update_book_status_on_loan();
+DROP FUNCTION IF EXISTS update_book_status_on_return();
+
+-- books.status jadi ringkasan: 'available' selama masih ada eksemplar tersedia
+CREATE OR REPLACE FUNCTION refresh_book_status()
+RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE books
+    SET status = CASE
+        WHEN EXISTS (SELECT 1 FROM book_copies WHERE book_id = NEW.book_id AND status = 'available') THEN 'available'
+        ELSE 'borrowed'
+    END
+    WHERE id = NEW.book_id;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER book_status_on_copy_change
+AFTER INSERT OR UPDATE OF status ON book_copies
+FOR EACH ROW
+EXECUTE FUNCTION refresh_book_status();
+
+CREATE OR REPLACE FUNCTION update_copy_status_on_loan()
+RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE book_copies
+    SET status = 'borrowed'
+    WHERE id = NEW.copy_id;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER copy_status_on_loan
+AFTER INSERT ON loans
+FOR EACH ROW
+EXECUTE FUNCTION update_copy_status_on_loan();
+
+CREATE OR REPLACE FUNCTION update_copy_status_on_return()
+RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE book_copies
+    SET status = 'available'
+    WHERE id = NEW.copy_id;
+
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER copy_status_on_return
+AFTER UPDATE OF status ON loans
+FOR EACH ROW
+WHEN (OLD.status <> 'returned' AND NEW.status = 'returned')
+EXECUTE FUNCTION update_copy_status_on_return();
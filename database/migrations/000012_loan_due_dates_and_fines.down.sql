@@ -0,0 +1,14 @@
+DROP TABLE IF EXISTS fines;
+DROP TABLE IF EXISTS loan_policies;
+
+DROP INDEX IF EXISTS idx_loans_open_due_date;
+DROP INDEX IF EXISTS unique_loan_copy;
+
+UPDATE loans SET status = 'borrowed' WHERE status = 'overdue';
+ALTER TABLE loans DROP CONSTRAINT IF EXISTS loans_status_check;
+ALTER TABLE loans ADD CONSTRAINT loans_status_check CHECK (status IN ('borrowed', 'returned'));
+
+UPDATE loans SET return_date = due_date WHERE return_date IS NULL;
+ALTER TABLE loans DROP COLUMN IF EXISTS due_date;
+
+CREATE UNIQUE INDEX unique_loan_copy ON loans (copy_id) WHERE status = 'borrowed';
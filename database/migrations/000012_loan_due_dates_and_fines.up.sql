@@ -0,0 +1,46 @@
+-- return_date sekarang berarti tanggal pengembalian sebenarnya
+ALTER TABLE loans ADD COLUMN due_date DATE;
+
+UPDATE loans SET due_date = COALESCE(return_date, loan_date + 14);
+UPDATE loans SET return_date = NULL WHERE status = 'borrowed';
+
+ALTER TABLE loans ALTER COLUMN due_date SET NOT NULL;
+
+ALTER TABLE loans DROP CONSTRAINT IF EXISTS loans_status_check;
+ALTER TABLE loans ADD CONSTRAINT loans_status_check CHECK (status IN ('borrowed', 'overdue', 'returned'));
+
+DROP INDEX IF EXISTS unique_loan_copy;
+CREATE UNIQUE INDEX unique_loan_copy ON loans (copy_id) WHERE return_date IS NULL;
+CREATE INDEX IF NOT EXISTS idx_loans_open_due_date ON loans (due_date) WHERE return_date IS NULL;
+
+-- Tabel loan_policies (lama pinjam dan denda per role)
+CREATE TABLE IF NOT EXISTS loan_policies (
+    id UUID NOT NULL DEFAULT GEN_RANDOM_UUID(),
+    role_id UUID NOT NULL UNIQUE REFERENCES roles(id) ON DELETE CASCADE,
+    loan_days INT NOT NULL CHECK (loan_days > 0),
+    daily_fine BIGINT NOT NULL DEFAULT 0 CHECK (daily_fine >= 0),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_by UUID,
+    updated_at TIMESTAMPTZ,
+    updated_by UUID,
+    PRIMARY KEY (id)
+);
+
+-- Tabel fines (satu baris denda per pinjaman)
+CREATE TABLE IF NOT EXISTS fines (
+    id UUID NOT NULL DEFAULT GEN_RANDOM_UUID(),
+    loan_id UUID NOT NULL UNIQUE REFERENCES loans(id) ON DELETE CASCADE,
+    member_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    days_overdue INT NOT NULL DEFAULT 0,
+    daily_rate BIGINT NOT NULL DEFAULT 0,
+    amount BIGINT NOT NULL DEFAULT 0,
+    status VARCHAR(10) NOT NULL DEFAULT 'accruing' CHECK (status IN ('accruing', 'settled')),
+    settled_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_by UUID,
+    updated_at TIMESTAMPTZ,
+    updated_by UUID,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_fines_member ON fines (member_id);
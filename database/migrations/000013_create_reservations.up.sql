@@ -0,0 +1,23 @@
+-- eksemplar yang disisihkan untuk antrean reservasi
+ALTER TABLE book_copies DROP CONSTRAINT IF EXISTS book_copies_status_check;
+ALTER TABLE book_copies ADD CONSTRAINT book_copies_status_check CHECK (status IN ('available', 'borrowed', 'on_hold', 'withdrawn'));
+
+-- Tabel reservations (antrean FIFO per buku)
+CREATE TABLE IF NOT EXISTS reservations (
+    id UUID NOT NULL DEFAULT GEN_RANDOM_UUID(),
+    book_id UUID NOT NULL REFERENCES books(id) ON DELETE CASCADE,
+    member_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    copy_id UUID REFERENCES book_copies(id) ON DELETE SET NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'waiting' CHECK (status IN ('waiting', 'ready', 'fulfilled', 'cancelled', 'expired')),
+    ready_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_by UUID,
+    updated_at TIMESTAMPTZ,
+    updated_by UUID,
+    PRIMARY KEY (id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS unique_active_reservation ON reservations (book_id, member_id) WHERE status IN ('waiting', 'ready');
+CREATE INDEX IF NOT EXISTS idx_reservations_queue ON reservations (book_id, created_at) WHERE status = 'waiting';
+CREATE INDEX IF NOT EXISTS idx_reservations_ready_expiry ON reservations (expires_at) WHERE status = 'ready';
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS loan_renewals;
+ALTER TABLE loans DROP COLUMN IF EXISTS renewal_count;
+ALTER TABLE loan_policies DROP COLUMN IF EXISTS max_renewals;
@@ -0,0 +1,15 @@
+ALTER TABLE loan_policies ADD COLUMN max_renewals INT NOT NULL DEFAULT 2 CHECK (max_renewals >= 0);
+ALTER TABLE loans ADD COLUMN renewal_count INT NOT NULL DEFAULT 0;
+
+-- Tabel loan_renewals (riwayat perpanjangan pinjaman)
+CREATE TABLE IF NOT EXISTS loan_renewals (
+    id UUID NOT NULL DEFAULT GEN_RANDOM_UUID(),
+    loan_id UUID NOT NULL REFERENCES loans(id) ON DELETE CASCADE,
+    previous_due_date DATE NOT NULL,
+    new_due_date DATE NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    created_by UUID,
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_loan_renewals_loan ON loan_renewals (loan_id, created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_users_created_at_id;
+DROP INDEX IF EXISTS idx_books_created_at_id;
+DROP INDEX IF EXISTS idx_loans_created_at_id;
@@ -0,0 +1,4 @@
+-- Index untuk keyset pagination (created_at, id) pada tabel yang besar
+CREATE INDEX IF NOT EXISTS idx_loans_created_at_id ON loans (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_books_created_at_id ON books (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users (created_at, id);
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_books_author_active;
+
+ALTER TABLE role_resources DROP COLUMN IF EXISTS deleted_at, DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE resources DROP COLUMN IF EXISTS deleted_at, DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE roles DROP COLUMN IF EXISTS deleted_at, DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE ratings DROP COLUMN IF EXISTS deleted_at, DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE books DROP COLUMN IF EXISTS deleted_at, DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE categories DROP COLUMN IF EXISTS deleted_at, DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE publishers DROP COLUMN IF EXISTS deleted_at, DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE authors DROP COLUMN IF EXISTS deleted_at, DROP COLUMN IF EXISTS deleted_by;
@@ -0,0 +1,13 @@
+-- Soft delete untuk entitas katalog dan identitas. Baris yang dihapus tetap
+-- ada (dan tetap memegang unique constraint-nya) sehingga bisa di-restore
+-- dan riwayat peminjaman tetap utuh.
+ALTER TABLE authors ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL, ADD COLUMN IF NOT EXISTS deleted_by UUID NULL;
+ALTER TABLE publishers ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL, ADD COLUMN IF NOT EXISTS deleted_by UUID NULL;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL, ADD COLUMN IF NOT EXISTS deleted_by UUID NULL;
+ALTER TABLE books ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL, ADD COLUMN IF NOT EXISTS deleted_by UUID NULL;
+ALTER TABLE ratings ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL, ADD COLUMN IF NOT EXISTS deleted_by UUID NULL;
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL, ADD COLUMN IF NOT EXISTS deleted_by UUID NULL;
+ALTER TABLE resources ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL, ADD COLUMN IF NOT EXISTS deleted_by UUID NULL;
+ALTER TABLE role_resources ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ NULL, ADD COLUMN IF NOT EXISTS deleted_by UUID NULL;
+
+CREATE INDEX IF NOT EXISTS idx_books_author_active ON books (author_id) WHERE deleted_at IS NULL;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_audit_logs_created_at_id;
+DROP INDEX IF EXISTS idx_audit_logs_entity;
+DROP INDEX IF EXISTS idx_audit_logs_user_created;
+
+ALTER TABLE audit_logs
+    DROP COLUMN IF EXISTS client_ip,
+    DROP COLUMN IF EXISTS request_id,
+    DROP COLUMN IF EXISTS after_data,
+    DROP COLUMN IF EXISTS before_data,
+    DROP COLUMN IF EXISTS entity_id,
+    DROP COLUMN IF EXISTS entity;
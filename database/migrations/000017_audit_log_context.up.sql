@@ -0,0 +1,12 @@
+-- Kolom tambahan audit_logs: entitas yang diubah, snapshot sebelum/sesudah, dan konteks request
+ALTER TABLE audit_logs
+    ADD COLUMN IF NOT EXISTS entity VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS entity_id UUID,
+    ADD COLUMN IF NOT EXISTS before_data JSONB,
+    ADD COLUMN IF NOT EXISTS after_data JSONB,
+    ADD COLUMN IF NOT EXISTS request_id VARCHAR(100),
+    ADD COLUMN IF NOT EXISTS client_ip VARCHAR(64);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_created ON audit_logs (user_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_entity ON audit_logs (entity, entity_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at_id ON audit_logs (created_at, id);
@@ -0,0 +1,40 @@
+-- Skema lama hanya punya satu method per resource dan endpoint yang unik.
+-- Resource yang tidak muat ke skema itu tidak digabung atau dihapus diam-diam
+-- karena role_resources menunjuk ke sana; rollback dihentikan sebelum mengubah
+-- apa pun dan data itu harus dirapikan dulu secara manual.
+DO $$
+DECLARE
+    duplicate_endpoints TEXT;
+    multi_method TEXT;
+BEGIN
+    SELECT string_agg(endpoint, ', ') INTO duplicate_endpoints
+    FROM (SELECT endpoint FROM resources GROUP BY endpoint HAVING COUNT(*) > 1) d;
+
+    IF duplicate_endpoints IS NOT NULL THEN
+        RAISE EXCEPTION 'cannot roll back 000018: several resources share endpoint(s) %', duplicate_endpoints;
+    END IF;
+
+    SELECT string_agg(endpoint, ', ') INTO multi_method
+    FROM resources
+    WHERE cardinality(methods) > 1 OR NOT (methods <@ ARRAY['GET', 'POST', 'PUT', 'DELETE']::VARCHAR(10)[]);
+
+    IF multi_method IS NOT NULL THEN
+        RAISE EXCEPTION 'cannot roll back 000018: resource(s) % allow more than one method or PATCH', multi_method;
+    END IF;
+END $$;
+
+DROP INDEX IF EXISTS idx_resources_endpoint;
+
+ALTER TABLE resources DROP CONSTRAINT IF EXISTS resources_methods_check;
+
+ALTER TABLE resources ADD COLUMN IF NOT EXISTS method VARCHAR(10);
+
+UPDATE resources SET method = COALESCE(methods[1], 'GET');
+
+ALTER TABLE resources
+    ALTER COLUMN method SET NOT NULL,
+    ADD CONSTRAINT resources_method_check CHECK (method IN ('GET', 'POST', 'PUT', 'DELETE'));
+
+ALTER TABLE resources DROP COLUMN IF EXISTS methods;
+
+ALTER TABLE resources ADD CONSTRAINT resources_endpoint_key UNIQUE (endpoint);
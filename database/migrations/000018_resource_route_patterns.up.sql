@@ -0,0 +1,15 @@
+-- Resource dicocokkan dengan template route mux (mis. /api/v1/books/{id}) atau
+-- wildcard (/api/v1/books/*). Satu resource bisa mengizinkan beberapa method dan
+-- beberapa resource boleh menunjuk endpoint yang sama.
+ALTER TABLE resources DROP CONSTRAINT IF EXISTS resources_endpoint_key;
+
+ALTER TABLE resources ADD COLUMN IF NOT EXISTS methods VARCHAR(10)[] NOT NULL DEFAULT '{}';
+
+UPDATE resources SET methods = ARRAY[method] WHERE method IS NOT NULL;
+
+ALTER TABLE resources DROP COLUMN IF EXISTS method;
+
+ALTER TABLE resources ADD CONSTRAINT resources_methods_check
+    CHECK (methods <@ ARRAY['GET', 'POST', 'PUT', 'DELETE', 'PATCH']::VARCHAR(10)[]);
+
+CREATE INDEX IF NOT EXISTS idx_resources_endpoint ON resources (endpoint);
@@ -0,0 +1 @@
+ALTER TABLE resources DROP COLUMN IF EXISTS route_missing_at;
@@ -0,0 +1,3 @@
+-- Diisi oleh `resources sync` saat tidak ada route mux yang cocok lagi dengan
+-- endpoint resource, dikosongkan lagi begitu route-nya kembali.
+ALTER TABLE resources ADD COLUMN IF NOT EXISTS route_missing_at TIMESTAMPTZ NULL;
@@ -0,0 +1,11 @@
+DELETE FROM role_resources WHERE effect = 'deny';
+
+ALTER TABLE role_resources DROP CONSTRAINT IF EXISTS role_resources_effect_check;
+
+ALTER TABLE role_resources DROP COLUMN IF EXISTS effect;
+
+DROP INDEX IF EXISTS idx_roles_parent_role_id;
+
+ALTER TABLE roles DROP CONSTRAINT IF EXISTS roles_parent_not_self;
+
+ALTER TABLE roles DROP COLUMN IF EXISTS parent_role_id;
@@ -0,0 +1,11 @@
+-- Role mewarisi grant dari parent-nya. Grant dengan effect 'deny' pada role yang
+-- lebih dekat menimpa 'allow' yang diwarisi.
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS parent_role_id UUID NULL REFERENCES roles(id) ON DELETE SET NULL;
+
+ALTER TABLE roles ADD CONSTRAINT roles_parent_not_self CHECK (parent_role_id <> id);
+
+CREATE INDEX IF NOT EXISTS idx_roles_parent_role_id ON roles (parent_role_id);
+
+ALTER TABLE role_resources ADD COLUMN IF NOT EXISTS effect VARCHAR(5) NOT NULL DEFAULT 'allow';
+
+ALTER TABLE role_resources ADD CONSTRAINT role_resources_effect_check CHECK (effect IN ('allow', 'deny'));
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_user_requests_user_created;
+DROP INDEX IF EXISTS idx_user_requests_one_pending;
+
+ALTER TABLE user_requests
+    DROP COLUMN IF EXISTS reviewed_at,
+    DROP COLUMN IF EXISTS reviewed_by,
+    DROP COLUMN IF EXISTS rejection_reason;
@@ -0,0 +1,21 @@
+ALTER TABLE user_requests
+    ADD COLUMN IF NOT EXISTS rejection_reason TEXT NULL,
+    ADD COLUMN IF NOT EXISTS reviewed_by UUID NULL REFERENCES users(id),
+    ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMPTZ NULL;
+
+-- Sisakan satu request pending per user dan role sebelum index unik dibuat.
+UPDATE user_requests
+SET status = 'rejected', rejection_reason = 'duplicate pending request', updated_at = NOW()
+WHERE status = 'pending'
+AND id NOT IN (
+    SELECT DISTINCT ON (user_id, requested_role_id) id
+    FROM user_requests
+    WHERE status = 'pending'
+    ORDER BY user_id, requested_role_id, created_at
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_requests_one_pending
+    ON user_requests (user_id, requested_role_id)
+    WHERE status = 'pending';
+
+CREATE INDEX IF NOT EXISTS idx_user_requests_user_created ON user_requests (user_id, created_at DESC);
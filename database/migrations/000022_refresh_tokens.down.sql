@@ -0,0 +1,14 @@
+DROP INDEX IF EXISTS idx_sessions_user_id;
+DROP INDEX IF EXISTS idx_sessions_family_id;
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS revoked_at,
+    DROP COLUMN IF EXISTS rotated_at,
+    DROP COLUMN IF EXISTS refresh_expires_at,
+    DROP COLUMN IF EXISTS refresh_token_hash,
+    DROP COLUMN IF EXISTS family_id;
+
+-- Hash tidak bisa dikembalikan ke token aslinya, semua user harus login ulang.
+DELETE FROM sessions;
+
+ALTER TABLE sessions RENAME COLUMN token_hash TO token;
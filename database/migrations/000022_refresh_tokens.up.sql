@@ -0,0 +1,20 @@
+-- Access token tidak lagi disimpan mentah, hanya hash SHA-256 (hex) seperti lib.HashToken.
+ALTER TABLE sessions RENAME COLUMN token TO token_hash;
+
+UPDATE sessions SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex');
+
+-- Setiap refresh membuat baris baru dalam family yang sama. Refresh token yang
+-- dipakai ulang mencabut seluruh family.
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS family_id UUID NULL,
+    ADD COLUMN IF NOT EXISTS refresh_token_hash TEXT NULL UNIQUE,
+    ADD COLUMN IF NOT EXISTS refresh_expires_at TIMESTAMPTZ NULL,
+    ADD COLUMN IF NOT EXISTS rotated_at TIMESTAMPTZ NULL,
+    ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ NULL;
+
+UPDATE sessions SET family_id = id WHERE family_id IS NULL;
+
+ALTER TABLE sessions ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_family_id ON sessions (family_id);
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions (user_id);
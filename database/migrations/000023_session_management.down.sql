@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_password_reset_expires_at;
+DROP INDEX IF EXISTS idx_sessions_expiration;
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS last_seen_at,
+    DROP COLUMN IF EXISTS user_agent,
+    DROP COLUMN IF EXISTS ip_address,
+    DROP COLUMN IF EXISTS device;
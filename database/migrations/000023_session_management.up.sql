@@ -0,0 +1,9 @@
+-- Perangkat asal login, diisi saat token diterbitkan dan saat refresh.
+ALTER TABLE sessions
+    ADD COLUMN IF NOT EXISTS device TEXT NULL,
+    ADD COLUMN IF NOT EXISTS ip_address TEXT NULL,
+    ADD COLUMN IF NOT EXISTS user_agent TEXT NULL,
+    ADD COLUMN IF NOT EXISTS last_seen_at TIMESTAMPTZ NULL;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_expiration ON sessions (expiration);
+CREATE INDEX IF NOT EXISTS idx_password_reset_expires_at ON password_reset (expires_at);
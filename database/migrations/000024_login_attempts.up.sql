@@ -0,0 +1,11 @@
+-- Hitungan gagal login per username dan per IP. Key berupa "username:<nama>"
+-- atau "ip:<alamat>" supaya username yang tidak terdaftar juga dihitung.
+CREATE TABLE IF NOT EXISTS login_attempts (
+    key TEXT NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    locked_until TIMESTAMPTZ NULL,
+    PRIMARY KEY (key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_attempts_last_failed_at ON login_attempts (last_failed_at);
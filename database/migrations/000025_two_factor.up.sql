@@ -0,0 +1,36 @@
+-- Secret TOTP per user. Baru aktif setelah confirmed_at diisi lewat kode pertama;
+-- last_used_step mencegah kode yang sama dipakai dua kali.
+CREATE TABLE IF NOT EXISTS user_totp (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    confirmed_at TIMESTAMPTZ NULL,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NULL,
+    PRIMARY KEY (user_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id UUID NOT NULL DEFAULT GEN_RANDOM_UUID(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id),
+    UNIQUE (user_id, code_hash)
+);
+
+-- Langkah kedua login: token challenge berumur pendek yang ditukar dengan sesi.
+CREATE TABLE IF NOT EXISTS login_challenges (
+    id UUID NOT NULL DEFAULT GEN_RANDOM_UUID(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash TEXT NOT NULL UNIQUE,
+    purpose VARCHAR(20) NOT NULL CHECK (purpose IN ('verify', 'enroll')),
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_challenges_expires_at ON login_challenges (expires_at);
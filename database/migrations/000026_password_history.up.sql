@@ -0,0 +1,10 @@
+-- Hash kata sandi yang pernah dipakai, supaya kata sandi lama tidak dipakai ulang.
+CREATE TABLE IF NOT EXISTS password_history (
+    id UUID NOT NULL DEFAULT GEN_RANDOM_UUID(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_history_user_id ON password_history (user_id, created_at DESC);
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_password_reset_user_id;
+
+DELETE FROM password_reset;
+
+ALTER TABLE password_reset RENAME COLUMN token_hash TO token;
+
+DROP INDEX IF EXISTS idx_users_email;
+
+ALTER TABLE users DROP COLUMN IF EXISTS email;
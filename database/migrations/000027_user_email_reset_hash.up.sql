@@ -0,0 +1,12 @@
+-- Alamat email pengguna, tujuan pengiriman tautan reset kata sandi.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(255) NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (LOWER(email));
+
+-- Token reset sekarang hanya disimpan sebagai hash; token lama yang tersimpan
+-- apa adanya dibuang.
+DELETE FROM password_reset;
+
+ALTER TABLE password_reset RENAME COLUMN token TO token_hash;
+
+CREATE INDEX IF NOT EXISTS idx_password_reset_user_id ON password_reset (user_id);
@@ -0,0 +1,33 @@
+DROP TABLE IF EXISTS email_verifications;
+
+DROP INDEX IF EXISTS idx_users_verified_email;
+
+-- alamat ganda dari pendaftaran yang belum diverifikasi dilepas dulu
+UPDATE users u SET email = NULL
+WHERE u.email_verified_at IS NULL
+AND EXISTS (
+    SELECT 1 FROM users o
+    WHERE LOWER(o.email) = LOWER(u.email)
+    AND o.id <> u.id
+    AND (o.email_verified_at IS NOT NULL OR o.created_at < u.created_at)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (LOWER(email));
+
+DROP INDEX IF EXISTS idx_users_status;
+DROP INDEX IF EXISTS idx_users_member_number;
+
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_status_check;
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS previous_status,
+    DROP COLUMN IF EXISTS status_changed_by,
+    DROP COLUMN IF EXISTS status_changed_at,
+    DROP COLUMN IF EXISTS status_reason,
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS email_verified_at,
+    DROP COLUMN IF EXISTS member_number,
+    DROP COLUMN IF EXISTS phone,
+    DROP COLUMN IF EXISTS full_name;
+
+DROP SEQUENCE IF EXISTS member_number_seq;
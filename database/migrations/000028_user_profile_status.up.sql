@@ -0,0 +1,44 @@
+-- Nomor anggota dibuat otomatis dari sequence, termasuk untuk user yang sudah ada.
+CREATE SEQUENCE IF NOT EXISTS member_number_seq;
+
+-- Profil dan status akun user. User yang sudah ada dianggap aktif.
+-- previous_status menyimpan status sebelum perubahan terakhir, supaya reactivate
+-- mengembalikan user yang disuspend sebelum memverifikasi email ke
+-- pending_verification, bukan active.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS full_name VARCHAR(255) NULL,
+    ADD COLUMN IF NOT EXISTS phone VARCHAR(32) NULL,
+    ADD COLUMN IF NOT EXISTS member_number VARCHAR(32) NOT NULL DEFAULT ('M' || LPAD(NEXTVAL('member_number_seq')::TEXT, 8, '0')),
+    ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMPTZ NULL,
+    ADD COLUMN IF NOT EXISTS status VARCHAR(32) NOT NULL DEFAULT 'active',
+    ADD COLUMN IF NOT EXISTS status_reason TEXT NULL,
+    ADD COLUMN IF NOT EXISTS status_changed_at TIMESTAMPTZ NULL,
+    ADD COLUMN IF NOT EXISTS status_changed_by UUID NULL,
+    ADD COLUMN IF NOT EXISTS previous_status VARCHAR(32) NULL;
+
+ALTER SEQUENCE member_number_seq OWNED BY users.member_number;
+
+ALTER TABLE users
+    ADD CONSTRAINT users_status_check CHECK (status IN ('pending_verification', 'active', 'suspended', 'closed'));
+
+-- Email hanya unik di antara alamat yang sudah diverifikasi, supaya pendaftaran
+-- yang tidak pernah diverifikasi tidak mengunci alamat orang lain.
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_verified_email ON users (LOWER(email)) WHERE email_verified_at IS NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_member_number ON users (member_number);
+CREATE INDEX IF NOT EXISTS idx_users_status ON users (status);
+
+-- Token verifikasi email. Email baru baru disalin ke users setelah tokennya dipakai.
+CREATE TABLE IF NOT EXISTS email_verifications (
+    id UUID NOT NULL DEFAULT GEN_RANDOM_UUID(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_email_verifications_user_id ON email_verifications (user_id);
+CREATE INDEX IF NOT EXISTS idx_email_verifications_expires_at ON email_verifications (expires_at);
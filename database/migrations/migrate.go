@@ -0,0 +1,494 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// advisory lock key supaya dua runner tidak jalan bersamaan
+const lockKey = 7264190331
+
+var filePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+var (
+	StateApplied = "applied"
+	StatePending = "pending"
+	StateDrifted = "drifted"
+	StateMissing = "missing"
+	StateGap     = "gap"
+)
+
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type AppliedMigration struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	State     string
+	AppliedAt time.Time
+}
+
+type Migrator struct {
+	db         *sqlx.DB
+	migrations []Migration
+}
+
+// NewMigrator loads the .sql files embedded in the binary.
+func NewMigrator(db *sqlx.DB) (*Migrator, error) {
+	migrations, err := Load(files)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		db:         db,
+		migrations: migrations,
+	}, nil
+}
+
+// Load parses NNNNNN_name.up.sql / NNNNNN_name.down.sql pairs from fsys,
+// sorted by version. Numbering may skip versions (000002 never existed).
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := filePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %w", entry.Name(), err)
+		}
+
+		content, err := fs.ReadFile(fsys, path.Join(".", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("duplicate migration version %d (%s and %s)", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+			sum := sha256.Sum256(content)
+			migration.Checksum = hex.EncodeToString(sum[:])
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	var migrations []Migration
+	for _, migration := range byVersion {
+		if migration.Checksum == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up script", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+func (m *Migrator) ensureTable(ctx context.Context) error {
+	var legacy bool
+	err := m.db.QueryRowxContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1
+			FROM information_schema.columns
+			WHERE table_schema = current_schema()
+			AND table_name = 'schema_migrations'
+			AND column_name = 'dirty'
+		)
+	`).Scan(&legacy)
+	if err != nil {
+		return err
+	}
+
+	if legacy {
+		return m.adoptLegacyTable(ctx)
+	}
+
+	_, err = m.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (version)
+		)
+	`)
+	return err
+}
+
+// adoptLegacyTable converts the (version, dirty) table written by the external
+// migrate tool into our format, marking every file up to that version applied.
+func (m *Migrator) adoptLegacyTable(ctx context.Context) error {
+	var version int64
+	var dirty bool
+	err := m.db.QueryRowxContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if dirty {
+		return fmt.Errorf("legacy schema_migrations is dirty at version %d, fix it manually first", version)
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	statements := []string{
+		`ALTER TABLE schema_migrations RENAME TO schema_migrations_legacy`,
+		`CREATE TABLE schema_migrations (
+			version BIGINT NOT NULL,
+			name VARCHAR(255) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+			PRIMARY KEY (version)
+		)`,
+	}
+	for _, statement := range statements {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return err
+		}
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Version > version {
+			break
+		}
+
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+			migration.Version, migration.Name, migration.Checksum,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	log.Info().Msgf("[Migrate] adopted legacy schema_migrations at version %d", version)
+	return tx.Commit()
+}
+
+func (m *Migrator) applied(ctx context.Context) ([]AppliedMigration, error) {
+	var applied []AppliedMigration
+	err := m.db.SelectContext(ctx, &applied, `
+		SELECT version, name, checksum, applied_at
+		FROM schema_migrations
+		ORDER BY version
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+func (m *Migrator) lock(ctx context.Context) (*sqlx.Conn, error) {
+	conn, err := m.db.Connx(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockKey); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return conn, nil
+}
+
+func unlock(conn *sqlx.Conn) {
+	conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, lockKey)
+	conn.Close()
+}
+
+// Status compares the embedded files with schema_migrations. It holds the lock
+// because creating or adopting the table must not race another runner.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	conn, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock(conn)
+
+	if err := m.ensureTable(ctx); err != nil {
+		return nil, err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return m.compare(applied), nil
+}
+
+func (m *Migrator) compare(applied []AppliedMigration) []MigrationStatus {
+	appliedByVersion := map[int64]AppliedMigration{}
+	var highest int64
+	for _, a := range applied {
+		appliedByVersion[a.Version] = a
+		if a.Version > highest {
+			highest = a.Version
+		}
+	}
+
+	var statuses []MigrationStatus
+	known := map[int64]bool{}
+	for _, migration := range m.migrations {
+		known[migration.Version] = true
+		status := MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+
+		a, ok := appliedByVersion[migration.Version]
+		switch {
+		case ok && a.Checksum != migration.Checksum:
+			status.State = StateDrifted
+			status.AppliedAt = a.AppliedAt
+		case ok:
+			status.State = StateApplied
+			status.AppliedAt = a.AppliedAt
+		case migration.Version < highest:
+			status.State = StateGap
+		default:
+			status.State = StatePending
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	for _, a := range applied {
+		if known[a.Version] {
+			continue
+		}
+		statuses = append(statuses, MigrationStatus{
+			Version:   a.Version,
+			Name:      a.Name,
+			State:     StateMissing,
+			AppliedAt: a.AppliedAt,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses
+}
+
+// verify refuses to touch the schema when history and files disagree.
+func verify(statuses []MigrationStatus) error {
+	var problems []string
+	for _, status := range statuses {
+		switch status.State {
+		case StateDrifted:
+			problems = append(problems, fmt.Sprintf("%d_%s: checksum differs from applied version", status.Version, status.Name))
+		case StateMissing:
+			problems = append(problems, fmt.Sprintf("%d_%s: applied but file not found", status.Version, status.Name))
+		case StateGap:
+			problems = append(problems, fmt.Sprintf("%d_%s: not applied but newer migrations are", status.Version, status.Name))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("migration history is inconsistent:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return nil
+}
+
+// Up applies every pending migration.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.Goto(ctx, -1)
+}
+
+// Down rolls back the given number of applied migrations. The target is read
+// under the same lock as the rollback, so a runner that migrates in between
+// cannot make it roll back the wrong versions.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps <= 0 {
+		return nil
+	}
+
+	return m.migrate(ctx, func(applied []AppliedMigration) int64 {
+		if steps >= len(applied) {
+			return 0
+		}
+		return applied[len(applied)-steps-1].Version
+	})
+}
+
+// Goto migrates up or down until version is the latest applied migration.
+// A negative version means the newest embedded migration.
+func (m *Migrator) Goto(ctx context.Context, version int64) error {
+	return m.migrate(ctx, func([]AppliedMigration) int64 {
+		return version
+	})
+}
+
+// migrate moves to the version that target picks from the applied history,
+// holding the lock from reading the history until the last script has run.
+func (m *Migrator) migrate(ctx context.Context, target func(applied []AppliedMigration) int64) error {
+	// kunci dulu, baru buat/adopsi tabel, supaya dua instance tidak sama-sama mengadopsi
+	conn, err := m.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock(conn)
+
+	if err := m.ensureTable(ctx); err != nil {
+		return err
+	}
+
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := verify(m.compare(applied)); err != nil {
+		return err
+	}
+
+	// setelah verify, semua yang tercatat punya file dan urut per versi
+	version := target(applied)
+
+	if version < 0 && len(m.migrations) > 0 {
+		version = m.migrations[len(m.migrations)-1].Version
+	}
+
+	if version > 0 && m.find(version) == nil {
+		return fmt.Errorf("migration version %d not found", version)
+	}
+
+	isApplied := map[int64]bool{}
+	for _, a := range applied {
+		isApplied[a.Version] = true
+	}
+
+	for _, migration := range m.migrations {
+		if migration.Version > version || isApplied[migration.Version] {
+			continue
+		}
+		if err := m.run(ctx, migration, true); err != nil {
+			return err
+		}
+	}
+
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		migration := m.migrations[i]
+		if migration.Version <= version || !isApplied[migration.Version] {
+			continue
+		}
+		if err := m.run(ctx, migration, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return &m.migrations[i]
+		}
+	}
+	return nil
+}
+
+// run executes one script and its schema_migrations bookkeeping in a single transaction.
+func (m *Migrator) run(ctx context.Context, migration Migration, up bool) error {
+	script := migration.Up
+	direction := "up"
+	if !up {
+		script = migration.Down
+		direction = "down"
+	}
+
+	if strings.TrimSpace(script) == "" {
+		return fmt.Errorf("migration %d_%s has no %s script", migration.Version, migration.Name, direction)
+	}
+
+	tx, err := m.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return fmt.Errorf("migration %d_%s %s failed: %w", migration.Version, migration.Name, direction, err)
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx,
+			`INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)`,
+			migration.Version, migration.Name, migration.Checksum,
+		)
+	} else {
+		_, err = tx.ExecContext(ctx, `DELETE FROM schema_migrations WHERE version = $1`, migration.Version)
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	log.Info().Msgf("[Migrate] %d_%s %s", migration.Version, migration.Name, direction)
+	return nil
+}
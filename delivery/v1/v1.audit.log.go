@@ -0,0 +1,12 @@
+package v1
+
+import (
+	"app-bookstore/router"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func NewAPIAuditLog(r *mux.Router) {
+	r.HandleFunc("/audit-logs", router.HandlerAuditLogList).Methods(http.MethodGet)
+}
@@ -12,4 +12,6 @@ func NewAPIAuthors(r *mux.Router) {
 	r.HandleFunc("/authors/{id}", router.HandlerAuthorsDetail).Methods(http.MethodGet)
 	r.HandleFunc("/authors", router.HandlerAuthorsCreate).Methods(http.MethodPost)
 	r.HandleFunc("/authors/{id}", router.HandlerAuthorsUpdate).Methods(http.MethodPut)
+	r.HandleFunc("/authors/{id}", router.HandlerAuthorsDelete).Methods(http.MethodDelete)
+	r.HandleFunc("/authors/{id}/restore", router.HandlerAuthorsRestore).Methods(http.MethodPost)
 }
@@ -0,0 +1,15 @@
+package v1
+
+import (
+	"app-bookstore/router"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func NewAPIBookCopies(r *mux.Router) {
+	r.HandleFunc("/books/{id}/copies", router.HandlerBookCopyList).Methods(http.MethodGet)
+	r.HandleFunc("/books/{id}/copies", router.HandlerBookCopyCreate).Methods(http.MethodPost)
+	r.HandleFunc("/book-copies/{id}", router.HandlerBookCopyDetail).Methods(http.MethodGet)
+	r.HandleFunc("/book-copies/{id}", router.HandlerBookCopyUpdate).Methods(http.MethodPut)
+}
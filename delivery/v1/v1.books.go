@@ -12,4 +12,6 @@ func NewAPIBooks(r *mux.Router) {
 	r.HandleFunc("/books/{id}", router.HandlerBookDetail).Methods(http.MethodGet)
 	r.HandleFunc("/books", router.HandlerBookCreate).Methods(http.MethodPost)
 	r.HandleFunc("/books/{id}", router.HandlerBookUpdate).Methods(http.MethodPut)
+	r.HandleFunc("/books/{id}", router.HandlerBookDelete).Methods(http.MethodDelete)
+	r.HandleFunc("/books/{id}/restore", router.HandlerBookRestore).Methods(http.MethodPost)
 }
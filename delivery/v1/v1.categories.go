@@ -12,4 +12,6 @@ func NewAPICategories(r *mux.Router) {
 	r.HandleFunc("/categories/{id}", router.HandlerCategoryDetail).Methods(http.MethodGet)
 	r.HandleFunc("/categories", router.HandlerCategoryCreate).Methods(http.MethodPost)
 	r.HandleFunc("/categories/{id}", router.HandlerCategoryUpdate).Methods(http.MethodPut)
+	r.HandleFunc("/categories/{id}", router.HandlerCategoryDelete).Methods(http.MethodDelete)
+	r.HandleFunc("/categories/{id}/restore", router.HandlerCategoryRestore).Methods(http.MethodPost)
 }
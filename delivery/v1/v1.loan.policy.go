@@ -0,0 +1,15 @@
+package v1
+
+import (
+	"app-bookstore/router"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func NewAPILoanPolicy(r *mux.Router) {
+	r.HandleFunc("/loan-policies", router.HandlerLoanPolicyList).Methods(http.MethodGet)
+	r.HandleFunc("/loan-policies/{id}", router.HandlerLoanPolicyDetail).Methods(http.MethodGet)
+	r.HandleFunc("/loan-policies", router.HandlerLoanPolicyCreate).Methods(http.MethodPost)
+	r.HandleFunc("/loan-policies/{id}", router.HandlerLoanPolicyUpdate).Methods(http.MethodPut)
+}
@@ -9,7 +9,10 @@ import (
 
 func NewAPILoans(r *mux.Router) {
 	r.HandleFunc("/loans", router.HandlerListLoan).Methods(http.MethodGet)
+	r.HandleFunc("/loans/overdue", router.HandlerLoanOverdueList).Methods(http.MethodGet)
 	r.HandleFunc("/loans/{id}", router.HandlerLoansDetail).Methods(http.MethodGet)
 	r.HandleFunc("/loans", router.HandlerLoanCreate).Methods(http.MethodPost)
 	r.HandleFunc("/loans/{id}", router.HandlerLoansUpdate).Methods(http.MethodPut)
+	r.HandleFunc("/loans/{id}/renew", router.HandlerLoanRenew).Methods(http.MethodPost)
+	r.HandleFunc("/members/{id}/balance", router.HandlerMemberBalance).Methods(http.MethodGet)
 }
@@ -0,0 +1,25 @@
+package v1
+
+import (
+	"app-bookstore/router"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// NewAPIMe registers endpoints any signed-in user may call about themselves.
+func NewAPIMe(r *mux.Router) {
+	r.HandleFunc("/me", router.HandlerMe).Methods(http.MethodGet)
+	r.HandleFunc("/me", router.HandlerMeUpdate).Methods(http.MethodPut)
+	r.HandleFunc("/me", router.HandlerMeClose).Methods(http.MethodDelete)
+	r.HandleFunc("/me/permissions", router.HandlerMyPermissions).Methods(http.MethodGet)
+	r.HandleFunc("/me/requests", router.HandlerMyUserRequests).Methods(http.MethodGet)
+	r.HandleFunc("/me/sessions", router.HandlerMySessions).Methods(http.MethodGet)
+	r.HandleFunc("/me/sessions", router.HandlerMyOtherSessionsRevoke).Methods(http.MethodDelete)
+	r.HandleFunc("/me/sessions/{id}", router.HandlerMySessionRevoke).Methods(http.MethodDelete)
+	r.HandleFunc("/me/2fa", router.HandlerMyTwoFactor).Methods(http.MethodGet)
+	r.HandleFunc("/me/2fa", router.HandlerMyTwoFactorDisable).Methods(http.MethodDelete)
+	r.HandleFunc("/me/2fa/enroll", router.HandlerMyTwoFactorEnroll).Methods(http.MethodPost)
+	r.HandleFunc("/me/2fa/confirm", router.HandlerMyTwoFactorConfirm).Methods(http.MethodPost)
+	r.HandleFunc("/me/2fa/recovery-codes", router.HandlerMyRecoveryCodes).Methods(http.MethodPost)
+}
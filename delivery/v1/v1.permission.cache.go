@@ -0,0 +1,12 @@
+package v1
+
+import (
+	"app-bookstore/router"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func NewAPIPermissionCache(r *mux.Router) {
+	r.HandleFunc("/permission-cache/stats", router.HandlerPermissionCacheStats).Methods(http.MethodGet)
+}
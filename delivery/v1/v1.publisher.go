@@ -12,4 +12,6 @@ func NewAPIPublisher(r *mux.Router) {
 	r.HandleFunc("/publisher/{id}", router.HandlerPublishDetail).Methods(http.MethodGet)
 	r.HandleFunc("/publisher", router.HandlerPublisherCreate).Methods(http.MethodPost)
 	r.HandleFunc("/publisher/{id}", router.HandlerPubliserUpdate).Methods(http.MethodPut)
+	r.HandleFunc("/publisher/{id}", router.HandlerPublisherDelete).Methods(http.MethodDelete)
+	r.HandleFunc("/publisher/{id}/restore", router.HandlerPublisherRestore).Methods(http.MethodPost)
 }
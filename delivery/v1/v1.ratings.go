@@ -12,4 +12,6 @@ func NewAPIRating(r *mux.Router) {
 	r.HandleFunc("/ratings/{id}", router.HandlerRatingDetail).Methods(http.MethodGet)
 	r.HandleFunc("/ratings", router.HandlerRatingCreate).Methods(http.MethodPost)
 	r.HandleFunc("/ratings/{id}", router.HandlerRatingUpdate).Methods(http.MethodPut)
+	r.HandleFunc("/ratings/{id}", router.HandlerRatingDelete).Methods(http.MethodDelete)
+	r.HandleFunc("/ratings/{id}/restore", router.HandlerRatingRestore).Methods(http.MethodPost)
 }
@@ -0,0 +1,15 @@
+package v1
+
+import (
+	"app-bookstore/router"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func NewAPIReservation(r *mux.Router) {
+	r.HandleFunc("/reservations", router.HandlerReservationList).Methods(http.MethodGet)
+	r.HandleFunc("/reservations", router.HandlerReservationCreate).Methods(http.MethodPost)
+	r.HandleFunc("/reservations/{id}", router.HandlerReservationCancel).Methods(http.MethodDelete)
+	r.HandleFunc("/books/{id}/reservations", router.HandlerBookReservationQueue).Methods(http.MethodGet)
+}
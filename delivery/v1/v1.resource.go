@@ -12,4 +12,6 @@ func NewAPIResource(r *mux.Router) {
 	r.HandleFunc("/resource/{id}", router.HandlerResourceDetail).Methods(http.MethodGet)
 	r.HandleFunc("/resource", router.HandlerResourceCreate).Methods(http.MethodPost)
 	r.HandleFunc("/resource/{id}", router.HandlerResourceUpdate).Methods(http.MethodPut)
+	r.HandleFunc("/resource/{id}", router.HandlerResourceDelete).Methods(http.MethodDelete)
+	r.HandleFunc("/resource/{id}/restore", router.HandlerResourceRestore).Methods(http.MethodPost)
 }
@@ -12,5 +12,8 @@ func NewAPIRole(r *mux.Router) {
 	r.HandleFunc("/roles/{id}", router.HandlerRolesDetail).Methods(http.MethodGet)
 	r.HandleFunc("/roles", router.HandlerRoleCreate).Methods(http.MethodPost)
 	r.HandleFunc("/roles/{id}", router.HandlerRoleUpdate).Methods(http.MethodPut)
+	r.HandleFunc("/roles/{id}", router.HandlerRoleDelete).Methods(http.MethodDelete)
+	r.HandleFunc("/roles/{id}/restore", router.HandlerRoleRestore).Methods(http.MethodPost)
+	r.HandleFunc("/roles/{id}/effective-permissions", router.HandlerRoleEffectivePermissions).Methods(http.MethodGet)
 
 }
@@ -12,4 +12,6 @@ func NewAPIRoleResource(r *mux.Router) {
 	r.HandleFunc("/role-resources/{id}", router.HandlerRoleResourceDetail).Methods(http.MethodGet)
 	r.HandleFunc("/role-resources", router.HandlerRoleResourceCreate).Methods(http.MethodPost)
 	r.HandleFunc("/role-resources/{id}", router.HandlerRoleResourceUpdate).Methods(http.MethodPut)
+	r.HandleFunc("/role-resources/{id}", router.HandlerRoleResourceDelete).Methods(http.MethodDelete)
+	r.HandleFunc("/role-resources/{id}/restore", router.HandlerRoleResourceRestore).Methods(http.MethodPost)
 }
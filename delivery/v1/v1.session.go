@@ -0,0 +1,14 @@
+package v1
+
+import (
+	"app-bookstore/router"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func NewAPISession(r *mux.Router) {
+	r.HandleFunc("/users/{id}/sessions", router.HandlerUserSessions).Methods(http.MethodGet)
+	r.HandleFunc("/users/{id}/sessions", router.HandlerUserSessionsRevoke).Methods(http.MethodDelete)
+	r.HandleFunc("/users/{id}/sessions/{session_id}", router.HandlerUserSessionRevoke).Methods(http.MethodDelete)
+}
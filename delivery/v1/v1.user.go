@@ -9,8 +9,22 @@ import (
 
 func NewAPIUser(r *mux.Router) {
 	r.HandleFunc("/register", router.HandlerRegisterUser).Methods(http.MethodPost)
+	r.HandleFunc("/verify-email", router.HandlerVerifyEmail).Methods(http.MethodPost)
+	r.HandleFunc("/verify-email/resend", router.HandlerResendVerification).Methods(http.MethodPost)
 	r.HandleFunc("/login", router.HandlerLogin).Methods(http.MethodPost)
+	r.HandleFunc("/login/2fa", router.HandlerLoginTwoFactor).Methods(http.MethodPost)
+	r.HandleFunc("/login/2fa/enroll", router.HandlerLoginTwoFactorEnroll).Methods(http.MethodPost)
+	r.HandleFunc("/login/2fa/enroll/confirm", router.HandlerLoginTwoFactorConfirm).Methods(http.MethodPost)
+	r.HandleFunc("/token/refresh", router.HandlerTokenRefresh).Methods(http.MethodPost)
 	r.HandleFunc("/change-password", router.HandlerChangePassword).Methods(http.MethodPost)
 	r.HandleFunc("/users", router.HandlerUser).Methods(http.MethodGet)
 	r.HandleFunc("/logout", router.HandlerLogout).Methods(http.MethodDelete)
 }
+
+// NewAPIUserAdmin registers the user endpoints that need an access grant.
+func NewAPIUserAdmin(r *mux.Router) {
+	r.HandleFunc("/users/{id}/unlock", router.HandlerUserUnlock).Methods(http.MethodPost)
+	r.HandleFunc("/users/{id}/2fa", router.HandlerUserTwoFactorReset).Methods(http.MethodDelete)
+	r.HandleFunc("/users/{id}/suspend", router.HandlerUserSuspend).Methods(http.MethodPost)
+	r.HandleFunc("/users/{id}/reactivate", router.HandlerUserReactivate).Methods(http.MethodPost)
+}
@@ -0,0 +1,13 @@
+package v1
+
+import (
+	"app-bookstore/router"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// NewAPIWellKnown registers the unversioned discovery endpoints on the root router.
+func NewAPIWellKnown(r *mux.Router) {
+	r.HandleFunc("/.well-known/jwks.json", router.HandlerJWKS).Methods(http.MethodGet)
+}
@@ -10,98 +10,263 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
+	"github.com/rs/zerolog/log"
 )
 
-type KeyType string
-
-const userKey KeyType = "user"
-
 type Middleware struct {
-	JWT lib.Jwt
-	DB  *sqlx.DB
+	JWT   lib.Jwt
+	DB    *sqlx.DB
+	Cache *lib.PermissionCache
+	// Proxies are the peers allowed to report the client IP; nil trusts none.
+	Proxies lib.TrustedProxies
 }
 
-func NewMiddleware(jwt lib.Jwt, db *sqlx.DB) *Middleware {
+// NewMiddleware builds the auth middleware. cache may be nil, in which case
+// every request goes to the database.
+func NewMiddleware(jwt lib.Jwt, db *sqlx.DB, cache *lib.PermissionCache) *Middleware {
 	return &Middleware{
-		JWT: jwt,
-		DB:  db,
+		JWT:   jwt,
+		DB:    db,
+		Cache: cache,
 	}
 }
 
-func (m *Middleware) CheckAccess(next http.Handler) http.Handler {
+// RequestContext tags each request with an ID (taken from X-Request-ID when the
+// caller sent one), the client IP and user agent, so downstream code can log
+// and audit it.
+func (m *Middleware) RequestContext(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
-		authHeader := r.Header.Get("Authorization")
-
-		// cek token di header
-		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			lib.Error(w, http.StatusUnauthorized, "Missing or invalid authorization header", nil)
-			return
+		requestID := r.Header.Get(lib.RequestIDHeader)
+		if requestID == "" || len(requestID) > 100 {
+			requestID = uuid.NewString()
 		}
+		w.Header().Set(lib.RequestIDHeader, requestID)
 
-		token := strings.TrimPrefix(authHeader, "Bearer ")
-		token = strings.TrimSpace(token)
+		ctx := lib.WithRequestMeta(r.Context(), lib.RequestMeta{
+			RequestID: requestID,
+			ClientIP:  m.Proxies.ClientIP(r),
+			UserAgent: r.UserAgent(),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
 
-		if token == "" {
-			lib.Error(w, http.StatusUnauthorized, "Invalid token", nil)
-			return
-		}
+// authenticate verifies the bearer token, that its session is still active and
+// that the account is active. On failure it writes the error response and
+// returns ok=false.
+func (m *Middleware) authenticate(w http.ResponseWriter, r *http.Request) (claims *lib.JwtData, userID uuid.UUID, ok bool) {
+	authHeader := r.Header.Get("Authorization")
 
-		// verif token
-		claims, err := m.JWT.VerifyAccessToken(token)
-		if err != nil {
-			lib.Error(w, http.StatusUnauthorized, "Invalid or expired token", err)
-			return
-		}
+	// cek token di header
+	if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
+		lib.Error(w, http.StatusUnauthorized, "Missing or invalid authorization header", nil)
+		return nil, uuid.Nil, false
+	}
 
-		// cek token apa masih aktif bre
-		sessionExists, err := model.CheckSessionExists(r.Context(), m.DB, token)
-		if err != nil {
-			lib.Error(w, http.StatusInternalServerError, "Failed to check session", err)
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	token = strings.TrimSpace(token)
+
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "Invalid token", nil)
+		return nil, uuid.Nil, false
+	}
+
+	// verif token
+	claims, err := m.JWT.VerifyAccessToken(token)
+	if err != nil {
+		lib.Error(w, http.StatusUnauthorized, "Invalid or expired token", err)
+		return nil, uuid.Nil, false
+	}
+
+	// cek token apa masih aktif bre
+	sessionExists, err := m.sessionExists(r.Context(), token)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "Failed to check session", err)
+		return nil, uuid.Nil, false
+	}
+
+	if !sessionExists {
+		lib.Error(w, http.StatusUnauthorized, "Session has expired, please login again", fmt.Errorf("session not found"))
+		return nil, uuid.Nil, false
+	}
+
+	// ambil user_id dari token
+	userID, err = uuid.Parse(claims.UserID)
+	if err != nil {
+		lib.Error(w, http.StatusUnauthorized, "Invalid user ID", err)
+		return nil, uuid.Nil, false
+	}
+
+	// akun yang disuspend atau ditutup tidak boleh memakai sesi yang tersisa
+	status, err := m.userStatus(r.Context(), userID)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "Failed to check account status", err)
+		return nil, uuid.Nil, false
+	}
+
+	if err := lib.AccountStatusError(status); err != nil {
+		lib.Error(w, http.StatusForbidden, "Account is not active", err)
+		return nil, uuid.Nil, false
+	}
+
+	return claims, userID, true
+}
+
+// Authenticate only requires a valid session. It guards endpoints every
+// signed-in user may call, such as /me/permissions.
+func (m *Middleware) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, _, ok := m.authenticate(w, r)
+		if !ok {
 			return
 		}
 
-		if !sessionExists {
-			lib.Error(w, http.StatusUnauthorized, "Session has expired, please login again", fmt.Errorf("session not found"))
+		ctx := lib.WithClaims(r.Context(), claims)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// CheckAccess requires a valid session and a grant for the endpoint and method
+// on at least one of the user's roles.
+func (m *Middleware) CheckAccess(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		claims, userID, ok := m.authenticate(w, r)
+		if !ok {
 			return
 		}
 
-		// ambil user_id dari token
-		userID, err := uuid.Parse(claims.UserID)
+		// ambil semua role milik user
+		roleIDs, err := m.userRoleIDs(r.Context(), userID)
 		if err != nil {
-			lib.Error(w, http.StatusUnauthorized, "Invalid user ID", err)
+			lib.Error(w, http.StatusInternalServerError, "Failed to retrieve user roles", err)
 			return
 		}
 
-		// ambil role_id dari user role
-		roleID, err := model.GetUserRoleID(r.Context(), m.DB, userID)
-		if err != nil {
-			lib.Error(w, http.StatusInternalServerError, "Failed to retrieve user role ID", err)
+		if len(roleIDs) == 0 {
+			lib.Error(w, http.StatusForbidden, "User does not have an assigned role", nil)
 			return
 		}
 
-		// cek role apakah memiliki akses endpoint
-		requestedEndpoint := lib.NormalizeEndpoint(r.URL.Path)
+		// cek apakah salah satu role memiliki akses ke template route
+		requestedEndpoint := routeTemplate(r)
 		requestedMethod := r.Method
 
-		allowed, err := model.CheckRoleAccess(r.Context(), m.DB, roleID, requestedEndpoint, requestedMethod)
+		allowed, err := m.rolesAllow(r.Context(), roleIDs, requestedEndpoint, requestedMethod)
 		if err != nil {
 			lib.Error(w, http.StatusInternalServerError, "Failed to check role access", err)
 			return
 		}
 
 		if !allowed {
-			lib.Error(w, http.StatusForbidden, "Access denied", fmt.Errorf("access denied for role %s on %s %s", roleID, requestedMethod, requestedEndpoint))
+			lib.Error(w, http.StatusForbidden, "Access denied", fmt.Errorf("access denied for user %s on %s %s", userID, requestedMethod, requestedEndpoint))
 			return
 		}
 
 		// set ccontext untuk user
-		ctx := context.WithValue(r.Context(), userKey, claims)
+		ctx := lib.WithClaims(r.Context(), claims)
 		next.ServeHTTP(w, r.WithContext(ctx))
 
-		// logging debug
-		microSec := time.Since(start).Microseconds()
-		fmt.Printf("[DEBUG] User %s accessed %s | Method: %s | Duration: %vμs\n", userID, requestedEndpoint, requestedMethod, microSec)
+		// logging debug, ikut level log global supaya bisa dimatikan
+		log.Debug().Msgf("User %s accessed %s | Method: %s | Duration: %vμs", userID, requestedEndpoint, requestedMethod, time.Since(start).Microseconds())
 	})
 }
+
+func (m *Middleware) sessionExists(ctx context.Context, token string) (bool, error) {
+	if m.Cache != nil && m.Cache.SessionActive(token) {
+		return true, nil
+	}
+
+	exists, err := model.CheckSessionExists(ctx, m.DB, lib.HashToken(token))
+	if err != nil {
+		return false, err
+	}
+
+	// hanya sesi yang aktif yang di-cache, sesi yang tidak ada selalu dicek ulang
+	if exists && m.Cache != nil {
+		m.Cache.StoreSession(token)
+	}
+	return exists, nil
+}
+
+func (m *Middleware) userStatus(ctx context.Context, userID uuid.UUID) (string, error) {
+	if m.Cache != nil {
+		if status, found := m.Cache.UserStatus(userID); found {
+			return status, nil
+		}
+	}
+
+	status, err := model.GetUserStatus(ctx, m.DB, userID)
+	if err != nil {
+		return "", err
+	}
+
+	if m.Cache != nil {
+		m.Cache.StoreUserStatus(userID, status)
+	}
+	return status, nil
+}
+
+func (m *Middleware) userRoleIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	if m.Cache != nil {
+		if roleIDs, found := m.Cache.UserRoles(userID); found {
+			return roleIDs, nil
+		}
+	}
+
+	roleIDs, err := model.GetUserRoleIDs(ctx, m.DB, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if m.Cache != nil {
+		m.Cache.StoreUserRoles(userID, roleIDs)
+	}
+	return roleIDs, nil
+}
+
+func (m *Middleware) rolesAllow(ctx context.Context, roleIDs []uuid.UUID, endpoint string, method string) (bool, error) {
+	if m.Cache == nil {
+		return model.CheckRoleAccess(ctx, m.DB, roleIDs, endpoint, method)
+	}
+
+	for _, roleID := range roleIDs {
+		allowed, found := m.Cache.RoleAllows(roleID, endpoint, method)
+		if !found {
+			permissions, err := model.GetEffectivePermissions(ctx, m.DB, roleID)
+			if err != nil {
+				return false, err
+			}
+
+			rules := make([]lib.GrantRule, len(permissions))
+			for i, permission := range permissions {
+				rules[i] = permission.Rule()
+			}
+
+			grants := lib.NewGrantSet(rules)
+			m.Cache.StoreRoleGrants(roleID, grants)
+			allowed = grants.Allows(endpoint, method)
+		}
+
+		if allowed {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// routeTemplate is the path template of the matched mux route, e.g.
+// /api/v1/books/{id}. Requests that matched no route fall back to the
+// normalized path.
+func routeTemplate(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+
+	return lib.NormalizeEndpoint(r.URL.Path)
+}
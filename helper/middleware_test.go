@@ -0,0 +1,95 @@
+package helper
+
+import (
+	"app-bookstore/internal/dbtest"
+	"app-bookstore/lib"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"github.com/rs/zerolog"
+)
+
+func BenchmarkCheckAccess(b *testing.B) {
+	level := zerolog.GlobalLevel()
+	zerolog.SetGlobalLevel(zerolog.Disabled)
+	defer zerolog.SetGlobalLevel(level)
+
+	userID := uuid.New()
+	roleID := uuid.New()
+
+	jwt := &lib.Options{SigningKey: "bench", Issuer: "bench", AccessTTL: time.Hour, AcceptHMAC: true}
+	token, _, err := jwt.GenerateToken(&lib.JwtData{UserID: userID.String()})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// lewat router supaya CheckAccess membaca template rutenya, seperti di server
+	const template = "/api/v1/books/{id}"
+	path := "/api/v1/books/" + uuid.NewString()
+
+	// jawaban tetap untuk query middleware, supaya yang diukur middleware-nya
+	// sendiri dan bukan database
+	db, mock := dbtest.New(b)
+	mock.ExpectQuery(`FROM sessions WHERE token_hash = \$1`).
+		WithArgs(lib.HashToken(token)).
+		WillReturnRows(dbtest.NewRows("exists").AddRow(true)).
+		Repeatedly()
+	mock.ExpectQuery(`^SELECT status FROM users WHERE id = \$1$`).
+		WithArgs(userID).
+		WillReturnRows(dbtest.NewRows("status").AddRow(lib.UserActive)).
+		Repeatedly()
+	mock.ExpectQuery(`SELECT ur\.role_id FROM user_roles ur`).
+		WithArgs(userID).
+		WillReturnRows(dbtest.NewRows("role_id").AddRow(roleID.String())).
+		Repeatedly()
+	mock.ExpectQuery(`SELECT EXISTS \( SELECT 1 FROM decisions WHERE effect = 'allow'`).
+		WithArgs(dbtest.Any, template, http.MethodGet).
+		WillReturnRows(dbtest.NewRows("exists").AddRow(true)).
+		Repeatedly()
+
+	cache := lib.NewPermissionCache(time.Hour, time.Hour)
+	cache.StoreRoleGrants(roleID, lib.NewGrantSet([]lib.GrantRule{{Endpoint: template, Method: http.MethodGet}}))
+
+	modes := []struct {
+		name  string
+		cache *lib.PermissionCache
+	}{
+		{"uncached", nil},
+		{"cached", cache},
+	}
+
+	for _, mode := range modes {
+		b.Run(mode.name, func(b *testing.B) {
+			handler := mux.NewRouter()
+			handler.Use(NewMiddleware(jwt, db, mode.cache).CheckAccess)
+			handler.Handle(template, next).Methods(http.MethodGet)
+
+			serve := func() int {
+				req := httptest.NewRequest(http.MethodGet, path, nil)
+				req.Header.Set("Authorization", "Bearer "+token)
+				rec := httptest.NewRecorder()
+				handler.ServeHTTP(rec, req)
+				return rec.Code
+			}
+
+			// request pemanasan, sekaligus mengisi cache pada mode cached
+			if status := serve(); status != http.StatusNoContent {
+				b.Fatalf("warm-up request returned %d", status)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				serve()
+			}
+		})
+	}
+}
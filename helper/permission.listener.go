@@ -0,0 +1,50 @@
+package helper
+
+import (
+	"app-bookstore/lib"
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+// ListenPermissionChanges applies notifications on lib.PermissionChannel to
+// cache until ctx is cancelled. Whenever the connection drops, notifications
+// may have been missed, so the whole cache is purged.
+func ListenPermissionChanges(ctx context.Context, dsn string, cache *lib.PermissionCache) error {
+	listener := pq.NewListener(dsn, time.Second, time.Minute, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Error().Err(err).Msg("[PermissionListener] connection problem")
+		}
+	})
+	defer listener.Close()
+
+	err := listener.Listen(lib.PermissionChannel)
+	if err != nil {
+		return err
+	}
+
+	log.Info().Msgf("[PermissionListener] listening on %s", lib.PermissionChannel)
+
+	ticker := time.NewTicker(90 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case notification := <-listener.Notify:
+			// nil artinya koneksi baru tersambung ulang
+			if notification == nil {
+				cache.Invalidate(lib.ScopeAll, "")
+				continue
+			}
+
+			scope, key := lib.ParsePermissionEvent(notification.Extra)
+			cache.Invalidate(scope, key)
+		case <-ticker.C:
+			go listener.Ping()
+		}
+	}
+}
@@ -0,0 +1,331 @@
+// Package dbtest is a database/sql driver for tests that answers statements
+// from explicit expectations, in the spirit of sqlmock. A statement that
+// matches no expectation fails the test instead of getting a guessed answer,
+// and expectations that were never used fail it at cleanup.
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Any matches every argument value in WithArgs.
+var Any = anyArg{}
+
+type anyArg struct{}
+
+// Rows is the result set of an expected query.
+type Rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func NewRows(columns ...string) *Rows {
+	return &Rows{columns: columns}
+}
+
+func (r *Rows) AddRow(values ...driver.Value) *Rows {
+	r.values = append(r.values, values)
+	return r
+}
+
+// Expectation is one statement the code under test is expected to run.
+// It is met once unless Times or Repeatedly says otherwise.
+type Expectation struct {
+	exec    bool
+	pattern *regexp.Regexp
+	args    []driver.Value
+
+	rows   func(args []driver.Value) (*Rows, error)
+	result func(args []driver.Value) (int64, error)
+
+	times      int
+	repeatedly bool
+	calls      int
+}
+
+// WithArgs only matches statements run with exactly these arguments; Any
+// matches every value. Values are compared after the driver conversion, so a
+// uuid.UUID matches its string.
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args = make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg == Any {
+			e.args[i] = Any
+			continue
+		}
+
+		value, err := driver.DefaultParameterConverter.ConvertValue(arg)
+		if err != nil {
+			panic(fmt.Sprintf("dbtest: argument %d: %v", i, err))
+		}
+		e.args[i] = value
+	}
+	return e
+}
+
+func (e *Expectation) WillReturnRows(rows *Rows) *Expectation {
+	return e.WillReturnRowsFunc(func([]driver.Value) (*Rows, error) { return rows, nil })
+}
+
+// WillReturnRowsFunc answers the query from its arguments, for fakes that
+// keep state between statements.
+func (e *Expectation) WillReturnRowsFunc(fn func(args []driver.Value) (*Rows, error)) *Expectation {
+	e.rows = fn
+	return e
+}
+
+func (e *Expectation) WillReturnResult(rowsAffected int64) *Expectation {
+	return e.WillReturnResultFunc(func([]driver.Value) (int64, error) { return rowsAffected, nil })
+}
+
+// WillReturnResultFunc answers the exec from its arguments with the number of
+// affected rows.
+func (e *Expectation) WillReturnResultFunc(fn func(args []driver.Value) (int64, error)) *Expectation {
+	e.result = fn
+	return e
+}
+
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.rows = func([]driver.Value) (*Rows, error) { return nil, err }
+	e.result = func([]driver.Value) (int64, error) { return 0, err }
+	return e
+}
+
+// Times expects the statement n times.
+func (e *Expectation) Times(n int) *Expectation {
+	e.times = n
+	return e
+}
+
+// Repeatedly accepts the statement any number of times, none included.
+func (e *Expectation) Repeatedly() *Expectation {
+	e.repeatedly = true
+	return e
+}
+
+func (e *Expectation) String() string {
+	kind := "query"
+	if e.exec {
+		kind = "exec"
+	}
+	return fmt.Sprintf("%s matching %q", kind, e.pattern)
+}
+
+func (e *Expectation) matches(exec bool, query string, args []driver.Value) bool {
+	if e.exec != exec || (!e.repeatedly && e.calls >= e.times) || !e.pattern.MatchString(query) {
+		return false
+	}
+
+	if e.args == nil {
+		return true
+	}
+
+	if len(e.args) != len(args) {
+		return false
+	}
+
+	for i, want := range e.args {
+		if want != Any && !reflect.DeepEqual(want, args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Mock holds the expectations of one test.
+type Mock struct {
+	t            testing.TB
+	mu           sync.Mutex
+	expectations []*Expectation
+}
+
+// New opens a database answered by the returned Mock. The database is closed
+// and the expectations are checked when the test ends.
+func New(t testing.TB) (*sqlx.DB, *Mock) {
+	t.Helper()
+
+	mock := &Mock{t: t}
+	db := sqlx.NewDb(sql.OpenDB(connector{mock}), "postgres")
+
+	t.Cleanup(func() {
+		db.Close()
+		mock.ExpectationsWereMet()
+	})
+
+	return db, mock
+}
+
+// ExpectQuery expects a statement returning rows whose SQL, with whitespace
+// collapsed, matches the regular expression pattern.
+func (m *Mock) ExpectQuery(pattern string) *Expectation {
+	return m.expect(false, pattern)
+}
+
+// ExpectExec is ExpectQuery for statements that return no rows.
+func (m *Mock) ExpectExec(pattern string) *Expectation {
+	return m.expect(true, pattern)
+}
+
+func (m *Mock) expect(exec bool, pattern string) *Expectation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e := &Expectation{exec: exec, pattern: regexp.MustCompile(pattern), times: 1}
+	m.expectations = append(m.expectations, e)
+	return e
+}
+
+// ExpectationsWereMet fails the test for every expectation that was run fewer
+// times than expected.
+func (m *Mock) ExpectationsWereMet() {
+	m.t.Helper()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, e := range m.expectations {
+		if !e.repeatedly && e.calls < e.times {
+			m.t.Errorf("dbtest: %s ran %d of %d times", e, e.calls, e.times)
+		}
+	}
+}
+
+// next returns the first expectation, in the order they were declared, that
+// still matches the statement.
+func (m *Mock) next(exec bool, query string, args []driver.Value) (*Expectation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	query = strings.Join(strings.Fields(query), " ")
+	for _, e := range m.expectations {
+		if e.matches(exec, query, args) {
+			e.calls++
+			return e, nil
+		}
+	}
+
+	err := fmt.Errorf("dbtest: unexpected statement %q with args %v", query, args)
+	m.t.Error(err)
+	return nil, err
+}
+
+type connector struct {
+	mock *Mock
+}
+
+func (c connector) Connect(ctx context.Context) (driver.Conn, error) {
+	return conn(c), nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return c
+}
+
+func (c connector) Open(name string) (driver.Conn, error) {
+	return conn(c), nil
+}
+
+type conn connector
+
+func (c conn) Prepare(query string) (driver.Stmt, error) {
+	return stmt{mock: c.mock, query: query}, nil
+}
+
+func (c conn) Close() error {
+	return nil
+}
+
+func (c conn) Begin() (driver.Tx, error) {
+	return tx{}, nil
+}
+
+type tx struct{}
+
+func (tx) Commit() error {
+	return nil
+}
+
+func (tx) Rollback() error {
+	return nil
+}
+
+type stmt struct {
+	mock  *Mock
+	query string
+}
+
+func (s stmt) Close() error {
+	return nil
+}
+
+func (s stmt) NumInput() int {
+	return -1
+}
+
+func (s stmt) Exec(args []driver.Value) (driver.Result, error) {
+	e, err := s.mock.next(true, s.query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.result == nil {
+		return driver.RowsAffected(0), nil
+	}
+
+	affected, err := e.result(args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(affected), nil
+}
+
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	e, err := s.mock.next(false, s.query, args)
+	if err != nil {
+		return nil, err
+	}
+
+	if e.rows == nil {
+		return &rows{}, nil
+	}
+
+	result, err := e.rows(args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: result.columns, values: result.values}, nil
+}
+
+type rows struct {
+	columns []string
+	values  [][]driver.Value
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
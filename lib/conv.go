@@ -1,13 +1,136 @@
 package lib
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"sync"
 
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Argon2Params are the argon2id costs new hashes are made with. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  int
+	KeyLength   uint32
+}
+
+// PasswordHashParams follow the OWASP minimum for argon2id. Hashes made with
+// other costs still verify, and NeedsRehash reports them.
+var PasswordHashParams = Argon2Params{
+	Memory:      19 * 1024,
+	Iterations:  2,
+	Parallelism: 1,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+const argon2Prefix = "$argon2id$"
+
+var hashEncoding = base64.RawStdEncoding
+
+// HashPassword hashes with argon2id in the PHC string format,
+// $argon2id$v=19$m=...,t=...,p=...$salt$hash, so the algorithm and its costs
+// travel with the hash.
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 14)
-	return string(bytes), err
+	params := PasswordHashParams
+
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2Prefix, argon2.Version, params.Memory, params.Iterations, params.Parallelism,
+		hashEncoding.EncodeToString(salt), hashEncoding.EncodeToString(key),
+	), nil
 }
 
+// CheckPassword verifies argon2id hashes and the bcrypt hashes stored before
+// the switch to argon2id.
 func CheckPassword(password, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
-	return err == nil
+	if !strings.HasPrefix(hash, argon2Prefix) {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		return err == nil
+	}
+
+	params, salt, key, err := parseArgon2Hash(hash)
+	if err != nil {
+		return false
+	}
+
+	candidate := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism, params.KeyLength)
+	return subtle.ConstantTimeCompare(candidate, key) == 1
+}
+
+// NeedsRehash reports whether hash should be replaced by a HashPassword of
+// the same password: it is bcrypt, or argon2id with other costs.
+func NeedsRehash(hash string) bool {
+	if !strings.HasPrefix(hash, argon2Prefix) {
+		return true
+	}
+
+	params, salt, _, err := parseArgon2Hash(hash)
+	if err != nil {
+		return true
+	}
+
+	current := PasswordHashParams
+	return params.Memory != current.Memory ||
+		params.Iterations != current.Iterations ||
+		params.Parallelism != current.Parallelism ||
+		params.KeyLength != current.KeyLength ||
+		len(salt) != current.SaltLength
+}
+
+func parseArgon2Hash(hash string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return params, nil, nil, fmt.Errorf("malformed argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, fmt.Errorf("unsupported argon2 version")
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism); err != nil {
+		return params, nil, nil, fmt.Errorf("malformed argon2id parameters: %w", err)
+	}
+
+	salt, err := hashEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, err
+	}
+
+	key, err := hashEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, err
+	}
+
+	params.SaltLength = len(salt)
+	params.KeyLength = uint32(len(key))
+	return params, salt, key, nil
+}
+
+var dummyPasswordHash = sync.OnceValue(func() string {
+	hash, _ := HashPassword("dummy-password")
+	return hash
+})
+
+// CheckDummyPassword costs as much as CheckPassword and always fails. Login
+// runs it for unknown usernames so the response time does not reveal them.
+func CheckDummyPassword(password string) bool {
+	CheckPassword(password, dummyPasswordHash())
+	return false
 }
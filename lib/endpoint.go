@@ -1,8 +1,41 @@
 package lib
 
-import "regexp"
+import (
+	"regexp"
+	"strings"
+)
 
+// NormalizeEndpoint rewrites UUIDs in a raw path to {id}. It is only the
+// fallback for requests that did not match a mux route; matched requests are
+// authorized by their route template.
 func NormalizeEndpoint(url string) string {
 	uuidPattern := regexp.MustCompile(`[a-f0-9-]{36}`)
 	return uuidPattern.ReplaceAllString(url, "{id}")
 }
+
+// IsWildcardEndpoint reports whether pattern ends in "/*".
+func IsWildcardEndpoint(pattern string) bool {
+	return strings.HasSuffix(pattern, "/*")
+}
+
+// MatchEndpoint reports whether a resource endpoint pattern covers a route
+// template. "/api/v1/books/*" covers "/api/v1/books" and everything below it;
+// any other pattern must equal the template.
+func MatchEndpoint(pattern string, template string) bool {
+	if !IsWildcardEndpoint(pattern) {
+		return pattern == template
+	}
+
+	prefix := strings.TrimSuffix(pattern, "/*")
+	return template == prefix || strings.HasPrefix(template, prefix+"/")
+}
+
+// ValidEndpointPattern accepts absolute paths where "*" may only appear as the
+// last segment.
+func ValidEndpointPattern(pattern string) bool {
+	if !strings.HasPrefix(pattern, "/") {
+		return false
+	}
+
+	return !strings.Contains(strings.TrimSuffix(pattern, "/*"), "*")
+}
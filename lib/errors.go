@@ -1,10 +1,244 @@
 package lib
 
-import "errors"
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/lib/pq"
+	"github.com/rs/zerolog/log"
+)
+
+type ErrorKind string
+
+const (
+	KindBadRequest   ErrorKind = "bad_request"
+	KindUnauthorized ErrorKind = "unauthorized"
+	KindForbidden    ErrorKind = "forbidden"
+	KindNotFound     ErrorKind = "not_found"
+	KindConflict     ErrorKind = "conflict"
+	KindValidation   ErrorKind = "validation_failed"
+	KindTooMany      ErrorKind = "too_many_requests"
+	KindInternal     ErrorKind = "internal"
+)
+
+var kindStatus = map[ErrorKind]int{
+	KindBadRequest:   http.StatusBadRequest,
+	KindUnauthorized: http.StatusUnauthorized,
+	KindForbidden:    http.StatusForbidden,
+	KindNotFound:     http.StatusNotFound,
+	KindConflict:     http.StatusConflict,
+	KindValidation:   http.StatusUnprocessableEntity,
+	KindTooMany:      http.StatusTooManyRequests,
+	KindInternal:     http.StatusInternalServerError,
+}
+
+// AppError is an error that knows how it should be reported to the client.
+// Message is always safe to show; Err keeps the underlying cause for logs.
+type AppError struct {
+	Kind    ErrorKind
+	Status  int
+	Code    string
+	Message string
+	Fields  map[string]string
+	Err     error
+	// RetryAfter is sent as the Retry-After header when set.
+	RetryAfter time.Duration
+}
+
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+func NewError(kind ErrorKind, code string, message string) *AppError {
+	return &AppError{
+		Kind:    kind,
+		Status:  kindStatus[kind],
+		Code:    code,
+		Message: message,
+	}
+}
+
+func BadRequest(message string) *AppError {
+	return NewError(KindBadRequest, string(KindBadRequest), message)
+}
+
+func Unauthorized(message string) *AppError {
+	return NewError(KindUnauthorized, string(KindUnauthorized), message)
+}
+
+func Forbidden(message string) *AppError {
+	return NewError(KindForbidden, string(KindForbidden), message)
+}
+
+func NotFound(message string) *AppError {
+	return NewError(KindNotFound, string(KindNotFound), message)
+}
+
+func Conflict(message string) *AppError {
+	return NewError(KindConflict, string(KindConflict), message)
+}
+
+func Unprocessable(message string) *AppError {
+	return NewError(KindValidation, string(KindValidation), message)
+}
+
+// TooManyRequests tells the client to wait retryAfter before trying again.
+func TooManyRequests(message string, retryAfter time.Duration) *AppError {
+	appErr := NewError(KindTooMany, string(KindTooMany), message)
+	appErr.RetryAfter = retryAfter
+	return appErr
+}
+
+// AccountStatusError is nil for an active account and otherwise the 403 that
+// says why the account cannot be used.
+func AccountStatusError(status string) error {
+	var appErr *AppError
+	switch status {
+	case UserActive:
+		return nil
+	case UserPendingVerification:
+		appErr = Forbidden("verify your email address before logging in")
+		appErr.Code = "email_not_verified"
+	case UserSuspended:
+		appErr = Forbidden("this account has been suspended")
+		appErr.Code = "account_suspended"
+	case UserClosed:
+		appErr = Forbidden("this account has been closed")
+		appErr.Code = "account_closed"
+	default:
+		appErr = Forbidden("this account cannot be used")
+		appErr.Code = "account_inactive"
+	}
+	return appErr
+}
+
+func Internal(err error) *AppError {
+	appErr := NewError(KindInternal, string(KindInternal), "internal server error")
+	appErr.Err = err
+	return appErr
+}
 
 var (
-	ErrMissingAuthHeader = errors.New("missing authorization header")
-	ErrMissingToken      = errors.New("missing token")
-	ErrInvalidToken      = errors.New("invalid or expired token")
-	ErrClaimsFound       = errors.New("no claims found in context")
+	ErrMissingAuthHeader = Unauthorized("missing authorization header")
+	ErrMissingToken      = Unauthorized("missing token")
+	ErrInvalidToken      = Unauthorized("invalid or expired token")
+	ErrClaimsFound       = Unauthorized("no claims found in context")
 )
+
+// AsAppError classifies err. Errors that are not recognised keep the status the
+// handler asked for; their text is only shown for 4xx so internals don't leak.
+func AsAppError(err error, fallbackStatus int) *AppError {
+	if err == nil {
+		return statusError(fallbackStatus, http.StatusText(fallbackStatus))
+	}
+
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return NotFound("resource not found")
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "23505":
+			conflict := Conflict("resource already exists")
+			conflict.Code = "duplicate"
+			conflict.Err = err
+			return conflict
+		case "23503":
+			conflict := Conflict("resource is referenced by or refers to a missing record")
+			conflict.Code = "foreign_key_violation"
+			conflict.Err = err
+			return conflict
+		case "23514", "22P02", "23502":
+			invalid := Unprocessable("value rejected by database constraint")
+			invalid.Err = err
+			return invalid
+		}
+	}
+
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		invalid := Unprocessable("request validation failed")
+		invalid.Fields = map[string]string{}
+		for _, fieldErr := range validationErrs {
+			invalid.Fields[fieldName(fieldErr)] = fieldMessage(fieldErr)
+		}
+		return invalid
+	}
+
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return BadRequest("malformed JSON body")
+	}
+
+	if fallbackStatus >= http.StatusInternalServerError {
+		return Internal(err)
+	}
+
+	appErr = statusError(fallbackStatus, err.Error())
+	appErr.Err = err
+	return appErr
+}
+
+func statusError(status int, message string) *AppError {
+	for kind, kindCode := range kindStatus {
+		if kindCode == status {
+			appErr := NewError(kind, string(kind), message)
+			return appErr
+		}
+	}
+
+	if status >= http.StatusInternalServerError {
+		return NewError(KindInternal, string(KindInternal), message)
+	}
+
+	appErr := NewError(KindBadRequest, string(KindBadRequest), message)
+	appErr.Status = status
+	return appErr
+}
+
+func fieldName(fieldErr validator.FieldError) string {
+	namespace := fieldErr.Namespace()
+	if _, rest, found := strings.Cut(namespace, "."); found {
+		return rest
+	}
+	return fieldErr.Field()
+}
+
+func fieldMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return fmt.Sprintf("must be one of: %s", fieldErr.Param())
+	case "gt", "gte", "lt", "lte", "min", "max":
+		return fmt.Sprintf("must satisfy %s=%s", fieldErr.Tag(), fieldErr.Param())
+	default:
+		return fmt.Sprintf("failed on %s", fieldErr.Tag())
+	}
+}
+
+func logAppError(appErr *AppError) {
+	if appErr.Status >= http.StatusInternalServerError && appErr.Err != nil {
+		log.Error().Err(appErr.Err).Str("code", appErr.Code).Msg("internal error")
+	}
+}
@@ -0,0 +1,39 @@
+package lib
+
+import "time"
+
+// FinePolicy amounts are in the smallest currency unit.
+type FinePolicy struct {
+	DailyRate int64
+	MaxAmount int64
+}
+
+func truncateDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// DueDate returns the calendar day a loan started at loanDate must be back.
+func DueDate(loanDate time.Time, periodDays int) time.Time {
+	return truncateDay(loanDate).AddDate(0, 0, periodDays)
+}
+
+// DaysOverdue counts whole calendar days after dueDate up to asOf.
+func DaysOverdue(dueDate, asOf time.Time) int {
+	days := int(truncateDay(asOf).Sub(truncateDay(dueDate)).Hours() / 24)
+	if days < 0 {
+		return 0
+	}
+	return days
+}
+
+// CalculateFine returns the overdue days and the fine owed as of asOf.
+// A MaxAmount of 0 means the fine is not capped.
+func CalculateFine(dueDate, asOf time.Time, policy FinePolicy) (int, int64) {
+	days := DaysOverdue(dueDate, asOf)
+	amount := int64(days) * policy.DailyRate
+	if policy.MaxAmount > 0 && amount > policy.MaxAmount {
+		amount = policy.MaxAmount
+	}
+	return days, amount
+}
@@ -0,0 +1,130 @@
+package lib
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestDueDate(t *testing.T) {
+	loanDate := time.Date(2024, 3, 1, 18, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		periodDays int
+		want       time.Time
+	}{
+		{"regular period", 14, time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{"zero period is due the same day", 0, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{"negative period is already due", -2, time.Date(2024, 2, 28, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DueDate(loanDate, tt.periodDays)
+			if !got.Equal(tt.want) {
+				t.Errorf("DueDate(%v, %d) = %v, want %v", loanDate, tt.periodDays, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCalculateFine(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	policy := FinePolicy{DailyRate: 1000}
+	capped := FinePolicy{DailyRate: 1000, MaxAmount: 2500}
+	due := time.Date(2024, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		dueDate    time.Time
+		asOf       time.Time
+		policy     FinePolicy
+		wantDays   int
+		wantAmount int64
+	}{
+		{
+			name:     "same-day return",
+			dueDate:  DueDate(time.Date(2024, 3, 15, 9, 0, 0, 0, time.UTC), 0),
+			asOf:     time.Date(2024, 3, 15, 23, 59, 0, 0, time.UTC),
+			policy:   policy,
+			wantDays: 0,
+		},
+		{
+			name:     "returned before due",
+			dueDate:  due,
+			asOf:     due.AddDate(0, 0, -3),
+			policy:   policy,
+			wantDays: 0,
+		},
+		{
+			name:     "last second of the due day",
+			dueDate:  due,
+			asOf:     due.Add(24*time.Hour - time.Second),
+			policy:   policy,
+			wantDays: 0,
+		},
+		{
+			name:       "first moment after the due day",
+			dueDate:    due,
+			asOf:       due.Add(24 * time.Hour),
+			policy:     policy,
+			wantDays:   1,
+			wantAmount: 1000,
+		},
+		{
+			// 10 Maret 2024 jam di New York maju satu jam; hari itu hanya 23 jam
+			name:       "across a DST change",
+			dueDate:    time.Date(2024, 3, 9, 0, 0, 0, 0, newYork),
+			asOf:       time.Date(2024, 3, 11, 0, 30, 0, 0, newYork),
+			policy:     policy,
+			wantDays:   2,
+			wantAmount: 2000,
+		},
+		{
+			name:       "fine is capped",
+			dueDate:    due,
+			asOf:       due.AddDate(0, 0, 10),
+			policy:     capped,
+			wantDays:   10,
+			wantAmount: 2500,
+		},
+		{
+			name:       "under the cap",
+			dueDate:    due,
+			asOf:       due.AddDate(0, 0, 2),
+			policy:     capped,
+			wantDays:   2,
+			wantAmount: 2000,
+		},
+		{
+			name:       "zero period is fined from the next day",
+			dueDate:    DueDate(due, 0),
+			asOf:       due.AddDate(0, 0, 1),
+			policy:     policy,
+			wantDays:   1,
+			wantAmount: 1000,
+		},
+		{
+			name:       "negative period counts the days before the loan",
+			dueDate:    DueDate(due, -2),
+			asOf:       due,
+			policy:     policy,
+			wantDays:   2,
+			wantAmount: 2000,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			days, amount := CalculateFine(tt.dueDate, tt.asOf, tt.policy)
+			if days != tt.wantDays || amount != tt.wantAmount {
+				t.Errorf("CalculateFine(%v, %v) = (%d, %d), want (%d, %d)", tt.dueDate, tt.asOf, days, amount, tt.wantDays, tt.wantAmount)
+			}
+		})
+	}
+}
@@ -5,12 +5,29 @@ import (
 	"encoding/base64"
 )
 
-func GenerateResetToken() (string, error) {
-	bytes := make([]byte, 32)
+// generateToken returns n random bytes as unpadded URL-safe base64, for
+// tokens that are sent to the user and only stored as their HashToken.
+func generateToken(n int) (string, error) {
+	bytes := make([]byte, n)
 	_, err := rand.Read(bytes)
 	if err != nil {
 		return "", err
 	}
 
-	return base64.URLEncoding.EncodeToString(bytes), nil
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// GenerateResetToken returns the token of a password reset link.
+func GenerateResetToken() (string, error) {
+	return generateToken(32)
+}
+
+// GenerateChallengeToken returns the opaque token of a login challenge.
+func GenerateChallengeToken() (string, error) {
+	return generateToken(32)
+}
+
+// GenerateVerificationToken returns the token of an email verification link.
+func GenerateVerificationToken() (string, error) {
+	return generateToken(32)
 }
@@ -0,0 +1,58 @@
+package lib
+
+// GrantRule is one role_resources row as seen from a role: Depth is 0 for the
+// role's own grants and grows by one per parent it was inherited through.
+type GrantRule struct {
+	Endpoint string
+	Method   string
+	Depth    int
+	Deny     bool
+}
+
+// overrides reports whether r wins over other: the closer role wins, and deny
+// wins over allow at the same depth.
+func (r GrantRule) overrides(other GrantRule) bool {
+	if r.Depth != other.Depth {
+		return r.Depth < other.Depth
+	}
+	return r.Deny && !other.Deny
+}
+
+// GrantSet answers access checks for one role and everything it inherits.
+type GrantSet struct {
+	exact     map[string]GrantRule
+	wildcards []GrantRule
+}
+
+func NewGrantSet(rules []GrantRule) GrantSet {
+	set := GrantSet{exact: make(map[string]GrantRule, len(rules))}
+	for _, rule := range rules {
+		if IsWildcardEndpoint(rule.Endpoint) {
+			set.wildcards = append(set.wildcards, rule)
+			continue
+		}
+
+		key := grantKey(rule.Endpoint, rule.Method)
+		if current, found := set.exact[key]; !found || rule.overrides(current) {
+			set.exact[key] = rule
+		}
+	}
+
+	return set
+}
+
+// Allows reports whether the deciding rule for the route template and method
+// is an allow. Without any matching rule the request is denied.
+func (gs GrantSet) Allows(template string, method string) bool {
+	decision, found := gs.exact[grantKey(template, method)]
+	for _, rule := range gs.wildcards {
+		if rule.Method != method || !MatchEndpoint(rule.Endpoint, template) {
+			continue
+		}
+		if !found || rule.overrides(decision) {
+			decision, found = rule, true
+		}
+	}
+
+	return found && !decision.Deny
+}
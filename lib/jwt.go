@@ -2,32 +2,113 @@ package lib
 
 import (
 	"app-bookstore/config"
+	"context"
 	"errors"
+	"fmt"
+	"sort"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type JwtData struct {
-	UserID string `json:"user_id"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"sid,omitempty"`
 	jwt.RegisteredClaims
 }
 
+type claimsKey struct{}
+
+// WithClaims stores verified token claims on the request context.
+func WithClaims(ctx context.Context, claims *JwtData) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+func ClaimsFromContext(ctx context.Context) (*JwtData, error) {
+	claims, ok := ctx.Value(claimsKey{}).(*JwtData)
+	if !ok || claims == nil {
+		return nil, ErrClaimsFound
+	}
+	return claims, nil
+}
+
 type Jwt interface {
 	GenerateToken(data *JwtData) (string, int64, error)
 	VerifyAccessToken(token string) (*JwtData, error)
+	JWKS() JWKS
 }
 
+// Options signs with Signer when one is configured and with the HMAC
+// SigningKey otherwise. HMAC tokens are only accepted while AcceptHMAC is set,
+// which covers tokens issued before the switch to asymmetric keys.
 type Options struct {
 	SigningKey string
 	Issuer     string
+	AccessTTL  time.Duration
+	Signer     *SigningKey
+	VerifyKeys map[string]*VerificationKey
+	AcceptHMAC bool
 }
 
-func NewJWT(cfg *config.Config) Jwt {
-	return &Options{
+func NewJWT(cfg *config.Config) (Jwt, error) {
+	options := &Options{
 		SigningKey: cfg.App.JwtSecretKey,
 		Issuer:     cfg.App.JwtIssuer,
+		AccessTTL:  cfg.App.AccessTokenTTL,
+		VerifyKeys: map[string]*VerificationKey{},
+		AcceptHMAC: true,
+	}
+
+	if cfg.App.JwtSigningKeyFile == "" {
+		return options, nil
+	}
+
+	signer, err := LoadSigningKey(cfg.App.JwtSigningKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	options.Signer = signer
+	options.VerifyKeys[signer.ID] = signer.Verification()
+	options.AcceptHMAC = cfg.App.JwtAcceptHMAC && cfg.App.JwtSecretKey != ""
+
+	for _, path := range cfg.App.JwtVerifyKeyFiles {
+		key, err := LoadVerificationKey(path)
+		if err != nil {
+			return nil, err
+		}
+		options.VerifyKeys[key.ID] = key
 	}
+
+	return options, nil
+}
+
+// JWKS lists the public keys tokens may currently be verified with.
+func (o *Options) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	if o.Signer != nil {
+		jwks.Keys = append(jwks.Keys, o.VerifyKeys[o.Signer.ID].JWK())
+	}
+
+	// kunci lama diurutkan supaya respons stabil antar request
+	var previous []JWK
+	for id, key := range o.VerifyKeys {
+		if o.Signer != nil && id == o.Signer.ID {
+			continue
+		}
+		previous = append(previous, key.JWK())
+	}
+	sort.Slice(previous, func(i, j int) bool {
+		return previous[i].Kid < previous[j].Kid
+	})
+
+	jwks.Keys = append(jwks.Keys, previous...)
+	return jwks
+}
+
+// GenerateRefreshToken returns an opaque random token. Only its HashToken is
+// stored, so a leaked sessions table cannot be used to refresh.
+func GenerateRefreshToken() (string, error) {
+	return generateToken(32)
 }
 
 func (o *Options) GenerateToken(data *JwtData) (string, int64, error) {
@@ -36,11 +117,25 @@ func (o *Options) GenerateToken(data *JwtData) (string, int64, error) {
 	}
 
 	now := time.Now().UTC()
-	expiredAt := now.Add(24 * time.Hour)
+	ttl := o.AccessTTL
+	if ttl <= 0 {
+		ttl = 15 * time.Minute
+	}
+	expiredAt := now.Add(ttl)
 	data.RegisteredClaims.ExpiresAt = jwt.NewNumericDate(expiredAt)
 	data.RegisteredClaims.Issuer = o.Issuer
 	data.RegisteredClaims.NotBefore = jwt.NewNumericDate(now)
 
+	if o.Signer != nil {
+		token := jwt.NewWithClaims(o.Signer.Method, data)
+		token.Header["kid"] = o.Signer.ID
+		signedToken, err := token.SignedString(o.Signer.Private)
+		if err != nil {
+			return "", 0, err
+		}
+		return signedToken, expiredAt.Unix(), nil
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, data)
 	signedToken, err := token.SignedString([]byte(o.SigningKey))
 	if err != nil {
@@ -50,12 +145,9 @@ func (o *Options) GenerateToken(data *JwtData) (string, int64, error) {
 }
 
 func (o *Options) VerifyAccessToken(token string) (*JwtData, error) {
-	parsedToken, err := jwt.ParseWithClaims(token, &JwtData{}, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("invalid signing method")
-		}
-		return []byte(o.SigningKey), nil
-	})
+	parsedToken, err := jwt.ParseWithClaims(token, &JwtData{}, o.verificationKey,
+		jwt.WithValidMethods([]string{"HS256", "RS256", "EdDSA"}),
+	)
 
 	if err != nil {
 		return nil, err
@@ -67,3 +159,24 @@ func (o *Options) VerifyAccessToken(token string) (*JwtData, error) {
 
 	return nil, errors.New("invalid or expired token")
 }
+
+func (o *Options) verificationKey(t *jwt.Token) (interface{}, error) {
+	if _, ok := t.Method.(*jwt.SigningMethodHMAC); ok {
+		if !o.AcceptHMAC || o.SigningKey == "" {
+			return nil, errors.New("HMAC signed tokens are no longer accepted")
+		}
+		return []byte(o.SigningKey), nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	key, found := o.VerifyKeys[kid]
+	if !found {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+
+	if key.Method.Alg() != t.Method.Alg() {
+		return nil, errors.New("invalid signing method")
+	}
+
+	return key.Public, nil
+}
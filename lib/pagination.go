@@ -0,0 +1,114 @@
+package lib
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor points at the last row of a keyset page, ordered by (created_at, id).
+type Cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAtStr, idStr, found := strings.Cut(string(raw), "|")
+	if !found {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtStr)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &Cursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+type Pagination struct {
+	Total      int64  `json:"total"`
+	Limit      int    `json:"limit"`
+	Offset     int    `json:"offset"`
+	HasNext    bool   `json:"has_next"`
+	Next       string `json:"next,omitempty"`
+	Prev       string `json:"prev,omitempty"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// TrimPage drops the extra row a keyset page reads past filter.Limit, and
+// reports whether there was one.
+func TrimPage[T any](filter Filter, rows []T) ([]T, bool) {
+	if !filter.Keyset || len(rows) <= filter.Limit {
+		return rows, false
+	}
+
+	return rows[:filter.Limit], true
+}
+
+// NewPagination describes the page that was just read. In keyset mode hasMore
+// tells whether rows follow the page, and last, the final row of the page,
+// becomes the next cursor.
+func NewPagination(filter Filter, total int64, count int, hasMore bool, last Cursor) Pagination {
+	pagination := Pagination{
+		Total:  total,
+		Limit:  filter.Limit,
+		Offset: filter.Offset,
+	}
+
+	if filter.Keyset {
+		pagination.Offset = 0
+		if hasMore && count > 0 {
+			pagination.HasNext = true
+			pagination.NextCursor = last.Encode()
+		}
+		return pagination
+	}
+
+	pagination.HasNext = int64(filter.Offset+count) < total
+	return pagination
+}
+
+// WithLinks fills next/prev from the request URL, keeping the other query parameters.
+func (p Pagination) WithLinks(u *url.URL) Pagination {
+	if p.HasNext {
+		query := u.Query()
+		if p.NextCursor != "" {
+			query.Set("cursor", p.NextCursor)
+			query.Del("offset")
+		} else {
+			query.Set("offset", strconv.Itoa(p.Offset+p.Limit))
+		}
+		p.Next = u.Path + "?" + query.Encode()
+	}
+
+	if p.NextCursor == "" && p.Offset > 0 {
+		query := u.Query()
+		query.Set("offset", strconv.Itoa(max(p.Offset-p.Limit, 0)))
+		p.Prev = u.Path + "?" + query.Encode()
+	}
+
+	return p
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"reflect"
 	"regexp"
 	"strconv"
 	"strings"
@@ -16,37 +17,60 @@ import (
 )
 
 type Filter struct {
-	Limit         int       `json:"limit" validate:"lte=100"`
-	Offset        int       `json:"offset"`
-	Dir           string    `json:"dir"`
-	Search        string    `json:"search" validate:"omitempty,alphanum_space"`
-	UserID        uuid.UUID `json:"user_id"`
-	RoleID        uuid.UUID `json:"role_id"`
-	CategoryID    uuid.UUID `json:"category_id"`
-	IsPending     bool      `json:"is_pending"`
-	IsApprove     bool      `json:"is_approve"`
-	IsRejected    bool      `json:"is_rejected"`
-	AuthorBook    string    `json:"author_book"`
-	PublisherID   uuid.UUID `json:"publisher_id"`
-	AuthorID      uuid.UUID `json:"author_id"`
-	PublishedYear int       `json:"published_year"`
-	Status        string    `json:"status"`
-	AccessLevel   string    `json:"access_level"`
-	Available     bool      `json:"available"`
-	Borrowed      bool      `json:"borrowed"`
-	Public        bool      `json:"public"`
-	MemberOnly    bool      `json:"member_only"`
-	AdminOnly     bool      `json:"admin_only"`
-	MemberID      uuid.UUID `json:"member_id"`
-	Returned      bool      `json:"returned"`
-	BookID        uuid.UUID `json:"book_id"`
+	Limit          int       `json:"limit" validate:"lte=100"`
+	Offset         int       `json:"offset"`
+	Dir            string    `json:"dir"`
+	Sort           string    `json:"sort"`
+	Search         string    `json:"search" validate:"omitempty,alphanum_space"`
+	UserID         uuid.UUID `json:"user_id"`
+	RoleID         uuid.UUID `json:"role_id"`
+	CategoryID     uuid.UUID `json:"category_id"`
+	IsPending      bool      `json:"is_pending"`
+	IsApprove      bool      `json:"is_approve"`
+	IsRejected     bool      `json:"is_rejected"`
+	AuthorBook     string    `json:"author_book"`
+	PublisherID    uuid.UUID `json:"publisher_id"`
+	AuthorID       uuid.UUID `json:"author_id"`
+	PublishedYear  int       `json:"published_year"`
+	Status         string    `json:"status"`
+	AccessLevel    string    `json:"access_level"`
+	Available      bool      `json:"available"`
+	Borrowed       bool      `json:"borrowed"`
+	Public         bool      `json:"public"`
+	MemberOnly     bool      `json:"member_only"`
+	AdminOnly      bool      `json:"admin_only"`
+	MemberID       uuid.UUID `json:"member_id"`
+	Returned       bool      `json:"returned"`
+	Overdue        bool      `json:"overdue"`
+	BookID         uuid.UUID `json:"book_id"`
+	AccessLevels   []string  `json:"-"`
+	Keyset         bool      `json:"keyset"`
+	IncludeDeleted bool      `json:"include_deleted"`
+	Cursor         *Cursor   `json:"-"`
 }
 
 var validate *validator.Validate
 
 func init() {
 	validate = validator.New(validator.WithRequiredStructEnabled())
+	// pakai nama field JSON supaya detail validasi cocok dengan body request
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
 	validate.RegisterValidation("alphanum_space", isValidAlphanumWithSpace)
+	validate.RegisterValidation("phone", isValidPhone)
+}
+
+var phoneRegex = regexp.MustCompile(`^\+?[0-9][0-9 -]{5,30}$`)
+
+// isValidPhone accepts digits with an optional leading + and spaces or dashes
+// between them, e.g. +62 812-3456-7890.
+func isValidPhone(fl validator.FieldLevel) bool {
+	return phoneRegex.MatchString(fl.Field().String())
 }
 
 func isValidAlphanumWithSpace(fl validator.FieldLevel) bool {
@@ -73,11 +97,22 @@ func ParseQueryParam(ctx context.Context, r *http.Request) (Filter, error) {
 		filter.Offset = 0
 	}
 
-	dir := strings.ToUpper(urisVal.Get("dir"))
-	if dir != "ASC" && dir != "DESC" {
-		filter.Dir = "ASC"
+	filter.Dir = GetValidDirection(urisVal.Get("dir"))
+	filter.Sort = urisVal.Get("sort")
+
+	// keyset pagination: pagination=keyset untuk halaman pertama, lalu cursor dari next_cursor
+	if cursor := urisVal.Get("cursor"); cursor != "" {
+		decoded, err := DecodeCursor(cursor)
+		if err != nil {
+			return Filter{}, err
+		}
+		filter.Cursor = decoded
+		filter.Keyset = true
+	}
+
+	if urisVal.Get("pagination") == "keyset" {
+		filter.Keyset = true
 	}
-	filter.Dir = dir
 
 	search := urisVal.Get("search")
 	filter.Search = search
@@ -155,13 +190,16 @@ func ParseQueryParam(ctx context.Context, r *http.Request) (Filter, error) {
 	filter.Available = urisVal.Get("is_available") == "true"
 	filter.Borrowed = urisVal.Get("is_borrowed") == "true"
 	filter.Returned = urisVal.Get("is_returned") == "true"
+	filter.Overdue = urisVal.Get("is_overdue") == "true"
 	filter.Public = urisVal.Get("is_public") == "true"
 	filter.MemberOnly = urisVal.Get("is_member_only") == "true"
 	filter.AdminOnly = urisVal.Get("is_admin_only") == "true"
+	filter.IncludeDeleted = urisVal.Get("include_deleted") == "true"
 
+	// dikembalikan apa adanya supaya AsAppError menjawab 422 per parameter, sama seperti ParseBody
 	err = validate.Struct(filter)
 	if err != nil {
-		return Filter{}, errors.New("invalid request parameter")
+		return Filter{}, err
 	}
 
 	return filter, nil
@@ -0,0 +1,32 @@
+package lib
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseQueryParamValidationErrors(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/books?limit=101&search=a%3Bb", nil)
+
+	_, err := ParseQueryParam(context.Background(), r)
+	if err == nil {
+		t.Fatal("ParseQueryParam() error = nil, want a validation error")
+	}
+
+	appErr := AsAppError(err, http.StatusBadRequest)
+	if appErr.Status != http.StatusUnprocessableEntity {
+		t.Errorf("status = %d, want %d", appErr.Status, http.StatusUnprocessableEntity)
+	}
+
+	want := map[string]string{
+		"limit":  "must satisfy lte=100",
+		"search": "failed on alphanum_space",
+	}
+	for field, message := range want {
+		if got := appErr.Fields[field]; got != message {
+			t.Errorf("Fields[%q] = %q, want %q", field, got, message)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package lib
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//go:embed common-passwords.txt
+var commonPasswordList string
+
+var commonPasswords = func() map[string]bool {
+	passwords := map[string]bool{}
+	for _, line := range strings.Split(commonPasswordList, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		passwords[strings.ToLower(line)] = true
+	}
+	return passwords
+}()
+
+// Password policy rules, used as the field keys of a PasswordPolicyError.
+const (
+	RuleMinLength = "min_length"
+	RuleMaxLength = "max_length"
+	RuleUpper     = "uppercase"
+	RuleLower     = "lowercase"
+	RuleDigit     = "digit"
+	RuleSymbol    = "symbol"
+	RuleCommon    = "common"
+	RuleUsername  = "username"
+	RuleReuse     = "reuse"
+)
+
+// PasswordPolicy is what a new password must satisfy. Reuse of earlier
+// passwords needs their hashes and is checked by the caller with CheckPassword.
+type PasswordPolicy struct {
+	MinLength     int
+	MaxLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	DenyCommon    bool
+}
+
+// Validate returns a message per broken rule, keyed by rule, or nil when the
+// password is acceptable. Length counts characters, not bytes.
+func (p PasswordPolicy) Validate(password string, username string) map[string]string {
+	violations := map[string]string{}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		violations[RuleMinLength] = fmt.Sprintf("must be at least %d characters long", p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		violations[RuleMaxLength] = fmt.Sprintf("must be at most %d characters long", p.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r), unicode.IsSymbol(r), unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	if p.RequireUpper && !upper {
+		violations[RuleUpper] = "must contain an uppercase letter"
+	}
+	if p.RequireLower && !lower {
+		violations[RuleLower] = "must contain a lowercase letter"
+	}
+	if p.RequireDigit && !digit {
+		violations[RuleDigit] = "must contain a digit"
+	}
+	if p.RequireSymbol && !symbol {
+		violations[RuleSymbol] = "must contain a symbol"
+	}
+
+	if p.DenyCommon && commonPasswords[strings.ToLower(password)] {
+		violations[RuleCommon] = "is too common, choose a less predictable password"
+	}
+
+	username = strings.ToLower(strings.TrimSpace(username))
+	if len(username) >= 3 && strings.Contains(strings.ToLower(password), username) {
+		violations[RuleUsername] = "must not contain the username"
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+	return violations
+}
+
+// PasswordPolicyError reports the broken rules as fields of one 422 error.
+func PasswordPolicyError(violations map[string]string) *AppError {
+	appErr := Unprocessable("password does not meet the password policy")
+	appErr.Code = "password_policy"
+	appErr.Fields = violations
+	return appErr
+}
@@ -0,0 +1,264 @@
+package lib
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PermissionChannel is the Postgres NOTIFY channel used to keep the permission
+// caches of every server instance in sync.
+const PermissionChannel = "permission_cache"
+
+// Scopes of a permission change notification. The payload is "scope" or "scope:key".
+const (
+	ScopeAll     = "all"
+	ScopeGrants  = "grants"
+	ScopeUser    = "user"
+	ScopeSession = "session"
+)
+
+type CacheStats struct {
+	SessionHits   uint64 `json:"session_hits"`
+	SessionMisses uint64 `json:"session_misses"`
+	RoleHits      uint64 `json:"role_hits"`
+	RoleMisses    uint64 `json:"role_misses"`
+	StatusHits    uint64 `json:"status_hits"`
+	StatusMisses  uint64 `json:"status_misses"`
+	GrantHits     uint64 `json:"grant_hits"`
+	GrantMisses   uint64 `json:"grant_misses"`
+}
+
+type cachedRoles struct {
+	roleIDs   []uuid.UUID
+	expiresAt time.Time
+}
+
+type cachedStatus struct {
+	status    string
+	expiresAt time.Time
+}
+
+type cachedGrants struct {
+	grants    GrantSet
+	expiresAt time.Time
+}
+
+// PermissionCache keeps what CheckAccess needs in memory: which sessions are
+// active, which roles a user holds, the account status of a user and which
+// endpoints a role may call.
+// Sessions expire quickly; roles and grants are dropped on change notifications
+// and expire after permissionTTL as a safety net.
+type PermissionCache struct {
+	mu            sync.RWMutex
+	sessionTTL    time.Duration
+	permissionTTL time.Duration
+	sessions      map[string]time.Time
+	userRoles     map[uuid.UUID]cachedRoles
+	userStatus    map[uuid.UUID]cachedStatus
+	roleGrants    map[uuid.UUID]cachedGrants
+
+	sessionHits, sessionMisses atomic.Uint64
+	roleHits, roleMisses       atomic.Uint64
+	statusHits, statusMisses   atomic.Uint64
+	grantHits, grantMisses     atomic.Uint64
+}
+
+func NewPermissionCache(sessionTTL time.Duration, permissionTTL time.Duration) *PermissionCache {
+	return &PermissionCache{
+		sessionTTL:    sessionTTL,
+		permissionTTL: permissionTTL,
+		sessions:      map[string]time.Time{},
+		userRoles:     map[uuid.UUID]cachedRoles{},
+		userStatus:    map[uuid.UUID]cachedStatus{},
+		roleGrants:    map[uuid.UUID]cachedGrants{},
+	}
+}
+
+// HashToken is the session cache key; raw tokens never leave the process.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func grantKey(endpoint string, method string) string {
+	return method + " " + endpoint
+}
+
+// SessionActive reports whether token is a known active session. ok is false on a miss.
+func (pc *PermissionCache) SessionActive(token string) (ok bool) {
+	pc.mu.RLock()
+	expiresAt, found := pc.sessions[HashToken(token)]
+	pc.mu.RUnlock()
+
+	if found && time.Now().Before(expiresAt) {
+		pc.sessionHits.Add(1)
+		return true
+	}
+
+	pc.sessionMisses.Add(1)
+	return false
+}
+
+func (pc *PermissionCache) StoreSession(token string) {
+	pc.mu.Lock()
+	pc.sessions[HashToken(token)] = time.Now().Add(pc.sessionTTL)
+	pc.mu.Unlock()
+}
+
+func (pc *PermissionCache) UserRoles(userID uuid.UUID) ([]uuid.UUID, bool) {
+	pc.mu.RLock()
+	entry, found := pc.userRoles[userID]
+	pc.mu.RUnlock()
+
+	if found && time.Now().Before(entry.expiresAt) {
+		pc.roleHits.Add(1)
+		return entry.roleIDs, true
+	}
+
+	pc.roleMisses.Add(1)
+	return nil, false
+}
+
+func (pc *PermissionCache) StoreUserRoles(userID uuid.UUID, roleIDs []uuid.UUID) {
+	pc.mu.Lock()
+	pc.userRoles[userID] = cachedRoles{roleIDs: roleIDs, expiresAt: time.Now().Add(pc.permissionTTL)}
+	pc.mu.Unlock()
+}
+
+func (pc *PermissionCache) UserStatus(userID uuid.UUID) (string, bool) {
+	pc.mu.RLock()
+	entry, found := pc.userStatus[userID]
+	pc.mu.RUnlock()
+
+	if found && time.Now().Before(entry.expiresAt) {
+		pc.statusHits.Add(1)
+		return entry.status, true
+	}
+
+	pc.statusMisses.Add(1)
+	return "", false
+}
+
+func (pc *PermissionCache) StoreUserStatus(userID uuid.UUID, status string) {
+	pc.mu.Lock()
+	pc.userStatus[userID] = cachedStatus{status: status, expiresAt: time.Now().Add(pc.permissionTTL)}
+	pc.mu.Unlock()
+}
+
+// RoleAllows reports whether the role may call the route template with method.
+// found is false when the role's grants are not cached yet.
+func (pc *PermissionCache) RoleAllows(roleID uuid.UUID, template string, method string) (allowed bool, found bool) {
+	pc.mu.RLock()
+	entry, found := pc.roleGrants[roleID]
+	pc.mu.RUnlock()
+
+	if !found || !time.Now().Before(entry.expiresAt) {
+		pc.grantMisses.Add(1)
+		return false, false
+	}
+
+	pc.grantHits.Add(1)
+	return entry.grants.Allows(template, method), true
+}
+
+// StoreRoleGrants caches the effective grants of the role, inherited ones included.
+func (pc *PermissionCache) StoreRoleGrants(roleID uuid.UUID, grants GrantSet) {
+	pc.mu.Lock()
+	pc.roleGrants[roleID] = cachedGrants{grants: grants, expiresAt: time.Now().Add(pc.permissionTTL)}
+	pc.mu.Unlock()
+}
+
+// Invalidate drops the entries a change notification refers to.
+func (pc *PermissionCache) Invalidate(scope string, key string) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	switch scope {
+	case ScopeGrants:
+		pc.roleGrants = map[uuid.UUID]cachedGrants{}
+	case ScopeUser:
+		userID, err := uuid.Parse(key)
+		if err != nil {
+			pc.userRoles = map[uuid.UUID]cachedRoles{}
+			pc.userStatus = map[uuid.UUID]cachedStatus{}
+			return
+		}
+		delete(pc.userRoles, userID)
+		delete(pc.userStatus, userID)
+	case ScopeSession:
+		delete(pc.sessions, key)
+	default:
+		pc.sessions = map[string]time.Time{}
+		pc.userRoles = map[uuid.UUID]cachedRoles{}
+		pc.userStatus = map[uuid.UUID]cachedStatus{}
+		pc.roleGrants = map[uuid.UUID]cachedGrants{}
+	}
+}
+
+// Sweep drops expired entries. Entries are only checked for expiry when read,
+// so without it the tokens of sessions that never come back pile up.
+func (pc *PermissionCache) Sweep() int {
+	now := time.Now()
+	removed := 0
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	for key, expiresAt := range pc.sessions {
+		if !now.Before(expiresAt) {
+			delete(pc.sessions, key)
+			removed++
+		}
+	}
+	for userID, entry := range pc.userRoles {
+		if !now.Before(entry.expiresAt) {
+			delete(pc.userRoles, userID)
+			removed++
+		}
+	}
+	for userID, entry := range pc.userStatus {
+		if !now.Before(entry.expiresAt) {
+			delete(pc.userStatus, userID)
+			removed++
+		}
+	}
+	for roleID, entry := range pc.roleGrants {
+		if !now.Before(entry.expiresAt) {
+			delete(pc.roleGrants, roleID)
+			removed++
+		}
+	}
+
+	return removed
+}
+
+func (pc *PermissionCache) Stats() CacheStats {
+	return CacheStats{
+		SessionHits:   pc.sessionHits.Load(),
+		SessionMisses: pc.sessionMisses.Load(),
+		RoleHits:      pc.roleHits.Load(),
+		RoleMisses:    pc.roleMisses.Load(),
+		StatusHits:    pc.statusHits.Load(),
+		StatusMisses:  pc.statusMisses.Load(),
+		GrantHits:     pc.grantHits.Load(),
+		GrantMisses:   pc.grantMisses.Load(),
+	}
+}
+
+func PermissionEventPayload(scope string, key string) string {
+	if key == "" {
+		return scope
+	}
+	return scope + ":" + key
+}
+
+func ParsePermissionEvent(payload string) (scope string, key string) {
+	scope, key, _ = strings.Cut(payload, ":")
+	return scope, key
+}
@@ -1,14 +1,192 @@
 package lib
 
 import (
-	"context"
+	"fmt"
 	"strings"
 )
 
-func SearchGenerate(ctx context.Context, operator string, search []string) string {
-	if len(search) == 0 {
+// QueryBuilder collects WHERE conditions, ORDER BY and LIMIT/OFFSET for list
+// queries. Every value is bound as a $n argument; only column names taken from
+// a whitelist ever end up in the SQL text.
+type QueryBuilder struct {
+	conditions []string
+	args       []interface{}
+	whereArgs  int
+	seek       string
+	orderBy    string
+	limit      string
+}
+
+func NewQueryBuilder() *QueryBuilder {
+	return &QueryBuilder{}
+}
+
+// Arg binds a value and returns its placeholder.
+func (qb *QueryBuilder) Arg(value interface{}) string {
+	qb.args = append(qb.args, value)
+	return fmt.Sprintf("$%d", len(qb.args))
+}
+
+// Where adds a condition; each "?" in it is replaced by the placeholder of the
+// matching value, e.g. Where("b.created_at BETWEEN ? AND ?", start, end).
+func (qb *QueryBuilder) Where(condition string, values ...interface{}) *QueryBuilder {
+	qb.conditions = append(qb.conditions, qb.bind(condition, values))
+	qb.whereArgs = len(qb.args)
+	return qb
+}
+
+// Condition is a condition with "?" placeholders and their values, as taken by Where.
+type Condition struct {
+	SQL    string
+	Values []interface{}
+}
+
+func Cond(condition string, values ...interface{}) Condition {
+	return Condition{SQL: condition, Values: values}
+}
+
+// WhereAny adds the conditions as a single OR group, binding their values
+// the same way as Where.
+func (qb *QueryBuilder) WhereAny(conditions ...Condition) *QueryBuilder {
+	if len(conditions) == 0 {
+		return qb
+	}
+
+	bound := make([]string, len(conditions))
+	for i, condition := range conditions {
+		bound[i] = qb.bind(condition.SQL, condition.Values)
+	}
+
+	qb.conditions = append(qb.conditions, "("+strings.Join(bound, " OR ")+")")
+	qb.whereArgs = len(qb.args)
+	return qb
+}
+
+// WhereIn adds "column IN (...)". An empty list matches nothing.
+func (qb *QueryBuilder) WhereIn(column string, values []string) *QueryBuilder {
+	if len(values) == 0 {
+		qb.conditions = append(qb.conditions, "FALSE")
+		return qb
+	}
+
+	placeHolders := make([]string, len(values))
+	for i, value := range values {
+		placeHolders[i] = qb.Arg(value)
+	}
+
+	qb.conditions = append(qb.conditions, fmt.Sprintf("%s IN (%s)", column, strings.Join(placeHolders, ", ")))
+	qb.whereArgs = len(qb.args)
+	return qb
+}
+
+// WhereContains adds a case-insensitive substring match on column.
+func (qb *QueryBuilder) WhereContains(column string, value string) *QueryBuilder {
+	return qb.Where(column+" ILIKE ?", "%"+EscapeLike(value)+"%")
+}
+
+// OrderBy sorts by the column registered under sort in columns, or by
+// fallback when sort is empty or unknown. Anything but DESC sorts ascending.
+func (qb *QueryBuilder) OrderBy(sort string, dir string, columns map[string]string, fallback string) *QueryBuilder {
+	column, ok := columns[sort]
+	if !ok {
+		column = columns[fallback]
+	}
+
+	qb.orderBy = fmt.Sprintf("ORDER BY %s %s", column, GetValidDirection(dir))
+	return qb
+}
+
+// Keyset orders by (createdColumn, idColumn) and, given a cursor, only keeps
+// rows after it in that order. It replaces OFFSET for deep pages.
+func (qb *QueryBuilder) Keyset(createdColumn string, idColumn string, cursor *Cursor, dir string) *QueryBuilder {
+	dir = GetValidDirection(dir)
+	if cursor != nil {
+		operator := ">"
+		if dir == Desc {
+			operator = "<"
+		}
+		qb.seek = fmt.Sprintf("(%s, %s) %s (%s, %s)", createdColumn, idColumn, operator, qb.Arg(cursor.CreatedAt), qb.Arg(cursor.ID))
+	}
+
+	qb.orderBy = fmt.Sprintf("ORDER BY %s %s, %s %s", createdColumn, dir, idColumn, dir)
+	return qb
+}
+
+// Page applies the sorting and pagination requested in filter: keyset on
+// (createdColumn, idColumn) when opted in, otherwise a whitelisted sort with
+// LIMIT/OFFSET. A keyset page reads one row more than the limit; see TrimPage.
+// Call it after all conditions have been added.
+func (qb *QueryBuilder) Page(filter Filter, columns map[string]string, fallback string, createdColumn string, idColumn string) *QueryBuilder {
+	if filter.Keyset {
+		qb.Keyset(createdColumn, idColumn, filter.Cursor, filter.Dir)
+		// satu baris lebih untuk tahu apakah masih ada halaman berikutnya
+		qb.limit = fmt.Sprintf("LIMIT %s", qb.Arg(filter.Limit+1))
+		return qb
+	}
+
+	return qb.OrderBy(filter.Sort, filter.Dir, columns, fallback).Paginate(filter.Limit, filter.Offset)
+}
+
+func (qb *QueryBuilder) Paginate(limit int, offset int) *QueryBuilder {
+	qb.limit = fmt.Sprintf("LIMIT %s OFFSET %s", qb.Arg(limit), qb.Arg(offset))
+	return qb
+}
+
+func (qb *QueryBuilder) WhereClause() string {
+	if len(qb.conditions) == 0 {
 		return ""
 	}
 
-	return "WHERE " + strings.Join(search, " "+operator+" ")
+	return "WHERE " + strings.Join(qb.conditions, " AND ")
+}
+
+// Clauses returns WHERE, ORDER BY and LIMIT/OFFSET ready to append to a SELECT.
+func (qb *QueryBuilder) Clauses() string {
+	where := qb.WhereClause()
+	if qb.seek != "" {
+		if where == "" {
+			where = "WHERE " + qb.seek
+		} else {
+			where += " AND " + qb.seek
+		}
+	}
+
+	var clauses []string
+	for _, clause := range []string{where, qb.orderBy, qb.limit} {
+		if clause != "" {
+			clauses = append(clauses, clause)
+		}
+	}
+
+	return strings.Join(clauses, "\n")
+}
+
+func (qb *QueryBuilder) Args() []interface{} {
+	return qb.args
+}
+
+// CountArgs returns only the arguments used by WhereClause, for a COUNT(*)
+// over the same filters without the cursor and pagination.
+func (qb *QueryBuilder) CountArgs() []interface{} {
+	return qb.args[:qb.whereArgs]
+}
+
+func (qb *QueryBuilder) bind(condition string, values []interface{}) string {
+	var sb strings.Builder
+	i := 0
+	for _, r := range condition {
+		if r == '?' && i < len(values) {
+			sb.WriteString(qb.Arg(values[i]))
+			i++
+			continue
+		}
+		sb.WriteRune(r)
+	}
+
+	return sb.String()
+}
+
+// EscapeLike escapes the LIKE wildcards so user input only matches literally.
+func EscapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
 }
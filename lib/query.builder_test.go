@@ -0,0 +1,238 @@
+package lib
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var testSortColumns = map[string]string{
+	"title":      "b.title",
+	"created_at": "b.created_at",
+}
+
+func TestQueryBuilderOrderBy(t *testing.T) {
+	tests := []struct {
+		name string
+		sort string
+		dir  string
+		want string
+	}{
+		{"known column", "title", "desc", "ORDER BY b.title DESC"},
+		{"unknown column uses fallback", "title; DROP TABLE books", "asc", "ORDER BY b.created_at ASC"},
+		{"empty sort uses fallback", "", "DESC", "ORDER BY b.created_at DESC"},
+		{"invalid direction sorts ascending", "title", "sideways", "ORDER BY b.title ASC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewQueryBuilder().OrderBy(tt.sort, tt.dir, testSortColumns, "created_at").Clauses()
+			if got != tt.want {
+				t.Errorf("Clauses() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryBuilderPlaceholders(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+	cursor := &Cursor{CreatedAt: start, ID: uuid.MustParse("7f1c2d3e-4b5a-4c6d-8e9f-0a1b2c3d4e5f")}
+
+	tests := []struct {
+		name      string
+		build     func(qb *QueryBuilder)
+		want      string
+		args      []interface{}
+		countArgs int
+	}{
+		{
+			name:  "no conditions",
+			build: func(qb *QueryBuilder) {},
+			want:  "",
+			args:  nil,
+		},
+		{
+			name: "where numbers placeholders in order",
+			build: func(qb *QueryBuilder) {
+				qb.Where("b.status = ?", "active").Where("b.created_at BETWEEN ? AND ?", start, end)
+			},
+			want:      "WHERE b.status = $1 AND b.created_at BETWEEN $2 AND $3",
+			args:      []interface{}{"active", start, end},
+			countArgs: 3,
+		},
+		{
+			name: "where any continues the numbering",
+			build: func(qb *QueryBuilder) {
+				qb.Where("b.status = ?", "active").
+					WhereAny(Cond("b.title ILIKE ?", "%go%"), Cond("b.isbn = ?", "123"), Cond("b.stock > 0")).
+					Where("b.access_level = ?", Public)
+			},
+			want:      "WHERE b.status = $1 AND (b.title ILIKE $2 OR b.isbn = $3 OR b.stock > 0) AND b.access_level = $4",
+			args:      []interface{}{"active", "%go%", "123", Public},
+			countArgs: 4,
+		},
+		{
+			name: "empty where any adds nothing",
+			build: func(qb *QueryBuilder) {
+				qb.WhereAny().Where("b.status = ?", "active")
+			},
+			want:      "WHERE b.status = $1",
+			args:      []interface{}{"active"},
+			countArgs: 1,
+		},
+		{
+			name: "where in and empty where in",
+			build: func(qb *QueryBuilder) {
+				qb.WhereIn("b.access_level", []string{Public, MemberOnly}).WhereIn("b.status", nil)
+			},
+			want:      "WHERE b.access_level IN ($1, $2) AND FALSE",
+			args:      []interface{}{Public, MemberOnly},
+			countArgs: 2,
+		},
+		{
+			name: "offset page follows the conditions",
+			build: func(qb *QueryBuilder) {
+				qb.Where("b.status = ?", "active").
+					Page(Filter{Limit: 10, Offset: 20, Sort: "title", Dir: "desc"}, testSortColumns, "created_at", "b.created_at", "b.id")
+			},
+			want:      "WHERE b.status = $1\nORDER BY b.title DESC\nLIMIT $2 OFFSET $3",
+			args:      []interface{}{"active", 10, 20},
+			countArgs: 1,
+		},
+		{
+			name: "keyset page seeks after the cursor",
+			build: func(qb *QueryBuilder) {
+				qb.Where("b.status = ?", "active").
+					Page(Filter{Limit: 10, Keyset: true, Cursor: cursor, Dir: "desc"}, testSortColumns, "created_at", "b.created_at", "b.id")
+			},
+			want:      "WHERE b.status = $1 AND (b.created_at, b.id) < ($2, $3)\nORDER BY b.created_at DESC, b.id DESC\nLIMIT $4",
+			args:      []interface{}{"active", cursor.CreatedAt, cursor.ID, 11},
+			countArgs: 1,
+		},
+		{
+			name: "keyset first page",
+			build: func(qb *QueryBuilder) {
+				qb.Page(Filter{Limit: 5, Keyset: true}, testSortColumns, "created_at", "b.created_at", "b.id")
+			},
+			want:      "ORDER BY b.created_at ASC, b.id ASC\nLIMIT $1",
+			args:      []interface{}{6},
+			countArgs: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qb := NewQueryBuilder()
+			tt.build(qb)
+
+			if got := qb.Clauses(); got != tt.want {
+				t.Errorf("Clauses() = %q, want %q", got, tt.want)
+			}
+			if got := qb.Args(); !reflect.DeepEqual(got, tt.args) {
+				t.Errorf("Args() = %#v, want %#v", got, tt.args)
+			}
+			if got := len(qb.CountArgs()); got != tt.countArgs {
+				t.Errorf("len(CountArgs()) = %d, want %d", got, tt.countArgs)
+			}
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"plain", "plain"},
+		{"100%", `100\%`},
+		{"snake_case", `snake\_case`},
+		{`back\slash`, `back\\slash`},
+		{`\%_`, `\\\%\_`},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			if got := EscapeLike(tt.value); got != tt.want {
+				t.Errorf("EscapeLike(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryBuilderInjection(t *testing.T) {
+	payloads := []string{
+		`x' OR 1=1 --`,
+		`%_\`,
+		`'); DROP TABLE books; --`,
+	}
+	cursor := &Cursor{CreatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), ID: uuid.MustParse("7f1c2d3e-4b5a-4c6d-8e9f-0a1b2c3d4e5f")}
+
+	for _, payload := range payloads {
+		tests := []struct {
+			name  string
+			build func(qb *QueryBuilder)
+			want  string
+			args  []interface{}
+		}{
+			{
+				name:  "where",
+				build: func(qb *QueryBuilder) { qb.Where("b.title = ?", payload) },
+				want:  "WHERE b.title = $1",
+				args:  []interface{}{payload},
+			},
+			{
+				name:  "where contains",
+				build: func(qb *QueryBuilder) { qb.WhereContains("b.title", payload) },
+				want:  "WHERE b.title ILIKE $1",
+				args:  []interface{}{"%" + EscapeLike(payload) + "%"},
+			},
+			{
+				name:  "where in",
+				build: func(qb *QueryBuilder) { qb.WhereIn("b.status", []string{payload, "active"}) },
+				want:  "WHERE b.status IN ($1, $2)",
+				args:  []interface{}{payload, "active"},
+			},
+			{
+				name:  "keyset direction",
+				build: func(qb *QueryBuilder) { qb.Keyset("b.created_at", "b.id", cursor, payload) },
+				want:  "WHERE (b.created_at, b.id) > ($1, $2)\nORDER BY b.created_at ASC, b.id ASC",
+				args:  []interface{}{cursor.CreatedAt, cursor.ID},
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name+"/"+payload, func(t *testing.T) {
+				qb := NewQueryBuilder()
+				tt.build(qb)
+
+				got := qb.Clauses()
+				if got != tt.want {
+					t.Errorf("Clauses() = %q, want %q", got, tt.want)
+				}
+				if strings.Contains(got, payload) {
+					t.Errorf("Clauses() = %q contains the payload", got)
+				}
+				if !reflect.DeepEqual(qb.Args(), tt.args) {
+					t.Errorf("Args() = %#v, want %#v", qb.Args(), tt.args)
+				}
+			})
+		}
+
+		t.Run("keyset cursor/"+payload, func(t *testing.T) {
+			if _, err := DecodeCursor(payload); err != ErrInvalidCursor {
+				t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", payload, err)
+			}
+		})
+	}
+
+	// wildcard dari input hanya cocok secara harfiah
+	qb := NewQueryBuilder().WhereContains("b.title", `%_\`)
+	if want := []interface{}{`%\%\_\\%`}; !reflect.DeepEqual(qb.Args(), want) {
+		t.Errorf("WhereContains args = %#v, want %#v", qb.Args(), want)
+	}
+}
@@ -0,0 +1,97 @@
+package lib
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.0.2.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		proxies    TrustedProxies
+		remoteAddr string
+		forwarded  []string
+		realIP     string
+		want       string
+	}{
+		{
+			name:       "no proxies trusted ignores forwarded headers",
+			remoteAddr: "203.0.113.7:5000",
+			forwarded:  []string{"198.51.100.1"},
+			realIP:     "198.51.100.2",
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "untrusted peer cannot forge its address",
+			proxies:    proxies,
+			remoteAddr: "203.0.113.7:5000",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "203.0.113.7",
+		},
+		{
+			name:       "trusted proxy reports the client",
+			proxies:    proxies,
+			remoteAddr: "10.1.2.3:5000",
+			forwarded:  []string{"198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "client supplied hops left of the proxy are ignored",
+			proxies:    proxies,
+			remoteAddr: "10.1.2.3:5000",
+			forwarded:  []string{"1.1.1.1, 198.51.100.1"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "chain of trusted proxies",
+			proxies:    proxies,
+			remoteAddr: "192.0.2.1:5000",
+			forwarded:  []string{"198.51.100.1, 10.9.9.9", "10.1.2.3"},
+			want:       "198.51.100.1",
+		},
+		{
+			name:       "garbage hop stops the walk",
+			proxies:    proxies,
+			remoteAddr: "10.1.2.3:5000",
+			forwarded:  []string{"not-an-ip"},
+			want:       "10.1.2.3",
+		},
+		{
+			name:       "real ip from a trusted proxy",
+			proxies:    proxies,
+			remoteAddr: "10.1.2.3:5000",
+			realIP:     "198.51.100.2",
+			want:       "198.51.100.2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = tt.remoteAddr
+			for _, value := range tt.forwarded {
+				r.Header.Add("X-Forwarded-For", value)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+
+			if got := tt.proxies.ClientIP(r); got != tt.want {
+				t.Errorf("ClientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxiesRejectsInvalid(t *testing.T) {
+	for _, entry := range []string{"proxy.local", "10.0.0.0/40"} {
+		if _, err := ParseTrustedProxies([]string{entry}); err == nil {
+			t.Errorf("ParseTrustedProxies(%q) error = nil, want an error", entry)
+		}
+	}
+}
@@ -2,13 +2,22 @@ package lib
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
 )
 
+type ErrorBody struct {
+	Code    string            `json:"code"`
+	Message string            `json:"message"`
+	Fields  map[string]string `json:"fields,omitempty"`
+}
+
 type ApiResponse struct {
-	Status string      `json:"status"`
-	Data   interface{} `json:"data"`
-	Error  *string     `json:"error,omitempty"`
+	Status     string      `json:"status"`
+	Data       interface{} `json:"data"`
+	Error      *ErrorBody  `json:"error,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
 }
 
 func Success(w http.ResponseWriter, status string, data interface{}) {
@@ -20,12 +29,37 @@ func Success(w http.ResponseWriter, status string, data interface{}) {
 	})
 }
 
+func SuccessList(w http.ResponseWriter, r *http.Request, status string, data interface{}, pagination Pagination) {
+	pagination = pagination.WithLinks(r.URL)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(ApiResponse{
+		Status:     status,
+		Data:       data,
+		Pagination: &pagination,
+	})
+}
+
+// Error writes err as JSON. statusCode is used when err does not carry its own
+// status, e.g. a plain errors.New from a module.
 func Error(w http.ResponseWriter, statusCode int, status string, err error) {
-	errMsg := err.Error()
+	appErr := AsAppError(err, statusCode)
+	if err == nil {
+		appErr.Message = status
+	}
+	logAppError(appErr)
+
+	if appErr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(appErr.RetryAfter.Seconds()))))
+	}
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusBadRequest)
+	w.WriteHeader(appErr.Status)
 	json.NewEncoder(w).Encode(ApiResponse{
 		Status: status,
-		Error:  &errMsg,
+		Error: &ErrorBody{
+			Code:    appErr.Code,
+			Message: appErr.Message,
+			Fields:  appErr.Fields,
+		},
 	})
 }
@@ -17,9 +17,18 @@ var (
 	Available  = "available"
 	Borrowed   = "borrowed"
 	Public     = "public"
-	MemberOnly = "member_only"
-	AdminOnly  = "admin_only"
+	MemberOnly = "member-only"
+	AdminOnly  = "admin-only"
 	Returned   = "returned"
+	Overdue    = "overdue"
+	Accruing   = "accruing"
+	Settled    = "settled"
+	OnHold     = "on_hold"
+	Waiting    = "waiting"
+	Ready      = "ready"
+	Fulfilled  = "fulfilled"
+	Cancelled  = "cancelled"
+	Expired    = "expired"
 	Asc        = "ASC"
 	Desc       = "DESC"
 
@@ -35,6 +44,7 @@ var (
 		"MEMBER_ONLY": MemberOnly,
 		"ADMIN_ONLY":  AdminOnly,
 		"RETURNED":    Returned,
+		"OVERDUE":     Overdue,
 	}
 
 	DirMap = map[string]string{
@@ -43,6 +53,14 @@ var (
 	}
 )
 
+// Account statuses of a user. Only active users can log in or use a session.
+const (
+	UserPendingVerification = "pending_verification"
+	UserActive              = "active"
+	UserSuspended           = "suspended"
+	UserClosed              = "closed"
+)
+
 func IsValidStatus(status string) bool {
 	_, exists := StatusMap[strings.ToUpper(status)]
 	return exists
@@ -0,0 +1,231 @@
+package model
+
+import (
+	"app-bookstore/lib"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/jmoiron/sqlx/types"
+)
+
+// Audit entities are named after their table.
+const (
+	EntityAuthors        = "authors"
+	EntityPublishers     = "publishers"
+	EntityCategories     = "categories"
+	EntityBooks          = "books"
+	EntityBookCopies     = "book_copies"
+	EntityLoans          = "loans"
+	EntityLoanPolicies   = "loan_policies"
+	EntityReservations   = "reservations"
+	EntityRatings        = "ratings"
+	EntityRoles          = "roles"
+	EntityResources      = "resources"
+	EntityRoleResources  = "role_resources"
+	EntityUserRoles      = "user_roles"
+	EntityUserRequests   = "user_requests"
+	EntityUsers          = "users"
+	EntityPasswordResets = "password_resets"
+	EntitySessions       = "sessions"
+)
+
+const (
+	AuditCreate         = "create"
+	AuditUpdate         = "update"
+	AuditDelete         = "delete"
+	AuditRestore        = "restore"
+	AuditReturn         = "return"
+	AuditRenew          = "renew"
+	AuditCancel         = "cancel"
+	AuditLogin          = "login"
+	AuditLogout         = "logout"
+	AuditRegister       = "register"
+	AuditPasswordChange = "password_change"
+	AuditResetRequest   = "password_reset_request"
+	AuditPasswordReset  = "password_reset"
+	AuditSync           = "sync"
+	AuditTokenRefresh   = "token_refresh"
+	AuditTokenReuse     = "token_reuse"
+	AuditSessionRevoke  = "session_revoke"
+	AuditSessionEvict   = "session_evict"
+	AuditLoginFailed    = "login_failed"
+	AuditUnlock         = "unlock"
+	AuditTwoFactorOn    = "two_factor_enable"
+	AuditTwoFactorOff   = "two_factor_disable"
+	AuditRecoveryCodes  = "recovery_codes_regenerate"
+	AuditProfileUpdate  = "profile_update"
+	AuditEmailVerify    = "email_verify"
+	AuditSuspend        = "suspend"
+	AuditReactivate     = "reactivate"
+	AuditAccountClose   = "account_close"
+)
+
+type AuditLogModel struct {
+	ID         uuid.UUID          `db:"id"`
+	UserID     uuid.NullUUID      `db:"user_id"`
+	Action     string             `db:"action"`
+	Entity     string             `db:"entity"`
+	EntityID   uuid.NullUUID      `db:"entity_id"`
+	BeforeData types.NullJSONText `db:"before_data"`
+	AfterData  types.NullJSONText `db:"after_data"`
+	Details    types.NullJSONText `db:"details"`
+	RequestID  string             `db:"request_id"`
+	ClientIP   string             `db:"client_ip"`
+	CreatedAt  time.Time          `db:"created_at"`
+}
+
+type AuditLogResponse struct {
+	ID        uuid.UUID       `json:"id"`
+	UserID    uuid.UUID       `json:"user_id"`
+	Action    string          `json:"action"`
+	Entity    string          `json:"entity"`
+	EntityID  uuid.UUID       `json:"entity_id"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	Details   json.RawMessage `json:"details,omitempty"`
+	RequestID string          `json:"request_id"`
+	ClientIP  string          `json:"client_ip"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+func (al *AuditLogModel) Response() AuditLogResponse {
+	return AuditLogResponse{
+		ID:        al.ID,
+		UserID:    al.UserID.UUID,
+		Action:    al.Action,
+		Entity:    al.Entity,
+		EntityID:  al.EntityID.UUID,
+		Before:    rawJSON(al.BeforeData),
+		After:     rawJSON(al.AfterData),
+		Details:   rawJSON(al.Details),
+		RequestID: al.RequestID,
+		ClientIP:  al.ClientIP,
+		CreatedAt: al.CreatedAt,
+	}
+}
+
+func rawJSON(value types.NullJSONText) json.RawMessage {
+	if !value.Valid {
+		return nil
+	}
+	return json.RawMessage(value.JSONText)
+}
+
+type AuditLogFilter struct {
+	ActorID  uuid.UUID
+	Entity   string
+	EntityID uuid.UUID
+	Action   string
+}
+
+// auditLogSortColumns lists the columns GET /audit-logs may be sorted by.
+var auditLogSortColumns = map[string]string{
+	"created_at": "al.created_at",
+	"action":     "al.action",
+	"entity":     "al.entity",
+}
+
+func GetAllAuditLogs(ctx context.Context, db *sqlx.DB, filter lib.Filter, auditFilter AuditLogFilter, dateFilter DateFilter) ([]AuditLogModel, int64, error) {
+	qb := lib.NewQueryBuilder()
+
+	if auditFilter.ActorID != uuid.Nil {
+		qb.Where("al.user_id = ?", auditFilter.ActorID)
+	}
+
+	if auditFilter.Entity != "" {
+		qb.Where("al.entity = ?", auditFilter.Entity)
+	}
+
+	if auditFilter.EntityID != uuid.Nil {
+		qb.Where("al.entity_id = ?", auditFilter.EntityID)
+	}
+
+	if auditFilter.Action != "" {
+		qb.Where("al.action = ?", auditFilter.Action)
+	}
+
+	if !dateFilter.StartDate.IsZero() && !dateFilter.EndDate.IsZero() {
+		qb.Where("al.created_at BETWEEN ? AND ?", dateFilter.StartDate, dateFilter.EndDate)
+	}
+
+	qb.Page(filter, auditLogSortColumns, "created_at", "al.created_at", "al.id")
+
+	from := `
+		FROM
+			audit_logs al
+	`
+
+	total, err := countRows(ctx, db, from, qb)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT
+			al.id,
+			al.user_id,
+			al.action,
+			COALESCE(al.entity, '') AS entity,
+			al.entity_id,
+			al.before_data,
+			al.after_data,
+			al.details,
+			COALESCE(al.request_id, '') AS request_id,
+			COALESCE(al.client_ip, '') AS client_ip,
+			al.created_at
+	` + from + qb.Clauses()
+
+	rows, err := db.QueryxContext(ctx, query, qb.Args()...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	var auditLogs []AuditLogModel
+	for rows.Next() {
+		var auditLog AuditLogModel
+		err := rows.StructScan(&auditLog)
+		if err != nil {
+			return nil, 0, err
+		}
+		auditLogs = append(auditLogs, auditLog)
+	}
+
+	return auditLogs, total, nil
+}
+
+func (al *AuditLogModel) Insert(ctx context.Context, db sqlx.ExtContext) error {
+	query := `
+		INSERT INTO audit_logs (
+			user_id, action, entity, entity_id, before_data, after_data, details, request_id, client_ip, created_by
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), $10
+		) RETURNING id, created_at
+	`
+
+	err := db.QueryRowxContext(ctx, query,
+		al.UserID,
+		al.Action,
+		al.Entity,
+		al.EntityID,
+		al.BeforeData,
+		al.AfterData,
+		al.Details,
+		al.RequestID,
+		al.ClientIP,
+		al.UserID,
+	).Scan(
+		&al.ID,
+		&al.CreatedAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
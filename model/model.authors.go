@@ -3,7 +3,6 @@ package model
 import (
 	"app-bookstore/lib"
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -19,16 +18,19 @@ type AuthorsModel struct {
 	CreatedBy uuid.UUID     `db:"created_by"`
 	UpdatedAt pq.NullTime   `db:"updated_at"`
 	UpdatedBy uuid.NullUUID `db:"updated_by"`
+	DeletedAt pq.NullTime   `db:"deleted_at"`
+	DeletedBy uuid.NullUUID `db:"deleted_by"`
 }
 
 type AuthorsRespose struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Bio       string    `json:"bio"`
-	CreatedAt time.Time `json:"created_at"`
-	CreatedBy uuid.UUID `json:"created_by"`
-	UpdatedAt time.Time `json:"updated_at"`
-	UpdatedBy uuid.UUID `json:"updated_by"`
+	ID        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	Bio       string     `json:"bio"`
+	CreatedAt time.Time  `json:"created_at"`
+	CreatedBy uuid.UUID  `json:"created_by"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	UpdatedBy uuid.UUID  `json:"updated_by"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 func (a *AuthorsModel) Response() AuthorsRespose {
@@ -40,29 +42,47 @@ func (a *AuthorsModel) Response() AuthorsRespose {
 		CreatedBy: a.CreatedBy,
 		UpdatedAt: a.UpdatedAt.Time,
 		UpdatedBy: a.UpdatedBy.UUID,
+		DeletedAt: nullTime(a.DeletedAt),
 	}
 }
 
-func GetAllAuthors(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter DateFilter) ([]AuthorsModel, error) {
-	var filters []string
+// authorSortColumns lists the columns GET /authors may be sorted by.
+var authorSortColumns = map[string]string{
+	"created_at": "a.created_at",
+	"name":       "a.name",
+}
+
+func GetAllAuthors(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter DateFilter) ([]AuthorsModel, int64, error) {
+	qb := lib.NewQueryBuilder()
+	if !filter.IncludeDeleted {
+		qb.Where("a.deleted_at IS NULL")
+	}
 
 	if filter.Search != "" {
-		filters = append(filters, fmt.Sprintf("a.name ILIKE '%%%s%%'", filter.Search))
+		qb.WhereContains("a.name", filter.Search)
 	}
 
 	if filter.AuthorBook != "" {
-		filters = append(filters, fmt.Sprintf("b.title ILIKE '%%%s%%'", filter.AuthorBook))
+		qb.Where("EXISTS (SELECT 1 FROM books b WHERE b.author_id = a.id AND b.deleted_at IS NULL AND b.title ILIKE ?)", "%"+lib.EscapeLike(filter.AuthorBook)+"%")
 	}
 
 	if !dateFilter.StartDate.IsZero() && !dateFilter.EndDate.IsZero() {
-		filters = append(filters, fmt.Sprintf(
-			"a.created_at BETWEEN '%s' AND '%s'",
-			dateFilter.StartDate.Format("2006-01-02"),
-			dateFilter.EndDate.Format("2006-01-02"),
-		))
+		qb.Where("a.created_at BETWEEN ? AND ?", dateFilter.StartDate, dateFilter.EndDate)
+	}
+
+	qb.Page(filter, authorSortColumns, "created_at", "a.created_at", "a.id")
+
+	from := `
+		FROM
+			authors a
+	`
+
+	total, err := countRows(ctx, db, from, qb)
+	if err != nil {
+		return nil, 0, err
 	}
-	query := fmt.Sprintf(
-		`
+
+	query := `
 		SELECT
 			a.id, 
 			a.name, 
@@ -70,21 +90,14 @@ func GetAllAuthors(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilt
 			a.created_at, 
 			a.created_by, 
 			a.updated_at, 
-			a.updated_by
-		FROM
-			authors a
-		INNER JOIN
-			books b
-		ON
-			a.id = b.author_id
-		%s
-		ORDER BY a.created_at %s
-		LIMIT $1 OFFSET $2
-	`, lib.SearchGenerate(ctx, "AND", filters), filter.Dir)
-
-	rows, err := db.QueryxContext(ctx, query, filter.Limit, filter.Offset)
+			a.updated_by,
+			a.deleted_at,
+			a.deleted_by
+	` + from + qb.Clauses()
+
+	rows, err := db.QueryxContext(ctx, query, qb.Args()...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	defer rows.Close()
@@ -94,12 +107,12 @@ func GetAllAuthors(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilt
 		var author AuthorsModel
 		err := rows.StructScan(&author)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		authors = append(authors, author)
 	}
 
-	return authors, nil
+	return authors, total, nil
 }
 
 func GetOneAuthors(ctx context.Context, db *sqlx.DB, id uuid.UUID) (AuthorsModel, error) {
@@ -116,6 +129,7 @@ func GetOneAuthors(ctx context.Context, db *sqlx.DB, id uuid.UUID) (AuthorsModel
 			authors
 		WHERE 
 			id = $1
+		AND deleted_at IS NULL
 	`
 
 	authorRequest := AuthorsModel{}
@@ -164,6 +178,7 @@ func (a *AuthorsModel) Update(ctx context.Context, db *sqlx.DB, id uuid.UUID) er
             updated_by = $4
 		WHERE
 			id = $5
+		AND deleted_at IS NULL
 		RETURNING id, name, bio, created_at, created_by, updated_at, updated_by
 	`
 
@@ -0,0 +1,251 @@
+package model
+
+import (
+	"app-bookstore/lib"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type BookCopyModel struct {
+	ID              uuid.UUID     `db:"id"`
+	BookID          uuid.UUID     `db:"book_id"`
+	Barcode         string        `db:"barcode"`
+	Condition       string        `db:"condition"`
+	ShelfLocation   string        `db:"shelf_location"`
+	AcquisitionDate pq.NullTime   `db:"acquisition_date"`
+	Status          string        `db:"status"`
+	CreatedAt       time.Time     `db:"created_at"`
+	CreatedBy       uuid.UUID     `db:"created_by"`
+	UpdatedAt       pq.NullTime   `db:"updated_at"`
+	UpdatedBy       uuid.NullUUID `db:"updated_by"`
+}
+
+type BookCopyResponse struct {
+	ID              uuid.UUID `json:"id"`
+	BookID          uuid.UUID `json:"book_id"`
+	Barcode         string    `json:"barcode"`
+	Condition       string    `json:"condition"`
+	ShelfLocation   string    `json:"shelf_location"`
+	AcquisitionDate time.Time `json:"acquisition_date"`
+	Status          string    `json:"status"`
+	CreatedAt       time.Time `json:"created_at"`
+	CreatedBy       uuid.UUID `json:"created_by"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	UpdatedBy       uuid.UUID `json:"updated_by"`
+}
+
+func (bc *BookCopyModel) Response() BookCopyResponse {
+	return BookCopyResponse{
+		ID:              bc.ID,
+		BookID:          bc.BookID,
+		Barcode:         bc.Barcode,
+		Condition:       bc.Condition,
+		ShelfLocation:   bc.ShelfLocation,
+		AcquisitionDate: bc.AcquisitionDate.Time,
+		Status:          bc.Status,
+		CreatedAt:       bc.CreatedAt,
+		CreatedBy:       bc.CreatedBy,
+		UpdatedAt:       bc.UpdatedAt.Time,
+		UpdatedBy:       bc.UpdatedBy.UUID,
+	}
+}
+
+// bookCopySortColumns lists the columns the book copy list may be sorted by.
+var bookCopySortColumns = map[string]string{
+	"created_at": "created_at",
+	"barcode":    "barcode",
+	"status":     "status",
+}
+
+func GetAllBookCopies(ctx context.Context, db *sqlx.DB, bookID uuid.UUID, filter lib.Filter) ([]BookCopyModel, int64, error) {
+	qb := lib.NewQueryBuilder()
+	qb.Where("book_id = ?", bookID)
+	qb.Page(filter, bookCopySortColumns, "created_at", "created_at", "id")
+
+	from := `
+		FROM
+			book_copies
+	`
+
+	total, err := countRows(ctx, db, from, qb)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT
+			id, book_id, barcode, condition, COALESCE(shelf_location, '') AS shelf_location, acquisition_date, status, created_at, created_by, updated_at, updated_by
+	` + from + qb.Clauses()
+
+	rows, err := db.QueryxContext(ctx, query, qb.Args()...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	var bookCopies []BookCopyModel
+	for rows.Next() {
+		var bookCopy BookCopyModel
+		err := rows.StructScan(&bookCopy)
+		if err != nil {
+			return nil, 0, err
+		}
+		bookCopies = append(bookCopies, bookCopy)
+	}
+
+	return bookCopies, total, nil
+}
+
+func GetOneBookCopy(ctx context.Context, db *sqlx.DB, id uuid.UUID) (BookCopyModel, error) {
+	query := `
+		SELECT
+			id, book_id, barcode, condition, COALESCE(shelf_location, '') AS shelf_location, acquisition_date, status, created_at, created_by, updated_at, updated_by
+		FROM
+			book_copies
+		WHERE
+			id = $1
+	`
+
+	bookCopy := BookCopyModel{}
+	err := db.QueryRowxContext(ctx, query, id).StructScan(&bookCopy)
+	if err != nil {
+		return bookCopy, err
+	}
+
+	return bookCopy, nil
+}
+
+// GetAvailableCopyID picks the available copy of a book that has been on the shelf longest.
+func GetAvailableCopyID(ctx context.Context, db *sqlx.DB, bookID uuid.UUID) (uuid.UUID, error) {
+	query := `
+		SELECT
+			id
+		FROM
+			book_copies
+		WHERE
+			book_id = $1
+		AND status = 'available'
+		ORDER BY updated_at NULLS FIRST, created_at
+		LIMIT 1
+	`
+
+	var copyID uuid.UUID
+	err := db.QueryRowxContext(ctx, query, bookID).Scan(&copyID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	return copyID, nil
+}
+
+func (bc *BookCopyModel) Insert(ctx context.Context, db *sqlx.DB) error {
+	query := `
+		INSERT INTO book_copies (
+			id, book_id, barcode, condition, shelf_location, acquisition_date, status, created_at, created_by
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
+		) RETURNING id, created_at
+	`
+
+	err := db.QueryRowxContext(ctx, query,
+		bc.ID,
+		bc.BookID,
+		bc.Barcode,
+		bc.Condition,
+		bc.ShelfLocation,
+		bc.AcquisitionDate,
+		bc.Status,
+		bc.CreatedAt,
+		bc.CreatedBy,
+	).Scan(
+		&bc.ID,
+		&bc.CreatedAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (bc *BookCopyModel) Update(ctx context.Context, db *sqlx.DB) error {
+	query := `
+		UPDATE
+			book_copies
+		SET
+			barcode = $1,
+			condition = $2,
+			shelf_location = $3,
+			acquisition_date = $4,
+			status = $5,
+			updated_at = $6,
+			updated_by = $7
+		WHERE
+			id = $8
+		RETURNING id, book_id, barcode, condition, COALESCE(shelf_location, ''), acquisition_date, status, created_at, created_by, updated_at, updated_by
+	`
+
+	err := db.QueryRowxContext(ctx, query,
+		bc.Barcode,
+		bc.Condition,
+		bc.ShelfLocation,
+		bc.AcquisitionDate,
+		bc.Status,
+		bc.UpdatedAt.Time,
+		bc.UpdatedBy.UUID,
+		bc.ID,
+	).Scan(
+		&bc.ID,
+		&bc.BookID,
+		&bc.Barcode,
+		&bc.Condition,
+		&bc.ShelfLocation,
+		&bc.AcquisitionDate,
+		&bc.Status,
+		&bc.CreatedAt,
+		&bc.CreatedBy,
+		&bc.UpdatedAt,
+		&bc.UpdatedBy,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LockCopyStatus locks the copy row until the surrounding transaction ends and
+// returns its current status.
+func LockCopyStatus(ctx context.Context, db sqlx.QueryerContext, id uuid.UUID) (string, error) {
+	query := `SELECT status FROM book_copies WHERE id = $1 FOR UPDATE`
+
+	var status string
+	err := db.QueryRowxContext(ctx, query, id).Scan(&status)
+	if err != nil {
+		return "", err
+	}
+
+	return status, nil
+}
+
+func SetCopyStatus(ctx context.Context, db sqlx.ExtContext, id uuid.UUID, status string) error {
+	query := `
+		UPDATE
+			book_copies
+		SET
+			status = $1,
+			updated_at = NOW()
+		WHERE
+			id = $2
+	`
+
+	_, err := db.ExecContext(ctx, query, status, id)
+	return err
+}
@@ -4,7 +4,6 @@ import (
 	"app-bookstore/lib"
 	"context"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -13,142 +12,165 @@ import (
 )
 
 type BookModel struct {
-	ID            uuid.UUID     `db:"id"`
-	Title         string        `db:"title"`
-	AuthorID      uuid.UUID     `db:"author_id"`
-	PublisherID   uuid.NullUUID `db:"publisher_id"`
-	CategoryID    uuid.NullUUID `db:"category_id"`
-	PublishedYear int           `db:"published_year"`
-	ISBN          string        `db:"isbn"`
-	Status        string        `db:"status"`
-	AccessLevel   string        `db:"access_level"`
-	CreatedAt     time.Time     `db:"created_at"`
-	CreatedBy     uuid.UUID     `db:"created_by"`
-	UpdatedAt     pq.NullTime   `db:"updated_at"`
-	UpdatedBy     uuid.NullUUID `db:"updated_by"`
+	ID              uuid.UUID     `db:"id"`
+	Title           string        `db:"title"`
+	AuthorID        uuid.UUID     `db:"author_id"`
+	PublisherID     uuid.NullUUID `db:"publisher_id"`
+	CategoryID      uuid.NullUUID `db:"category_id"`
+	PublishedYear   int           `db:"published_year"`
+	ISBN            string        `db:"isbn"`
+	Status          string        `db:"status"`
+	AccessLevel     string        `db:"access_level"`
+	TotalCopies     int           `db:"total_copies"`
+	AvailableCopies int           `db:"available_copies"`
+	CreatedAt       time.Time     `db:"created_at"`
+	CreatedBy       uuid.UUID     `db:"created_by"`
+	UpdatedAt       pq.NullTime   `db:"updated_at"`
+	UpdatedBy       uuid.NullUUID `db:"updated_by"`
+	DeletedAt       pq.NullTime   `db:"deleted_at"`
+	DeletedBy       uuid.NullUUID `db:"deleted_by"`
 }
 
 type BookResponse struct {
-	ID            uuid.UUID `json:"id"`
-	Title         string    `json:"title"`
-	AuthorID      uuid.UUID `json:"author_id"`
-	PublisherID   uuid.UUID `json:"publisher_id"`
-	CategoryID    uuid.UUID `json:"category_id"`
-	PublishedYear int       `json:"published_year"`
-	ISBN          string    `json:"isbn"`
-	Status        string    `json:"status"`
-	AccessLevel   string    `json:"access_level"`
-	CreatedAt     time.Time `json:"created_at"`
-	CreatedBy     uuid.UUID `json:"created_by"`
-	UpdatedAt     time.Time `json:"updated_at,omitempty"`
-	UpdatedBy     uuid.UUID `json:"updated_by,omitempty"`
+	ID              uuid.UUID  `json:"id"`
+	Title           string     `json:"title"`
+	AuthorID        uuid.UUID  `json:"author_id"`
+	PublisherID     uuid.UUID  `json:"publisher_id"`
+	CategoryID      uuid.UUID  `json:"category_id"`
+	PublishedYear   int        `json:"published_year"`
+	ISBN            string     `json:"isbn"`
+	Status          string     `json:"status"`
+	AccessLevel     string     `json:"access_level"`
+	TotalCopies     int        `json:"total_copies"`
+	AvailableCopies int        `json:"available_copies"`
+	Availability    string     `json:"availability"`
+	CreatedAt       time.Time  `json:"created_at"`
+	CreatedBy       uuid.UUID  `json:"created_by"`
+	UpdatedAt       time.Time  `json:"updated_at,omitempty"`
+	UpdatedBy       uuid.UUID  `json:"updated_by,omitempty"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
 }
 
 func (b *BookModel) Response() BookResponse {
 	return BookResponse{
-		ID:            b.ID,
-		Title:         b.Title,
-		AuthorID:      b.AuthorID,
-		PublisherID:   b.PublisherID.UUID,
-		CategoryID:    b.CategoryID.UUID,
-		PublishedYear: b.PublishedYear,
-		ISBN:          b.ISBN,
-		Status:        b.Status,
-		AccessLevel:   b.AccessLevel,
-		CreatedAt:     b.CreatedAt,
-		CreatedBy:     b.CreatedBy,
-		UpdatedAt:     b.UpdatedAt.Time,
-		UpdatedBy:     b.UpdatedBy.UUID,
+		ID:              b.ID,
+		Title:           b.Title,
+		AuthorID:        b.AuthorID,
+		PublisherID:     b.PublisherID.UUID,
+		CategoryID:      b.CategoryID.UUID,
+		PublishedYear:   b.PublishedYear,
+		ISBN:            b.ISBN,
+		Status:          b.Status,
+		AccessLevel:     b.AccessLevel,
+		TotalCopies:     b.TotalCopies,
+		AvailableCopies: b.AvailableCopies,
+		Availability:    fmt.Sprintf("%d of %d available", b.AvailableCopies, b.TotalCopies),
+		CreatedAt:       b.CreatedAt,
+		CreatedBy:       b.CreatedBy,
+		UpdatedAt:       b.UpdatedAt.Time,
+		UpdatedBy:       b.UpdatedBy.UUID,
+		DeletedAt:       nullTime(b.DeletedAt),
 	}
 }
 
-func GetAllBooks(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter DateFilter) ([]BookModel, error) {
-	var filters []string
-	var statuses []string
+// copyCountsQuery counts lendable copies per title; withdrawn copies are not counted.
+const copyCountsQuery = `
+	SELECT
+		book_id,
+		COUNT(*) FILTER (WHERE status <> 'withdrawn') AS total_copies,
+		COUNT(*) FILTER (WHERE status = 'available') AS available_copies
+	FROM
+		book_copies
+	GROUP BY book_id
+`
+
+// bookSortColumns lists the columns GET /books may be sorted by.
+var bookSortColumns = map[string]string{
+	"created_at":     "b.created_at",
+	"title":          "b.title",
+	"published_year": "b.published_year",
+}
+
+func GetAllBooks(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter DateFilter) ([]BookModel, int64, error) {
+	qb := lib.NewQueryBuilder()
+	var accessLevels []string
+	var availability []lib.Condition
+
+	if !filter.IncludeDeleted {
+		qb.Where("b.deleted_at IS NULL")
+	}
 
 	if filter.Search != "" {
-		filters = append(filters, fmt.Sprintf("b.title ILIKE '%%%s%%'", filter.Search))
+		qb.WhereContains("b.title", filter.Search)
 	}
 
 	if filter.AuthorID != uuid.Nil {
-		filters = append(filters, fmt.Sprintf("b.author_id = '%s'", filter.AuthorID))
+		qb.Where("b.author_id = ?", filter.AuthorID)
 	}
 
 	if filter.PublisherID != uuid.Nil {
-		filters = append(filters, fmt.Sprintf("b.publisher_id = '%s'", filter.PublisherID))
+		qb.Where("b.publisher_id = ?", filter.PublisherID)
 	}
 
 	if filter.CategoryID != uuid.Nil {
-		filters = append(filters, fmt.Sprintf("b.category_id = '%s'", filter.CategoryID))
+		qb.Where("b.category_id = ?", filter.CategoryID)
 	}
 
 	if filter.PublishedYear > 0 {
-		filters = append(filters, fmt.Sprintf("b.published_year = %d", filter.PublishedYear))
+		qb.Where("b.published_year = ?", filter.PublishedYear)
 	}
 
 	if filter.Status != "" {
-		filters = append(filters, fmt.Sprintf("b.status = '%s'", filter.Status))
+		qb.Where("b.status = ?", filter.Status)
 	}
 
 	if filter.AccessLevel != "" {
-		filters = append(filters, fmt.Sprintf("b.access_level = '%s'", filter.AccessLevel))
+		qb.Where("b.access_level = ?", filter.AccessLevel)
 	}
 
 	if filter.Available {
-		statuses = append(statuses, lib.Available)
+		availability = append(availability, lib.Cond("COALESCE(bc.available_copies, 0) > 0"))
 	}
 
 	if filter.Borrowed {
-		statuses = append(statuses, lib.Borrowed)
+		availability = append(availability, lib.Cond("(COALESCE(bc.total_copies, 0) > 0 AND COALESCE(bc.available_copies, 0) = 0)"))
 	}
 
+	qb.WhereAny(availability...)
+
 	if filter.Public {
-		statuses = append(statuses, lib.Public)
+		accessLevels = append(accessLevels, lib.Public)
 	}
 
 	if filter.MemberOnly {
-		statuses = append(statuses, lib.MemberOnly)
+		accessLevels = append(accessLevels, lib.MemberOnly)
 	}
 
 	if filter.AdminOnly {
-		statuses = append(statuses, lib.AdminOnly)
+		accessLevels = append(accessLevels, lib.AdminOnly)
 	}
 
-	if len(statuses) > 0 {
-		placeHolders := make([]string, len(statuses))
-		for i, status := range statuses {
-			placeHolders[i] = fmt.Sprintf("'%s'", status)
-		}
+	if len(accessLevels) > 0 {
+		qb.WhereIn("b.access_level", accessLevels)
+	}
 
-		filters = append(filters, fmt.Sprintf("b.status IN (%s)", strings.Join(placeHolders, ", ")))
+	// batas akses dari role peminjam selalu ikut, terlepas dari filter di atas
+	if filter.AccessLevels != nil {
+		qb.WhereIn("b.access_level", filter.AccessLevels)
 	}
 
 	if !dateFilter.StartDate.IsZero() && !dateFilter.EndDate.IsZero() {
-		filters = append(filters, fmt.Sprintf(
-			"b.created_at BETWEEN '%s' AND '%s'",
-			dateFilter.StartDate.Format("2006-01-02"),
-			dateFilter.EndDate.Format("2006-01-02"),
-		))
+		qb.Where("b.created_at BETWEEN ? AND ?", dateFilter.StartDate, dateFilter.EndDate)
 	}
 
-	query := fmt.Sprintf(
-		`
-		SELECT
-			b.id, 
-			b.title, 
-			b.author_id, 
-			b.publisher_id,
-			b.category_id, 
-			b.published_year, 
-			b.isbn, 
-			b.status, 
-			b.access_level, 
-			b.created_at, 
-			b.created_by, 
-			b.updated_at, 
-			b.updated_by
+	qb.Page(filter, bookSortColumns, "created_at", "b.created_at", "b.id")
+
+	from := fmt.Sprintf(`
 		FROM
 			books b
+		LEFT JOIN (%s) bc
+		ON
+			bc.book_id = b.id
 		INNER JOIN 
 			authors a 
 		ON 
@@ -161,14 +183,37 @@ func GetAllBooks(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter
 			categories c 
 		ON 
 			b.category_id = c.id
-		%s
-		ORDER BY b.created_at %s
-		LIMIT $1 OFFSET $2
-	`, lib.SearchGenerate(ctx, "AND", filters), filter.Dir)
+	`, copyCountsQuery)
+
+	total, err := countRows(ctx, db, from, qb)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT
+			b.id, 
+			b.title, 
+			b.author_id, 
+			b.publisher_id,
+			b.category_id, 
+			b.published_year, 
+			b.isbn, 
+			b.status, 
+			b.access_level, 
+			COALESCE(bc.total_copies, 0) AS total_copies,
+			COALESCE(bc.available_copies, 0) AS available_copies,
+			b.created_at, 
+			b.created_by, 
+			b.updated_at, 
+			b.updated_by,
+			b.deleted_at,
+			b.deleted_by
+	` + from + qb.Clauses()
 
-	rows, err := db.QueryxContext(ctx, query, filter.Limit, filter.Offset)
+	rows, err := db.QueryxContext(ctx, query, qb.Args()...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	defer rows.Close()
@@ -178,24 +223,31 @@ func GetAllBooks(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter
 		var book BookModel
 		err := rows.StructScan(&book)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		books = append(books, book)
 	}
 
-	return books, nil
+	return books, total, nil
 }
 
 func GetOneBooks(ctx context.Context, db *sqlx.DB, id uuid.UUID) (BookModel, error) {
-	query := `
+	query := fmt.Sprintf(`
 		SELECT
-			id, title, author_id, publisher_id, category_id, published_year, isbn, status, access_level, created_at, created_by, updated_at, updated_by
+			b.id, b.title, b.author_id, b.publisher_id, b.category_id, b.published_year, b.isbn, b.status, b.access_level,
+			COALESCE(bc.total_copies, 0) AS total_copies,
+			COALESCE(bc.available_copies, 0) AS available_copies,
+			b.created_at, b.created_by, b.updated_at, b.updated_by
 		FROM
-			books
+			books b
+		LEFT JOIN (%s) bc
+		ON
+			bc.book_id = b.id
 		WHERE 
-			id = $1
-	`
+			b.id = $1
+		AND b.deleted_at IS NULL
+	`, copyCountsQuery)
 
 	bookRequest := BookModel{}
 	err := db.QueryRowxContext(ctx, query, id).StructScan(&bookRequest)
@@ -209,10 +261,10 @@ func GetOneBooks(ctx context.Context, db *sqlx.DB, id uuid.UUID) (BookModel, err
 func (b *BookModel) Insert(ctx context.Context, db *sqlx.DB) error {
 	query := `
 		INSERT INTO books (
-			id, title, author_id, publisher_id, category_id, published_year, isbn, status, access_level, created_at, created_by, updated_at, updated_by
+			id, title, author_id, publisher_id, category_id, published_year, isbn, access_level, created_at, created_by, updated_at, updated_by
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13
-		) RETURNING id, created_at
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+		) RETURNING id, status, created_at
 	`
 
 	err := db.QueryRowxContext(ctx, query,
@@ -223,7 +275,6 @@ func (b *BookModel) Insert(ctx context.Context, db *sqlx.DB) error {
 		b.CategoryID.UUID,
 		b.PublishedYear,
 		b.ISBN,
-		b.Status,
 		b.AccessLevel,
 		b.CreatedAt,
 		b.CreatedBy,
@@ -231,6 +282,7 @@ func (b *BookModel) Insert(ctx context.Context, db *sqlx.DB) error {
 		b.UpdatedBy.UUID,
 	).Scan(
 		&b.ID,
+		&b.Status,
 		&b.CreatedAt,
 	)
 
@@ -252,11 +304,11 @@ func (b *BookModel) Update(ctx context.Context, db *sqlx.DB, id uuid.UUID) error
 			category_id = $4, 
 			published_year = $5, 
 			isbn = $6, 
-			status = $7, 
-			access_level = $8,
+			access_level = $7,
 			updated_at = NOW(),
-			updated_by = $9
-		WHERE id = $10
+			updated_by = $8
+		WHERE id = $9
+		AND deleted_at IS NULL
 		RETURNING id, title, author_id, publisher_id, category_id, published_year, isbn, status, access_level, updated_at, updated_by
 	`
 
@@ -267,7 +319,6 @@ func (b *BookModel) Update(ctx context.Context, db *sqlx.DB, id uuid.UUID) error
 		b.CategoryID.UUID,
 		b.PublishedYear,
 		b.ISBN,
-		b.Status,
 		b.AccessLevel,
 		b.UpdatedBy.UUID,
 		b.ID,
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"app-bookstore/lib"
 	"context"
 	"time"
 
@@ -16,15 +17,18 @@ type CategoryModel struct {
 	CreatedBy uuid.UUID     `db:"created_by"`
 	UpdatedAt pq.NullTime   `db:"updated_at"`
 	UpdatedBy uuid.NullUUID `db:"updated_by"`
+	DeletedAt pq.NullTime   `db:"deleted_at"`
+	DeletedBy uuid.NullUUID `db:"deleted_by"`
 }
 
 type CategoryResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	CreatedAt time.Time `json:"created_at"`
-	CreatedBy uuid.UUID `json:"created_by"`
-	UpdatedAt time.Time `json:"updated_at"`
-	UpdatedBy uuid.UUID `json:"updated_by"`
+	ID        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	CreatedAt time.Time  `json:"created_at"`
+	CreatedBy uuid.UUID  `json:"created_by"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	UpdatedBy uuid.UUID  `json:"updated_by"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 func (c *CategoryModel) Response() CategoryResponse {
@@ -35,20 +39,41 @@ func (c *CategoryModel) Response() CategoryResponse {
 		CreatedBy: c.CreatedBy,
 		UpdatedAt: c.UpdatedAt.Time,
 		UpdatedBy: c.UpdatedBy.UUID,
+		DeletedAt: nullTime(c.DeletedAt),
 	}
 }
 
-func GetAllCategory(ctx context.Context, db *sqlx.DB) ([]CategoryModel, error) {
-	query := `
-		SELECT
-			id, name, created_at, created_by, updated_at, updated_by
+// categorySortColumns lists the columns the category list may be sorted by.
+var categorySortColumns = map[string]string{
+	"created_at": "created_at",
+	"name":       "name",
+}
+
+func GetAllCategory(ctx context.Context, db *sqlx.DB, filter lib.Filter) ([]CategoryModel, int64, error) {
+	qb := lib.NewQueryBuilder()
+	if !filter.IncludeDeleted {
+		qb.Where("deleted_at IS NULL")
+	}
+	qb.Page(filter, categorySortColumns, "created_at", "created_at", "id")
+
+	from := `
 		FROM
 			categories
 	`
 
-	rows, err := db.QueryxContext(ctx, query)
+	total, err := countRows(ctx, db, from, qb)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT
+			id, name, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by
+	` + from + qb.Clauses()
+
+	rows, err := db.QueryxContext(ctx, query, qb.Args()...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	defer rows.Close()
@@ -58,12 +83,12 @@ func GetAllCategory(ctx context.Context, db *sqlx.DB) ([]CategoryModel, error) {
 		var category CategoryModel
 		err := rows.StructScan(&category)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		categories = append(categories, category)
 	}
 
-	return categories, nil
+	return categories, total, nil
 }
 
 func GetOneCategories(ctx context.Context, db *sqlx.DB, id uuid.UUID) (CategoryModel, error) {
@@ -74,6 +99,7 @@ func GetOneCategories(ctx context.Context, db *sqlx.DB, id uuid.UUID) (CategoryM
 			categories
 		WHERE 
 			id = $1
+		AND deleted_at IS NULL
 	`
 
 	categoryRequest := CategoryModel{}
@@ -119,6 +145,7 @@ func (c *CategoryModel) Update(ctx context.Context, db *sqlx.DB, id uuid.UUID) e
 			updated_by = $3
         WHERE
 			id = $4
+        AND deleted_at IS NULL
 		RETURNING id, name, created_at, created_by, updated_at, updated_by
 	`
 
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// EmailVerificationModel is a pending email address of a user. The address
+// only replaces users.email once the token, stored as a hash, is used.
+type EmailVerificationModel struct {
+	ID        uuid.UUID `db:"id"`
+	UserID    uuid.UUID `db:"user_id"`
+	Email     string    `db:"email"`
+	TokenHash string    `db:"token_hash"`
+	ExpiresAt time.Time `db:"expires_at"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+func (ev *EmailVerificationModel) Insert(ctx context.Context, db sqlx.ExtContext) error {
+	query := `
+		INSERT INTO email_verifications (
+			user_id, email, token_hash, expires_at
+		) VALUES (
+			$1, $2, $3, $4
+		) RETURNING id, created_at
+	`
+
+	err := db.QueryRowxContext(ctx, query,
+		ev.UserID,
+		ev.Email,
+		ev.TokenHash,
+		ev.ExpiresAt,
+	).Scan(
+		&ev.ID,
+		&ev.CreatedAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetPendingEmail is the address the user last asked to verify, if its token
+// has not expired.
+func GetPendingEmail(ctx context.Context, db sqlx.QueryerContext, userID uuid.UUID) (string, error) {
+	query := `
+		SELECT email FROM email_verifications
+		WHERE user_id = $1 AND expires_at > NOW()
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	var email string
+	err := db.QueryRowxContext(ctx, query, userID).Scan(&email)
+	if err != nil {
+		return "", err
+	}
+	return email, nil
+}
+
+// ConsumeEmailVerification deletes the token and returns what it verifies.
+// It returns sql.ErrNoRows for an unknown or expired token.
+func ConsumeEmailVerification(ctx context.Context, db sqlx.QueryerContext, tokenHash string) (EmailVerificationModel, error) {
+	query := `
+		DELETE FROM email_verifications
+		WHERE token_hash = $1 AND expires_at > NOW()
+		RETURNING id, user_id, email, token_hash, expires_at, created_at
+	`
+
+	verification := EmailVerificationModel{}
+	err := db.QueryRowxContext(ctx, query, tokenHash).StructScan(&verification)
+	if err != nil {
+		return verification, err
+	}
+
+	return verification, nil
+}
+
+// DeleteUserEmailVerifications invalidates every pending verification of the user.
+func DeleteUserEmailVerifications(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM email_verifications WHERE user_id = $1`, userID)
+	return err
+}
+
+// PurgeExpiredEmailVerifications deletes verification tokens that can no longer be used.
+func PurgeExpiredEmailVerifications(ctx context.Context, db sqlx.ExtContext) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM email_verifications WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
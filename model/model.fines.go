@@ -0,0 +1,126 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type FineModel struct {
+	ID          uuid.UUID     `db:"id"`
+	LoanID      uuid.UUID     `db:"loan_id"`
+	MemberID    uuid.UUID     `db:"member_id"`
+	DaysOverdue int           `db:"days_overdue"`
+	DailyRate   int64         `db:"daily_rate"`
+	Amount      int64         `db:"amount"`
+	Status      string        `db:"status"`
+	SettledAt   pq.NullTime   `db:"settled_at"`
+	CreatedAt   time.Time     `db:"created_at"`
+	CreatedBy   uuid.UUID     `db:"created_by"`
+	UpdatedAt   pq.NullTime   `db:"updated_at"`
+	UpdatedBy   uuid.NullUUID `db:"updated_by"`
+}
+
+type FineResponse struct {
+	ID          uuid.UUID `json:"id"`
+	LoanID      uuid.UUID `json:"loan_id"`
+	MemberID    uuid.UUID `json:"member_id"`
+	DaysOverdue int       `json:"days_overdue"`
+	DailyRate   int64     `json:"daily_rate"`
+	Amount      int64     `json:"amount"`
+	Status      string    `json:"status"`
+	SettledAt   time.Time `json:"settled_at"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (f *FineModel) Response() FineResponse {
+	return FineResponse{
+		ID:          f.ID,
+		LoanID:      f.LoanID,
+		MemberID:    f.MemberID,
+		DaysOverdue: f.DaysOverdue,
+		DailyRate:   f.DailyRate,
+		Amount:      f.Amount,
+		Status:      f.Status,
+		SettledAt:   f.SettledAt.Time,
+		CreatedAt:   f.CreatedAt,
+		UpdatedAt:   f.UpdatedAt.Time,
+	}
+}
+
+func GetFinesByMember(ctx context.Context, db *sqlx.DB, memberID uuid.UUID) ([]FineModel, error) {
+	query := `
+		SELECT
+			id, loan_id, member_id, days_overdue, daily_rate, amount, status, settled_at, created_at, created_by, updated_at, updated_by
+		FROM
+			fines
+		WHERE
+			member_id = $1
+		ORDER BY created_at DESC
+	`
+
+	rows, err := db.QueryxContext(ctx, query, memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var fines []FineModel
+	for rows.Next() {
+		var fine FineModel
+		err := rows.StructScan(&fine)
+		if err != nil {
+			return nil, err
+		}
+		fines = append(fines, fine)
+	}
+
+	return fines, nil
+}
+
+// Upsert records the fine for a loan. Settled fines are never changed again.
+func (f *FineModel) Upsert(ctx context.Context, db sqlx.ExtContext) error {
+	query := `
+		INSERT INTO fines (
+			loan_id, member_id, days_overdue, daily_rate, amount, status, settled_at, created_by
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8
+		)
+		ON CONFLICT (loan_id) DO UPDATE SET
+			days_overdue = EXCLUDED.days_overdue,
+			daily_rate = EXCLUDED.daily_rate,
+			amount = EXCLUDED.amount,
+			status = EXCLUDED.status,
+			settled_at = EXCLUDED.settled_at,
+			updated_at = NOW(),
+			updated_by = EXCLUDED.created_by
+		WHERE
+			fines.status = 'accruing'
+		RETURNING id, created_at
+	`
+
+	err := db.QueryRowxContext(ctx, query,
+		f.LoanID,
+		f.MemberID,
+		f.DaysOverdue,
+		f.DailyRate,
+		f.Amount,
+		f.Status,
+		f.SettledAt,
+		f.CreatedBy,
+	).Scan(
+		&f.ID,
+		&f.CreatedAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
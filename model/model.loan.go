@@ -3,8 +3,6 @@ package model
 import (
 	"app-bookstore/lib"
 	"context"
-	"fmt"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,60 +12,81 @@ import (
 )
 
 type LoansModel struct {
-	ID         uuid.UUID     `db:"id"`
-	BookID     uuid.UUID     `db:"book_id"`
-	MemberID   uuid.UUID     `db:"member_id"`
-	LoanDate   time.Time     `db:"loan_date"`
-	ReturnDate pq.NullTime   `db:"return_date"`
-	Status     string        `db:"status"`
-	CreatedAt  time.Time     `db:"created_at"`
-	CreatedBy  uuid.UUID     `db:"created_by"`
-	UpdatedAt  pq.NullTime   `db:"updated_at"`
-	UpdatedBy  uuid.NullUUID `db:"updated_by"`
+	ID           uuid.UUID     `db:"id"`
+	BookID       uuid.UUID     `db:"book_id"`
+	CopyID       uuid.UUID     `db:"copy_id"`
+	MemberID     uuid.UUID     `db:"member_id"`
+	LoanDate     time.Time     `db:"loan_date"`
+	DueDate      time.Time     `db:"due_date"`
+	ReturnDate   pq.NullTime   `db:"return_date"`
+	RenewalCount int           `db:"renewal_count"`
+	Status       string        `db:"status"`
+	CreatedAt    time.Time     `db:"created_at"`
+	CreatedBy    uuid.UUID     `db:"created_by"`
+	UpdatedAt    pq.NullTime   `db:"updated_at"`
+	UpdatedBy    uuid.NullUUID `db:"updated_by"`
 }
 
 type LoansResponse struct {
-	ID         uuid.UUID `db:"id"`
-	BookID     uuid.UUID `db:"book_id"`
-	MemberID   uuid.UUID `db:"member_id"`
-	LoanDate   time.Time `db:"loan_date"`
-	ReturnDate time.Time `db:"return_date"`
-	Status     string    `db:"status"`
-	CreatedAt  time.Time `db:"created_at"`
-	CreatedBy  uuid.UUID `db:"created_by"`
-	UpdatedAt  time.Time `db:"updated_at"`
-	UpdatedBy  uuid.UUID `db:"updated_by"`
+	ID           uuid.UUID `db:"id"`
+	BookID       uuid.UUID `db:"book_id"`
+	CopyID       uuid.UUID `db:"copy_id"`
+	MemberID     uuid.UUID `db:"member_id"`
+	LoanDate     time.Time `db:"loan_date"`
+	DueDate      time.Time `db:"due_date"`
+	ReturnDate   time.Time `db:"return_date"`
+	RenewalCount int       `db:"renewal_count"`
+	Status       string    `db:"status"`
+	CreatedAt    time.Time `db:"created_at"`
+	CreatedBy    uuid.UUID `db:"created_by"`
+	UpdatedAt    time.Time `db:"updated_at"`
+	UpdatedBy    uuid.UUID `db:"updated_by"`
 }
 
 func (l *LoansModel) Response() LoansResponse {
 	return LoansResponse{
-		ID:         l.ID,
-		BookID:     l.BookID,
-		MemberID:   l.MemberID,
-		LoanDate:   l.LoanDate,
-		ReturnDate: l.ReturnDate.Time,
-		Status:     l.Status,
-		CreatedAt:  l.CreatedAt,
-		CreatedBy:  l.CreatedBy,
-		UpdatedAt:  l.UpdatedAt.Time,
-		UpdatedBy:  l.UpdatedBy.UUID,
+		ID:           l.ID,
+		BookID:       l.BookID,
+		CopyID:       l.CopyID,
+		MemberID:     l.MemberID,
+		LoanDate:     l.LoanDate,
+		DueDate:      l.DueDate,
+		ReturnDate:   l.ReturnDate.Time,
+		RenewalCount: l.RenewalCount,
+		Status:       l.Status,
+		CreatedAt:    l.CreatedAt,
+		CreatedBy:    l.CreatedBy,
+		UpdatedAt:    l.UpdatedAt.Time,
+		UpdatedBy:    l.UpdatedBy.UUID,
 	}
 }
 
-func GetAllLoans(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter DateFilter) ([]LoansModel, error) {
-	var filters []string
+// loanSortColumns lists the columns GET /loans may be sorted by.
+var loanSortColumns = map[string]string{
+	"created_at": "l.created_at",
+	"loan_date":  "l.loan_date",
+	"due_date":   "l.due_date",
+}
+
+func GetAllLoans(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter DateFilter) ([]LoansModel, int64, error) {
+	qb := lib.NewQueryBuilder()
 	var statuses []string
 
 	if filter.Search != "" {
-		filters = append(filters, fmt.Sprintf("b.title ILIKE '%%%s%%'", filter.Search))
+		qb.WhereContains("b.title", filter.Search)
 	}
 
 	if filter.BookID != uuid.Nil {
-		filters = append(filters, fmt.Sprintf("l.book_id = '%s'", filter.BookID))
+		qb.Where("l.book_id = ?", filter.BookID)
 	}
 
 	if filter.MemberID != uuid.Nil {
-		filters = append(filters, fmt.Sprintf("l.member_id = '%s'", filter.MemberID))
+		qb.Where("l.member_id = ?", filter.MemberID)
+	}
+
+	// EXISTS supaya member dengan beberapa role tidak muncul berulang
+	if filter.RoleID != uuid.Nil {
+		qb.Where("EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = l.member_id AND ur.role_id = ?)", filter.RoleID)
 	}
 
 	log.Logger.Println(filter.MemberID)
@@ -76,58 +95,58 @@ func GetAllLoans(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter
 		statuses = append(statuses, lib.Borrowed)
 	}
 
+	if filter.Overdue {
+		statuses = append(statuses, lib.Overdue)
+	}
+
 	if filter.Returned {
 		statuses = append(statuses, lib.Returned)
 	}
 
 	if len(statuses) > 0 {
-		placeHOlders := make([]string, len(statuses))
-		for i, status := range statuses {
-			placeHOlders[i] = fmt.Sprintf("'%s'", status)
-		}
-
-		filters = append(filters, fmt.Sprintf("l.status IN (%s)", strings.Join(placeHOlders, ", ")))
+		qb.WhereIn("l.status", statuses)
 	}
 
 	if !dateFilter.StartDate.IsZero() && !dateFilter.EndDate.IsZero() {
-		filters = append(filters, fmt.Sprintf(
-			"l.loan_date BETWEEN '%s' AND '%s'",
-			dateFilter.StartDate.Format("2006-01-02"),
-			dateFilter.EndDate.Format("2006-01-02"),
-		))
+		qb.Where("l.loan_date BETWEEN ? AND ?", dateFilter.StartDate, dateFilter.EndDate)
 	}
 
-	query := fmt.Sprintf(
-		`
+	qb.Page(filter, loanSortColumns, "created_at", "l.created_at", "l.id")
+
+	from := `
+		FROM
+			loans l
+		INNER JOIN
+			books b
+		ON
+			b.id = l.book_id
+	`
+
+	total, err := countRows(ctx, db, from, qb)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
 		SELECT 
 			l.id, 
 			l.book_id, 
+			l.copy_id, 
 			l.member_id, 
 			l.loan_date, 
+			l.due_date, 
 			l.return_date, 
+			l.renewal_count, 
 			l.status, 
 			l.created_at, 
 			l.created_by, 
 			l.updated_at, 
 			l.updated_by
-		FROM
-			loans l
-		INNER JOIN
-			books b
-		ON
-			b.id = l.book_id
-		INNER JOIN
-			user_roles ur
-		ON
-			ur.user_id = l.member_id
-		%s 
-		ORDER BY l.created_at %s
-		LIMIT $1 OFFSET $2
-	`, lib.SearchGenerate(ctx, "AND", filters), filter.Dir)
+	` + from + qb.Clauses()
 
-	rows, err := db.QueryxContext(ctx, query, filter.Limit, filter.Offset)
+	rows, err := db.QueryxContext(ctx, query, qb.Args()...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	defer rows.Close()
@@ -137,18 +156,18 @@ func GetAllLoans(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter
 		var loan LoansModel
 		err := rows.StructScan(&loan)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		loans = append(loans, loan)
 	}
 
-	return loans, nil
+	return loans, total, nil
 }
 
 func GetOneLoans(ctx context.Context, db *sqlx.DB, id uuid.UUID) (LoansModel, error) {
 	query := `
 		SELECT
-			id, book_id, member_id, loan_date, return_date, status, created_at, created_by, updated_at, updated_by
+			id, book_id, copy_id, member_id, loan_date, due_date, return_date, renewal_count, status, created_at, created_by, updated_at, updated_by
 		FROM 
 			loans
 		WHERE
@@ -163,20 +182,22 @@ func GetOneLoans(ctx context.Context, db *sqlx.DB, id uuid.UUID) (LoansModel, er
 	return loan, nil
 }
 
-func (l *LoansModel) Insert(ctx context.Context, db *sqlx.DB) error {
+func (l *LoansModel) Insert(ctx context.Context, db sqlx.ExtContext) error {
 	query := `
 		INSERT INTO loans (
-			id, book_id, member_id, loan_date, return_date, status, created_at, created_by
+			id, book_id, copy_id, member_id, loan_date, due_date, return_date, status, created_at, created_by
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8 
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
 		) RETURNING id, created_at
 	`
 
 	err := db.QueryRowxContext(ctx, query,
 		l.ID,
 		l.BookID,
+		l.CopyID,
 		l.MemberID,
 		l.LoanDate,
+		l.DueDate,
 		l.ReturnDate,
 		l.Status,
 		l.CreatedAt,
@@ -193,7 +214,9 @@ func (l *LoansModel) Insert(ctx context.Context, db *sqlx.DB) error {
 	return nil
 }
 
-func (l *LoansModel) Update(ctx context.Context, db *sqlx.DB, id uuid.UUID) error {
+// Update closes the loan. It returns sql.ErrNoRows when the loan has already
+// been returned, so a concurrent return cannot run twice.
+func (l *LoansModel) Update(ctx context.Context, db sqlx.ExtContext, id uuid.UUID) error {
 	query := `
 		UPDATE
 			loans
@@ -204,7 +227,8 @@ func (l *LoansModel) Update(ctx context.Context, db *sqlx.DB, id uuid.UUID) erro
 			updated_by = $4
 		WHERE
 			id = $5
-		RETURNING id, book_id, member_id, loan_date, return_date, status, created_at, created_by, updated_at, updated_by
+		AND status <> 'returned'
+		RETURNING id, book_id, copy_id, member_id, loan_date, due_date, return_date, renewal_count, status, created_at, created_by, updated_at, updated_by
 	`
 
 	err := db.QueryRowxContext(ctx, query,
@@ -216,9 +240,12 @@ func (l *LoansModel) Update(ctx context.Context, db *sqlx.DB, id uuid.UUID) erro
 	).Scan(
 		&l.ID,
 		&l.BookID,
+		&l.CopyID,
 		&l.MemberID,
 		&l.LoanDate,
+		&l.DueDate,
 		&l.ReturnDate,
+		&l.RenewalCount,
 		&l.Status,
 		&l.CreatedAt,
 		&l.CreatedBy,
@@ -231,3 +258,89 @@ func (l *LoansModel) Update(ctx context.Context, db *sqlx.DB, id uuid.UUID) erro
 	}
 	return nil
 }
+
+// GetOpenLoansDueBefore returns loans that are still out and were due before day.
+func GetOpenLoansDueBefore(ctx context.Context, db *sqlx.DB, day time.Time) ([]LoansModel, error) {
+	query := `
+		SELECT
+			id, book_id, copy_id, member_id, loan_date, due_date, return_date, renewal_count, status, created_at, created_by, updated_at, updated_by
+		FROM
+			loans
+		WHERE
+			return_date IS NULL
+		AND due_date < $1
+		ORDER BY due_date
+	`
+
+	rows, err := db.QueryxContext(ctx, query, day)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var loans []LoansModel
+	for rows.Next() {
+		var loan LoansModel
+		err := rows.StructScan(&loan)
+		if err != nil {
+			return nil, err
+		}
+		loans = append(loans, loan)
+	}
+
+	return loans, nil
+}
+
+func MarkLoanOverdue(ctx context.Context, db *sqlx.DB, id uuid.UUID) error {
+	query := `
+		UPDATE
+			loans
+		SET
+			status = 'overdue',
+			updated_at = NOW()
+		WHERE
+			id = $1
+		AND status = 'borrowed'
+	`
+
+	_, err := db.ExecContext(ctx, query, id)
+	return err
+}
+
+// Renew moves the due date forward. It fails with sql.ErrNoRows when the loan
+// was renewed, returned or flagged overdue concurrently.
+func (l *LoansModel) Renew(ctx context.Context, db sqlx.ExtContext, newDueDate time.Time, updatedBy uuid.UUID) error {
+	query := `
+		UPDATE
+			loans
+		SET
+			due_date = $1,
+			renewal_count = renewal_count + 1,
+			updated_at = NOW(),
+			updated_by = $2
+		WHERE
+			id = $3
+		AND renewal_count = $4
+		AND status = 'borrowed'
+		RETURNING due_date, renewal_count, updated_at, updated_by
+	`
+
+	err := db.QueryRowxContext(ctx, query,
+		newDueDate,
+		updatedBy,
+		l.ID,
+		l.RenewalCount,
+	).Scan(
+		&l.DueDate,
+		&l.RenewalCount,
+		&l.UpdatedAt,
+		&l.UpdatedBy,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
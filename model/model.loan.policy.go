@@ -0,0 +1,204 @@
+package model
+
+import (
+	"app-bookstore/lib"
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type LoanPolicyModel struct {
+	ID          uuid.UUID     `db:"id"`
+	RoleID      uuid.UUID     `db:"role_id"`
+	LoanDays    int           `db:"loan_days"`
+	DailyFine   int64         `db:"daily_fine"`
+	MaxRenewals int           `db:"max_renewals"`
+	CreatedAt   time.Time     `db:"created_at"`
+	CreatedBy   uuid.UUID     `db:"created_by"`
+	UpdatedAt   pq.NullTime   `db:"updated_at"`
+	UpdatedBy   uuid.NullUUID `db:"updated_by"`
+}
+
+type LoanPolicyResponse struct {
+	ID          uuid.UUID `json:"id"`
+	RoleID      uuid.UUID `json:"role_id"`
+	LoanDays    int       `json:"loan_days"`
+	DailyFine   int64     `json:"daily_fine"`
+	MaxRenewals int       `json:"max_renewals"`
+	CreatedAt   time.Time `json:"created_at"`
+	CreatedBy   uuid.UUID `json:"created_by"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	UpdatedBy   uuid.UUID `json:"updated_by"`
+}
+
+func (lp *LoanPolicyModel) Response() LoanPolicyResponse {
+	return LoanPolicyResponse{
+		ID:          lp.ID,
+		RoleID:      lp.RoleID,
+		LoanDays:    lp.LoanDays,
+		DailyFine:   lp.DailyFine,
+		MaxRenewals: lp.MaxRenewals,
+		CreatedAt:   lp.CreatedAt,
+		CreatedBy:   lp.CreatedBy,
+		UpdatedAt:   lp.UpdatedAt.Time,
+		UpdatedBy:   lp.UpdatedBy.UUID,
+	}
+}
+
+// loanPolicySortColumns lists the columns the loan policy list may be sorted by.
+var loanPolicySortColumns = map[string]string{
+	"created_at": "created_at",
+	"loan_days":  "loan_days",
+}
+
+func GetAllLoanPolicies(ctx context.Context, db *sqlx.DB, filter lib.Filter) ([]LoanPolicyModel, int64, error) {
+	qb := lib.NewQueryBuilder()
+	qb.Page(filter, loanPolicySortColumns, "created_at", "created_at", "id")
+
+	from := `
+		FROM loan_policies
+	`
+
+	total, err := countRows(ctx, db, from, qb)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, role_id, loan_days, daily_fine, max_renewals, created_at, created_by, updated_at, updated_by
+	` + from + qb.Clauses()
+
+	rows, err := db.QueryxContext(ctx, query, qb.Args()...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	defer rows.Close()
+
+	var policies []LoanPolicyModel
+	for rows.Next() {
+		var policy LoanPolicyModel
+		err := rows.StructScan(&policy)
+		if err != nil {
+			return nil, 0, err
+		}
+		policies = append(policies, policy)
+	}
+
+	return policies, total, nil
+}
+
+func GetOneLoanPolicy(ctx context.Context, db *sqlx.DB, id uuid.UUID) (LoanPolicyModel, error) {
+	query := `
+		SELECT id, role_id, loan_days, daily_fine, max_renewals, created_at, created_by, updated_at, updated_by
+		FROM loan_policies
+		WHERE id = $1
+	`
+
+	policy := LoanPolicyModel{}
+	err := db.QueryRowxContext(ctx, query, id).StructScan(&policy)
+	if err != nil {
+		return policy, err
+	}
+
+	return policy, nil
+}
+
+// GetLoanPolicyForUser returns the most generous policy among the user's roles.
+func GetLoanPolicyForUser(ctx context.Context, db *sqlx.DB, userID uuid.UUID) (LoanPolicyModel, error) {
+	query := `
+		SELECT
+			lp.id, lp.role_id, lp.loan_days, lp.daily_fine, lp.max_renewals, lp.created_at, lp.created_by, lp.updated_at, lp.updated_by
+		FROM
+			loan_policies lp
+		INNER JOIN
+			user_roles ur
+		ON
+			ur.role_id = lp.role_id
+		WHERE
+			ur.user_id = $1
+		ORDER BY lp.loan_days DESC, lp.daily_fine ASC
+		LIMIT 1
+	`
+
+	policy := LoanPolicyModel{}
+	err := db.QueryRowxContext(ctx, query, userID).StructScan(&policy)
+	if err != nil {
+		return policy, err
+	}
+
+	return policy, nil
+}
+
+func (lp *LoanPolicyModel) Insert(ctx context.Context, db *sqlx.DB) error {
+	query := `
+		INSERT INTO loan_policies (
+			id, role_id, loan_days, daily_fine, max_renewals, created_at, created_by
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7
+		) RETURNING id, created_at
+	`
+
+	err := db.QueryRowxContext(ctx, query,
+		lp.ID,
+		lp.RoleID,
+		lp.LoanDays,
+		lp.DailyFine,
+		lp.MaxRenewals,
+		lp.CreatedAt,
+		lp.CreatedBy,
+	).Scan(
+		&lp.ID,
+		&lp.CreatedAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (lp *LoanPolicyModel) Update(ctx context.Context, db *sqlx.DB) error {
+	query := `
+		UPDATE
+			loan_policies
+		SET
+			loan_days = $1,
+			daily_fine = $2,
+			max_renewals = $3,
+			updated_at = $4,
+			updated_by = $5
+		WHERE
+			id = $6
+		RETURNING id, role_id, loan_days, daily_fine, max_renewals, created_at, created_by, updated_at, updated_by
+	`
+
+	err := db.QueryRowxContext(ctx, query,
+		lp.LoanDays,
+		lp.DailyFine,
+		lp.MaxRenewals,
+		lp.UpdatedAt.Time,
+		lp.UpdatedBy.UUID,
+		lp.ID,
+	).Scan(
+		&lp.ID,
+		&lp.RoleID,
+		&lp.LoanDays,
+		&lp.DailyFine,
+		&lp.MaxRenewals,
+		&lp.CreatedAt,
+		&lp.CreatedBy,
+		&lp.UpdatedAt,
+		&lp.UpdatedBy,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
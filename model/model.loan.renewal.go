@@ -0,0 +1,86 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type LoanRenewalModel struct {
+	ID              uuid.UUID `db:"id"`
+	LoanID          uuid.UUID `db:"loan_id"`
+	PreviousDueDate time.Time `db:"previous_due_date"`
+	NewDueDate      time.Time `db:"new_due_date"`
+	CreatedAt       time.Time `db:"created_at"`
+	CreatedBy       uuid.UUID `db:"created_by"`
+}
+
+type LoanRenewalResponse struct {
+	ID              uuid.UUID `json:"id"`
+	LoanID          uuid.UUID `json:"loan_id"`
+	PreviousDueDate time.Time `json:"previous_due_date"`
+	NewDueDate      time.Time `json:"new_due_date"`
+	CreatedAt       time.Time `json:"created_at"`
+	CreatedBy       uuid.UUID `json:"created_by"`
+}
+
+func (lr *LoanRenewalModel) Response() LoanRenewalResponse {
+	return LoanRenewalResponse{
+		ID:              lr.ID,
+		LoanID:          lr.LoanID,
+		PreviousDueDate: lr.PreviousDueDate,
+		NewDueDate:      lr.NewDueDate,
+		CreatedAt:       lr.CreatedAt,
+		CreatedBy:       lr.CreatedBy,
+	}
+}
+
+func GetLoanRenewals(ctx context.Context, db *sqlx.DB, loanID uuid.UUID) ([]LoanRenewalModel, error) {
+	query := `
+		SELECT
+			id, loan_id, previous_due_date, new_due_date, created_at, created_by
+		FROM
+			loan_renewals
+		WHERE
+			loan_id = $1
+		ORDER BY created_at
+	`
+
+	var renewals []LoanRenewalModel
+	err := db.SelectContext(ctx, &renewals, query, loanID)
+	if err != nil {
+		return nil, err
+	}
+
+	return renewals, nil
+}
+
+func (lr *LoanRenewalModel) Insert(ctx context.Context, db sqlx.ExtContext) error {
+	query := `
+		INSERT INTO loan_renewals (
+			id, loan_id, previous_due_date, new_due_date, created_at, created_by
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		) RETURNING id, created_at
+	`
+
+	err := db.QueryRowxContext(ctx, query,
+		lr.ID,
+		lr.LoanID,
+		lr.PreviousDueDate,
+		lr.NewDueDate,
+		lr.CreatedAt,
+		lr.CreatedBy,
+	).Scan(
+		&lr.ID,
+		&lr.CreatedAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,145 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// LoginAttemptModel counts the recent failed logins of one username or client
+// IP. Key is "username:<name>" or "ip:<address>". Attempts still being checked
+// are counted too, until a correct one is released.
+type LoginAttemptModel struct {
+	Key          string      `db:"key"`
+	Failures     int         `db:"failures"`
+	LastFailedAt time.Time   `db:"last_failed_at"`
+	LockedUntil  pq.NullTime `db:"locked_until"`
+}
+
+func GetLoginAttempts(ctx context.Context, db sqlx.QueryerContext, keys []string) ([]LoginAttemptModel, error) {
+	query := `
+		SELECT key, failures, last_failed_at, locked_until
+		FROM login_attempts
+		WHERE key = ANY($1)
+	`
+
+	var attempts []LoginAttemptModel
+	err := sqlx.SelectContext(ctx, db, &attempts, query, pq.Array(keys))
+	if err != nil {
+		return nil, err
+	}
+
+	return attempts, nil
+}
+
+// AttemptLimit is how one login_attempts key is throttled. A Threshold of 0
+// never locks and a BackoffBase of 0 never backs off; the backoff doubles with
+// every failure up to BackoffLimit.
+type AttemptLimit struct {
+	Window       time.Duration
+	Threshold    int
+	Lockout      time.Duration
+	BackoffBase  time.Duration
+	BackoffLimit time.Duration
+}
+
+// ReserveLoginAttempt counts an attempt for key before it is checked, so that
+// parallel attempts cannot all pass a check made before any of them failed.
+// The count starts over when the last attempt is older than the window or a
+// lockout has ended; reaching the threshold locks the key. It reports false,
+// without counting, while the key is locked or still backing off.
+func ReserveLoginAttempt(ctx context.Context, db sqlx.QueryerContext, key string, limit AttemptLimit) (LoginAttemptModel, bool, error) {
+	query := `
+		INSERT INTO login_attempts (key, failures, last_failed_at, locked_until)
+		VALUES ($1, 1, NOW(), CASE WHEN $3 = 1 THEN NOW() + make_interval(secs => $4) END)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.last_failed_at < NOW() - make_interval(secs => $2)
+					OR login_attempts.locked_until <= NOW() THEN 1
+				ELSE login_attempts.failures + 1
+			END,
+			last_failed_at = NOW(),
+			locked_until = CASE
+				WHEN login_attempts.locked_until > NOW() THEN login_attempts.locked_until
+				WHEN login_attempts.last_failed_at >= NOW() - make_interval(secs => $2)
+					AND login_attempts.locked_until IS NULL
+					AND $3 > 0 AND login_attempts.failures + 1 >= $3 THEN NOW() + make_interval(secs => $4)
+			END
+		WHERE NOT (
+			COALESCE(login_attempts.locked_until > NOW(), FALSE)
+			OR (
+				login_attempts.failures > 0
+				AND login_attempts.last_failed_at
+					+ make_interval(secs => LEAST($5 * POWER(2, LEAST(login_attempts.failures - 1, 30)), $6)) > NOW()
+			)
+		)
+		RETURNING key, failures, last_failed_at, locked_until
+	`
+
+	attempt := LoginAttemptModel{}
+	err := db.QueryRowxContext(ctx, query,
+		key,
+		limit.Window.Seconds(),
+		limit.Threshold,
+		limit.Lockout.Seconds(),
+		limit.BackoffBase.Seconds(),
+		limit.BackoffLimit.Seconds(),
+	).StructScan(&attempt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return attempt, false, nil
+		}
+		return attempt, false, err
+	}
+
+	return attempt, true, nil
+}
+
+// ReleaseLoginAttempt gives back an attempt reserved for key that turned out
+// to be correct, lifting the lockout when that attempt was what reached the
+// threshold.
+func ReleaseLoginAttempt(ctx context.Context, db sqlx.ExtContext, key string, threshold int) error {
+	query := `
+		UPDATE login_attempts
+		SET
+			failures = failures - 1,
+			locked_until = CASE WHEN failures - 1 < $2 THEN NULL ELSE locked_until END
+		WHERE key = $1
+		AND failures > 0
+	`
+
+	_, err := db.ExecContext(ctx, query, key, threshold)
+	return err
+}
+
+// ClearLoginAttempts forgets the failures of the keys, which also lifts their
+// lockouts. It returns how many keys had failures.
+func ClearLoginAttempts(ctx context.Context, db sqlx.ExtContext, keys []string) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM login_attempts WHERE key = ANY($1)`, pq.Array(keys))
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// PurgeLoginAttempts deletes counters that are no longer locked and whose last
+// failure is older than window.
+func PurgeLoginAttempts(ctx context.Context, db sqlx.ExtContext, window time.Duration) (int64, error) {
+	query := `
+		DELETE FROM login_attempts
+		WHERE last_failed_at < NOW() - make_interval(secs => $1)
+		AND (locked_until IS NULL OR locked_until <= NOW())
+	`
+
+	result, err := db.ExecContext(ctx, query, window.Seconds())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
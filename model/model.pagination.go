@@ -0,0 +1,23 @@
+package model
+
+import (
+	"app-bookstore/lib"
+	"context"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// countRows counts the rows a list query matches before pagination. from is the
+// FROM clause of that query, joins included.
+func countRows(ctx context.Context, db *sqlx.DB, from string, qb *lib.QueryBuilder) (int64, error) {
+	query := fmt.Sprintf("SELECT COUNT(*) %s %s", from, qb.WhereClause())
+
+	var total int64
+	err := db.GetContext(ctx, &total, query, qb.CountArgs()...)
+	if err != nil {
+		return 0, err
+	}
+
+	return total, nil
+}
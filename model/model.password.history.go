@@ -0,0 +1,50 @@
+package model
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// GetPasswordHistory returns the newest limit password hashes of the user.
+func GetPasswordHistory(ctx context.Context, db sqlx.QueryerContext, userID uuid.UUID, limit int) ([]string, error) {
+	query := `
+		SELECT password_hash
+		FROM password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id
+		LIMIT $2
+	`
+
+	var hashes []string
+	err := sqlx.SelectContext(ctx, db, &hashes, query, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return hashes, nil
+}
+
+// AddPasswordHistory records a newly set password hash and drops all but the
+// newest keep entries of the user.
+func AddPasswordHistory(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, passwordHash string, keep int) error {
+	_, err := db.ExecContext(ctx, `INSERT INTO password_history (user_id, password_hash, created_at) VALUES ($1, $2, clock_timestamp())`, userID, passwordHash)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		DELETE FROM password_history
+		WHERE user_id = $1
+		AND id NOT IN (
+			SELECT id FROM password_history
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id
+			LIMIT $2
+		)
+	`
+
+	_, err = db.ExecContext(ctx, query, userID, keep)
+	return err
+}
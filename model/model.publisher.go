@@ -3,7 +3,6 @@ package model
 import (
 	"app-bookstore/lib"
 	"context"
-	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -20,17 +19,20 @@ type PublisherModel struct {
 	CreatedBy uuid.UUID     `db:"created_by"`
 	UpdatedAt pq.NullTime   `db:"updated_at"`
 	UpdatedBy uuid.NullUUID `db:"updated_by"`
+	DeletedAt pq.NullTime   `db:"deleted_at"`
+	DeletedBy uuid.NullUUID `db:"deleted_by"`
 }
 
 type PublisherResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Address   string    `json:"address"`
-	Phone     string    `json:"phone"`
-	CreatedAt time.Time `json:"created_at"`
-	CreatedBy uuid.UUID `json:"created_by"`
-	UpdatedAt time.Time `json:"updated_at"`
-	UpdatedBy uuid.UUID `json:"updated_by"`
+	ID        uuid.UUID  `json:"id"`
+	Name      string     `json:"name"`
+	Address   string     `json:"address"`
+	Phone     string     `json:"phone"`
+	CreatedAt time.Time  `json:"created_at"`
+	CreatedBy uuid.UUID  `json:"created_by"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	UpdatedBy uuid.UUID  `json:"updated_by"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 func (p *PublisherModel) Response() PublisherResponse {
@@ -43,25 +45,42 @@ func (p *PublisherModel) Response() PublisherResponse {
 		CreatedBy: p.CreatedBy,
 		UpdatedAt: p.UpdatedAt.Time,
 		UpdatedBy: p.UpdatedBy.UUID,
+		DeletedAt: nullTime(p.DeletedAt),
 	}
 }
 
-func GetAllPublisher(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter DateFilter) ([]PublisherModel, error) {
-	var filters []string
+// publisherSortColumns lists the columns GET /publishers may be sorted by.
+var publisherSortColumns = map[string]string{
+	"created_at": "created_at",
+	"name":       "name",
+}
+
+func GetAllPublisher(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter DateFilter) ([]PublisherModel, int64, error) {
+	qb := lib.NewQueryBuilder()
+	if !filter.IncludeDeleted {
+		qb.Where("deleted_at IS NULL")
+	}
 	if filter.Search != "" {
-		filters = append(filters, fmt.Sprintf("name ILIKE '%%%s%%'", filter.Search))
+		qb.WhereContains("name", filter.Search)
 	}
 
 	if !dateFilter.StartDate.IsZero() && !dateFilter.EndDate.IsZero() {
-		filters = append(filters, fmt.Sprintf(
-			"created_at BETWEEN '%s' AND '%s'",
-			dateFilter.StartDate.Format("2006-01-02"),
-			dateFilter.EndDate.Format("2006-01-02"),
-		))
+		qb.Where("created_at BETWEEN ? AND ?", dateFilter.StartDate, dateFilter.EndDate)
 	}
 
-	query := fmt.Sprintf(
-		`
+	qb.Page(filter, publisherSortColumns, "created_at", "created_at", "id")
+
+	from := `
+		FROM
+			publishers 
+	`
+
+	total, err := countRows(ctx, db, from, qb)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
 		SELECT
 			id, 
 			name, 
@@ -70,17 +89,14 @@ func GetAllPublisher(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFi
 			created_at, 
 			created_by, 
 			updated_at, 
-			updated_by
-		FROM
-			publishers 
-		%s
-		ORDER BY created_at %s
-		LIMIT $1 OFFSET $2
-	`, lib.SearchGenerate(ctx, "AND", filters), filter.Dir)
+			updated_by,
+			deleted_at,
+			deleted_by
+	` + from + qb.Clauses()
 
-	rows, err := db.QueryxContext(ctx, query, filter.Limit, filter.Offset)
+	rows, err := db.QueryxContext(ctx, query, qb.Args()...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	defer rows.Close()
@@ -90,13 +106,13 @@ func GetAllPublisher(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFi
 		var publisher PublisherModel
 		err := rows.StructScan(&publisher)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		publishers = append(publishers, publisher)
 	}
 
-	return publishers, nil
+	return publishers, total, nil
 }
 
 func GetOnePublisher(ctx context.Context, db *sqlx.DB, id uuid.UUID) (PublisherModel, error) {
@@ -114,6 +130,7 @@ func GetOnePublisher(ctx context.Context, db *sqlx.DB, id uuid.UUID) (PublisherM
 			publishers
 		WHERE 
 			id = $1
+		AND deleted_at IS NULL
 	`
 	publisherRequest := PublisherModel{}
 	err := db.QueryRowxContext(ctx, query, id).StructScan(&publisherRequest)
@@ -169,6 +186,7 @@ func (p *PublisherModel) Update(ctx context.Context, db *sqlx.DB) error {
             updated_by = $5
 		WHERE
 			id = $6
+		AND deleted_at IS NULL
 		RETURNING id, name, address, phone, created_at, created_by, updated_at, updated_by
 	`
 
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"app-bookstore/lib"
 	"context"
 	"time"
 
@@ -19,18 +20,21 @@ type RatingModel struct {
 	CreatedBy uuid.UUID     `db:"created_by"`
 	UpdatedAt pq.NullTime   `db:"updated_at"`
 	UpdatedBy uuid.NullUUID `db:"updated_by"`
+	DeletedAt pq.NullTime   `db:"deleted_at"`
+	DeletedBy uuid.NullUUID `db:"deleted_by"`
 }
 
 type RatingResponse struct {
-	ID        uuid.UUID `json:"id"`
-	BookID    uuid.UUID `json:"book_id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Rating    int       `json:"rating"`
-	Review    string    `json:"review"`
-	CreatedAt time.Time `json:"created_at"`
-	CreatedBy uuid.UUID `json:"created_by"`
-	UpdatedAt time.Time `json:"updated_at"`
-	UpdatedBy uuid.UUID `json:"updated_by"`
+	ID        uuid.UUID  `json:"id"`
+	BookID    uuid.UUID  `json:"book_id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Rating    int        `json:"rating"`
+	Review    string     `json:"review"`
+	CreatedAt time.Time  `json:"created_at"`
+	CreatedBy uuid.UUID  `json:"created_by"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	UpdatedBy uuid.UUID  `json:"updated_by"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
 }
 
 func (rt *RatingModel) Response() RatingResponse {
@@ -44,20 +48,41 @@ func (rt *RatingModel) Response() RatingResponse {
 		CreatedBy: rt.CreatedBy,
 		UpdatedAt: rt.UpdatedAt.Time,
 		UpdatedBy: rt.UpdatedBy.UUID,
+		DeletedAt: nullTime(rt.DeletedAt),
 	}
 }
 
-func GetAllRatings(ctx context.Context, db *sqlx.DB) ([]RatingModel, error) {
-	query := `
-		SELECT
-			id, book_id, user_id, rating, review, created_at, created_by, updated_at, updated_by
+// ratingSortColumns lists the columns the rating list may be sorted by.
+var ratingSortColumns = map[string]string{
+	"created_at": "created_at",
+	"rating":     "rating",
+}
+
+func GetAllRatings(ctx context.Context, db *sqlx.DB, filter lib.Filter) ([]RatingModel, int64, error) {
+	qb := lib.NewQueryBuilder()
+	if !filter.IncludeDeleted {
+		qb.Where("deleted_at IS NULL")
+	}
+	qb.Page(filter, ratingSortColumns, "created_at", "created_at", "id")
+
+	from := `
 		FROM
 			ratings
 	`
 
-	rows, err := db.QueryxContext(ctx, query)
+	total, err := countRows(ctx, db, from, qb)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT
+			id, book_id, user_id, rating, review, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by
+	` + from + qb.Clauses()
+
+	rows, err := db.QueryxContext(ctx, query, qb.Args()...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	defer rows.Close()
@@ -67,12 +92,12 @@ func GetAllRatings(ctx context.Context, db *sqlx.DB) ([]RatingModel, error) {
 		var rating RatingModel
 		err := rows.StructScan(&rating)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		ratings = append(ratings, rating)
 	}
 
-	return ratings, nil
+	return ratings, total, nil
 }
 
 func GetOneRating(ctx context.Context, db *sqlx.DB, id uuid.UUID) (RatingModel, error) {
@@ -82,6 +107,7 @@ func GetOneRating(ctx context.Context, db *sqlx.DB, id uuid.UUID) (RatingModel,
         FROM 
             ratings
         WHERE id = $1
+        AND deleted_at IS NULL
     `
 
 	var rating RatingModel
@@ -133,6 +159,7 @@ func (rt *RatingModel) Update(ctx context.Context, db *sqlx.DB, id uuid.UUID) er
             updated_by = $4
 		WHERE
 			id = $5
+		AND deleted_at IS NULL
 		RETURNING id, book_id, user_id, rating, review, created_at, created_by, updated_at, updated_by
 	`
 
@@ -0,0 +1,336 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+type ReservationModel struct {
+	ID        uuid.UUID     `db:"id"`
+	BookID    uuid.UUID     `db:"book_id"`
+	MemberID  uuid.UUID     `db:"member_id"`
+	CopyID    uuid.NullUUID `db:"copy_id"`
+	Status    string        `db:"status"`
+	Position  int           `db:"position"`
+	ReadyAt   pq.NullTime   `db:"ready_at"`
+	ExpiresAt pq.NullTime   `db:"expires_at"`
+	CreatedAt time.Time     `db:"created_at"`
+	CreatedBy uuid.UUID     `db:"created_by"`
+	UpdatedAt pq.NullTime   `db:"updated_at"`
+	UpdatedBy uuid.NullUUID `db:"updated_by"`
+}
+
+type ReservationResponse struct {
+	ID        uuid.UUID `json:"id"`
+	BookID    uuid.UUID `json:"book_id"`
+	MemberID  uuid.UUID `json:"member_id"`
+	CopyID    uuid.UUID `json:"copy_id"`
+	Status    string    `json:"status"`
+	Position  int       `json:"position"`
+	ReadyAt   time.Time `json:"ready_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	CreatedBy uuid.UUID `json:"created_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+	UpdatedBy uuid.UUID `json:"updated_by"`
+}
+
+func (rv *ReservationModel) Response() ReservationResponse {
+	return ReservationResponse{
+		ID:        rv.ID,
+		BookID:    rv.BookID,
+		MemberID:  rv.MemberID,
+		CopyID:    rv.CopyID.UUID,
+		Status:    rv.Status,
+		Position:  rv.Position,
+		ReadyAt:   rv.ReadyAt.Time,
+		ExpiresAt: rv.ExpiresAt.Time,
+		CreatedAt: rv.CreatedAt,
+		CreatedBy: rv.CreatedBy,
+		UpdatedAt: rv.UpdatedAt.Time,
+		UpdatedBy: rv.UpdatedBy.UUID,
+	}
+}
+
+// position is the 1-based place in the waiting queue, 0 once the hold left the queue.
+const reservationColumns = `
+	r.id, r.book_id, r.member_id, r.copy_id, r.status,
+	CASE WHEN r.status = 'waiting' THEN (
+		SELECT COUNT(*) FROM reservations q
+		WHERE q.book_id = r.book_id AND q.status = 'waiting' AND q.created_at <= r.created_at
+	) ELSE 0 END AS position,
+	r.ready_at, r.expires_at, r.created_at, r.created_by, r.updated_at, r.updated_by
+`
+
+func scanReservations(rows *sqlx.Rows) ([]ReservationModel, error) {
+	defer rows.Close()
+
+	var reservations []ReservationModel
+	for rows.Next() {
+		var reservation ReservationModel
+		err := rows.StructScan(&reservation)
+		if err != nil {
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	return reservations, nil
+}
+
+func GetReservationsByMember(ctx context.Context, db *sqlx.DB, memberID uuid.UUID) ([]ReservationModel, error) {
+	query := `
+		SELECT ` + reservationColumns + `
+		FROM
+			reservations r
+		WHERE
+			r.member_id = $1
+		ORDER BY r.created_at DESC
+	`
+
+	rows, err := db.QueryxContext(ctx, query, memberID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanReservations(rows)
+}
+
+// GetReservationQueue returns the active holds of a book, ready ones first.
+func GetReservationQueue(ctx context.Context, db *sqlx.DB, bookID uuid.UUID) ([]ReservationModel, error) {
+	query := `
+		SELECT ` + reservationColumns + `
+		FROM
+			reservations r
+		WHERE
+			r.book_id = $1
+		AND r.status IN ('waiting', 'ready')
+		ORDER BY r.status = 'waiting', r.created_at
+	`
+
+	rows, err := db.QueryxContext(ctx, query, bookID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanReservations(rows)
+}
+
+func GetExpiredReservations(ctx context.Context, db *sqlx.DB, now time.Time) ([]ReservationModel, error) {
+	query := `
+		SELECT ` + reservationColumns + `
+		FROM
+			reservations r
+		WHERE
+			r.status = 'ready'
+		AND r.expires_at < $1
+		ORDER BY r.expires_at
+	`
+
+	rows, err := db.QueryxContext(ctx, query, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanReservations(rows)
+}
+
+func GetOneReservation(ctx context.Context, db *sqlx.DB, id uuid.UUID) (ReservationModel, error) {
+	query := `
+		SELECT ` + reservationColumns + `
+		FROM
+			reservations r
+		WHERE
+			r.id = $1
+	`
+
+	reservation := ReservationModel{}
+	err := db.QueryRowxContext(ctx, query, id).StructScan(&reservation)
+	if err != nil {
+		return reservation, err
+	}
+
+	return reservation, nil
+}
+
+// GetReadyReservation returns the member's hold on a book that has a copy set aside.
+func GetReadyReservation(ctx context.Context, db *sqlx.DB, bookID uuid.UUID, memberID uuid.UUID) (ReservationModel, error) {
+	query := `
+		SELECT ` + reservationColumns + `
+		FROM
+			reservations r
+		WHERE
+			r.book_id = $1
+		AND r.member_id = $2
+		AND r.status = 'ready'
+	`
+
+	reservation := ReservationModel{}
+	err := db.QueryRowxContext(ctx, query, bookID, memberID).StructScan(&reservation)
+	if err != nil {
+		return reservation, err
+	}
+
+	return reservation, nil
+}
+
+func CountWaitingReservations(ctx context.Context, db *sqlx.DB, bookID uuid.UUID) (int, error) {
+	query := `SELECT COUNT(*) FROM reservations WHERE book_id = $1 AND status = 'waiting'`
+
+	var count int
+	err := db.QueryRowxContext(ctx, query, bookID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (rv *ReservationModel) Insert(ctx context.Context, db *sqlx.DB) error {
+	query := `
+		INSERT INTO reservations (
+			id, book_id, member_id, status, created_at, created_by
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		) RETURNING id, created_at
+	`
+
+	err := db.QueryRowxContext(ctx, query,
+		rv.ID,
+		rv.BookID,
+		rv.MemberID,
+		rv.Status,
+		rv.CreatedAt,
+		rv.CreatedBy,
+	).Scan(
+		&rv.ID,
+		&rv.CreatedAt,
+	)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// LockReservation locks the reservation row until the surrounding transaction ends.
+func LockReservation(ctx context.Context, db sqlx.QueryerContext, id uuid.UUID) (ReservationModel, error) {
+	query := `
+		SELECT ` + reservationColumns + `
+		FROM
+			reservations r
+		WHERE
+			r.id = $1
+		FOR UPDATE OF r
+	`
+
+	reservation := ReservationModel{}
+	err := db.QueryRowxContext(ctx, query, id).StructScan(&reservation)
+	if err != nil {
+		return reservation, err
+	}
+
+	return reservation, nil
+}
+
+// UpdateReservationStatus closes an active hold. It returns sql.ErrNoRows when the
+// hold is no longer waiting or ready.
+func UpdateReservationStatus(ctx context.Context, db sqlx.QueryerContext, id uuid.UUID, status string, updatedBy uuid.UUID) error {
+	query := `
+		UPDATE
+			reservations
+		SET
+			status = $1,
+			updated_at = NOW(),
+			updated_by = $2
+		WHERE
+			id = $3
+		AND status IN ('waiting', 'ready')
+		RETURNING id
+	`
+
+	return db.QueryRowxContext(ctx, query, status, updatedBy, id).Scan(&id)
+}
+
+// PromoteNextReservation sets copyID aside for the first member waiting on bookID.
+// It reports false when nobody is waiting.
+func PromoteNextReservation(ctx context.Context, db sqlx.ExtContext, bookID uuid.UUID, copyID uuid.UUID, expiresAt time.Time) (bool, error) {
+	query := `
+		WITH next AS (
+			SELECT id
+			FROM reservations
+			WHERE book_id = $1
+			AND status = 'waiting'
+			ORDER BY created_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE
+			reservations r
+		SET
+			status = 'ready',
+			copy_id = $2,
+			ready_at = NOW(),
+			expires_at = $3,
+			updated_at = NOW()
+		FROM
+			next
+		WHERE
+			r.id = next.id
+	`
+
+	result, err := db.ExecContext(ctx, query, bookID, copyID, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	if affected == 0 {
+		return false, nil
+	}
+
+	err = SetCopyStatus(ctx, db, copyID, "on_hold")
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+type PromotableCopy struct {
+	BookID uuid.UUID `db:"book_id"`
+	CopyID uuid.UUID `db:"copy_id"`
+}
+
+// GetPromotableCopies finds one available copy for every book that still has a waiting queue.
+func GetPromotableCopies(ctx context.Context, db *sqlx.DB) ([]PromotableCopy, error) {
+	query := `
+		SELECT DISTINCT ON (c.book_id)
+			c.book_id, c.id AS copy_id
+		FROM
+			book_copies c
+		WHERE
+			c.status = 'available'
+		AND EXISTS (
+			SELECT 1 FROM reservations r WHERE r.book_id = c.book_id AND r.status = 'waiting'
+		)
+		ORDER BY c.book_id, c.created_at
+	`
+
+	var copies []PromotableCopy
+	err := db.SelectContext(ctx, &copies, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return copies, nil
+}
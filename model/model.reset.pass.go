@@ -8,44 +8,28 @@ import (
 	"github.com/jmoiron/sqlx"
 )
 
+// PasswordResetModel is a pending reset. Only the hash of the token is
+// stored; the token itself is only ever in the email sent to the user.
 type PasswordResetModel struct {
 	ID        uuid.UUID `db:"id"`
 	UserID    uuid.UUID `db:"user_id"`
-	Token     string    `db:"token"`
+	TokenHash string    `db:"token_hash"`
 	ExpiresAt time.Time `db:"expires_at"`
 	CreatedAt time.Time `db:"created_at"`
 }
 
-type PasswordResetResponse struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
-}
-
-func (pr *PasswordResetModel) Response() PasswordResetResponse {
-	return PasswordResetResponse{
-		ID:        pr.ID,
-		UserID:    pr.UserID,
-		Token:     pr.Token,
-		ExpiresAt: pr.ExpiresAt,
-		CreatedAt: pr.CreatedAt,
-	}
-}
-
-func (pr PasswordResetModel) Insert(ctx context.Context, db *sqlx.DB) error {
+func (pr *PasswordResetModel) Insert(ctx context.Context, db sqlx.ExtContext) error {
 	query := `
 		INSERT INTO password_reset (
-			user_id, token, expires_at
+			user_id, token_hash, expires_at
 		) VALUES (
-			$1, $2, $3 
+			$1, $2, $3
 		) RETURNING id, created_at
 	`
 
 	err := db.QueryRowxContext(ctx, query,
 		pr.UserID,
-		pr.Token,
+		pr.TokenHash,
 		pr.ExpiresAt,
 	).Scan(
 		&pr.ID,
@@ -59,32 +43,68 @@ func (pr PasswordResetModel) Insert(ctx context.Context, db *sqlx.DB) error {
 	return nil
 }
 
-func ValidateResetToken(ctx context.Context, db *sqlx.DB, token string) (uuid.UUID, error) {
+func ValidateResetToken(ctx context.Context, db *sqlx.DB, tokenHash string) (uuid.UUID, error) {
 	var userID uuid.UUID
 	query := `
 		SELECT user_id FROM password_reset
-		WHERE token = $1 AND expires_at > NOW()
+		WHERE token_hash = $1 AND expires_at > NOW()
 		LIMIT 1
 	`
 
-	err := db.QueryRowxContext(ctx, query, token).Scan(&userID)
+	err := db.QueryRowxContext(ctx, query, tokenHash).Scan(&userID)
 	if err != nil {
 		return uuid.Nil, err
 	}
 	return userID, nil
 }
 
-func DeleteResetToken(ctx context.Context, db *sqlx.DB, token string) error {
+// ConsumeResetToken deletes the token and returns its user, so a token can
+// only be used once. It returns sql.ErrNoRows for an unknown or expired token.
+func ConsumeResetToken(ctx context.Context, db sqlx.QueryerContext, tokenHash string) (uuid.UUID, error) {
+	var userID uuid.UUID
 	query := `
 		DELETE FROM password_reset
-		WHERE token = $1
+		WHERE token_hash = $1 AND expires_at > NOW()
+		RETURNING user_id
 	`
 
-	_, err := db.ExecContext(ctx, query, token)
+	err := db.QueryRowxContext(ctx, query, tokenHash).Scan(&userID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return userID, nil
+}
+
+// LockUserResetTokens serializes reset requests of the user until the
+// transaction ends, so concurrent requests cannot both pass the cooldown.
+func LockUserResetTokens(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID) error {
+	_, err := db.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('password_reset:' || $1::text))`, userID)
 	return err
 }
 
-func UpdatePassword(ctx context.Context, db *sqlx.DB, userID uuid.UUID, hashedPassword string) error {
+// ResetIssuedSince reports whether a reset token was issued to the user after since.
+func ResetIssuedSince(ctx context.Context, db sqlx.QueryerContext, userID uuid.UUID, since time.Time) (bool, error) {
+	var issued bool
+	query := `SELECT EXISTS(SELECT 1 FROM password_reset WHERE user_id = $1 AND created_at > $2)`
+	err := db.QueryRowxContext(ctx, query, userID, since).Scan(&issued)
+	if err != nil {
+		return false, err
+	}
+	return issued, nil
+}
+
+// DeleteUserResetTokens invalidates every pending reset of the user.
+func DeleteUserResetTokens(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID) error {
+	query := `
+		DELETE FROM password_reset
+		WHERE user_id = $1
+	`
+
+	_, err := db.ExecContext(ctx, query, userID)
+	return err
+}
+
+func UpdatePassword(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, hashedPassword string) error {
 	query := `
 		UPDATE users
 		SET password = $1,
@@ -95,3 +115,27 @@ func UpdatePassword(ctx context.Context, db *sqlx.DB, userID uuid.UUID, hashedPa
 	_, err := db.ExecContext(ctx, query, hashedPassword, userID)
 	return err
 }
+
+// PurgeExpiredResetTokens deletes reset tokens that can no longer be used.
+func PurgeExpiredResetTokens(ctx context.Context, db sqlx.ExtContext) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM password_reset WHERE expires_at <= NOW()`)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// UpdatePasswordHash swaps the stored hash for one of the same password in a
+// newer format. It does nothing if the password changed since oldHash was read.
+func UpdatePasswordHash(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, oldHash string, newHash string) error {
+	query := `
+		UPDATE users
+		SET password = $3
+		WHERE id = $1
+		AND password = $2
+	`
+
+	_, err := db.ExecContext(ctx, query, userID, oldHash, newHash)
+	return err
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"app-bookstore/lib"
 	"context"
 	"time"
 
@@ -10,27 +11,34 @@ import (
 )
 
 type ResourceModel struct {
-	ID          uuid.UUID     `db:"id"`
-	Name        string        `db:"name"`
-	Endpoint    string        `db:"endpoint"`
-	Method      string        `db:"method"`
-	Description string        `db:"description"`
-	CreatedAt   time.Time     `db:"created_at"`
-	CreatedBy   uuid.UUID     `db:"created_by"`
-	UpdatedAt   pq.NullTime   `db:"updated_at"`
-	UpdatedBy   uuid.NullUUID `db:"updated_by"`
+	ID          uuid.UUID      `db:"id"`
+	Name        string         `db:"name"`
+	Endpoint    string         `db:"endpoint"`
+	Methods     pq.StringArray `db:"methods"`
+	Description string         `db:"description"`
+	CreatedAt   time.Time      `db:"created_at"`
+	CreatedBy   uuid.UUID      `db:"created_by"`
+	UpdatedAt   pq.NullTime    `db:"updated_at"`
+	UpdatedBy   uuid.NullUUID  `db:"updated_by"`
+	DeletedAt   pq.NullTime    `db:"deleted_at"`
+	DeletedBy   uuid.NullUUID  `db:"deleted_by"`
+
+	RouteMissingAt pq.NullTime `db:"route_missing_at"`
 }
 
 type ResourceResponse struct {
-	ID          uuid.UUID `json:"id"`
-	Name        string    `json:"name"`
-	Endpoint    string    `json:"endpoint"`
-	Method      string    `json:"method"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	CreatedBy   uuid.UUID `json:"created_by"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	UpdatedBy   uuid.UUID `json:"updated_by"`
+	ID          uuid.UUID  `json:"id"`
+	Name        string     `json:"name"`
+	Endpoint    string     `json:"endpoint"`
+	Methods     []string   `json:"methods"`
+	Description string     `json:"description"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CreatedBy   uuid.UUID  `json:"created_by"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	UpdatedBy   uuid.UUID  `json:"updated_by"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+
+	RouteMissingAt *time.Time `json:"route_missing_at,omitempty"`
 }
 
 func (rs *ResourceModel) Response() ResourceResponse {
@@ -38,24 +46,48 @@ func (rs *ResourceModel) Response() ResourceResponse {
 		ID:          rs.ID,
 		Name:        rs.Name,
 		Endpoint:    rs.Endpoint,
-		Method:      rs.Method,
+		Methods:     rs.Methods,
 		Description: rs.Description,
 		CreatedAt:   rs.CreatedAt,
 		CreatedBy:   rs.CreatedBy,
 		UpdatedAt:   rs.UpdatedAt.Time,
 		UpdatedBy:   rs.UpdatedBy.UUID,
+		DeletedAt:   nullTime(rs.DeletedAt),
+
+		RouteMissingAt: nullTime(rs.RouteMissingAt),
 	}
 }
 
-func GetAllResources(ctx context.Context, db *sqlx.DB) ([]ResourceModel, error) {
-	query := `
-		SELECT id, name, endpoint, method, description, created_at, created_by, updated_at, updated_by
+// resourceSortColumns lists the columns the resource list may be sorted by.
+var resourceSortColumns = map[string]string{
+	"created_at": "created_at",
+	"name":       "name",
+	"endpoint":   "endpoint",
+}
+
+func GetAllResources(ctx context.Context, db *sqlx.DB, filter lib.Filter) ([]ResourceModel, int64, error) {
+	qb := lib.NewQueryBuilder()
+	if !filter.IncludeDeleted {
+		qb.Where("deleted_at IS NULL")
+	}
+	qb.Page(filter, resourceSortColumns, "created_at", "created_at", "id")
+
+	from := `
 		FROM resources
 	`
 
-	rows, err := db.QueryxContext(ctx, query)
+	total, err := countRows(ctx, db, from, qb)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, name, endpoint, methods, description, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by, route_missing_at
+	` + from + qb.Clauses()
+
+	rows, err := db.QueryxContext(ctx, query, qb.Args()...)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	defer rows.Close()
@@ -65,23 +97,24 @@ func GetAllResources(ctx context.Context, db *sqlx.DB) ([]ResourceModel, error)
 		var resource ResourceModel
 		err := rows.StructScan(&resource)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		resources = append(resources, resource)
 	}
 
-	return resources, nil
+	return resources, total, nil
 }
 
 func GetOneResource(ctx context.Context, db *sqlx.DB, id uuid.UUID) (ResourceModel, error) {
 	query := `
 		SELECT 
-			id, name, endpoint, method, description, created_at, created_by, updated_at, updated_by
+			id, name, endpoint, methods, description, created_at, created_by, updated_at, updated_by, route_missing_at
 		FROM
 			resources
 		WHERE 
 			id = $1
+		AND deleted_at IS NULL
 	`
 
 	resource := ResourceModel{}
@@ -98,7 +131,7 @@ func (rs *ResourceModel) Insert(ctx context.Context, db *sqlx.DB) error {
 		INSERT INTO resources (
 			name,
 			endpoint,
-            method,
+            methods,
             description,
             created_by
 		) VALUES (
@@ -109,7 +142,7 @@ func (rs *ResourceModel) Insert(ctx context.Context, db *sqlx.DB) error {
 	err := db.QueryRowxContext(ctx, query,
 		rs.Name,
 		rs.Endpoint,
-		rs.Method,
+		rs.Methods,
 		rs.Description,
 		rs.CreatedBy,
 	).Scan(
@@ -131,19 +164,20 @@ func (rs *ResourceModel) Update(ctx context.Context, db *sqlx.DB) error {
 		SET
 			name = COALESCE(NULLIF($1, ''), name),
 			endpoint = COALESCE(NULLIF($2, ''), endpoint),
-            method = COALESCE(NULLIF($3, ''), method),
+            methods = COALESCE($3, methods),
             description = COALESCE(NULLIF($4, ''), description),
             updated_at = $5,
             updated_by = $6
 		WHERE 
 			id = $7
-		RETURNING id, name, endpoint, method, description, created_at, created_by, updated_at, updated_by
+		AND deleted_at IS NULL
+		RETURNING id, name, endpoint, methods, description, created_at, created_by, updated_at, updated_by
 	`
 
 	err := db.QueryRowxContext(ctx, query,
 		rs.Name,
 		rs.Endpoint,
-		rs.Method,
+		rs.Methods,
 		rs.Description,
 		time.Now(),
 		rs.UpdatedBy.UUID,
@@ -152,7 +186,7 @@ func (rs *ResourceModel) Update(ctx context.Context, db *sqlx.DB) error {
 		&rs.ID,
 		&rs.Name,
 		&rs.Endpoint,
-		&rs.Method,
+		&rs.Methods,
 		&rs.Description,
 		&rs.CreatedAt,
 		&rs.CreatedBy,
@@ -166,3 +200,78 @@ func (rs *ResourceModel) Update(ctx context.Context, db *sqlx.DB) error {
 
 	return nil
 }
+
+// GetLiveResources returns every resource that is not deleted, oldest first.
+func GetLiveResources(ctx context.Context, db sqlx.QueryerContext) ([]ResourceModel, error) {
+	query := `
+		SELECT
+			id, name, endpoint, methods, description, created_at, created_by, updated_at, updated_by, route_missing_at
+		FROM
+			resources
+		WHERE
+			deleted_at IS NULL
+		ORDER BY created_at, id
+	`
+
+	var resources []ResourceModel
+	err := sqlx.SelectContext(ctx, db, &resources, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return resources, nil
+}
+
+// InsertRouteResource creates a resource for a route found by the resource
+// sync. It has no creator since no user triggered it.
+func (rs *ResourceModel) InsertRouteResource(ctx context.Context, db sqlx.ExtContext) error {
+	query := `
+		INSERT INTO resources (
+			id, name, endpoint, methods, description
+		) VALUES (
+			$1, $2, $3, $4, NULLIF($5, '')
+		) RETURNING created_at
+	`
+
+	return db.QueryRowxContext(ctx, query,
+		rs.ID,
+		rs.Name,
+		rs.Endpoint,
+		rs.Methods,
+		rs.Description,
+	).Scan(
+		&rs.CreatedAt,
+	)
+}
+
+func SetResourceMethods(ctx context.Context, db sqlx.ExtContext, id uuid.UUID, methods []string) error {
+	query := `
+		UPDATE
+			resources
+		SET
+			methods = $1,
+			updated_at = NOW()
+		WHERE
+			id = $2
+		AND deleted_at IS NULL
+	`
+
+	_, err := db.ExecContext(ctx, query, pq.Array(methods), id)
+	return err
+}
+
+// SetResourceRouteMissing flags or clears resources whose endpoint no longer
+// matches any registered route.
+func SetResourceRouteMissing(ctx context.Context, db sqlx.ExtContext, ids []uuid.UUID, missing bool) error {
+	query := `
+		UPDATE
+			resources
+		SET
+			route_missing_at = CASE WHEN $1::BOOLEAN THEN NOW() END
+		WHERE
+			id = ANY($2)
+	`
+
+	_, err := db.ExecContext(ctx, query, missing, pq.Array(ids))
+	return err
+}
@@ -1,9 +1,8 @@
 package model
 
 import (
+	"app-bookstore/lib"
 	"context"
-	"database/sql"
-	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -15,20 +14,25 @@ type RoleModel struct {
 	ID          uuid.UUID     `db:"id"`
 	Identifier  string        `db:"identifier"`
 	Description string        `db:"description"`
+	ParentID    uuid.NullUUID `db:"parent_role_id"`
 	CreatedAt   time.Time     `db:"created_at"`
 	CreatedBy   uuid.UUID     `db:"created_by"`
 	UpdatedAt   pq.NullTime   `db:"updated_at"`
 	UpdatedBy   uuid.NullUUID `db:"updated_by"`
+	DeletedAt   pq.NullTime   `db:"deleted_at"`
+	DeletedBy   uuid.NullUUID `db:"deleted_by"`
 }
 
 type RoleResponse struct {
-	ID          uuid.UUID `json:"id"`
-	Identifier  string    `json:"identifier"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	CreatedBy   uuid.UUID `json:"created_by"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	UpdatedBy   uuid.UUID `json:"updated_by"`
+	ID          uuid.UUID     `json:"id"`
+	Identifier  string        `json:"identifier"`
+	Description string        `json:"description"`
+	ParentID    uuid.NullUUID `json:"parent_role_id"`
+	CreatedAt   time.Time     `json:"created_at"`
+	CreatedBy   uuid.UUID     `json:"created_by"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	UpdatedBy   uuid.UUID     `json:"updated_by"`
+	DeletedAt   *time.Time    `json:"deleted_at,omitempty"`
 }
 
 func (r *RoleModel) Response() RoleResponse {
@@ -36,22 +40,44 @@ func (r *RoleModel) Response() RoleResponse {
 		ID:          r.ID,
 		Identifier:  r.Identifier,
 		Description: r.Description,
+		ParentID:    r.ParentID,
 		CreatedAt:   r.CreatedAt,
 		CreatedBy:   r.CreatedBy,
 		UpdatedAt:   r.UpdatedAt.Time,
 		UpdatedBy:   r.UpdatedBy.UUID,
+		DeletedAt:   nullTime(r.DeletedAt),
 	}
 }
 
-func GetAllRoles(ctx context.Context, db *sqlx.DB) ([]RoleModel, error) {
-	query := `
-		SELECT id, identifier, description, created_at, created_by, updated_at, updated_by
+// roleSortColumns lists the columns the role list may be sorted by.
+var roleSortColumns = map[string]string{
+	"created_at": "created_at",
+	"identifier": "identifier",
+}
+
+func GetAllRoles(ctx context.Context, db *sqlx.DB, filter lib.Filter) ([]RoleModel, int64, error) {
+	qb := lib.NewQueryBuilder()
+	if !filter.IncludeDeleted {
+		qb.Where("deleted_at IS NULL")
+	}
+	qb.Page(filter, roleSortColumns, "created_at", "created_at", "id")
+
+	from := `
 		FROM roles
 	`
 
-	rows, err := db.QueryxContext(ctx, query)
+	total, err := countRows(ctx, db, from, qb)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, identifier, description, parent_role_id, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by
+	` + from + qb.Clauses()
+
+	rows, err := db.QueryxContext(ctx, query, qb.Args()...)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	defer rows.Close()
@@ -61,20 +87,21 @@ func GetAllRoles(ctx context.Context, db *sqlx.DB) ([]RoleModel, error) {
 		var role RoleModel
 		err := rows.StructScan(&role)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		roles = append(roles, role)
 	}
-	return roles, err
+	return roles, total, err
 }
 
 func GetOneRole(ctx context.Context, db *sqlx.DB, id uuid.UUID) (RoleModel, error) {
 	query := `
 		SELECT
-			id, identifier, description, created_at, created_by, updated_at, updated_by
+			id, identifier, description, parent_role_id, created_at, created_by, updated_at, updated_by
 			FROM roles
 			WHERE id = $1
+			AND deleted_at IS NULL
 	`
 
 	role := RoleModel{}
@@ -86,50 +113,65 @@ func GetOneRole(ctx context.Context, db *sqlx.DB, id uuid.UUID) (RoleModel, erro
 	return role, nil
 }
 
-func GetUserRoleID(ctx context.Context, db *sqlx.DB, userID uuid.UUID) (uuid.UUID, error) {
+func GetRoleByIdentifier(ctx context.Context, db *sqlx.DB, identifier string) (RoleModel, error) {
 	query := `
-		SELECT 
-			role_id
-		FROM
-			user_roles
-		WHERE user_id = $1
-		LIMIT 1
+		SELECT
+			id, identifier, description, parent_role_id, created_at, created_by, updated_at, updated_by
+			FROM roles
+			WHERE identifier = $1
+			AND deleted_at IS NULL
 	`
 
-	var roleID uuid.UUID
-	err := db.QueryRowxContext(ctx, query,
-		userID,
-	).Scan(
-		&roleID,
-	)
+	role := RoleModel{}
+	err := db.QueryRowxContext(ctx, query, identifier).StructScan(&role)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			fmt.Printf("Error: user %s tidak memiliki role di db\n", userID)
-			return uuid.Nil, fmt.Errorf("user does not have an assigned role")
-		}
+		return role, err
+	}
 
-		fmt.Printf("Error: Query gagal di GetUserRoleID() - %v\n", err)
-		return uuid.Nil, err
+	return role, nil
+}
+
+// GetUserRoleIDs returns every live role assigned to the user.
+func GetUserRoleIDs(ctx context.Context, db *sqlx.DB, userID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT
+			ur.role_id
+		FROM
+			user_roles ur
+		JOIN
+			roles r
+		ON
+			r.id = ur.role_id
+		WHERE
+			ur.user_id = $1
+		AND r.deleted_at IS NULL
+	`
+
+	var roleIDs []uuid.UUID
+	err := sqlx.SelectContext(ctx, db, &roleIDs, query, userID)
+	if err != nil {
+		return nil, err
 	}
 
-	fmt.Printf("Error: User %s memiliki role %s\n", userID, roleID)
-	return roleID, nil
+	return roleIDs, nil
 }
 
-func (r *RoleModel) Insert(ctx context.Context, db *sqlx.DB) error {
+func (r *RoleModel) Insert(ctx context.Context, db sqlx.ExtContext) error {
 	query := `
 		INSERT INTO roles (
 			identifier,
 			description,
+			parent_role_id,
 			created_by
 		) VALUES (
-			$1, $2, $3 
+			$1, $2, $3, $4 
 		) RETURNING id, created_at
 	`
 
 	err := db.QueryRowxContext(ctx, query,
 		r.Identifier,
 		r.Description,
+		r.ParentID,
 		r.CreatedBy,
 	).Scan(
 		&r.ID,
@@ -143,22 +185,25 @@ func (r *RoleModel) Insert(ctx context.Context, db *sqlx.DB) error {
 	return nil
 }
 
-func (r *RoleModel) Update(ctx context.Context, db *sqlx.DB) error {
+func (r *RoleModel) Update(ctx context.Context, db sqlx.ExtContext) error {
 	query := `
 		UPDATE 
 			roles
 		SET
 			identifier = $1,
 			description = $2,
-			updated_at = $3,
-			updated_by = $4
+			parent_role_id = $3,
+			updated_at = $4,
+			updated_by = $5
 		WHERE 
-			id = $5
+			id = $6
+		AND deleted_at IS NULL
 	`
 
 	_, err := db.ExecContext(ctx, query,
 		r.Identifier,
 		r.Description,
+		r.ParentID,
 		r.UpdatedAt.Time,
 		r.UpdatedBy.UUID,
 		r.ID,
@@ -169,3 +214,33 @@ func (r *RoleModel) Update(ctx context.Context, db *sqlx.DB) error {
 	}
 	return nil
 }
+
+// LockRoleHierarchy serializes parent changes until the transaction ends, so
+// two concurrent updates cannot build a cycle the other one did not see.
+func LockRoleHierarchy(ctx context.Context, db sqlx.ExtContext) error {
+	_, err := db.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('roles.parent_role_id'))`)
+	return err
+}
+
+// GetRoleAncestorIDs returns the parent of the role, its parent and so on.
+func GetRoleAncestorIDs(ctx context.Context, db sqlx.QueryerContext, roleID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		WITH RECURSIVE ancestors (id) AS (
+			SELECT parent_role_id FROM roles WHERE id = $1 AND parent_role_id IS NOT NULL
+			UNION
+			SELECT r.parent_role_id
+			FROM roles r
+			JOIN ancestors a ON r.id = a.id
+			WHERE r.parent_role_id IS NOT NULL
+		)
+		SELECT id FROM ancestors
+	`
+
+	var ancestorIDs []uuid.UUID
+	err := sqlx.SelectContext(ctx, db, &ancestorIDs, query, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return ancestorIDs, nil
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"app-bookstore/lib"
 	"context"
 	"time"
 
@@ -9,28 +10,40 @@ import (
 	"github.com/lib/pq"
 )
 
+// Effects of a role_resources row. A deny on a role overrides allows it
+// inherits from its parents.
+const (
+	EffectAllow = "allow"
+	EffectDeny  = "deny"
+)
+
 type RoleResourceModel struct {
 	ID         uuid.UUID     `db:"id"`
 	RoleID     uuid.UUID     `db:"role_id"`
 	ResourceID uuid.UUID     `db:"resource_id"`
 	Method     string        `db:"method"`
+	Effect     string        `db:"effect"`
 	IsActive   bool          `db:"is_active"`
 	CreatedAt  time.Time     `db:"created_at"`
 	CreatedBy  uuid.UUID     `db:"created_by"`
 	UpdatedAt  pq.NullTime   `db:"updated_at"`
 	UpdatedBy  uuid.NullUUID `db:"updated_by"`
+	DeletedAt  pq.NullTime   `db:"deleted_at"`
+	DeletedBy  uuid.NullUUID `db:"deleted_by"`
 }
 
 type RoleResourceResponse struct {
-	ID         uuid.UUID `json:"id"`
-	RoleID     uuid.UUID `json:"role_id"`
-	ResourceID uuid.UUID `json:"resource_id"`
-	Method     string    `json:"method"`
-	IsActive   bool      `json:"is_active"`
-	CreatedAt  time.Time `json:"created_at"`
-	CreatedBy  uuid.UUID `json:"created_by"`
-	UpdatedAt  time.Time `json:"updated_at"`
-	UpdatedBy  uuid.UUID `json:"updated_by"`
+	ID         uuid.UUID  `json:"id"`
+	RoleID     uuid.UUID  `json:"role_id"`
+	ResourceID uuid.UUID  `json:"resource_id"`
+	Method     string     `json:"method"`
+	Effect     string     `json:"effect"`
+	IsActive   bool       `json:"is_active"`
+	CreatedAt  time.Time  `json:"created_at"`
+	CreatedBy  uuid.UUID  `json:"created_by"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	UpdatedBy  uuid.UUID  `json:"updated_by"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
 }
 
 func (rr *RoleResourceModel) Response() RoleResourceResponse {
@@ -39,23 +52,44 @@ func (rr *RoleResourceModel) Response() RoleResourceResponse {
 		RoleID:     rr.RoleID,
 		ResourceID: rr.ResourceID,
 		Method:     rr.Method,
+		Effect:     rr.Effect,
 		IsActive:   rr.IsActive,
 		CreatedAt:  rr.CreatedAt,
 		CreatedBy:  rr.CreatedBy,
 		UpdatedAt:  rr.UpdatedAt.Time,
 		UpdatedBy:  rr.UpdatedBy.UUID,
+		DeletedAt:  nullTime(rr.DeletedAt),
 	}
 }
 
-func GetAllRoleResource(ctx context.Context, db *sqlx.DB) ([]RoleResourceModel, error) {
-	query := `
-		SELECT id, role_id, resource_id, method, is_active, created_at, created_by, updated_at, updated_by
+// roleResourceSortColumns lists the columns the role resource list may be sorted by.
+var roleResourceSortColumns = map[string]string{
+	"created_at": "created_at",
+}
+
+func GetAllRoleResource(ctx context.Context, db *sqlx.DB, filter lib.Filter) ([]RoleResourceModel, int64, error) {
+	qb := lib.NewQueryBuilder()
+	if !filter.IncludeDeleted {
+		qb.Where("deleted_at IS NULL")
+	}
+	qb.Page(filter, roleResourceSortColumns, "created_at", "created_at", "id")
+
+	from := `
 		FROM role_resources
 	`
 
-	rows, err := db.QueryxContext(ctx, query)
+	total, err := countRows(ctx, db, from, qb)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, role_id, resource_id, method, effect, is_active, created_at, created_by, updated_at, updated_by, deleted_at, deleted_by
+	` + from + qb.Clauses()
+
+	rows, err := db.QueryxContext(ctx, query, qb.Args()...)
+	if err != nil {
+		return nil, 0, err
 	}
 
 	defer rows.Close()
@@ -65,20 +99,21 @@ func GetAllRoleResource(ctx context.Context, db *sqlx.DB) ([]RoleResourceModel,
 		var roleResource RoleResourceModel
 		err := rows.StructScan(&roleResource)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		roleResources = append(roleResources, roleResource)
 	}
 
-	return roleResources, nil
+	return roleResources, total, nil
 }
 
 func GetOneRoleResource(ctx context.Context, db *sqlx.DB, id uuid.UUID) (RoleResourceModel, error) {
 	query := `
-		SELECT id, role_id, resource_id, method, is_active, created_at, created_by, updated_at, updated_by
+		SELECT id, role_id, resource_id, method, effect, is_active, created_at, created_by, updated_at, updated_by
 		FROM role_resources
 		WHERE id = $1
+		AND deleted_at IS NULL
 	`
 
 	roleResource := RoleResourceModel{}
@@ -90,23 +125,61 @@ func GetOneRoleResource(ctx context.Context, db *sqlx.DB, id uuid.UUID) (RoleRes
 	return roleResource, nil
 }
 
-func CheckRoleAccess(ctx context.Context, db *sqlx.DB, roleID uuid.UUID, endpoint string, method string) (bool, error) {
-	query := `
-		SELECT EXISTS (
-			SELECT 1
-			FROM role_resources rr
+// roleChainCTE expands the roles in $1 to themselves (depth 0) and their live
+// ancestors, keeping origin_id so grants can be decided per starting role. The
+// depth limit only guards against a cycle that slipped past the API checks.
+const roleChainCTE = `
+	WITH RECURSIVE chain AS (
+		SELECT ro.id AS role_id, ro.id AS origin_id, 0 AS depth
+		FROM roles ro
+		WHERE ro.id = ANY($1)
+		AND ro.deleted_at IS NULL
+		UNION ALL
+		SELECT parent.id, chain.origin_id, chain.depth + 1
+		FROM chain
+		JOIN roles child ON child.id = chain.role_id
+		JOIN roles parent ON parent.id = child.parent_role_id
+		WHERE parent.deleted_at IS NULL
+		AND chain.depth < 16
+	)
+`
+
+// CheckRoleAccess reports whether any of the roles is allowed the route
+// template and method. Resources ending in "/*" cover every template below
+// them, see lib.MatchEndpoint. For each role the grant of the closest role in
+// its chain decides, and a deny beats an allow at the same depth.
+func CheckRoleAccess(ctx context.Context, db *sqlx.DB, roleIDs []uuid.UUID, template string, method string) (bool, error) {
+	query := roleChainCTE + `,
+		decisions AS (
+			SELECT DISTINCT ON (chain.origin_id)
+				chain.origin_id,
+				rr.effect
+			FROM chain
+			JOIN role_resources rr ON rr.role_id = chain.role_id
 			JOIN resources r ON rr.resource_id = r.id
-			WHERE rr.role_id = $1
-			AND r.endpoint = $2
+			WHERE (
+				r.endpoint = $2
+				OR (
+					r.endpoint LIKE '%/*'
+					AND ($2 = LEFT(r.endpoint, -2) OR LEFT($2, LENGTH(r.endpoint) - 1) = LEFT(r.endpoint, -1))
+				)
+			)
 			AND rr.method = $3
+			AND rr.method = ANY(r.methods)
 			AND rr.is_active = true
+			AND rr.deleted_at IS NULL
+			AND r.deleted_at IS NULL
+			ORDER BY chain.origin_id, chain.depth, rr.effect = 'deny' DESC
+		)
+		SELECT EXISTS (
+			SELECT 1 FROM decisions WHERE effect = 'allow'
 		)
 	`
 
 	var exists bool
 	err := db.QueryRowxContext(ctx, query,
-		roleID,
-		endpoint,
+		pq.Array(roleIDs),
+		template,
 		method,
 	).Scan(
 		&exists,
@@ -119,16 +192,201 @@ func CheckRoleAccess(ctx context.Context, db *sqlx.DB, roleID uuid.UUID, endpoin
 	return exists, nil
 }
 
+type PermissionModel struct {
+	ResourceID uuid.UUID `db:"resource_id"`
+	Name       string    `db:"name"`
+	Endpoint   string    `db:"endpoint"`
+	Method     string    `db:"method"`
+}
+
+type PermissionResponse struct {
+	ResourceID uuid.UUID `json:"resource_id"`
+	Name       string    `json:"name"`
+	Endpoint   string    `json:"endpoint"`
+	Method     string    `json:"method"`
+}
+
+func (p *PermissionModel) Response() PermissionResponse {
+	return PermissionResponse{
+		ResourceID: p.ResourceID,
+		Name:       p.Name,
+		Endpoint:   p.Endpoint,
+		Method:     p.Method,
+	}
+}
+
+// GetRolesPermissions returns the union of what the roles are allowed,
+// inherited grants included and denied ones left out.
+func GetRolesPermissions(ctx context.Context, db *sqlx.DB, roleIDs []uuid.UUID) ([]PermissionModel, error) {
+	query := roleChainCTE + `,
+		decisions AS (
+			SELECT DISTINCT ON (chain.origin_id, r.id, rr.method)
+				r.id AS resource_id,
+				r.name,
+				r.endpoint,
+				rr.method,
+				rr.effect
+			FROM chain
+			JOIN role_resources rr ON rr.role_id = chain.role_id
+			JOIN resources r ON rr.resource_id = r.id
+			WHERE rr.method = ANY(r.methods)
+			AND rr.is_active = true
+			AND rr.deleted_at IS NULL
+			AND r.deleted_at IS NULL
+			ORDER BY chain.origin_id, r.id, rr.method, chain.depth, rr.effect = 'deny' DESC
+		)
+		SELECT DISTINCT
+			resource_id,
+			name,
+			endpoint,
+			method
+		FROM decisions
+		WHERE effect = 'allow'
+		ORDER BY endpoint, method
+	`
+
+	var permissions []PermissionModel
+	err := sqlx.SelectContext(ctx, db, &permissions, query, pq.Array(roleIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+type EffectivePermissionModel struct {
+	ResourceID uuid.UUID `db:"resource_id"`
+	Name       string    `db:"name"`
+	Endpoint   string    `db:"endpoint"`
+	Method     string    `db:"method"`
+	Effect     string    `db:"effect"`
+	SourceID   uuid.UUID `db:"source_role_id"`
+	Source     string    `db:"source_role"`
+	Depth      int       `db:"depth"`
+}
+
+type EffectivePermissionResponse struct {
+	ResourceID uuid.UUID `json:"resource_id"`
+	Name       string    `json:"name"`
+	Endpoint   string    `json:"endpoint"`
+	Method     string    `json:"method"`
+	Effect     string    `json:"effect"`
+	SourceID   uuid.UUID `json:"source_role_id"`
+	Source     string    `json:"source_role"`
+	Inherited  bool      `json:"inherited"`
+	Depth      int       `json:"depth"`
+	Applied    bool      `json:"applied"`
+}
+
+func (p *EffectivePermissionModel) Response() EffectivePermissionResponse {
+	return EffectivePermissionResponse{
+		ResourceID: p.ResourceID,
+		Name:       p.Name,
+		Endpoint:   p.Endpoint,
+		Method:     p.Method,
+		Effect:     p.Effect,
+		SourceID:   p.SourceID,
+		Source:     p.Source,
+		Inherited:  p.Depth > 0,
+		Depth:      p.Depth,
+	}
+}
+
+func (p *EffectivePermissionModel) Rule() lib.GrantRule {
+	return lib.GrantRule{
+		Endpoint: p.Endpoint,
+		Method:   p.Method,
+		Depth:    p.Depth,
+		Deny:     p.Effect == EffectDeny,
+	}
+}
+
+// GetEffectivePermissions returns every active grant of the role and of its
+// ancestors, with the role it comes from. Rows for the same resource and
+// method are ordered so the deciding one comes first.
+func GetEffectivePermissions(ctx context.Context, db *sqlx.DB, roleID uuid.UUID) ([]EffectivePermissionModel, error) {
+	query := roleChainCTE + `
+		SELECT
+			r.id AS resource_id,
+			r.name,
+			r.endpoint,
+			rr.method,
+			rr.effect,
+			src.id AS source_role_id,
+			src.identifier AS source_role,
+			chain.depth
+		FROM chain
+		JOIN roles src ON src.id = chain.role_id
+		JOIN role_resources rr ON rr.role_id = chain.role_id
+		JOIN resources r ON rr.resource_id = r.id
+		WHERE rr.method = ANY(r.methods)
+		AND rr.is_active = true
+		AND rr.deleted_at IS NULL
+		AND r.deleted_at IS NULL
+		ORDER BY r.endpoint, r.id, rr.method, chain.depth, rr.effect = 'deny' DESC
+	`
+
+	var permissions []EffectivePermissionModel
+	err := sqlx.SelectContext(ctx, db, &permissions, query, pq.Array([]uuid.UUID{roleID}))
+	if err != nil {
+		return nil, err
+	}
+
+	return permissions, nil
+}
+
+// GetRoleGrantKeys returns "METHOD resource_id" for every grant row of the
+// role, including revoked and deleted ones so the resource sync leaves them be.
+func GetRoleGrantKeys(ctx context.Context, db *sqlx.DB, roleID uuid.UUID) (map[string]bool, error) {
+	query := `
+		SELECT
+			method || ' ' || resource_id::TEXT
+		FROM
+			role_resources
+		WHERE
+			role_id = $1
+	`
+
+	var keys []string
+	err := db.SelectContext(ctx, &keys, query, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	grants := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		grants[key] = true
+	}
+
+	return grants, nil
+}
+
+// GrantRoleResource adds an active grant unless the role already has a row for
+// the resource and method.
+func GrantRoleResource(ctx context.Context, db sqlx.ExtContext, roleID uuid.UUID, resourceID uuid.UUID, method string) error {
+	query := `
+		INSERT INTO role_resources (
+			role_id, resource_id, method, is_active
+		) VALUES (
+			$1, $2, $3, true
+		) ON CONFLICT (role_id, resource_id, method) DO NOTHING
+	`
+
+	_, err := db.ExecContext(ctx, query, roleID, resourceID, method)
+	return err
+}
+
 func (rr *RoleResourceModel) Insert(ctx context.Context, db *sqlx.DB) error {
 	query := `
 		INSERT INTO role_resources (
 			role_id,
 			resource_id,
 			method,
+			effect,
 			is_active, 
 			created_by
 		) VALUES (
-			$1, $2, $3, $4, $5 
+			$1, $2, $3, $4, $5, $6 
 		) RETURNING id, created_at
 	`
 
@@ -136,6 +394,7 @@ func (rr *RoleResourceModel) Insert(ctx context.Context, db *sqlx.DB) error {
 		rr.RoleID,
 		rr.ResourceID,
 		rr.Method,
+		rr.Effect,
 		rr.IsActive,
 		rr.CreatedBy,
 	).Scan(
@@ -158,18 +417,21 @@ func (rr *RoleResourceModel) Update(ctx context.Context, db *sqlx.DB) error {
 			role_id = $1,
 			resource_id = $2,
 			method = $3,
-			is_active = $4,
-			updated_at = $5,
-			updated_by = $6
+			effect = $4,
+			is_active = $5,
+			updated_at = $6,
+			updated_by = $7
 		WHERE
-			id = $7
-		RETURNING id, role_id, resource_id, method, is_active, created_at, created_by, updated_at, updated_by
+			id = $8
+		AND deleted_at IS NULL
+		RETURNING id, role_id, resource_id, method, effect, is_active, created_at, created_by, updated_at, updated_by
 	`
 
 	err := db.QueryRowxContext(ctx, query,
 		rr.RoleID,
 		rr.ResourceID,
 		rr.Method,
+		rr.Effect,
 		rr.IsActive,
 		time.Now(),
 		rr.UpdatedBy.UUID,
@@ -179,6 +441,7 @@ func (rr *RoleResourceModel) Update(ctx context.Context, db *sqlx.DB) error {
 		&rr.RoleID,
 		&rr.ResourceID,
 		&rr.Method,
+		&rr.Effect,
 		&rr.IsActive,
 		&rr.CreatedAt,
 		&rr.CreatedBy,
@@ -192,3 +455,10 @@ func (rr *RoleResourceModel) Update(ctx context.Context, db *sqlx.DB) error {
 
 	return nil
 }
+
+// NotifyPermissionChange tells every server instance listening on
+// lib.PermissionChannel to drop the cached permissions in scope.
+func NotifyPermissionChange(ctx context.Context, db sqlx.ExtContext, scope string, key string) error {
+	_, err := db.ExecContext(ctx, `SELECT pg_notify($1, $2)`, lib.PermissionChannel, lib.PermissionEventPayload(scope, key))
+	return err
+}
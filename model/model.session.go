@@ -9,21 +9,39 @@ import (
 	"github.com/lib/pq"
 )
 
+// SessionModel is one access/refresh token pair. Refreshing rotates the pair
+// into a new row of the same family; tokens are only stored as hashes.
 type SessionModel struct {
-	ID         uuid.UUID     `db:"id"`
-	UserID     uuid.UUID     `db:"user_id"`
-	Token      string        `db:"token"`
-	Expiration time.Time     `db:"expiration"`
-	CreatedAt  time.Time     `db:"created_at"`
-	CreatedBy  uuid.UUID     `db:"created_by"`
-	UpdatedAt  pq.NullTime   `db:"updated_at"`
-	UpdatedBy  uuid.NullUUID `db:"updated_by"`
+	ID               uuid.UUID     `db:"id"`
+	FamilyID         uuid.UUID     `db:"family_id"`
+	UserID           uuid.UUID     `db:"user_id"`
+	TokenHash        string        `db:"token_hash"`
+	Expiration       time.Time     `db:"expiration"`
+	RefreshTokenHash string        `db:"refresh_token_hash"`
+	RefreshExpiresAt pq.NullTime   `db:"refresh_expires_at"`
+	RotatedAt        pq.NullTime   `db:"rotated_at"`
+	RevokedAt        pq.NullTime   `db:"revoked_at"`
+	Device           string        `db:"device"`
+	IPAddress        string        `db:"ip_address"`
+	UserAgent        string        `db:"user_agent"`
+	LastSeenAt       pq.NullTime   `db:"last_seen_at"`
+	CreatedAt        time.Time     `db:"created_at"`
+	CreatedBy        uuid.UUID     `db:"created_by"`
+	UpdatedAt        pq.NullTime   `db:"updated_at"`
+	UpdatedBy        uuid.NullUUID `db:"updated_by"`
 }
 
+// liveSession matches session rows that can still be used: the access token
+// has not expired, or the refresh token can still be rotated.
+const liveSession = `
+	revoked_at IS NULL
+	AND (expiration > NOW() OR (rotated_at IS NULL AND refresh_expires_at > NOW()))
+`
+
 type SessionResponse struct {
 	ID         uuid.UUID `json:"id"`
+	FamilyID   uuid.UUID `json:"family_id"`
 	UserID     uuid.UUID `json:"user_id"`
-	Token      string    `json:"token"`
 	Expiration time.Time `json:"expiration"`
 	CreatedAt  time.Time `json:"created_at"`
 	CreatedBy  uuid.UUID `json:"created_by"`
@@ -34,8 +52,8 @@ type SessionResponse struct {
 func (s *SessionModel) Response() SessionResponse {
 	return SessionResponse{
 		ID:         s.ID,
+		FamilyID:   s.FamilyID,
 		UserID:     s.UserID,
-		Token:      s.Token,
 		Expiration: s.Expiration,
 		CreatedAt:  s.CreatedAt,
 		CreatedBy:  s.CreatedBy,
@@ -44,16 +62,27 @@ func (s *SessionModel) Response() SessionResponse {
 	}
 }
 
-func (s *SessionModel) Insert(ctx context.Context, db *sqlx.DB) error {
+func (s *SessionModel) Insert(ctx context.Context, db sqlx.ExtContext) error {
 	query := `
-		INSERT INTO sessions (user_id, token, expiration, created_at, created_by)
-		VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at
+		INSERT INTO sessions (
+			id, family_id, user_id, token_hash, expiration, refresh_token_hash, refresh_expires_at,
+			device, ip_address, user_agent, created_at, created_by
+		) VALUES (
+			$1, $2, $3, $4, $5, NULLIF($6, ''), $7, NULLIF($8, ''), NULLIF($9, ''), NULLIF($10, ''), $11, $12
+		) RETURNING id, created_at
 	`
 
 	err := db.QueryRowxContext(ctx, query,
+		s.ID,
+		s.FamilyID,
 		s.UserID,
-		s.Token,
+		s.TokenHash,
 		s.Expiration,
+		s.RefreshTokenHash,
+		s.RefreshExpiresAt,
+		s.Device,
+		s.IPAddress,
+		s.UserAgent,
 		s.CreatedAt,
 		s.CreatedBy,
 	).Scan(
@@ -68,20 +97,297 @@ func (s *SessionModel) Insert(ctx context.Context, db *sqlx.DB) error {
 	return nil
 }
 
-func CheckSessionExists(ctx context.Context, db *sqlx.DB, token string) (bool, error) {
+// CheckSessionExists reports whether the access token with this hash belongs
+// to a session that is neither expired nor revoked. A live session is marked
+// as seen, at most once a minute so busy clients do not write on every call.
+func CheckSessionExists(ctx context.Context, db *sqlx.DB, tokenHash string) (bool, error) {
 	var exists bool
 	query := `
-		SELECT EXISTS(SELECT 1 FROM sessions WHERE token = $1 AND expiration > NOW())
+		WITH live AS (
+			SELECT id, last_seen_at FROM sessions
+			WHERE token_hash = $1 AND expiration > NOW() AND revoked_at IS NULL
+		), touched AS (
+			UPDATE sessions s
+			SET last_seen_at = NOW()
+			FROM live
+			WHERE s.id = live.id
+			AND (live.last_seen_at IS NULL OR live.last_seen_at < NOW() - INTERVAL '1 minute')
+		)
+		SELECT EXISTS(SELECT 1 FROM live)
 	`
-	err := db.QueryRowxContext(ctx, query, token).Scan(&exists)
+	err := db.QueryRowxContext(ctx, query, tokenHash).Scan(&exists)
 	if err != nil {
 		return false, err
 	}
 	return exists, nil
 }
 
-func DeleteSessionByToken(ctx context.Context, db *sqlx.DB, token string) error {
-	query := `DELETE FROM sessions WHERE token = $1`
-	_, err := db.ExecContext(ctx, query, token)
+// GetSessionByRefreshToken locks the session the refresh token was issued with,
+// whatever its state, so reuse of a rotated token can be detected.
+func GetSessionByRefreshToken(ctx context.Context, db sqlx.QueryerContext, refreshTokenHash string) (SessionModel, error) {
+	query := `
+		SELECT
+			id, family_id, user_id, token_hash, expiration, COALESCE(refresh_token_hash, '') AS refresh_token_hash,
+			refresh_expires_at, rotated_at, revoked_at, COALESCE(device, '') AS device,
+			COALESCE(ip_address, '') AS ip_address, COALESCE(user_agent, '') AS user_agent, last_seen_at,
+			created_at, created_by, updated_at, updated_by
+		FROM
+			sessions
+		WHERE
+			refresh_token_hash = $1
+		FOR UPDATE
+	`
+
+	session := SessionModel{}
+	err := db.QueryRowxContext(ctx, query, refreshTokenHash).StructScan(&session)
+	if err != nil {
+		return session, err
+	}
+
+	return session, nil
+}
+
+// MarkSessionRotated retires the refresh token of the session; its access token
+// stays valid until it expires.
+func MarkSessionRotated(ctx context.Context, db sqlx.ExtContext, id uuid.UUID, userID uuid.UUID) error {
+	query := `
+		UPDATE sessions
+		SET rotated_at = NOW(), updated_at = NOW(), updated_by = $2
+		WHERE id = $1
+	`
+
+	_, err := db.ExecContext(ctx, query, id, userID)
+	return err
+}
+
+// RevokeSessionFamily revokes every live session of the family and returns
+// their access token hashes so cached sessions can be dropped.
+func RevokeSessionFamily(ctx context.Context, db sqlx.ExtContext, familyID uuid.UUID) ([]string, error) {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW(), updated_at = NOW()
+		WHERE family_id = $1
+		AND revoked_at IS NULL
+		RETURNING token_hash
+	`
+
+	var tokenHashes []string
+	err := sqlx.SelectContext(ctx, db, &tokenHashes, query, familyID)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenHashes, nil
+}
+
+// RevokeSessionFamilyByToken revokes the family of the session the access token
+// belongs to, which ends the login on every token it was rotated into.
+func RevokeSessionFamilyByToken(ctx context.Context, db sqlx.ExtContext, tokenHash string) ([]string, error) {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW(), updated_at = NOW()
+		WHERE family_id = (SELECT family_id FROM sessions WHERE token_hash = $1)
+		AND revoked_at IS NULL
+		RETURNING token_hash
+	`
+
+	var tokenHashes []string
+	err := sqlx.SelectContext(ctx, db, &tokenHashes, query, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenHashes, nil
+}
+
+// UserSessionModel is one login as the user sees it: a session family, with
+// the device details of its newest token.
+type UserSessionModel struct {
+	ID         uuid.UUID `db:"id"`
+	Device     string    `db:"device"`
+	IPAddress  string    `db:"ip_address"`
+	UserAgent  string    `db:"user_agent"`
+	Current    bool      `db:"current"`
+	ExpiresAt  time.Time `db:"expires_at"`
+	CreatedAt  time.Time `db:"created_at"`
+	LastSeenAt time.Time `db:"last_seen_at"`
+}
+
+type UserSessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	Device     string    `json:"device"`
+	IPAddress  string    `json:"ip_address"`
+	UserAgent  string    `json:"user_agent"`
+	Current    bool      `json:"current"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+}
+
+func (us *UserSessionModel) Response() UserSessionResponse {
+	return UserSessionResponse{
+		ID:         us.ID,
+		Device:     us.Device,
+		IPAddress:  us.IPAddress,
+		UserAgent:  us.UserAgent,
+		Current:    us.Current,
+		ExpiresAt:  us.ExpiresAt,
+		CreatedAt:  us.CreatedAt,
+		LastSeenAt: us.LastSeenAt,
+	}
+}
+
+// GetUserSessions lists the live sessions of the user, most recently seen
+// first. Current marks the session the access token with currentTokenHash
+// belongs to.
+func GetUserSessions(ctx context.Context, db sqlx.QueryerContext, userID uuid.UUID, currentTokenHash string) ([]UserSessionModel, error) {
+	query := `
+		WITH family AS (
+			SELECT
+				family_id,
+				MIN(created_at) AS created_at,
+				MAX(COALESCE(last_seen_at, created_at)) AS last_seen_at,
+				BOOL_OR(token_hash = $2) AS current
+			FROM sessions
+			WHERE user_id = $1
+			GROUP BY family_id
+		), latest AS (
+			SELECT DISTINCT ON (family_id)
+				family_id, device, ip_address, user_agent,
+				GREATEST(expiration, COALESCE(refresh_expires_at, expiration)) AS expires_at
+			FROM sessions
+			WHERE user_id = $1
+			AND ` + liveSession + `
+			ORDER BY family_id, created_at DESC
+		)
+		SELECT
+			l.family_id AS id,
+			COALESCE(l.device, '') AS device,
+			COALESCE(l.ip_address, '') AS ip_address,
+			COALESCE(l.user_agent, '') AS user_agent,
+			f.current,
+			l.expires_at,
+			f.created_at,
+			f.last_seen_at
+		FROM latest l
+		JOIN family f ON f.family_id = l.family_id
+		ORDER BY f.last_seen_at DESC, l.family_id
+	`
+
+	var sessions []UserSessionModel
+	err := sqlx.SelectContext(ctx, db, &sessions, query, userID, currentTokenHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// RevokeUserSession revokes the session family if it belongs to the user and
+// returns the revoked access token hashes; none means there was no such live
+// session.
+func RevokeUserSession(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, familyID uuid.UUID, revokedBy uuid.UUID) ([]string, error) {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW(), updated_at = NOW(), updated_by = $3
+		WHERE user_id = $1
+		AND family_id = $2
+		AND revoked_at IS NULL
+		RETURNING token_hash
+	`
+
+	var tokenHashes []string
+	err := sqlx.SelectContext(ctx, db, &tokenHashes, query, userID, familyID, revokedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenHashes, nil
+}
+
+// RevokedSessionModel is one token revoked along with its family.
+type RevokedSessionModel struct {
+	FamilyID  uuid.UUID `db:"family_id"`
+	TokenHash string    `db:"token_hash"`
+}
+
+// RevokeUserSessions revokes every session of the user except the family the
+// access token with keepTokenHash belongs to. An empty keepTokenHash revokes
+// them all.
+func RevokeUserSessions(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, keepTokenHash string, revokedBy uuid.UUID) ([]RevokedSessionModel, error) {
+	query := `
+		UPDATE sessions
+		SET revoked_at = NOW(), updated_at = NOW(), updated_by = $3
+		WHERE user_id = $1
+		AND revoked_at IS NULL
+		AND family_id IS DISTINCT FROM (SELECT family_id FROM sessions WHERE token_hash = $2)
+		RETURNING family_id, token_hash
+	`
+
+	var revoked []RevokedSessionModel
+	err := sqlx.SelectContext(ctx, db, &revoked, query, userID, keepTokenHash, revokedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return revoked, nil
+}
+
+// LockUserSessions serializes logins of the user until the transaction ends,
+// so concurrent logins cannot both slip under the session limit.
+func LockUserSessions(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID) error {
+	_, err := db.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('sessions:' || $1::text))`, userID)
 	return err
 }
+
+// EvictOldestSessions revokes the oldest live session families of the user so
+// that at most keep remain.
+func EvictOldestSessions(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, keep int) ([]RevokedSessionModel, error) {
+	query := `
+		WITH live AS (
+			SELECT family_id, MIN(created_at) AS created_at
+			FROM sessions
+			WHERE user_id = $1
+			AND ` + liveSession + `
+			GROUP BY family_id
+		), evicted AS (
+			SELECT family_id FROM live
+			ORDER BY created_at DESC, family_id
+			OFFSET $2
+		)
+		UPDATE sessions
+		SET revoked_at = NOW(), updated_at = NOW()
+		WHERE family_id IN (SELECT family_id FROM evicted)
+		AND revoked_at IS NULL
+		RETURNING family_id, token_hash
+	`
+
+	var revoked []RevokedSessionModel
+	err := sqlx.SelectContext(ctx, db, &revoked, query, userID, keep)
+	if err != nil {
+		return nil, err
+	}
+
+	return revoked, nil
+}
+
+// PurgeExpiredSessions deletes session families none of whose rows can still
+// be used. Families with a live row are kept whole so a rotated refresh token
+// presented again is still recognised as reuse.
+func PurgeExpiredSessions(ctx context.Context, db sqlx.ExtContext) (int64, error) {
+	query := `
+		DELETE FROM sessions s
+		WHERE NOT EXISTS (
+			SELECT 1 FROM sessions
+			WHERE family_id = s.family_id
+			AND ` + liveSession + `
+		)
+	`
+
+	result, err := db.ExecContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
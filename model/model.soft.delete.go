@@ -0,0 +1,130 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// SoftDeleteTable names a table that has deleted_at/deleted_by columns.
+type SoftDeleteTable string
+
+const (
+	TableAuthors       SoftDeleteTable = "authors"
+	TablePublishers    SoftDeleteTable = "publishers"
+	TableCategories    SoftDeleteTable = "categories"
+	TableBooks         SoftDeleteTable = "books"
+	TableRatings       SoftDeleteTable = "ratings"
+	TableRoles         SoftDeleteTable = "roles"
+	TableResources     SoftDeleteTable = "resources"
+	TableRoleResources SoftDeleteTable = "role_resources"
+)
+
+func nullTime(t pq.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// SoftDelete marks a live row as deleted. It returns sql.ErrNoRows when the row
+// does not exist or is already deleted.
+func SoftDelete(ctx context.Context, db sqlx.ExtContext, table SoftDeleteTable, id uuid.UUID, deletedBy uuid.UUID) error {
+	query := fmt.Sprintf(`
+		UPDATE
+			%s
+		SET
+			deleted_at = NOW(),
+			deleted_by = $1
+		WHERE
+			id = $2
+		AND deleted_at IS NULL
+	`, table)
+
+	result, err := db.ExecContext(ctx, query, deletedBy, id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result)
+}
+
+// Restore brings a deleted row back. It returns sql.ErrNoRows when the row
+// does not exist or is not deleted.
+func Restore(ctx context.Context, db sqlx.ExtContext, table SoftDeleteTable, id uuid.UUID, restoredBy uuid.UUID) error {
+	query := fmt.Sprintf(`
+		UPDATE
+			%s
+		SET
+			deleted_at = NULL,
+			deleted_by = NULL,
+			updated_at = NOW(),
+			updated_by = $1
+		WHERE
+			id = $2
+		AND deleted_at IS NOT NULL
+	`, table)
+
+	result, err := db.ExecContext(ctx, query, restoredBy, id)
+	if err != nil {
+		return err
+	}
+
+	return expectAffected(result)
+}
+
+func expectAffected(result sql.Result) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func GetActiveBookIDsByAuthor(ctx context.Context, db sqlx.ExtContext, authorID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		SELECT
+			id
+		FROM
+			books
+		WHERE
+			author_id = $1
+		AND deleted_at IS NULL
+	`
+
+	var bookIDs []uuid.UUID
+	err := sqlx.SelectContext(ctx, db, &bookIDs, query, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	return bookIDs, nil
+}
+
+// CountBookCirculation counts open loans and active holds on the given books.
+// Books in circulation are not deleted so the loan and hold flows stay consistent.
+func CountBookCirculation(ctx context.Context, db sqlx.ExtContext, bookIDs []uuid.UUID) (int, error) {
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM loans WHERE book_id = ANY($1) AND return_date IS NULL)
+			+ (SELECT COUNT(*) FROM reservations WHERE book_id = ANY($1) AND status IN ('waiting', 'ready'))
+	`
+
+	var count int
+	err := db.QueryRowxContext(ctx, query, pq.Array(bookIDs)).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,261 @@
+package model
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Purposes of a login challenge: verify asks for a code of an enrolled user,
+// enroll lets a user who must use two-factor set it up before getting a session.
+const (
+	ChallengeVerify = "verify"
+	ChallengeEnroll = "enroll"
+)
+
+// UserTOTPModel is the TOTP secret of a user. It only guards logins once
+// ConfirmedAt is set.
+type UserTOTPModel struct {
+	UserID       uuid.UUID   `db:"user_id"`
+	Secret       string      `db:"secret"`
+	ConfirmedAt  pq.NullTime `db:"confirmed_at"`
+	LastUsedStep int64       `db:"last_used_step"`
+	CreatedAt    time.Time   `db:"created_at"`
+	UpdatedAt    pq.NullTime `db:"updated_at"`
+}
+
+func GetUserTOTP(ctx context.Context, db sqlx.QueryerContext, userID uuid.UUID) (UserTOTPModel, error) {
+	query := `
+		SELECT user_id, secret, confirmed_at, last_used_step, created_at, updated_at
+		FROM user_totp
+		WHERE user_id = $1
+	`
+
+	totp := UserTOTPModel{}
+	err := db.QueryRowxContext(ctx, query, userID).StructScan(&totp)
+	if err != nil {
+		return totp, err
+	}
+
+	return totp, nil
+}
+
+// LockUserTOTP is GetUserTOTP holding the row until the transaction ends, so
+// two requests cannot use the same code.
+func LockUserTOTP(ctx context.Context, db sqlx.QueryerContext, userID uuid.UUID) (UserTOTPModel, error) {
+	query := `
+		SELECT user_id, secret, confirmed_at, last_used_step, created_at, updated_at
+		FROM user_totp
+		WHERE user_id = $1
+		FOR UPDATE
+	`
+
+	totp := UserTOTPModel{}
+	err := db.QueryRowxContext(ctx, query, userID).StructScan(&totp)
+	if err != nil {
+		return totp, err
+	}
+
+	return totp, nil
+}
+
+// SavePendingTOTP stores a new unconfirmed secret, replacing an earlier
+// unconfirmed one. It returns false when the user already has a confirmed secret.
+func SavePendingTOTP(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, secret string, now time.Time) (bool, error) {
+	query := `
+		INSERT INTO user_totp (user_id, secret, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = EXCLUDED.created_at, updated_at = NULL
+		WHERE user_totp.confirmed_at IS NULL
+	`
+
+	result, err := db.ExecContext(ctx, query, userID, secret, now)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// UseTOTPStep records step as used and confirms the secret if it was pending.
+func UseTOTPStep(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, step int64, now time.Time) error {
+	query := `
+		UPDATE user_totp
+		SET last_used_step = $2, confirmed_at = COALESCE(confirmed_at, $3), updated_at = $3
+		WHERE user_id = $1
+	`
+
+	_, err := db.ExecContext(ctx, query, userID, step, now)
+	return err
+}
+
+// DeleteUserTOTP turns two-factor off for the user, recovery codes included.
+func DeleteUserTOTP(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID) (bool, error) {
+	_, err := db.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := db.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// ReplaceRecoveryCodes drops every recovery code of the user and stores the
+// new ones, given as hashes.
+func ReplaceRecoveryCodes(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, codeHashes []string, now time.Time) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM user_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO user_recovery_codes (user_id, code_hash, created_at)
+		SELECT $1, code_hash, $3
+		FROM UNNEST($2::text[]) AS code_hash
+	`
+
+	_, err = db.ExecContext(ctx, query, userID, pq.Array(codeHashes), now)
+	return err
+}
+
+// UseRecoveryCode spends the recovery code with this hash. It returns false
+// when the code does not exist or was already used.
+func UseRecoveryCode(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, codeHash string, now time.Time) (bool, error) {
+	query := `
+		UPDATE user_recovery_codes
+		SET used_at = $3
+		WHERE user_id = $1
+		AND code_hash = $2
+		AND used_at IS NULL
+	`
+
+	result, err := db.ExecContext(ctx, query, userID, codeHash, now)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+func CountUnusedRecoveryCodes(ctx context.Context, db sqlx.QueryerContext, userID uuid.UUID) (int, error) {
+	var count int
+	err := db.QueryRowxContext(ctx, `SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// RolesRequireTwoFactor reports whether any of the roles, or a role they
+// inherit from, has one of the identifiers.
+func RolesRequireTwoFactor(ctx context.Context, db sqlx.QueryerContext, roleIDs []uuid.UUID, identifiers []string) (bool, error) {
+	if len(roleIDs) == 0 || len(identifiers) == 0 {
+		return false, nil
+	}
+
+	query := roleChainCTE + `
+		SELECT EXISTS (
+			SELECT 1
+			FROM chain
+			JOIN roles ro ON ro.id = chain.role_id
+			WHERE ro.identifier = ANY($2)
+		)
+	`
+
+	var required bool
+	err := db.QueryRowxContext(ctx, query, pq.Array(roleIDs), pq.Array(identifiers)).Scan(&required)
+	if err != nil {
+		return false, err
+	}
+
+	return required, nil
+}
+
+// LoginChallengeModel is the second step of a login. The token is only stored
+// as a hash and can be used once.
+type LoginChallengeModel struct {
+	ID        uuid.UUID   `db:"id"`
+	UserID    uuid.UUID   `db:"user_id"`
+	TokenHash string      `db:"token_hash"`
+	Purpose   string      `db:"purpose"`
+	Attempts  int         `db:"attempts"`
+	ExpiresAt time.Time   `db:"expires_at"`
+	UsedAt    pq.NullTime `db:"used_at"`
+	CreatedAt time.Time   `db:"created_at"`
+}
+
+func (lc *LoginChallengeModel) Insert(ctx context.Context, db sqlx.ExtContext) error {
+	query := `
+		INSERT INTO login_challenges (
+			id, user_id, token_hash, purpose, expires_at, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		)
+	`
+
+	_, err := db.ExecContext(ctx, query, lc.ID, lc.UserID, lc.TokenHash, lc.Purpose, lc.ExpiresAt, lc.CreatedAt)
+	return err
+}
+
+// LockLoginChallenge loads the challenge by token hash and holds it until the
+// transaction ends.
+func LockLoginChallenge(ctx context.Context, db sqlx.QueryerContext, tokenHash string) (LoginChallengeModel, error) {
+	query := `
+		SELECT id, user_id, token_hash, purpose, attempts, expires_at, used_at, created_at
+		FROM login_challenges
+		WHERE token_hash = $1
+		FOR UPDATE
+	`
+
+	challenge := LoginChallengeModel{}
+	err := db.QueryRowxContext(ctx, query, tokenHash).StructScan(&challenge)
+	if err != nil {
+		return challenge, err
+	}
+
+	return challenge, nil
+}
+
+func CountChallengeAttempt(ctx context.Context, db sqlx.ExtContext, id uuid.UUID) error {
+	_, err := db.ExecContext(ctx, `UPDATE login_challenges SET attempts = attempts + 1 WHERE id = $1`, id)
+	return err
+}
+
+func MarkChallengeUsed(ctx context.Context, db sqlx.ExtContext, id uuid.UUID, now time.Time) error {
+	_, err := db.ExecContext(ctx, `UPDATE login_challenges SET used_at = $2 WHERE id = $1`, id, now)
+	return err
+}
+
+// PurgeLoginChallenges deletes challenges that expired before now.
+func PurgeLoginChallenges(ctx context.Context, db sqlx.ExtContext, now time.Time) (int64, error) {
+	result, err := db.ExecContext(ctx, `DELETE FROM login_challenges WHERE expires_at < $1`, now)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
@@ -3,9 +3,8 @@ package model
 import (
 	"app-bookstore/lib"
 	"context"
+	"database/sql"
 	"errors"
-	"fmt"
-	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -14,52 +13,95 @@ import (
 )
 
 type UserModel struct {
-	ID        uuid.UUID     `db:"id"`
-	Username  string        `db:"username"`
-	Password  string        `db:"password"`
-	CreatedAt time.Time     `db:"created_at"`
-	CreatedBy uuid.UUID     `db:"created_by"`
-	UpdatedAt pq.NullTime   `db:"updated_at"`
-	UpdatedBy uuid.NullUUID `db:"updated_by"`
+	ID              uuid.UUID      `db:"id"`
+	Username        string         `db:"username"`
+	Email           sql.NullString `db:"email"`
+	EmailVerifiedAt pq.NullTime    `db:"email_verified_at"`
+	FullName        sql.NullString `db:"full_name"`
+	Phone           sql.NullString `db:"phone"`
+	MemberNumber    string         `db:"member_number"`
+	Status          string         `db:"status"`
+	PreviousStatus  sql.NullString `db:"previous_status"`
+	StatusReason    sql.NullString `db:"status_reason"`
+	StatusChangedAt pq.NullTime    `db:"status_changed_at"`
+	StatusChangedBy uuid.NullUUID  `db:"status_changed_by"`
+	Password        string         `db:"password"`
+	CreatedAt       time.Time      `db:"created_at"`
+	CreatedBy       uuid.UUID      `db:"created_by"`
+	UpdatedAt       pq.NullTime    `db:"updated_at"`
+	UpdatedBy       uuid.NullUUID  `db:"updated_by"`
 }
 
 type UserResponse struct {
-	ID        uuid.UUID `json:"id"`
-	Username  string    `json:"username"`
-	Password  string    `json:"-"`
-	CreatedAt time.Time `json:"created_at"`
-	CreatedBy uuid.UUID `json:"created_by"`
-	UpdatedAt time.Time `json:"updated_at"`
-	UpdatedBy uuid.UUID `json:"updated_by"`
+	ID              uuid.UUID  `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email,omitempty"`
+	EmailVerified   bool       `json:"email_verified"`
+	FullName        string     `json:"full_name,omitempty"`
+	Phone           string     `json:"phone,omitempty"`
+	MemberNumber    string     `json:"member_number"`
+	Status          string     `json:"status"`
+	StatusReason    string     `json:"status_reason,omitempty"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+	Password        string     `json:"-"`
+	CreatedAt       time.Time  `json:"created_at"`
+	CreatedBy       uuid.UUID  `json:"created_by"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	UpdatedBy       uuid.UUID  `json:"updated_by"`
 }
 
 func (u *UserModel) Response() UserResponse {
-	return UserResponse{
-		ID:        u.ID,
-		Username:  u.Username,
-		Password:  u.Password,
-		CreatedAt: u.CreatedAt,
-		CreatedBy: u.CreatedBy,
-		UpdatedAt: u.UpdatedAt.Time,
-		UpdatedBy: u.UpdatedBy.UUID,
+	response := UserResponse{
+		ID:            u.ID,
+		Username:      u.Username,
+		Email:         u.Email.String,
+		EmailVerified: u.EmailVerifiedAt.Valid,
+		FullName:      u.FullName.String,
+		Phone:         u.Phone.String,
+		MemberNumber:  u.MemberNumber,
+		Status:        u.Status,
+		StatusReason:  u.StatusReason.String,
+		Password:      u.Password,
+		CreatedAt:     u.CreatedAt,
+		CreatedBy:     u.CreatedBy,
+		UpdatedAt:     u.UpdatedAt.Time,
+		UpdatedBy:     u.UpdatedBy.UUID,
 	}
+
+	if u.StatusChangedAt.Valid {
+		response.StatusChangedAt = &u.StatusChangedAt.Time
+	}
+	return response
 }
 
+// userColumns are the columns of a single user lookup.
+const userColumns = `
+	id, username, email, email_verified_at, full_name, phone, member_number,
+	status, previous_status, status_reason, status_changed_at, status_changed_by, password,
+	created_at, created_by, updated_at, updated_by
+`
+
 type DateFilter struct {
 	StartDate time.Time `json:"start_date"`
 	EndDate   time.Time `json:"end_date"`
 }
 
-func GetAllUser(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter DateFilter) ([]UserModel, error) {
-	var filters []string
+// userSortColumns lists the columns GET /users may be sorted by.
+var userSortColumns = map[string]string{
+	"created_at": "u.created_at",
+	"username":   "u.username",
+}
+
+func GetAllUser(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter DateFilter) ([]UserModel, int64, error) {
+	qb := lib.NewQueryBuilder()
 	var statuses []string
 
 	if filter.Search != "" {
-		filters = append(filters, fmt.Sprintf("u.username ILIKE '%%%s%%'", filter.Search))
+		qb.WhereContains("u.username", filter.Search)
 	}
 
 	if filter.RoleID != uuid.Nil {
-		filters = append(filters, fmt.Sprintf("ur.role_id = '%s'", filter.RoleID))
+		qb.Where("ur.role_id = ?", filter.RoleID)
 	}
 
 	if filter.IsPending {
@@ -75,44 +117,47 @@ func GetAllUser(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter
 	}
 
 	if len(statuses) > 0 {
-		placeHOlders := make([]string, len(statuses))
-		for i, status := range statuses {
-			placeHOlders[i] = fmt.Sprintf("'%s'", status)
-		}
-
-		filters = append(filters, fmt.Sprintf("ur.status IN (%s)", strings.Join(placeHOlders, ", ")))
+		qb.WhereIn("ur.status", statuses)
 	}
 
 	if !dateFilter.StartDate.IsZero() && !dateFilter.EndDate.IsZero() {
-		filters = append(filters, fmt.Sprintf(
-			"u.created_at BETWEEN '%s' AND '%s'",
-			dateFilter.StartDate.Format("2006-01-02"),
-			dateFilter.EndDate.Format("2006-01-02"),
-		))
+		qb.Where("u.created_at BETWEEN ? AND ?", dateFilter.StartDate, dateFilter.EndDate)
 	}
 
-	query := fmt.Sprintf(
-		`
+	qb.Page(filter, userSortColumns, "created_at", "u.created_at", "u.id")
+
+	from := `
+		FROM 
+			users u
+		LEFT JOIN 
+			user_requests ur 
+		ON 
+			u.id = ur.user_id
+	`
+
+	total, err := countRows(ctx, db, from, qb)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
 		SELECT 
 			u.id, 
 			u.username, 
+			u.email, 
+			u.email_verified_at, 
+			u.full_name, 
+			u.phone, 
+			u.member_number, 
+			u.status, 
 			u.created_at, 
 			u.created_by, 
 			u.updated_at, 
 			u.updated_by 
-		FROM 
-			users u
-		LEFT JOIN 
-			user_requests ur 
-		ON 
-			u.id = ur.user_id
-		%s
-		ORDER BY u.created_at %s
-		LIMIT $1 OFFSET $2
-	`, lib.SearchGenerate(ctx, "AND", filters), filter.Dir)
-	rows, err := db.QueryxContext(ctx, query, filter.Limit, filter.Offset)
+	` + from + qb.Clauses()
+	rows, err := db.QueryxContext(ctx, query, qb.Args()...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	defer rows.Close()
@@ -122,21 +167,18 @@ func GetAllUser(ctx context.Context, db *sqlx.DB, filter lib.Filter, dateFilter
 		var user UserModel
 		err := rows.StructScan(&user)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		users = append(users, user)
 	}
-	return users, err
+	return users, total, err
 }
 
 func GetUserByUsername(ctx context.Context, db *sqlx.DB, username string) (*UserModel, error) {
 	var user UserModel
 	query := `
-		SELECT 
-			id,
-			username,
-			password
+		SELECT ` + userColumns + `
 		FROM
 			users
 		WHERE
@@ -153,9 +195,7 @@ func GetUserByUsername(ctx context.Context, db *sqlx.DB, username string) (*User
 func GetUserByID(ctx context.Context, db *sqlx.DB, userID uuid.UUID) (*UserModel, error) {
 	var user UserModel
 	query := `
-		SELECT
-			id,
-			password
+		SELECT ` + userColumns + `
 		FROM
 			users
 		WHERE
@@ -170,23 +210,32 @@ func GetUserByID(ctx context.Context, db *sqlx.DB, userID uuid.UUID) (*UserModel
 	return &user, nil
 }
 
-func (u *UserModel) Insert(ctx context.Context, db *sqlx.DB) error {
+func (u *UserModel) Insert(ctx context.Context, db sqlx.ExtContext) error {
 	query := `
 		INSERT INTO users (
 			username,
+			email,
+			full_name,
+			phone,
+			status,
 			password,
 			created_by
 		) VALUES (
-			$1, $2, $3
-		) RETURNING id, created_at
+			$1, $2, $3, $4, $5, $6, $7
+		) RETURNING id, member_number, created_at
 	`
 
 	err := db.QueryRowxContext(ctx, query,
 		u.Username,
+		u.Email,
+		u.FullName,
+		u.Phone,
+		u.Status,
 		u.Password,
 		u.CreatedBy,
 	).Scan(
 		&u.ID,
+		&u.MemberNumber,
 		&u.CreatedAt,
 	)
 
@@ -213,3 +262,99 @@ func Update(ctx context.Context, db *sqlx.DB, userID uuid.UUID, hashPass string)
 	}
 	return nil
 }
+
+// GetUserStatus is the account status of the user.
+func GetUserStatus(ctx context.Context, db sqlx.QueryerContext, userID uuid.UUID) (string, error) {
+	var status string
+	err := db.QueryRowxContext(ctx, `SELECT status FROM users WHERE id = $1`, userID).Scan(&status)
+	if err != nil {
+		return "", err
+	}
+	return status, nil
+}
+
+// LockUserStatus is GetUserStatus holding a share lock on the user until the
+// transaction ends, so a session cannot be issued while the status changes.
+func LockUserStatus(ctx context.Context, db sqlx.QueryerContext, userID uuid.UUID) (string, error) {
+	var status string
+	err := db.QueryRowxContext(ctx, `SELECT status FROM users WHERE id = $1 FOR SHARE`, userID).Scan(&status)
+	if err != nil {
+		return "", err
+	}
+	return status, nil
+}
+
+// SetUserStatus moves the user to status if their status is one of from. It
+// returns false when the user does not exist or is in another status.
+func SetUserStatus(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, from []string, status string, reason string, actor uuid.UUID) (bool, error) {
+	query := `
+		UPDATE users
+		SET previous_status = status,
+			status = $3,
+			status_reason = NULLIF($4, ''),
+			status_changed_at = NOW(),
+			status_changed_by = $5,
+			updated_at = NOW(),
+			updated_by = $5
+		WHERE id = $1
+		AND status = ANY($2)
+	`
+
+	result, err := db.ExecContext(ctx, query, userID, pq.Array(from), status, reason, actor)
+	if err != nil {
+		return false, err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return affected > 0, nil
+}
+
+// UpdateUserProfile sets the fields a user may edit themselves.
+func UpdateUserProfile(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, fullName sql.NullString, phone sql.NullString) error {
+	query := `
+		UPDATE users
+		SET full_name = $2,
+			phone = $3,
+			updated_at = NOW(),
+			updated_by = $1
+		WHERE id = $1
+	`
+
+	_, err := db.ExecContext(ctx, query, userID, fullName, phone)
+	return err
+}
+
+// VerifyUserEmail stores email as the verified address of the user and
+// activates a user who was waiting for verification.
+func VerifyUserEmail(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, email string) error {
+	query := `
+		UPDATE users
+		SET email = $2,
+			email_verified_at = NOW(),
+			status = CASE WHEN status = $3 THEN $4 ELSE status END,
+			status_changed_at = CASE WHEN status = $3 THEN NOW() ELSE status_changed_at END,
+			status_changed_by = CASE WHEN status = $3 THEN $1 ELSE status_changed_by END,
+			updated_at = NOW(),
+			updated_by = $1
+		WHERE id = $1
+	`
+
+	_, err := db.ExecContext(ctx, query, userID, email, lib.UserPendingVerification, lib.UserActive)
+	return err
+}
+
+// EmailInUse reports whether another user has already verified email as their
+// address. Unverified addresses do not count, the same as idx_users_verified_email.
+func EmailInUse(ctx context.Context, db sqlx.QueryerContext, email string, exceptUserID uuid.UUID) (bool, error) {
+	var inUse bool
+	query := `SELECT EXISTS(SELECT 1 FROM users WHERE LOWER(email) = LOWER($1) AND email_verified_at IS NOT NULL AND id <> $2)`
+	err := db.QueryRowxContext(ctx, query, email, exceptUserID).Scan(&inUse)
+	if err != nil {
+		return false, err
+	}
+	return inUse, nil
+}
@@ -1,7 +1,9 @@
 package model
 
 import (
+	"app-bookstore/lib"
 	"context"
+	"database/sql"
 	"time"
 
 	"github.com/google/uuid"
@@ -9,26 +11,38 @@ import (
 	"github.com/lib/pq"
 )
 
+const (
+	UserRequestPending  = "pending"
+	UserRequestApproved = "approved"
+	UserRequestRejected = "rejected"
+)
+
 type UserRequestModel struct {
-	ID              uuid.UUID     `db:"id"`
-	UserID          uuid.UUID     `db:"user_id"`
-	RequestUserRole uuid.UUID     `db:"requested_role_id"`
-	Status          string        `db:"status"`
-	CreatedAt       time.Time     `db:"created_at"`
-	CreatedBy       uuid.UUID     `db:"created_by"`
-	UpdatedAt       pq.NullTime   `db:"updated_at"`
-	UpdatedBy       uuid.NullUUID `db:"updated_by"`
+	ID              uuid.UUID      `db:"id"`
+	UserID          uuid.UUID      `db:"user_id"`
+	RequestUserRole uuid.UUID      `db:"requested_role_id"`
+	Status          string         `db:"status"`
+	RejectionReason sql.NullString `db:"rejection_reason"`
+	ReviewedBy      uuid.NullUUID  `db:"reviewed_by"`
+	ReviewedAt      pq.NullTime    `db:"reviewed_at"`
+	CreatedAt       time.Time      `db:"created_at"`
+	CreatedBy       uuid.UUID      `db:"created_by"`
+	UpdatedAt       pq.NullTime    `db:"updated_at"`
+	UpdatedBy       uuid.NullUUID  `db:"updated_by"`
 }
 
 type UserRequestResponse struct {
-	ID              uuid.UUID `json:"id"`
-	UserID          uuid.UUID `json:"user_id"`
-	RequestUserRole uuid.UUID `json:"requested_role_id"`
-	Status          string    `json:"status"`
-	CreatedAt       time.Time `json:"created_at"`
-	CreatedBy       uuid.UUID `json:"created_by"`
-	UpdatedAt       time.Time `json:"updated_at"`
-	UpdatedBy       uuid.UUID `json:"updated_by"`
+	ID              uuid.UUID  `json:"id"`
+	UserID          uuid.UUID  `json:"user_id"`
+	RequestUserRole uuid.UUID  `json:"requested_role_id"`
+	Status          string     `json:"status"`
+	RejectionReason string     `json:"rejection_reason,omitempty"`
+	ReviewedBy      uuid.UUID  `json:"reviewed_by"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	CreatedBy       uuid.UUID  `json:"created_by"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	UpdatedBy       uuid.UUID  `json:"updated_by"`
 }
 
 func (ur *UserRequestModel) Response() UserRequestResponse {
@@ -37,6 +51,9 @@ func (ur *UserRequestModel) Response() UserRequestResponse {
 		UserID:          ur.UserID,
 		RequestUserRole: ur.RequestUserRole,
 		Status:          ur.Status,
+		RejectionReason: ur.RejectionReason.String,
+		ReviewedBy:      ur.ReviewedBy.UUID,
+		ReviewedAt:      nullTime(ur.ReviewedAt),
 		CreatedAt:       ur.CreatedAt,
 		CreatedBy:       ur.CreatedBy,
 		UpdatedAt:       ur.UpdatedAt.Time,
@@ -63,24 +80,69 @@ func CheckRoleExist(ctx context.Context, db *sqlx.DB, roleID uuid.UUID) (bool, e
 	return exists, nil
 }
 
-func GetAllUserRequest(ctx context.Context, db *sqlx.DB) ([]UserRequestModel, error) {
+// CheckLiveRoleExist is CheckRoleExist for roles that are not soft deleted.
+func CheckLiveRoleExist(ctx context.Context, db sqlx.QueryerContext, roleID uuid.UUID) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(
+						SELECT 
+							1 
+						FROM 
+							roles 
+						WHERE 
+							id = $1
+						AND deleted_at IS NULL
+						)`
+
+	err := db.QueryRowxContext(ctx, query, roleID).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// userRequestSortColumns lists the columns the user request list may be sorted by.
+var userRequestSortColumns = map[string]string{
+	"created_at": "created_at",
+	"status":     "status",
+}
+
+// GetAllUserRequest lists requests, only those of userID unless it is uuid.Nil.
+func GetAllUserRequest(ctx context.Context, db *sqlx.DB, filter lib.Filter, userID uuid.UUID) ([]UserRequestModel, int64, error) {
+	qb := lib.NewQueryBuilder()
+	if userID != uuid.Nil {
+		qb.Where("user_id = ?", userID)
+	}
+	qb.Page(filter, userRequestSortColumns, "created_at", "created_at", "id")
+
+	from := `
+		FROM
+		    user_requests
+	`
+
+	total, err := countRows(ctx, db, from, qb)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	query := `
 		SELECT
 		    id, 
             user_id, 
             requested_role_id, 
             status, 
+            rejection_reason,
+            reviewed_by,
+            reviewed_at,
             created_at,
 			created_by,
 			updated_at,
 			updated_by
-		FROM
-		    user_requests
-	`
+	` + from + qb.Clauses()
 
-	rows, err := db.QueryxContext(ctx, query)
+	rows, err := db.QueryxContext(ctx, query, qb.Args()...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	defer rows.Close()
@@ -90,18 +152,18 @@ func GetAllUserRequest(ctx context.Context, db *sqlx.DB) ([]UserRequestModel, er
 		var userRequest UserRequestModel
 		err := rows.StructScan(&userRequest)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 
 		userRequests = append(userRequests, userRequest)
 	}
-	return userRequests, err
+	return userRequests, total, err
 }
 
 func GetOneUserRequest(ctx context.Context, db *sqlx.DB, id uuid.UUID) (UserRequestModel, error) {
 	query := `
 		SELECT
-			id, user_id, requested_role_id, status, created_at, created_by, updated_at, updated_by
+			id, user_id, requested_role_id, status, rejection_reason, reviewed_by, reviewed_at, created_at, created_by, updated_at, updated_by
 		FROM
 			user_requests
 		WHERE id = $1
@@ -149,29 +211,80 @@ func (ur *UserRequestModel) Insert(ctx context.Context, db *sqlx.DB) error {
 	return nil
 }
 
-func (ur *UserRequestModel) Update(ctx context.Context, db *sqlx.DB, id uuid.UUID) error {
+// GetUserRequestForReview locks the request row until the transaction ends so
+// two reviewers cannot process it at the same time.
+func GetUserRequestForReview(ctx context.Context, db sqlx.QueryerContext, id uuid.UUID) (UserRequestModel, error) {
+	query := `
+		SELECT
+			id, user_id, requested_role_id, status, rejection_reason, reviewed_by, reviewed_at, created_at, created_by, updated_at, updated_by
+		FROM
+			user_requests
+		WHERE id = $1
+		FOR UPDATE
+	`
+
+	userRequest := UserRequestModel{}
+	err := db.QueryRowxContext(ctx, query, id).StructScan(&userRequest)
+	if err != nil {
+		return userRequest, err
+	}
+
+	return userRequest, nil
+}
+
+func HasPendingUserRequest(ctx context.Context, db *sqlx.DB, userID uuid.UUID, roleID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM user_requests
+			WHERE user_id = $1
+			AND requested_role_id = $2
+			AND status = 'pending'
+		)
+	`
+
+	var exists bool
+	err := db.QueryRowxContext(ctx, query, userID, roleID).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// Review records the decision on a pending request. The status guard makes a
+// second review of the same request update nothing and return sql.ErrNoRows.
+func (ur *UserRequestModel) Review(ctx context.Context, db sqlx.ExtContext) error {
 	query := `
 		UPDATE
 			user_requests
 		SET
 			status = $1,
-			updated_at = $2,
+			rejection_reason = $2,
+			reviewed_by = $3,
+			reviewed_at = $4,
+			updated_at = $4,
 			updated_by = $3
 		WHERE
-			id = $4
-		RETURNING id, user_id, requested_role_id, status, created_at, created_by, updated_at, updated_by
+			id = $5
+		AND status = 'pending'
+		RETURNING id, user_id, requested_role_id, status, rejection_reason, reviewed_by, reviewed_at, created_at, created_by, updated_at, updated_by
 	`
 
 	err := db.QueryRowxContext(ctx, query,
 		ur.Status,
-		ur.UpdatedAt.Time,
-		ur.UpdatedBy.UUID,
+		ur.RejectionReason,
+		ur.ReviewedBy,
+		ur.ReviewedAt,
 		ur.ID,
 	).Scan(
 		&ur.ID,
 		&ur.UserID,
 		&ur.RequestUserRole,
 		&ur.Status,
+		&ur.RejectionReason,
+		&ur.ReviewedBy,
+		&ur.ReviewedAt,
 		&ur.CreatedAt,
 		&ur.CreatedBy,
 		&ur.UpdatedAt,
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"app-bookstore/lib"
 	"context"
 	"time"
 
@@ -41,7 +42,25 @@ func (uro *UserRoleModel) Response() UserRoleResponse {
 	}
 }
 
-func GetAllUserRoles(ctx context.Context, db *sqlx.DB) ([]UserRoleModel, error) {
+// userRoleSortColumns lists the columns the user role list may be sorted by.
+var userRoleSortColumns = map[string]string{
+	"created_at": "created_at",
+}
+
+func GetAllUserRoles(ctx context.Context, db *sqlx.DB, filter lib.Filter) ([]UserRoleModel, int64, error) {
+	qb := lib.NewQueryBuilder()
+	qb.Page(filter, userRoleSortColumns, "created_at", "created_at", "id")
+
+	from := `
+		FROM 
+			user_roles
+	`
+
+	total, err := countRows(ctx, db, from, qb)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	query := `
 		SELECT 
 			id, 
@@ -51,13 +70,11 @@ func GetAllUserRoles(ctx context.Context, db *sqlx.DB) ([]UserRoleModel, error)
 			created_by, 
 			updated_at, 
 			updated_by
-		FROM 
-			user_roles
-	`
+	` + from + qb.Clauses()
 
-	rows, err := db.QueryxContext(ctx, query)
+	rows, err := db.QueryxContext(ctx, query, qb.Args()...)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 
 	defer rows.Close()
@@ -67,12 +84,12 @@ func GetAllUserRoles(ctx context.Context, db *sqlx.DB) ([]UserRoleModel, error)
 		var userRole UserRoleModel
 		err := rows.StructScan(&userRole)
 		if err != nil {
-			return nil, err
+			return nil, 0, err
 		}
 		userRoles = append(userRoles, userRole)
 	}
 
-	return userRoles, err
+	return userRoles, total, err
 }
 
 func GetOneUserRole(ctx context.Context, db *sqlx.DB, id uuid.UUID) (UserRoleModel, error) {
@@ -98,7 +115,7 @@ func GetOneUserRole(ctx context.Context, db *sqlx.DB, id uuid.UUID) (UserRoleMod
 	return userRole, nil
 }
 
-func (uro *UserRoleModel) Insert(ctx context.Context, db *sqlx.DB) error {
+func (uro *UserRoleModel) Insert(ctx context.Context, db sqlx.ExtContext) error {
 	query := `
 		INSERT INTO user_roles (
 			id, 
@@ -165,7 +182,7 @@ func (uro *UserRoleModel) Update(ctx context.Context, db *sqlx.DB) error {
 	return nil
 }
 
-func GetGuestRoleID(ctx context.Context, db *sqlx.DB) (uuid.UUID, error) {
+func GetGuestRoleID(ctx context.Context, db sqlx.QueryerContext) (uuid.UUID, error) {
 	var roleID uuid.UUID
 	query := `
 		SELECT
@@ -185,7 +202,7 @@ func GetGuestRoleID(ctx context.Context, db *sqlx.DB) (uuid.UUID, error) {
 	return roleID, nil
 }
 
-func (uro *UserRoleModel) AssignGuestRoles(ctx context.Context, db *sqlx.DB) error {
+func (uro *UserRoleModel) AssignGuestRoles(ctx context.Context, db sqlx.ExtContext) error {
 	query := `
 		INSERT INTO user_roles(
 			id, 
@@ -215,3 +232,65 @@ func (uro *UserRoleModel) AssignGuestRoles(ctx context.Context, db *sqlx.DB) err
 
 	return nil
 }
+
+func GetUserRoleIdentifiers(ctx context.Context, db *sqlx.DB, userID uuid.UUID) ([]string, error) {
+	query := `
+		SELECT
+			r.identifier
+		FROM
+			user_roles ur
+		INNER JOIN
+			roles r
+		ON
+			r.id = ur.role_id
+		WHERE
+			ur.user_id = $1
+		AND r.deleted_at IS NULL
+	`
+
+	var identifiers []string
+	err := db.SelectContext(ctx, &identifiers, query, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return identifiers, nil
+}
+
+func UserHasRole(ctx context.Context, db sqlx.QueryerContext, userID uuid.UUID, roleID uuid.UUID) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM user_roles
+			WHERE user_id = $1
+			AND role_id = $2
+		)
+	`
+
+	var exists bool
+	err := db.QueryRowxContext(ctx, query, userID, roleID).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+// RemoveUserRole takes the role away from the user and returns the removed
+// assignment IDs.
+func RemoveUserRole(ctx context.Context, db sqlx.ExtContext, userID uuid.UUID, roleID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+		DELETE FROM user_roles
+		WHERE user_id = $1
+		AND role_id = $2
+		RETURNING id
+	`
+
+	var removed []uuid.UUID
+	err := sqlx.SelectContext(ctx, db, &removed, query, userID, roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	return removed, nil
+}
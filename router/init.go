@@ -2,6 +2,7 @@ package router
 
 import (
 	"app-bookstore/api"
+	"app-bookstore/config"
 	"app-bookstore/lib"
 
 	"github.com/jmoiron/sqlx"
@@ -9,6 +10,7 @@ import (
 
 var (
 	userService          *api.UserModule
+	sessionService       *api.SessionModule
 	roleService          *api.RoleModule
 	userRequestService   *api.UserRequestModule
 	userRolesService     *api.UserRoleModule
@@ -19,22 +21,38 @@ var (
 	publisherService     *api.PublisherModule
 	categoriesService    *api.CategoryModule
 	bookService          *api.BookModule
+	bookCopyService      *api.BookCopyModule
 	loanService          *api.LoansModule
+	loanPolicyService    *api.LoanPolicyModule
+	reservationService   *api.ReservationModule
 	ratingService        *api.RatingModule
+	auditLogService      *api.AuditLogModule
+
+	permissionCache *lib.PermissionCache
+	jwtService      lib.Jwt
 )
 
-func Init(db *sqlx.DB, jwt lib.Jwt) {
-	userService = api.NewUserModule(db, jwt)
-	roleService = api.NewRoleModule(db, jwt)
-	userRequestService = api.NewUserRequestModule(db, jwt)
+func Init(db *sqlx.DB, jwt lib.Jwt, cfg *config.Config, cache *lib.PermissionCache, mailer lib.Mailer) {
+	permissionCache = cache
+	jwtService = jwt
+	accessPolicy := api.NewAccessPolicy(db, cfg.Access)
+
+	userService = api.NewUserModule(db, jwt, cfg, mailer)
+	sessionService = api.NewSessionModule(db, jwt)
+	roleService = api.NewRoleModule(db, jwt, accessPolicy)
+	userRequestService = api.NewUserRequestModule(db, jwt, cfg.Access)
 	userRolesService = api.NewUserRolesModule(db, jwt)
-	resourceService = api.NewResourceModule(db, jwt)
-	roleResourceService = api.NewRoleResourceModule(db, jwt)
-	passwordResetService = api.NewPasswordResetModule(db, jwt)
-	authorsService = api.NewUserAuthorsModule(db, jwt)
-	publisherService = api.NewPublisherModule(db, jwt)
-	categoriesService = api.NewCategoriesModule(db, jwt)
-	bookService = api.NewBooksModule(db, jwt)
-	loanService = api.NewLoansModule(db, jwt)
-	ratingService = api.NewRatingModule(db, jwt)
+	resourceService = api.NewResourceModule(db, jwt, accessPolicy)
+	roleResourceService = api.NewRoleResourceModule(db, jwt, accessPolicy)
+	passwordResetService = api.NewPasswordResetModule(db, jwt, cfg, mailer)
+	authorsService = api.NewUserAuthorsModule(db, jwt, accessPolicy)
+	publisherService = api.NewPublisherModule(db, jwt, accessPolicy)
+	categoriesService = api.NewCategoriesModule(db, jwt, accessPolicy)
+	bookService = api.NewBooksModule(db, jwt, accessPolicy)
+	bookCopyService = api.NewBookCopiesModule(db, jwt)
+	loanService = api.NewLoansModule(db, jwt, cfg.Loan, accessPolicy)
+	loanPolicyService = api.NewLoanPolicyModule(db, jwt)
+	reservationService = api.NewReservationModule(db, jwt, cfg.Loan, accessPolicy)
+	ratingService = api.NewRatingModule(db, jwt, accessPolicy)
+	auditLogService = api.NewAuditLogModule(db, jwt)
 }
@@ -0,0 +1,50 @@
+package router
+
+import (
+	"app-bookstore/config"
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// StartJobs runs the server's periodic background work until ctx is done.
+func StartJobs(ctx context.Context, cfg *config.Config) {
+	go runEvery(ctx, "loan-overdue", cfg.Loan.SweepInterval, loanService.RefreshOverdue)
+	go runEvery(ctx, "hold-expiry", cfg.Loan.HoldSweepInterval, reservationService.ExpireHolds)
+	go runEvery(ctx, "session-purge", cfg.Session.PurgeInterval, sessionService.PurgeExpired)
+	go runEvery(ctx, "login-purge", cfg.Session.PurgeInterval, userService.PurgeLoginRecords)
+
+	if permissionCache != nil {
+		go runEvery(ctx, "permission-cache-sweep", cfg.Cache.SessionTTL, sweepPermissionCache)
+	}
+}
+
+func sweepPermissionCache(ctx context.Context) error {
+	if removed := permissionCache.Sweep(); removed > 0 {
+		log.Debug().Msgf("Swept %d expired permission cache entries", removed)
+	}
+	return nil
+}
+
+func runEvery(ctx context.Context, name string, interval time.Duration, job func(context.Context) error) {
+	if interval <= 0 {
+		log.Info().Msgf("[Job %s] disabled", name)
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(ctx); err != nil {
+			log.Error().Err(err).Msgf("[Job %s] failed", name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
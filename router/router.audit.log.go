@@ -0,0 +1,72 @@
+package router
+
+import (
+	"app-bookstore/lib"
+	"app-bookstore/model"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func HandlerAuditLogList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	res, err := lib.ParseQueryParam(ctx, r)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid query parameter", err)
+		return
+	}
+
+	query := r.URL.Query()
+	auditFilter := model.AuditLogFilter{
+		Entity: query.Get("entity"),
+		Action: query.Get("action"),
+	}
+
+	if actorIDStr := query.Get("actor_id"); actorIDStr != "" {
+		auditFilter.ActorID, err = uuid.Parse(actorIDStr)
+		if err != nil {
+			lib.Error(w, http.StatusBadRequest, "invalid actor_id", nil)
+			return
+		}
+	}
+
+	if entityIDStr := query.Get("entity_id"); entityIDStr != "" {
+		auditFilter.EntityID, err = uuid.Parse(entityIDStr)
+		if err != nil {
+			lib.Error(w, http.StatusBadRequest, "invalid entity_id", nil)
+			return
+		}
+	}
+
+	startDateStr := query.Get("start_date")
+	endDateStr := query.Get("end_date")
+
+	var dateFilter model.DateFilter
+	if startDateStr != "" && endDateStr != "" {
+		startDate, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			lib.Error(w, http.StatusBadRequest, "invalid start_date format, use YYYY-MM-DD", nil)
+			return
+		}
+		endDate, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			lib.Error(w, http.StatusBadRequest, "invalid end_date format, use YYYY-MM-DD", nil)
+			return
+		}
+
+		// end_date ikut dihitung sampai akhir hari
+		dateFilter = model.DateFilter{
+			StartDate: startDate,
+			EndDate:   endDate.Add(24*time.Hour - time.Nanosecond),
+		}
+	}
+
+	auditLogResponse, pagination, err := auditLogService.List(ctx, res, auditFilter, dateFilter)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retrieve audit logs", err)
+		return
+	}
+
+	lib.SuccessList(w, r, "success to retrieve audit logs", auditLogResponse, pagination)
+}
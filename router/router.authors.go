@@ -16,7 +16,7 @@ func HandlerAuthorsList(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	res, err := lib.ParseQueryParam(ctx, r)
 	if err != nil {
-		http.Error(w, "invalid query parameter", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid query parameter", err)
 		return
 	}
 
@@ -27,12 +27,12 @@ func HandlerAuthorsList(w http.ResponseWriter, r *http.Request) {
 	if startDateStr != "" && endDateStr != "" {
 		startDate, err := time.Parse("2006-01-02", startDateStr)
 		if err != nil {
-			http.Error(w, "invalid start_date format, use YYYY-MM-DD", http.StatusBadRequest)
+			lib.Error(w, http.StatusBadRequest, "invalid start_date format, use YYYY-MM-DD", nil)
 			return
 		}
 		endDate, err := time.Parse("2006-01-02", endDateStr)
 		if err != nil {
-			http.Error(w, "invalid end_date format, use YYYY-MM-DD", http.StatusBadRequest)
+			lib.Error(w, http.StatusBadRequest, "invalid end_date format, use YYYY-MM-DD", nil)
 			return
 		}
 
@@ -42,13 +42,13 @@ func HandlerAuthorsList(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	authorsResponse, err := authorsService.List(ctx, res, dateFilter)
+	authorsResponse, pagination, err := authorsService.List(ctx, res, dateFilter)
 	if err != nil {
 		lib.Error(w, http.StatusBadRequest, "failed to retrieve authors list", err)
 		return
 	}
 
-	lib.Success(w, "success to retrieve authors list", authorsResponse)
+	lib.SuccessList(w, r, "success to retrieve authors list", authorsResponse, pagination)
 }
 
 func HandlerAuthorsDetail(w http.ResponseWriter, r *http.Request) {
@@ -58,7 +58,7 @@ func HandlerAuthorsDetail(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid authors id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid authors id", nil)
 		return
 	}
 
@@ -76,20 +76,20 @@ func HandlerAuthorsCreate(w http.ResponseWriter, r *http.Request) {
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusInternalServerError)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.AuthorsParam
 	err := lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -109,26 +109,26 @@ func HandlerAuthorsUpdate(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid authors id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid authors id", nil)
 		return
 	}
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusInternalServerError)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.AuthorsParam
 	err = lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body"+err.Error(), http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -140,3 +140,69 @@ func HandlerAuthorsUpdate(w http.ResponseWriter, r *http.Request) {
 
 	lib.Success(w, "authors successfully updated", authorsResponse)
 }
+
+func HandlerAuthorsDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid author id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	force := r.URL.Query().Get("force") == "true"
+
+	err = authorsService.Delete(ctx, token, id, force)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to delete author", err)
+		return
+	}
+
+	lib.Success(w, "author successfully deleted", nil)
+}
+
+func HandlerAuthorsRestore(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid author id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	authorResponse, err := authorsService.Restore(ctx, token, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to restore author", err)
+		return
+	}
+
+	lib.Success(w, "author successfully restored", authorResponse)
+}
@@ -0,0 +1,134 @@
+package router
+
+import (
+	"app-bookstore/api"
+	"app-bookstore/lib"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func HandlerBookCopyList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	res, err := lib.ParseQueryParam(ctx, r)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid query parameter", err)
+		return
+	}
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	bookID, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid book id", nil)
+		return
+	}
+
+	copyResponse, pagination, err := bookCopyService.List(ctx, bookID, res)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retrieve book copies", err)
+		return
+	}
+
+	lib.SuccessList(w, r, "success to retrieve book copies", copyResponse, pagination)
+}
+
+func HandlerBookCopyDetail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid copy id", nil)
+		return
+	}
+
+	copyResponse, err := bookCopyService.Detail(ctx, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retrieve detail on book copy", err)
+		return
+	}
+
+	lib.Success(w, "success to retrieve detail on book copy", copyResponse)
+}
+
+func HandlerBookCopyCreate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	bookID, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid book id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	var input api.BookCopyParam
+	err = lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	copyResponse, err := bookCopyService.Create(ctx, token, input, bookID)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to create book copy", err)
+		return
+	}
+
+	lib.Success(w, "book copy successfully created", copyResponse)
+}
+
+func HandlerBookCopyUpdate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid copy id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	var input api.BookCopyParam
+	err = lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	copyResponse, err := bookCopyService.Update(ctx, token, input, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to update book copy", err)
+		return
+	}
+
+	lib.Success(w, "book copy successfully updated", copyResponse)
+}
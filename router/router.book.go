@@ -16,7 +16,7 @@ func HandlerBookList(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	res, err := lib.ParseQueryParam(ctx, r)
 	if err != nil {
-		http.Error(w, "invalid query parameter", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid query parameter", err)
 		return
 	}
 
@@ -27,12 +27,12 @@ func HandlerBookList(w http.ResponseWriter, r *http.Request) {
 	if startDateStr != "" && endDateStr != "" {
 		startDate, err := time.Parse("2006-01-02", startDateStr)
 		if err != nil {
-			http.Error(w, "invalid start_date format, use YYYY-MM-DD", http.StatusBadRequest)
+			lib.Error(w, http.StatusBadRequest, "invalid start_date format, use YYYY-MM-DD", nil)
 			return
 		}
 		endDate, err := time.Parse("2006-01-02", endDateStr)
 		if err != nil {
-			http.Error(w, "invalid end_date format, use YYYY-MM-DD", http.StatusBadRequest)
+			lib.Error(w, http.StatusBadRequest, "invalid end_date format, use YYYY-MM-DD", nil)
 			return
 		}
 
@@ -42,13 +42,25 @@ func HandlerBookList(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	bookResponse, err := bookService.List(ctx, res, dateFilter)
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	bookResponse, pagination, err := bookService.List(ctx, token, res, dateFilter)
 	if err != nil {
 		lib.Error(w, http.StatusInternalServerError, "failed to retrieve book", err)
 		return
 	}
 
-	lib.Success(w, "success to retrieve book list", bookResponse)
+	lib.SuccessList(w, r, "success to retrieve book list", bookResponse, pagination)
 }
 
 func HandlerBookDetail(w http.ResponseWriter, r *http.Request) {
@@ -58,11 +70,23 @@ func HandlerBookDetail(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid book id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid book id", nil)
 		return
 	}
 
-	bookResponse, err := bookService.Detail(ctx, id)
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	bookResponse, err := bookService.Detail(ctx, token, id)
 	if err != nil {
 		lib.Error(w, http.StatusInternalServerError, "failed to retrieve detail on book", err)
 		return
@@ -76,20 +100,20 @@ func HandlerBookCreate(w http.ResponseWriter, r *http.Request) {
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusInternalServerError)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.BookParam
 	err := lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -109,26 +133,26 @@ func HandlerBookUpdate(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid book id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid book id", nil)
 		return
 	}
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusInternalServerError)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.BookParam
 	err = lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body"+err.Error(), http.StatusInternalServerError)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -140,3 +164,67 @@ func HandlerBookUpdate(w http.ResponseWriter, r *http.Request) {
 
 	lib.Success(w, "book successfully updated", bookResponse)
 }
+
+func HandlerBookDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid book id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	err = bookService.Delete(ctx, token, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to delete book", err)
+		return
+	}
+
+	lib.Success(w, "book successfully deleted", nil)
+}
+
+func HandlerBookRestore(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid book id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	bookResponse, err := bookService.Restore(ctx, token, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to restore book", err)
+		return
+	}
+
+	lib.Success(w, "book successfully restored", bookResponse)
+}
@@ -12,14 +12,19 @@ import (
 
 func HandlerCategoriesList(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	res, err := lib.ParseQueryParam(ctx, r)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid query parameter", err)
+		return
+	}
 
-	categoriesResponse, err := categoriesService.List(ctx)
+	categoriesResponse, pagination, err := categoriesService.List(ctx, res)
 	if err != nil {
 		lib.Error(w, http.StatusBadRequest, "failed to retrieve categories list", err)
 		return
 	}
 
-	lib.Success(w, "success to retrieve categories list", categoriesResponse)
+	lib.SuccessList(w, r, "success to retrieve categories list", categoriesResponse, pagination)
 }
 
 func HandlerCategoryDetail(w http.ResponseWriter, r *http.Request) {
@@ -29,7 +34,7 @@ func HandlerCategoryDetail(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid category id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid category id", nil)
 		return
 	}
 
@@ -47,20 +52,20 @@ func HandlerCategoryCreate(w http.ResponseWriter, r *http.Request) {
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusInternalServerError)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.CategoryParam
 	err := lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -80,26 +85,26 @@ func HandlerCategoryUpdate(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid category id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid category id", nil)
 		return
 	}
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusInternalServerError)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.CategoryParam
 	err = lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -111,3 +116,67 @@ func HandlerCategoryUpdate(w http.ResponseWriter, r *http.Request) {
 
 	lib.Success(w, "category updated successfully", categoryResponse)
 }
+
+func HandlerCategoryDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid category id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	err = categoriesService.Delete(ctx, token, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to delete category", err)
+		return
+	}
+
+	lib.Success(w, "category successfully deleted", nil)
+}
+
+func HandlerCategoryRestore(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid category id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	categoryResponse, err := categoriesService.Restore(ctx, token, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to restore category", err)
+		return
+	}
+
+	lib.Success(w, "category successfully restored", categoryResponse)
+}
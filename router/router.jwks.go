@@ -0,0 +1,16 @@
+package router
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// HandlerJWKS serves the public keys access tokens can be verified with. The
+// body is a plain JWK set, not the usual response envelope, so standard JWT
+// libraries can read it directly.
+func HandlerJWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(jwtService.JWKS())
+}
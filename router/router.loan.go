@@ -16,7 +16,7 @@ func HandlerListLoan(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	res, err := lib.ParseQueryParam(ctx, r)
 	if err != nil {
-		http.Error(w, "invalid query parameter", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid query parameter", err)
 		return
 	}
 
@@ -27,12 +27,12 @@ func HandlerListLoan(w http.ResponseWriter, r *http.Request) {
 	if startDateStr != "" && endDateStr != "" {
 		startDate, err := time.Parse("2006-01-02", startDateStr)
 		if err != nil {
-			http.Error(w, "invalid start_date format, use YYYY-MM-DD", http.StatusBadRequest)
+			lib.Error(w, http.StatusBadRequest, "invalid start_date format, use YYYY-MM-DD", nil)
 			return
 		}
 		endDate, err := time.Parse("2006-01-02", endDateStr)
 		if err != nil {
-			http.Error(w, "invalid end_date format, use YYYY-MM-DD", http.StatusBadRequest)
+			lib.Error(w, http.StatusBadRequest, "invalid end_date format, use YYYY-MM-DD", nil)
 			return
 		}
 
@@ -42,13 +42,13 @@ func HandlerListLoan(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	loanResponse, err := loanService.List(ctx, res, dateFilter)
+	loanResponse, pagination, err := loanService.List(ctx, res, dateFilter)
 	if err != nil {
 		lib.Error(w, http.StatusInternalServerError, "failed to retrieve loan list", err)
 		return
 	}
 
-	lib.Success(w, "success to retrieve loan list", loanResponse)
+	lib.SuccessList(w, r, "success to retrieve loan list", loanResponse, pagination)
 }
 
 func HandlerLoansDetail(w http.ResponseWriter, r *http.Request) {
@@ -58,7 +58,7 @@ func HandlerLoansDetail(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid loan id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid loan id", nil)
 		return
 	}
 
@@ -76,20 +76,20 @@ func HandlerLoanCreate(w http.ResponseWriter, r *http.Request) {
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusInternalServerError)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.LoansParam
 	err := lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -109,26 +109,26 @@ func HandlerLoansUpdate(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid loan id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid loan id", nil)
 		return
 	}
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusInternalServerError)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.ReturnParam
 	err = lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -140,3 +140,67 @@ func HandlerLoansUpdate(w http.ResponseWriter, r *http.Request) {
 
 	lib.Success(w, "loan successfully returned", loanResponse)
 }
+
+func HandlerLoanRenew(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid loan id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	loanResponse, err := loanService.Renew(ctx, token, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to renew loan", err)
+		return
+	}
+
+	lib.Success(w, "loan successfully renewed", loanResponse)
+}
+
+func HandlerLoanOverdueList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	overdueResponse, err := loanService.ListOverdue(ctx)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retrieve overdue loans", err)
+		return
+	}
+
+	lib.Success(w, "success to retrieve overdue loans", overdueResponse)
+}
+
+func HandlerMemberBalance(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	memberID, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid member id", nil)
+		return
+	}
+
+	balanceResponse, err := loanService.MemberBalance(ctx, memberID)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retrieve member balance", err)
+		return
+	}
+
+	lib.Success(w, "success to retrieve member balance", balanceResponse)
+}
@@ -0,0 +1,118 @@
+package router
+
+import (
+	"app-bookstore/api"
+	"app-bookstore/lib"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func HandlerLoanPolicyList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	res, err := lib.ParseQueryParam(ctx, r)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid query parameter", err)
+		return
+	}
+
+	policyResponse, pagination, err := loanPolicyService.List(ctx, res)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retrieve loan policy", err)
+		return
+	}
+
+	lib.SuccessList(w, r, "success to retrieve loan policy", policyResponse, pagination)
+}
+
+func HandlerLoanPolicyDetail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid loan policy id", nil)
+		return
+	}
+
+	policyResponse, err := loanPolicyService.Detail(ctx, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retrieve loan policy detail", err)
+		return
+	}
+
+	lib.Success(w, "success to retrieve detail on loan policy", policyResponse)
+}
+
+func HandlerLoanPolicyCreate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	var input api.LoanPolicyParam
+	err := lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	policyResponse, err := loanPolicyService.Create(ctx, token, input)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to create loan policy", err)
+		return
+	}
+
+	lib.Success(w, "loan policy created", policyResponse)
+}
+
+func HandlerLoanPolicyUpdate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid loan policy id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	var input api.LoanPolicyParam
+	err = lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	policyResponse, err := loanPolicyService.Update(ctx, token, input, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to update loan policy", err)
+		return
+	}
+
+	lib.Success(w, "loan policy successfully updated", policyResponse)
+}
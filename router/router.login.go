@@ -13,7 +13,7 @@ func HandlerLogin(w http.ResponseWriter, r *http.Request) {
 	var input api.UserParam
 	err := lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body"+err.Error(), http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -26,25 +26,44 @@ func HandlerLogin(w http.ResponseWriter, r *http.Request) {
 	lib.Success(w, "user login successfully", loginResponse)
 }
 
+func HandlerTokenRefresh(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var input api.RefreshTokenParam
+	err := lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	tokenResponse, err := userService.Refresh(ctx, input)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to refresh token", err)
+		return
+	}
+
+	lib.Success(w, "token refreshed successfully", tokenResponse)
+}
+
 func HandlerChangePassword(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "Authorization header is required", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "authorization header is required", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "Invalid token", http.StatusUnauthorized)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.ChangePasswordParam
 	err := lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -62,13 +81,13 @@ func HandlerLogout(w http.ResponseWriter, r *http.Request) {
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusInternalServerError)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
@@ -80,3 +99,27 @@ func HandlerLogout(w http.ResponseWriter, r *http.Request) {
 
 	lib.Success(w, "User successfully logout", nil)
 }
+
+func HandlerMyPermissions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	permissionsResponse, err := userService.Permissions(ctx, token)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retrieve permissions", err)
+		return
+	}
+
+	lib.Success(w, "success to retrieve permissions", permissionsResponse)
+}
@@ -0,0 +1,15 @@
+package router
+
+import (
+	"app-bookstore/lib"
+	"net/http"
+)
+
+func HandlerPermissionCacheStats(w http.ResponseWriter, r *http.Request) {
+	if permissionCache == nil {
+		lib.Error(w, http.StatusNotFound, "permission cache is disabled", nil)
+		return
+	}
+
+	lib.Success(w, "success to retrieve permission cache stats", permissionCache.Stats())
+}
@@ -0,0 +1,211 @@
+package router
+
+import (
+	"app-bookstore/api"
+	"app-bookstore/lib"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func HandlerVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var input api.VerifyEmailParam
+	err := lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	response, err := userService.VerifyEmail(ctx, input)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to verify email", err)
+		return
+	}
+
+	lib.Success(w, "email verified", response)
+}
+
+func HandlerResendVerification(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var input api.ResendVerificationParam
+	err := lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	response, err := userService.ResendVerification(ctx, input)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to resend verification email", err)
+		return
+	}
+
+	lib.Success(w, "verification email requested", response)
+}
+
+func HandlerMe(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	profile, err := userService.Me(ctx, token)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retrieve profile", err)
+		return
+	}
+
+	lib.Success(w, "success to retrieve profile", profile)
+}
+
+func HandlerMeUpdate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	var input api.ProfileParam
+	err := lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	profile, err := userService.UpdateMe(ctx, token, input)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to update profile", err)
+		return
+	}
+
+	lib.Success(w, "profile successfully updated", profile)
+}
+
+func HandlerMeClose(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	var input api.CloseAccountParam
+	err := lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	err = userService.CloseMe(ctx, token, input)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to close account", err)
+		return
+	}
+
+	lib.Success(w, "account successfully closed", nil)
+}
+
+func HandlerUserSuspend(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	vars := mux.Vars(r)
+	userID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid user id", nil)
+		return
+	}
+
+	var input api.SuspendUserParam
+	err = lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	user, err := userService.Suspend(ctx, token, userID, input)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to suspend user", err)
+		return
+	}
+
+	lib.Success(w, "user successfully suspended", user)
+}
+
+func HandlerUserReactivate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	vars := mux.Vars(r)
+	userID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid user id", nil)
+		return
+	}
+
+	var input api.ReactivateUserParam
+	err = lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	user, err := userService.Reactivate(ctx, token, userID, input)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to reactivate user", err)
+		return
+	}
+
+	lib.Success(w, "user successfully reactivated", user)
+}
@@ -16,7 +16,7 @@ func HandlerPublisherList(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	res, err := lib.ParseQueryParam(ctx, r)
 	if err != nil {
-		http.Error(w, "invalid query parameter", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid query parameter", err)
 		return
 	}
 
@@ -28,12 +28,12 @@ func HandlerPublisherList(w http.ResponseWriter, r *http.Request) {
 		if startDateStr != "" && endDateStr != "" {
 			startDate, err := time.Parse("2006-01-02", startDateStr)
 			if err != nil {
-				http.Error(w, "invalid start_date format, use YYYY-MM-DD", http.StatusBadRequest)
+				lib.Error(w, http.StatusBadRequest, "invalid start_date format, use YYYY-MM-DD", nil)
 				return
 			}
 			endDate, err := time.Parse("2006-01-02", endDateStr)
 			if err != nil {
-				http.Error(w, "invalid end_date format, use YYYY-MM-DD", http.StatusBadRequest)
+				lib.Error(w, http.StatusBadRequest, "invalid end_date format, use YYYY-MM-DD", nil)
 				return
 			}
 
@@ -44,13 +44,13 @@ func HandlerPublisherList(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	publisherResponse, err := publisherService.List(ctx, res, dateFilter)
+	publisherResponse, pagination, err := publisherService.List(ctx, res, dateFilter)
 	if err != nil {
 		lib.Error(w, http.StatusInternalServerError, "failed to retrieve publisher", err)
 		return
 	}
 
-	lib.Success(w, "success to retreive publisher list", publisherResponse)
+	lib.SuccessList(w, r, "success to retreive publisher list", publisherResponse, pagination)
 }
 
 func HandlerPublishDetail(w http.ResponseWriter, r *http.Request) {
@@ -60,7 +60,7 @@ func HandlerPublishDetail(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid publisher id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid publisher id", nil)
 		return
 	}
 
@@ -78,20 +78,20 @@ func HandlerPublisherCreate(w http.ResponseWriter, r *http.Request) {
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusInternalServerError)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.PublisherParam
 	err := lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -111,26 +111,26 @@ func HandlerPubliserUpdate(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid publisher id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid publisher id", nil)
 		return
 	}
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusInternalServerError)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.PublisherParam
 	err = lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -142,3 +142,67 @@ func HandlerPubliserUpdate(w http.ResponseWriter, r *http.Request) {
 
 	lib.Success(w, "publisher successfully updated", publisherResponse)
 }
+
+func HandlerPublisherDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid publisher id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	err = publisherService.Delete(ctx, token, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to delete publisher", err)
+		return
+	}
+
+	lib.Success(w, "publisher successfully deleted", nil)
+}
+
+func HandlerPublisherRestore(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid publisher id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	publisherResponse, err := publisherService.Restore(ctx, token, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to restore publisher", err)
+		return
+	}
+
+	lib.Success(w, "publisher successfully restored", publisherResponse)
+}
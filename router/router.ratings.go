@@ -12,14 +12,19 @@ import (
 
 func HandlerRatingList(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	res, err := lib.ParseQueryParam(ctx, r)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid query parameter", err)
+		return
+	}
 
-	ratingResponse, err := ratingService.List(ctx)
+	ratingResponse, pagination, err := ratingService.List(ctx, res)
 	if err != nil {
 		lib.Error(w, http.StatusInternalServerError, "failed to retrieve rating list", err)
 		return
 	}
 
-	lib.Success(w, "success to retrieve rating list", ratingResponse)
+	lib.SuccessList(w, r, "success to retrieve rating list", ratingResponse, pagination)
 }
 
 func HandlerRatingDetail(w http.ResponseWriter, r *http.Request) {
@@ -29,7 +34,7 @@ func HandlerRatingDetail(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid rating id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid rating id", nil)
 		return
 	}
 
@@ -47,20 +52,20 @@ func HandlerRatingCreate(w http.ResponseWriter, r *http.Request) {
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusInternalServerError)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.RatingParam
 	err := lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -80,26 +85,26 @@ func HandlerRatingUpdate(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid rating id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid rating id", nil)
 		return
 	}
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusInternalServerError)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.RatingUpdateParam
 	err = lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body"+err.Error(), http.StatusInternalServerError)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -111,3 +116,67 @@ func HandlerRatingUpdate(w http.ResponseWriter, r *http.Request) {
 
 	lib.Success(w, "rating successfully updated", ratingResponse)
 }
+
+func HandlerRatingDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid rating id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	err = ratingService.Delete(ctx, token, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to delete rating", err)
+		return
+	}
+
+	lib.Success(w, "rating successfully deleted", nil)
+}
+
+func HandlerRatingRestore(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid rating id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	ratingResponse, err := ratingService.Restore(ctx, token, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to restore rating", err)
+		return
+	}
+
+	lib.Success(w, "rating successfully restored", ratingResponse)
+}
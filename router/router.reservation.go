@@ -0,0 +1,118 @@
+package router
+
+import (
+	"app-bookstore/api"
+	"app-bookstore/lib"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func HandlerReservationList(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	reservationResponse, err := reservationService.ListMine(ctx, token)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retrieve reservations", err)
+		return
+	}
+
+	lib.Success(w, "success to retrieve reservations", reservationResponse)
+}
+
+func HandlerBookReservationQueue(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	bookID, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid book id", nil)
+		return
+	}
+
+	reservationResponse, err := reservationService.Queue(ctx, bookID)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retrieve reservation queue", err)
+		return
+	}
+
+	lib.Success(w, "success to retrieve reservation queue", reservationResponse)
+}
+
+func HandlerReservationCreate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	var input api.ReservationParam
+	err := lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	reservationResponse, err := reservationService.Place(ctx, token, input)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to place reservation", err)
+		return
+	}
+
+	lib.Success(w, "reservation successfully placed", reservationResponse)
+}
+
+func HandlerReservationCancel(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid reservation id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	reservationResponse, err := reservationService.Cancel(ctx, token, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to cancel reservation", err)
+		return
+	}
+
+	lib.Success(w, "reservation successfully cancelled", reservationResponse)
+}
@@ -12,7 +12,7 @@ func HandlerRequestResetPassword(w http.ResponseWriter, r *http.Request) {
 	var input api.PasswordResetRequestParam
 	err := lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -44,7 +44,7 @@ func HandlerResetPassword(w http.ResponseWriter, r *http.Request) {
 	var input api.PasswordResetConfigParam
 	err := lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -12,14 +12,19 @@ import (
 
 func HandlerRoleGetAll(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	res, err := lib.ParseQueryParam(ctx, r)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid query parameter", err)
+		return
+	}
 
-	roleResponse, err := roleService.List(ctx)
+	roleResponse, pagination, err := roleService.List(ctx, res)
 	if err != nil {
 		lib.Error(w, http.StatusBadRequest, "failed to retreive role", err)
 		return
 	}
 
-	lib.Success(w, "success to retreive role", roleResponse)
+	lib.SuccessList(w, r, "success to retreive role", roleResponse, pagination)
 }
 
 func HandlerRolesDetail(w http.ResponseWriter, r *http.Request) {
@@ -29,7 +34,7 @@ func HandlerRolesDetail(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid role id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid role id", nil)
 		return
 	}
 
@@ -47,20 +52,20 @@ func HandlerRoleCreate(w http.ResponseWriter, r *http.Request) {
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.RoleParam
 	err := lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -81,26 +86,26 @@ func HandlerRoleUpdate(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid role id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid role id", nil)
 		return
 	}
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid or expired token", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid or expired token", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusInternalServerError)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.RoleParam
 	err = lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body"+err.Error(), http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -112,3 +117,87 @@ func HandlerRoleUpdate(w http.ResponseWriter, r *http.Request) {
 
 	lib.Success(w, "role successuly updated", roleResponse)
 }
+
+func HandlerRoleDelete(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid role id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	err = roleService.Delete(ctx, token, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to delete role", err)
+		return
+	}
+
+	lib.Success(w, "role successfully deleted", nil)
+}
+
+func HandlerRoleRestore(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid role id", nil)
+		return
+	}
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	roleResponse, err := roleService.Restore(ctx, token, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to restore role", err)
+		return
+	}
+
+	lib.Success(w, "role successfully restored", roleResponse)
+}
+
+func HandlerRoleEffectivePermissions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	idStr := vars["id"]
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid role id", nil)
+		return
+	}
+
+	permissionsResponse, err := roleService.EffectivePermissions(ctx, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retrieve effective permissions", err)
+		return
+	}
+
+	lib.Success(w, "success to retrieve effective permissions", permissionsResponse)
+}
@@ -0,0 +1,176 @@
+package router
+
+import (
+	"app-bookstore/lib"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func HandlerMySessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	sessions, err := sessionService.MySessions(ctx, token)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retrieve sessions", err)
+		return
+	}
+
+	lib.Success(w, "success to retrieve sessions", sessions)
+}
+
+func HandlerMySessionRevoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := uuid.Parse(vars["id"])
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid session id", nil)
+		return
+	}
+
+	err = sessionService.RevokeMySession(ctx, token, id)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to revoke session", err)
+		return
+	}
+
+	lib.Success(w, "session successfully revoked", nil)
+}
+
+func HandlerMyOtherSessionsRevoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	revoked, err := sessionService.RevokeMyOtherSessions(ctx, token)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to revoke sessions", err)
+		return
+	}
+
+	lib.Success(w, "other sessions successfully revoked", revoked)
+}
+
+func HandlerUserSessions(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	vars := mux.Vars(r)
+	userID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid user id", nil)
+		return
+	}
+
+	sessions, err := sessionService.UserSessions(ctx, userID)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retrieve sessions", err)
+		return
+	}
+
+	lib.Success(w, "success to retrieve sessions", sessions)
+}
+
+func HandlerUserSessionRevoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	vars := mux.Vars(r)
+	userID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid user id", nil)
+		return
+	}
+
+	sessionID, err := uuid.Parse(vars["session_id"])
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid session id", nil)
+		return
+	}
+
+	err = sessionService.RevokeUserSession(ctx, token, userID, sessionID)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to revoke session", err)
+		return
+	}
+
+	lib.Success(w, "session successfully revoked", nil)
+}
+
+func HandlerUserSessionsRevoke(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	vars := mux.Vars(r)
+	userID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid user id", nil)
+		return
+	}
+
+	revoked, err := sessionService.RevokeUserSessions(ctx, token, userID)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to revoke sessions", err)
+		return
+	}
+
+	lib.Success(w, "user sessions successfully revoked", revoked)
+}
@@ -0,0 +1,240 @@
+package router
+
+import (
+	"app-bookstore/api"
+	"app-bookstore/lib"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+)
+
+func HandlerLoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var input api.ChallengeCodeParam
+	err := lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	loginResponse, err := userService.VerifyLogin(ctx, input)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to verify two-factor code", err)
+		return
+	}
+
+	lib.Success(w, "user login successfully", loginResponse)
+}
+
+func HandlerLoginTwoFactorEnroll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var input api.ChallengeParam
+	err := lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	enrollResponse, err := userService.EnrollLogin(ctx, input)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to start two-factor enrollment", err)
+		return
+	}
+
+	lib.Success(w, "two-factor enrollment started", enrollResponse)
+}
+
+func HandlerLoginTwoFactorConfirm(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var input api.ChallengeCodeParam
+	err := lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	confirmResponse, err := userService.ConfirmEnrollLogin(ctx, input)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to confirm two-factor enrollment", err)
+		return
+	}
+
+	lib.Success(w, "two-factor enabled, user login successfully", confirmResponse)
+}
+
+func HandlerMyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	status, err := userService.TwoFactorStatus(ctx, token)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retrieve two-factor status", err)
+		return
+	}
+
+	lib.Success(w, "success to retrieve two-factor status", status)
+}
+
+func HandlerMyTwoFactorEnroll(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	enrollResponse, err := userService.EnrollTwoFactor(ctx, token)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to start two-factor enrollment", err)
+		return
+	}
+
+	lib.Success(w, "two-factor enrollment started", enrollResponse)
+}
+
+func HandlerMyTwoFactorConfirm(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	var input api.TwoFactorCodeParam
+	err := lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	confirmResponse, err := userService.ConfirmTwoFactor(ctx, token, input)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to confirm two-factor enrollment", err)
+		return
+	}
+
+	lib.Success(w, "two-factor successfully enabled", confirmResponse)
+}
+
+func HandlerMyRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	var input api.TwoFactorCodeParam
+	err := lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	codesResponse, err := userService.RegenerateRecoveryCodes(ctx, token, input)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to regenerate recovery codes", err)
+		return
+	}
+
+	lib.Success(w, "recovery codes successfully regenerated", codesResponse)
+}
+
+func HandlerMyTwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	var input api.TwoFactorCodeParam
+	err := lib.ParseBody(ctx, r, &input)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
+		return
+	}
+
+	err = userService.DisableTwoFactor(ctx, token, input)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to disable two-factor", err)
+		return
+	}
+
+	lib.Success(w, "two-factor successfully disabled", nil)
+}
+
+func HandlerUserTwoFactorReset(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	vars := mux.Vars(r)
+	userID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid user id", nil)
+		return
+	}
+
+	err = userService.ResetTwoFactor(ctx, token, userID)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to reset two-factor", err)
+		return
+	}
+
+	lib.Success(w, "two-factor successfully reset", nil)
+}
@@ -5,14 +5,18 @@ import (
 	"app-bookstore/lib"
 	"app-bookstore/model"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
 )
 
 func HandlerUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	res, err := lib.ParseQueryParam(ctx, r)
 	if err != nil {
-		http.Error(w, "invalid query parameter", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid query parameter", err)
 		return
 	}
 
@@ -23,12 +27,12 @@ func HandlerUser(w http.ResponseWriter, r *http.Request) {
 	if startDateStr != "" && endDateStr != "" {
 		startDate, err := time.Parse("2006-01-02", startDateStr)
 		if err != nil {
-			http.Error(w, "invalid start_date format, use YYYY-MM-DD", http.StatusBadRequest)
+			lib.Error(w, http.StatusBadRequest, "invalid start_date format, use YYYY-MM-DD", nil)
 			return
 		}
 		endDate, err := time.Parse("2006-01-02", endDateStr)
 		if err != nil {
-			http.Error(w, "invalid end_date format, use YYYY-MM-DD", http.StatusBadRequest)
+			lib.Error(w, http.StatusBadRequest, "invalid end_date format, use YYYY-MM-DD", nil)
 			return
 		}
 
@@ -38,22 +42,22 @@ func HandlerUser(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	userResponse, err := userService.List(ctx, res, dateFilter)
+	userResponse, pagination, err := userService.List(ctx, res, dateFilter)
 	if err != nil {
 		lib.Error(w, http.StatusBadRequest, "failed to retrieve user", err)
 		return
 	}
 
-	lib.Success(w, "success to retrieve user", userResponse)
+	lib.SuccessList(w, r, "success to retrieve user", userResponse, pagination)
 }
 
 func HandlerRegisterUser(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	var input api.UserParam
+	var input api.RegisterParam
 	err := lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "Failed to parse user"+err.Error(), http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "failed to parse user", err)
 		return
 	}
 
@@ -65,3 +69,34 @@ func HandlerRegisterUser(w http.ResponseWriter, r *http.Request) {
 
 	lib.Success(w, "user register successfully", userResponse)
 }
+
+func HandlerUserUnlock(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	vars := mux.Vars(r)
+	userID, err := uuid.Parse(vars["id"])
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid user id", nil)
+		return
+	}
+
+	err = userService.Unlock(ctx, token, userID)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to unlock user", err)
+		return
+	}
+
+	lib.Success(w, "user successfully unlocked", nil)
+}
@@ -12,14 +12,19 @@ import (
 
 func HandlerUserRequestList(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	res, err := lib.ParseQueryParam(ctx, r)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid query parameter", err)
+		return
+	}
 
-	userRequest, err := userRequestService.ListUserRequest(ctx)
+	userRequest, pagination, err := userRequestService.ListUserRequest(ctx, res)
 	if err != nil {
 		lib.Error(w, http.StatusInternalServerError, "failed to retreive user request", err)
 		return
 	}
 
-	lib.Success(w, "successfully to retreive user request", userRequest)
+	lib.SuccessList(w, r, "successfully to retreive user request", userRequest, pagination)
 }
 
 func HandlerUserRequestDetail(w http.ResponseWriter, r *http.Request) {
@@ -29,7 +34,7 @@ func HandlerUserRequestDetail(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid user request id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid user request id", nil)
 		return
 	}
 
@@ -47,20 +52,20 @@ func HandlerUserRequest(w http.ResponseWriter, r *http.Request) {
 
 	authHeader := r.Header.Get("authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.UserRequestParam
 	err := lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -80,26 +85,26 @@ func HandlerUserRequestUpdate(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid request id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request id", nil)
 		return
 	}
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid or expired token", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid or expired token", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusInternalServerError)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.UserRequestUpdateParam
 	err = lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body"+err.Error(), http.StatusInternalServerError)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -109,5 +114,35 @@ func HandlerUserRequestUpdate(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	lib.Success(w, "role successfully updated", userReqUpdate)
+	lib.Success(w, "user request successfully reviewed", userReqUpdate)
+}
+
+func HandlerMyUserRequests(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	authHeader := r.Header.Get("Authorization")
+	if authHeader == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
+		return
+	}
+
+	token := strings.TrimPrefix(authHeader, "Bearer ")
+	if token == "" {
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
+		return
+	}
+
+	res, err := lib.ParseQueryParam(ctx, r)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid query parameter", err)
+		return
+	}
+
+	userRequest, pagination, err := userRequestService.MyRequests(ctx, token, res)
+	if err != nil {
+		lib.Error(w, http.StatusInternalServerError, "failed to retreive user request", err)
+		return
+	}
+
+	lib.SuccessList(w, r, "successfully to retreive user request", userRequest, pagination)
 }
@@ -12,13 +12,18 @@ import (
 
 func HandlerUserRole(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
+	res, err := lib.ParseQueryParam(ctx, r)
+	if err != nil {
+		lib.Error(w, http.StatusBadRequest, "invalid query parameter", err)
+		return
+	}
 
-	userRoleResponse, err := userRolesService.ListUserRole(ctx)
+	userRoleResponse, pagination, err := userRolesService.ListUserRole(ctx, res)
 	if err != nil {
 		lib.Error(w, http.StatusInternalServerError, "failed to retrieve user roles", err)
 		return
 	}
-	lib.Success(w, "success to retreive user roles", userRoleResponse)
+	lib.SuccessList(w, r, "success to retreive user roles", userRoleResponse, pagination)
 }
 
 func HandlerUserRolesDetail(w http.ResponseWriter, r *http.Request) {
@@ -28,7 +33,7 @@ func HandlerUserRolesDetail(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid user role id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid user role id", nil)
 		return
 	}
 
@@ -46,20 +51,20 @@ func HandlerUserRolesCreate(w http.ResponseWriter, r *http.Request) {
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.UserRolesParam
 	err := lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 
@@ -79,26 +84,26 @@ func HandlerUserRolesUpdate(w http.ResponseWriter, r *http.Request) {
 	idStr := vars["id"]
 	id, err := uuid.Parse(idStr)
 	if err != nil {
-		http.Error(w, "invalid user role id", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid user role id", nil)
 		return
 	}
 
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		http.Error(w, "invalid authorization header", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid authorization header", nil)
 		return
 	}
 
 	token := strings.TrimPrefix(authHeader, "Bearer ")
 	if token == "" {
-		http.Error(w, "invalid token", http.StatusBadRequest)
+		lib.Error(w, http.StatusUnauthorized, "invalid token", nil)
 		return
 	}
 
 	var input api.UserRolesParam
 	err = lib.ParseBody(ctx, r, &input)
 	if err != nil {
-		http.Error(w, "invalid request body", http.StatusBadRequest)
+		lib.Error(w, http.StatusBadRequest, "invalid request body", err)
 		return
 	}
 